@@ -3,10 +3,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"html/template"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -14,15 +20,22 @@ import (
 	"github.com/mengbin92/example/config"
 	"github.com/mengbin92/example/lib/cache"
 	"github.com/mengbin92/example/lib/db"
+	"github.com/mengbin92/example/lib/db/driver"
 	"github.com/mengbin92/example/lib/logger"
 	"github.com/mengbin92/example/lib/middleware"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take after a shutdown signal.
+const shutdownTimeout = 10 * time.Second
+
 // Execute is the main entry point for the command-line interface.
-// It loads configuration and starts the HTTP server.
+// It loads configuration and starts the HTTP server, or runs the migrate or seed
+// subcommand when the first argument is "migrate" or "seed".
 //
 // The function will panic if critical initialization steps fail:
 //   - Configuration loading fails
@@ -30,24 +43,78 @@ import (
 func Execute() {
 	// Load configuration
 	config.LoadConfig()
+	log.Debug("Configuration loaded", "settings", config.Redacted())
+
+	// "example migrate ..." runs schema migrations instead of the server.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runMigrate(ctx, os.Args[2:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "example seed ..." applies seed data instead of the server.
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runSeed(ctx, os.Args[2:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	run()
 }
 
-// run initializes the database connection and starts the HTTP server.
+// run initializes the database connections and starts the HTTP server.
+// On SIGINT or SIGTERM the server stops accepting requests, finishes in-flight ones
+// and the database connections are closed.
 //
-// The function will panic if:
+// The function will exit the program if:
 //   - Database initialization fails
 //   - Server startup fails
+//
+// A SIGINT or SIGTERM received while connecting to the database exits cleanly.
 func run() {
-	dbInstance := loadDB()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbInstance := loadDB(ctx)
 	engine := setEngine(dbInstance)
 
-	addr := fmt.Sprintf(":%d", viper.GetInt("server.port"))
-	if err := engine.Run(addr); err != nil {
-		log.Error("Failed to run server: ", err)
-		fmt.Fprintf(os.Stderr, "Failed to run server: %v\n", err)
-		os.Exit(1)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", viper.GetInt("server.port")),
+		Handler: engine,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Failed to run server: ", err)
+			fmt.Fprintf(os.Stderr, "Failed to run server: %v\n", err)
+			_ = db.Close()
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to shut down server: %v\n", err)
+		}
+		cancel()
+	}
+
+	if err := db.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to close database: %v\n", err)
 	}
 }
 
@@ -63,24 +130,175 @@ func loadRedis(name string, cfg *cache.RedisConfig) *redis.Client {
 	return cache.GetRedisClient(name, cfg)
 }
 
-// loadDB initializes the database connection and returns the GORM database instance.
+// redisConfig builds the Redis configuration from the "redis" config section.
+// Timeouts in the config file are expressed in seconds.
+//
+// Returns:
+//   - *cache.RedisConfig: The Redis configuration
+func redisConfig() *cache.RedisConfig {
+	return &cache.RedisConfig{
+		Addr:         viper.GetString("redis.addr"),
+		Password:     viper.GetString("redis.password"),
+		DB:           viper.GetInt("redis.db"),
+		PoolSize:     viper.GetInt("redis.pool_size"),
+		DialTimeout:  time.Duration(viper.GetInt("redis.dial_timeout")) * time.Second,
+		ReadTimeout:  time.Duration(viper.GetInt("redis.read_timeout")) * time.Second,
+		WriteTimeout: time.Duration(viper.GetInt("redis.write_timeout")) * time.Second,
+	}
+}
+
+// dbConfig builds a database configuration from a config section such as "database"
+// or "databases.analytics". Durations use Go duration strings such as "30s".
+//
+// Parameters:
+//   - key: The config section of the connection
+//
+// Returns:
+//   - *db.Config: The database configuration
+//   - error: Error if the dsn params are not a valid query string
+func dbConfig(key string) (*db.Config, error) {
+	dsn, err := dsnConfig(key + ".dsn")
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s.dsn", key)
+	}
+	return &db.Config{
+		Driver:              viper.GetString(key + ".driver"),
+		Source:              viper.GetString(key + ".source"),
+		DSN:                 dsn,
+		MaxIdleConns:        viper.GetInt(key + ".max_idle_conns"),
+		MaxOpenConns:        viper.GetInt(key + ".max_open_conns"),
+		ConnMaxLifetime:     viper.GetDuration(key + ".conn_max_lifetime"),
+		ConnMaxIdleTime:     viper.GetDuration(key + ".conn_max_idle_time"),
+		MaxRetries:          viper.GetInt(key + ".max_retries"),
+		RetryBackoff:        viper.GetDuration(key + ".retry_backoff"),
+		MaxBackoff:          viper.GetDuration(key + ".max_backoff"),
+		ConnectTimeout:      viper.GetDuration(key + ".connect_timeout"),
+		LogLevel:            viper.GetString(key + ".log_level"),
+		Audit:               viper.GetBool(key + ".audit.enabled"),
+		AuditTable:          viper.GetString(key + ".audit.table"),
+		AuditAutoMigrate:    viper.GetBool(key + ".audit.auto_migrate"),
+		DegradedStart:       viper.GetBool(key + ".degraded_start"),
+		HealthCheckInterval: viper.GetDuration(key + ".health_check_interval"),
+		Metrics:             viper.GetBool(key + ".metrics"),
+	}, nil
+}
+
+// dsnConfig builds the structured connection settings from a "dsn" config section.
+// Params are a query string such as "charset=utf8mb4&parseTime=True", as viper
+// lowercases map keys and driver parameter names are case-sensitive.
+//
+// Parameters:
+//   - key: The config section, e.g. "database.dsn"
 //
 // Returns:
-//   - *gorm.DB: The GORM database instance
+//   - *db.DSNConfig: The settings, nil if the section is not set
+//   - error: Error if params is not a valid query string
+func dsnConfig(key string) (*db.DSNConfig, error) {
+	if !viper.IsSet(key) {
+		return nil, nil
+	}
+	query, err := url.ParseQuery(viper.GetString(key + ".params"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid params")
+	}
+	params := make(map[string]string, len(query))
+	for k, v := range query {
+		params[k] = v[len(v)-1]
+	}
+	return &db.DSNConfig{
+		Host:            viper.GetString(key + ".host"),
+		Port:            viper.GetInt(key + ".port"),
+		User:            viper.GetString(key + ".user"),
+		Password:        viper.GetString(key + ".password"),
+		PasswordEnv:     viper.GetString(key + ".password_env"),
+		PasswordFile:    viper.GetString(key + ".password_file"),
+		PasswordCommand: viper.GetString(key + ".password_command"),
+		DBName:          viper.GetString(key + ".dbname"),
+		Params:          params,
+		TLS: driver.TLS{
+			Mode:       viper.GetString(key + ".tls.mode"),
+			CAFile:     viper.GetString(key + ".tls.ca_file"),
+			CertFile:   viper.GetString(key + ".tls.cert_file"),
+			KeyFile:    viper.GetString(key + ".tls.key_file"),
+			ServerName: viper.GetString(key + ".tls.server_name"),
+		},
+	}, nil
+}
+
+// initDatabases initializes the default connection and the named connections.
+// Named connections are initialized in name order so startup is deterministic.
 //
-// The function will panic if:
+// Parameters:
+//   - ctx: Context bounding the connect phase
+//
+// Returns:
+//   - error: Error of the first connection that fails to initialize
+func initDatabases(ctx context.Context) error {
+	cfg, err := dbConfig("database")
+	if err != nil {
+		return err
+	}
+	if err := db.Init(ctx, cfg); err != nil {
+		return err
+	}
+	names := make([]string, 0)
+	for name := range viper.GetStringMap("databases") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cfg, err := dbConfig("databases." + name)
+		if err != nil {
+			return err
+		}
+		if err := db.InitNamed(ctx, name, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDB initializes the default database connection from the "database" section and
+// the named connections from the "databases" section, and returns the default one.
+// Named connections are available as db.Get("<name>").
+//
+// Parameters:
+//   - ctx: Context bounding the connect phase
+//
+// Returns:
+//   - *gorm.DB: The default GORM database instance
+//
+// The function will exit the program if:
 //   - Database initialization fails
-//   - Database connection fails
-func loadDB() *gorm.DB {
-	driver := viper.GetString("database.driver")
-	source := viper.GetString("database.source")
+//   - Database connection fails, unless degraded_start is set
+//   - ctx is cancelled while connecting (exits with status 0)
+func loadDB(ctx context.Context) *gorm.DB {
+	err := initDatabases(ctx)
 
-	if err := db.Init(driver, source); err != nil {
+	if err == nil && viper.GetBool("tenancy.enabled") {
+		err = db.InitTenancy(&db.TenancyConfig{
+			Mode:      viper.GetString("tenancy.mode"),
+			Column:    viper.GetString("tenancy.column"),
+			Databases: viper.GetStringMapString("tenancy.databases"),
+		})
+	}
+
+	if err != nil {
+		_ = db.Close()
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Startup interrupted: %v\n", err)
+			os.Exit(0)
+		}
 		log.Error("Failed to connect to database: ", err)
 		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
 		os.Exit(1)
 	}
 
+	for _, name := range db.Names() {
+		if st := db.GetStatus(name); st.State != db.StateUp {
+			log.Warn("Database started degraded", "database", name, "err", st.Err)
+		}
+	}
 	return db.Get()
 }
 
@@ -118,16 +336,107 @@ func setEngine(db *gorm.DB) *gin.Engine {
 	r.Use(middleware.SetDBMiddleware(db))
 	r.Use(middleware.SetLogMiddleware(zapLogger))
 
+	if viper.GetBool("tenancy.enabled") {
+		r.Use(middleware.SetTenantMiddleware(viper.GetBool("tenancy.required"), tenantResolvers()...))
+	}
+
+	if viper.GetBool("idempotency.enabled") {
+		rdb := loadRedis("default", redisConfig())
+		r.Use(middleware.SetIdempotencyMiddleware(rdb, middleware.IdempotencyConfig{
+			Prefix:    viper.GetString("idempotency.prefix"),
+			TTL:       viper.GetDuration("idempotency.ttl"),
+			LockTTL:   viper.GetDuration("idempotency.lock_ttl"),
+			ClaimsKey: viper.GetString("idempotency.claims_key"),
+		}, zapLogger))
+	}
+
 	// Setup routes
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
+	r.GET("/healthz", healthz)
+	// Prometheus metrics of the process, including database queries and connection pools.
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	return r
 }
 
+// healthz reports the health of the database connections. The default connection is
+// critical: the endpoint responds 503 while it is down; a named connection that is down
+// only degrades the service.
+//
+// Parameters:
+//   - c: The gin context
+func healthz(c *gin.Context) {
+	status, code := "healthy", http.StatusOK
+	details := gin.H{}
+	for _, st := range db.Health(c.Request.Context()) {
+		detail := gin.H{
+			"state":      st.State.String(),
+			"since":      st.Since.Format(time.RFC3339),
+			"latency_ms": st.Latency.Milliseconds(),
+			"open_conns": st.Stats.OpenConnections,
+			"in_use":     st.Stats.InUse,
+			"idle":       st.Stats.Idle,
+			"wait_count": st.Stats.WaitCount,
+		}
+		if st.Err != nil {
+			detail["error"] = st.Err.Error()
+		}
+		details[st.Name] = detail
+
+		if st.State != db.StateUp {
+			if st.Name == db.DefaultName {
+				status, code = "unhealthy", http.StatusServiceUnavailable
+			} else if status == "healthy" {
+				status = "degraded"
+			}
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "databases": details})
+}
+
+// tenantResolvers returns the tenant resolvers configured in tenancy.resolvers.
+// The "jwt" resolver reads the claims stored in the gin context by the authentication
+// middleware under tenancy.claims_key.
+//
+// Returns:
+//   - []middleware.TenantResolver: The resolvers in configuration order; the header resolver by default
+func tenantResolvers() []middleware.TenantResolver {
+	names := viper.GetStringSlice("tenancy.resolvers")
+	if len(names) == 0 {
+		names = []string{"header"}
+	}
+
+	resolvers := make([]middleware.TenantResolver, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "header":
+			header := viper.GetString("tenancy.header")
+			if header == "" {
+				header = "X-Tenant-ID"
+			}
+			resolvers = append(resolvers, middleware.TenantFromHeader(header))
+		case "jwt":
+			key, claim := viper.GetString("tenancy.claims_key"), viper.GetString("tenancy.jwt_claim")
+			if key == "" {
+				key = "claims"
+			}
+			if claim == "" {
+				claim = "tenant_id"
+			}
+			resolvers = append(resolvers, middleware.TenantFromClaims(key, claim))
+		case "subdomain":
+			resolvers = append(resolvers, middleware.TenantFromSubdomain(viper.GetString("tenancy.domain")))
+		default:
+			log.Error("Unknown tenant resolver ignored", "resolver", name)
+		}
+	}
+	return resolvers
+}
+
 // formatUnixTime formats a Unix timestamp to a human-readable date-time string.
 //
 // Parameters:
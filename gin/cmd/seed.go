@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mengbin92/example/lib/db"
+	"github.com/mengbin92/example/lib/logger"
+	"github.com/mengbin92/example/lib/seed"
+	"github.com/mengbin92/example/seeds"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// seedUsage describes the seed subcommands.
+const seedUsage = `usage: example seed <command>
+
+commands:
+  run [-env e] [set...]    apply the seed sets of the environment (all by default)
+  list [-env e]            list the seed sets of the environment
+
+the environment defaults to seed.env`
+
+// runSeed executes a seed subcommand.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - args: Arguments after "seed"
+//
+// Returns:
+//   - error: Error if the command is unknown or fails
+func runSeed(ctx context.Context, args []string) error {
+	if len(args) == 0 || (args[0] != "run" && args[0] != "list") {
+		return errors.New(seedUsage)
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	env := fs.String("env", viper.GetString("seed.env"), "environment selecting the seed sets")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	zapLogger := logger.DefaultLogger(viper.GetInt("log.level"), viper.GetString("log.format"))
+	seeder, err := seed.New(seeds.FS(), zapLogger)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SET\tDATABASE\tENVIRONMENTS")
+		for _, s := range seeder.Sets(*env) {
+			envs := strings.Join(s.Envs, ",")
+			if envs == "" {
+				envs = "all"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Connection, envs)
+		}
+		return w.Flush()
+	}
+
+	if err := initDatabases(ctx); err != nil {
+		db.Close()
+		return err
+	}
+	defer db.Close()
+
+	results, err := seeder.Run(ctx, *env, fs.Args()...)
+	for _, res := range results {
+		fmt.Printf("%s: %d inserted, %d updated, %d unchanged\n", res.Set, res.Inserted, res.Updated, res.Unchanged)
+	}
+	return err
+}
@@ -0,0 +1,516 @@
+// Package audit provides a GORM plugin recording creates, updates and deletes of
+// opted-in models into an audit table. Each entry holds the actor from the request
+// context, the table and primary key of the row, the action and the changed columns
+// before and after the change.
+//
+// Changes made with GORM model methods (Create, Save, Update(s), Delete) are recorded;
+// raw SQL executed with Exec is not. Entries are written on the statement's connection,
+// so they commit or roll back with the change when it runs in a transaction.
+package audit
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultTable is the default name of the audit table.
+	DefaultTable = "audit_logs"
+	// defaultActor is recorded when the context carries no actor.
+	defaultActor = "system"
+	// beforeKey is the statement setting holding the rows loaded before a change.
+	beforeKey = "audit:before"
+)
+
+// Actions recorded in Entry.Action.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Auditable is implemented by models whose changes are recorded.
+type Auditable interface {
+	// AuditExclude returns the columns left out of the entries, e.g. password hashes.
+	AuditExclude() []string
+}
+
+// Sensitive is implemented by GORM serializers of columns whose values are left out of
+// the entries, such as encrypted or hashed columns. Changes of such columns are recorded
+// with the value "[redacted]".
+type Sensitive interface {
+	Sensitive() bool
+}
+
+// Entry is a row of the audit table.
+type Entry struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	// Table is the table of the changed row
+	Table string `gorm:"column:table_name;size:128;not null;index:idx_audit_entity,priority:1" json:"table"`
+	// PrimaryKey is the primary key of the changed row; composite keys are joined with ","
+	PrimaryKey string `gorm:"size:255;not null;index:idx_audit_entity,priority:2" json:"primary_key"`
+	// Action is "create", "update" or "delete"
+	Action string `gorm:"size:16;not null" json:"action"`
+	// Actor is who made the change, from ContextWithActor or the plugin's ActorFunc
+	Actor string `gorm:"size:255;not null;index" json:"actor"`
+	// Before is a JSON object of the changed columns before the change (empty for creates)
+	Before string `gorm:"type:text" json:"before,omitempty"`
+	// After is a JSON object of the changed columns after the change (empty for deletes)
+	After string `gorm:"type:text" json:"after,omitempty"`
+	// CreatedAt is when the change was recorded
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+// TableName returns the default audit table name.
+func (Entry) TableName() string {
+	return DefaultTable
+}
+
+// actorKey is the context key of the actor.
+type actorKey struct{}
+
+// ContextWithActor returns a context recording actor as the author of changes made with it.
+// Call it in the authentication middleware with the user ID.
+//
+// Parameters:
+//   - ctx: The parent context
+//   - actor: The user or service making changes
+//
+// Returns:
+//   - context.Context: A context for db.WithContext(ctx)
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with ContextWithActor.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// Plugin is the GORM audit plugin.
+type Plugin struct {
+	table       string
+	actor       func(ctx context.Context) string
+	autoMigrate bool
+}
+
+// Option configures the Plugin.
+type Option func(*Plugin)
+
+// WithTable sets the name of the audit table (default "audit_logs").
+func WithTable(table string) Option {
+	return func(p *Plugin) {
+		if table != "" {
+			p.table = table
+		}
+	}
+}
+
+// ActorFunc sets the function returning the actor of a change when the context carries
+// none set with ContextWithActor.
+func ActorFunc(fn func(ctx context.Context) string) Option {
+	return func(p *Plugin) {
+		p.actor = fn
+	}
+}
+
+// WithAutoMigrate creates or updates the audit table when the plugin is registered.
+func WithAutoMigrate(enabled bool) Option {
+	return func(p *Plugin) {
+		p.autoMigrate = enabled
+	}
+}
+
+// New creates the audit plugin; register it with gormDB.Use.
+//
+// Parameters:
+//   - opts: Optional settings
+//
+// Returns:
+//   - *Plugin: The audit plugin
+func New(opts ...Option) *Plugin {
+	p := &Plugin{table: DefaultTable}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Name returns the plugin name.
+func (p *Plugin) Name() string {
+	return "audit"
+}
+
+// Initialize registers the audit callbacks.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	if p.autoMigrate {
+		if err := db.Table(p.table).AutoMigrate(&Entry{}); err != nil {
+			return errors.Wrap(err, "migrate audit table")
+		}
+	}
+
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().After("gorm:create").Register("audit:after_create", p.afterCreate),
+		cb.Update().Before("gorm:update").Register("audit:before_update", p.loadBefore),
+		cb.Update().After("gorm:update").Register("audit:after_update", p.afterUpdate),
+		cb.Delete().Before("gorm:delete").Register("audit:before_delete", p.loadBefore),
+		cb.Delete().After("gorm:delete").Register("audit:after_delete", p.afterDelete),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register audit callback")
+		}
+	}
+	return nil
+}
+
+// HistoryQuery selects audit entries; empty fields do not filter.
+type HistoryQuery struct {
+	// Table and PrimaryKey select the history of one row
+	Table      string
+	PrimaryKey string
+	// Actor selects the changes made by one actor
+	Actor string
+	// Since and Until bound CreatedAt
+	Since, Until time.Time
+	// BeforeID returns entries older than this ID, for paging through the history
+	BeforeID uint64
+	// Limit is the max number of entries (default 50)
+	Limit int
+}
+
+// History returns audit entries, newest first.
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - db: The database holding the audit table
+//   - q: The entries to select
+//   - opts: The plugin options used for the database, for a custom table name
+//
+// Returns:
+//   - []Entry: The matching entries; page with BeforeID set to the last ID
+//   - error: Error if the query fails
+func History(ctx context.Context, db *gorm.DB, q HistoryQuery, opts ...Option) ([]Entry, error) {
+	p := New(opts...)
+	tx := db.WithContext(ctx).Table(p.table)
+	if q.Table != "" {
+		tx = tx.Where("table_name = ?", q.Table)
+	}
+	if q.PrimaryKey != "" {
+		tx = tx.Where("primary_key = ?", q.PrimaryKey)
+	}
+	if q.Actor != "" {
+		tx = tx.Where("actor = ?", q.Actor)
+	}
+	if !q.Since.IsZero() {
+		tx = tx.Where("created_at >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		tx = tx.Where("created_at < ?", q.Until)
+	}
+	if q.BeforeID > 0 {
+		tx = tx.Where("id < ?", q.BeforeID)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var entries []Entry
+	err := tx.Order("id DESC").Limit(limit).Find(&entries).Error
+	return entries, errors.Wrap(err, "query audit history")
+}
+
+// auditable returns the excluded columns of the statement's model, or false if the
+// model does not opt in.
+func auditable(db *gorm.DB) (map[string]bool, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, false
+	}
+	model, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(Auditable)
+	if !ok {
+		return nil, false
+	}
+	exclude := make(map[string]bool)
+	for _, col := range model.AuditExclude() {
+		exclude[col] = true
+	}
+	return exclude, true
+}
+
+// afterCreate records the created rows.
+func (p *Plugin) afterCreate(db *gorm.DB) {
+	exclude, ok := auditable(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+
+	var entries []*Entry
+	eachRow(db.Statement.ReflectValue, func(row reflect.Value) {
+		entries = append(entries, p.entry(db, ActionCreate, row, nil, rowValues(db, row, exclude)))
+	})
+	p.write(db, entries)
+}
+
+// loadBefore loads the rows an update or delete is about to change.
+func (p *Plugin) loadBefore(db *gorm.DB) {
+	if _, ok := auditable(db); !ok {
+		return
+	}
+
+	stmt := db.Statement
+	query := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Model(reflect.New(stmt.Schema.ModelType).Interface())
+	if stmt.Unscoped {
+		query = query.Unscoped()
+	}
+	hasWhere := false
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			query = query.Clauses(clause.Where{Exprs: where.Exprs})
+			hasWhere = true
+		}
+	}
+	// Save and Model(&row).Updates(...) add the primary key of the model later in the
+	// update callback; apply it here too.
+	if pk := modelPrimaryKey(db); pk != nil {
+		query = query.Where(pk)
+		hasWhere = true
+	}
+	if !hasWhere {
+		return
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := query.Find(rows.Interface()).Error; err != nil {
+		_ = db.AddError(errors.Wrap(err, "audit: load rows before change"))
+		return
+	}
+	db.InstanceSet(beforeKey, rows.Elem())
+}
+
+// afterUpdate reloads the changed rows and records their changed columns.
+func (p *Plugin) afterUpdate(db *gorm.DB) {
+	exclude, ok := auditable(db)
+	before, loaded := db.InstanceGet(beforeKey)
+	if !ok || !loaded || db.RowsAffected == 0 {
+		return
+	}
+	beforeRows := before.(reflect.Value)
+	if beforeRows.Len() == 0 {
+		return
+	}
+
+	stmt := db.Statement
+	afterRows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Unscoped().
+		Model(reflect.New(stmt.Schema.ModelType).Interface()).
+		Where(primaryKeyIn(db, beforeRows)).Find(afterRows.Interface()).Error
+	if err != nil {
+		_ = db.AddError(errors.Wrap(err, "audit: load rows after change"))
+		return
+	}
+	afterByPK := make(map[string]reflect.Value, afterRows.Elem().Len())
+	for i := 0; i < afterRows.Elem().Len(); i++ {
+		row := afterRows.Elem().Index(i)
+		afterByPK[primaryKeyString(db, row)] = row
+	}
+
+	var entries []*Entry
+	for i := 0; i < beforeRows.Len(); i++ {
+		oldRow := beforeRows.Index(i)
+		newRow, ok := afterByPK[primaryKeyString(db, oldRow)]
+		if !ok {
+			continue
+		}
+		oldValues, newValues := diff(rowValues(db, oldRow, exclude), rowValues(db, newRow, exclude))
+		if len(newValues) == 0 {
+			continue
+		}
+		entries = append(entries, p.entry(db, ActionUpdate, oldRow, oldValues, newValues))
+	}
+	p.write(db, entries)
+}
+
+// afterDelete records the deleted rows.
+func (p *Plugin) afterDelete(db *gorm.DB) {
+	exclude, ok := auditable(db)
+	before, loaded := db.InstanceGet(beforeKey)
+	if !ok || !loaded || db.RowsAffected == 0 {
+		return
+	}
+
+	var entries []*Entry
+	eachRow(before.(reflect.Value), func(row reflect.Value) {
+		entries = append(entries, p.entry(db, ActionDelete, row, rowValues(db, row, exclude), nil))
+	})
+	p.write(db, entries)
+}
+
+// entry builds an audit entry for a row.
+func (p *Plugin) entry(db *gorm.DB, action string, row reflect.Value, before, after map[string]any) *Entry {
+	ctx := db.Statement.Context
+	actor, ok := ActorFromContext(ctx)
+	if !ok && p.actor != nil {
+		actor = p.actor(ctx)
+	}
+	if actor == "" {
+		actor = defaultActor
+	}
+
+	e := &Entry{
+		Table:      db.Statement.Table,
+		PrimaryKey: primaryKeyString(db, row),
+		Action:     action,
+		Actor:      actor,
+		CreatedAt:  time.Now(),
+	}
+	if before != nil {
+		data, _ := json.Marshal(before)
+		e.Before = string(data)
+	}
+	if after != nil {
+		data, _ := json.Marshal(after)
+		e.After = string(data)
+	}
+	return e
+}
+
+// write inserts the entries on the statement's connection.
+func (p *Plugin) write(db *gorm.DB, entries []*Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(p.table).Create(entries).Error
+	if err != nil {
+		_ = db.AddError(errors.Wrap(err, "audit: write entries"))
+	}
+}
+
+// eachRow calls fn for the struct or every element of the slice in v.
+func eachRow(v reflect.Value, fn func(row reflect.Value)) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fn(reflect.Indirect(v.Index(i)))
+		}
+	case reflect.Struct:
+		fn(v)
+	}
+}
+
+// rowValues returns the column values of a row, excluding the given columns.
+func rowValues(db *gorm.DB, row reflect.Value, exclude map[string]bool) map[string]any {
+	values := make(map[string]any)
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DBName == "" || exclude[field.DBName] {
+			continue
+		}
+		if s, ok := field.Serializer.(Sensitive); ok && s.Sensitive() {
+			values[field.DBName] = redacted{field.ReflectValueOf(db.Statement.Context, row).Interface()}
+			continue
+		}
+		v, _ := field.ValueOf(db.Statement.Context, row)
+		values[field.DBName] = normalize(v)
+	}
+	return values
+}
+
+// redacted stands in for the value of a sensitive column: it compares by the plain value,
+// so entries show that the column changed, but is recorded as "[redacted]".
+type redacted struct {
+	value any
+}
+
+// MarshalJSON implements json.Marshaler.
+func (redacted) MarshalJSON() ([]byte, error) {
+	return []byte(`"[redacted]"`), nil
+}
+
+// normalize converts driver.Valuer values such as sql.NullString to plain values.
+func normalize(v any) any {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		if dv, err := valuer.Value(); err == nil {
+			return dv
+		}
+	}
+	return v
+}
+
+// diff returns the columns whose values differ, with their old and new values.
+func diff(before, after map[string]any) (map[string]any, map[string]any) {
+	oldValues, newValues := make(map[string]any), make(map[string]any)
+	for col, newValue := range after {
+		if oldValue := before[col]; !reflect.DeepEqual(oldValue, newValue) {
+			oldValues[col] = oldValue
+			newValues[col] = newValue
+		}
+	}
+	return oldValues, newValues
+}
+
+// modelPrimaryKey returns the primary key condition of the statement's model value,
+// or nil if it is not set.
+func modelPrimaryKey(db *gorm.DB) clause.Expression {
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	if rv.Kind() != reflect.Struct || len(db.Statement.Schema.PrimaryFields) == 0 {
+		return nil
+	}
+	exprs := make([]clause.Expression, 0, len(db.Statement.Schema.PrimaryFields))
+	for _, field := range db.Statement.Schema.PrimaryFields {
+		v, zero := field.ValueOf(db.Statement.Context, rv)
+		if zero {
+			return nil
+		}
+		exprs = append(exprs, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: v})
+	}
+	return clause.And(exprs...)
+}
+
+// primaryKeyIn returns the condition selecting the rows by primary key.
+func primaryKeyIn(db *gorm.DB, rows reflect.Value) clause.Expression {
+	fields := db.Statement.Schema.PrimaryFields
+	or := make([]clause.Expression, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		and := make([]clause.Expression, 0, len(fields))
+		for _, field := range fields {
+			v, _ := field.ValueOf(db.Statement.Context, rows.Index(i))
+			and = append(and, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: v})
+		}
+		or = append(or, clause.And(and...))
+	}
+	return clause.Or(or...)
+}
+
+// primaryKeyValue returns the primary key values of a row.
+func primaryKeyValue(db *gorm.DB, row reflect.Value) []any {
+	values := make([]any, 0, len(db.Statement.Schema.PrimaryFields))
+	for _, field := range db.Statement.Schema.PrimaryFields {
+		v, _ := field.ValueOf(db.Statement.Context, row)
+		values = append(values, v)
+	}
+	return values
+}
+
+// primaryKeyString formats the primary key of a row; composite keys are joined with ",".
+func primaryKeyString(db *gorm.DB, row reflect.Value) string {
+	values := primaryKeyValue(db, row)
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ",")
+}
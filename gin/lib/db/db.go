@@ -4,123 +4,447 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/mengbin92/example/lib/db/mysql"
-	"github.com/mengbin92/example/lib/db/postgres"
-	"github.com/mengbin92/example/lib/db/sqlite3"
+	"github.com/mengbin92/example/lib/db/audit"
+	"github.com/mengbin92/example/lib/db/driver"
+	"github.com/mengbin92/example/lib/db/metrics"
+	// Built-in drivers register themselves with the driver registry.
+	_ "github.com/mengbin92/example/lib/db/mysql"
+	_ "github.com/mengbin92/example/lib/db/postgres"
+	_ "github.com/mengbin92/example/lib/db/sqlite3"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const (
+	defaultDriver          = "mysql"
+	defaultMaxIdleConns    = 10
+	defaultMaxOpenConns    = 100
+	defaultConnMaxLifetime = time.Hour
+	defaultMaxRetries      = 5
+	defaultRetryBackoff    = time.Second
+	defaultMaxBackoff      = 30 * time.Second
+	defaultConnectTimeout  = time.Minute
+	pingTimeout            = 2 * time.Second
+)
+
+// DefaultName is the name of the connection configured in the "database" section.
+const DefaultName = "default"
+
 var (
-	// gdb is the global GORM database instance
-	gdb *gorm.DB
-	// initDBOnce ensures the database is initialized only once (thread-safe)
-	initDBOnce sync.Once
+	// conns holds the initialized connections by name
+	conns = map[string]*gorm.DB{}
+	// monitors holds the health monitors of the connections by name
+	monitors = map[string]*monitor{}
+	// connOrder lists the connection names in initialization order, for Close
+	connOrder []string
+	// connsMu protects conns, monitors and connOrder
+	connsMu sync.RWMutex
+	// initMu serializes initialization so a name is never connected twice
+	initMu sync.Mutex
 )
 
-// Init initializes the database connection.
-// It uses sync.Once to ensure the database is initialized only once, even if called multiple times.
+// Config contains database connection, pool and retry settings.
+// Zero values fall back to the defaults noted on each field.
+type Config struct {
+	// Driver is the registered driver name or alias, e.g. "mysql", "postgres" or "sqlite" (default "mysql")
+	Driver string
+	// Source is the database connection source string (DSN) with the password inline;
+	// leave it empty to build the DSN from DSN
+	Source string
+	// DSN holds structured connection settings, used if Source is empty
+	DSN *DSNConfig
+	// MaxIdleConns is the max number of idle connections (default 10)
+	MaxIdleConns int
+	// MaxOpenConns is the max number of open connections (default 100)
+	MaxOpenConns int
+	// ConnMaxLifetime is the max lifetime of a connection (default 1h)
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the max idle time of a connection (default 0, no limit)
+	ConnMaxIdleTime time.Duration
+	// MaxRetries is the number of connect retries after the first failed attempt (default 5)
+	MaxRetries int
+	// RetryBackoff is the initial delay between connect attempts (default 1s)
+	RetryBackoff time.Duration
+	// MaxBackoff is the upper bound of the exponential backoff (default 30s)
+	MaxBackoff time.Duration
+	// ConnectTimeout is the deadline for the whole connect phase (default 1m)
+	ConnectTimeout time.Duration
+	// LogLevel is the GORM log level: "silent", "error" (default), "warn" or "info"
+	LogLevel string
+	// Audit records changes of models implementing audit.Auditable
+	Audit bool
+	// AuditTable is the audit table name (default "audit_logs")
+	AuditTable string
+	// AuditAutoMigrate creates the audit table on startup instead of with a migration
+	AuditAutoMigrate bool
+	// DegradedStart registers the connection even if the database is still unreachable
+	// after the retries; it reconnects in the background
+	DegradedStart bool
+	// HealthCheckInterval is the background ping interval (default 10s)
+	HealthCheckInterval time.Duration
+	// Metrics records query latency and errors as Prometheus metrics; pool statistics
+	// are always exported
+	Metrics bool
+}
+
+// Init initializes the default database connection.
+// Calling it again after a successful initialization is a no-op.
+//
+// Failed connection attempts are retried with exponential backoff until MaxRetries is
+// exhausted or ConnectTimeout expires. Cancelling ctx (e.g. on SIGTERM) aborts the
+// retries immediately. A failed Init leaves nothing behind, so it can be called again.
+//
+// With DegradedStart, a database that is still unreachable after the retries does not fail
+// Init: the connection is registered in StateDown and Get returns it, but queries fail until
+// the background health check sees the database come back; see GetStatus and OnConnect.
 //
-// Supported drivers:
-//   - "postgre": PostgreSQL database
-//   - "sqlite": SQLite database
-//   - Default: MySQL database
+// The data source name is Source, or is built by the driver from DSN with the password
+// taken inline or from PasswordEnv, PasswordFile or PasswordCommand.
+//
+// The driver is looked up in the driver registry; built-in drivers are:
+//   - "mysql" (alias "mariadb"): MySQL database, also used when Driver is empty
+//   - "postgres" (aliases "postgresql", "postgre", "pg"): PostgreSQL database
+//   - "sqlite" (alias "sqlite3"): SQLite database
+//
+// Other drivers can be added with driver.Register.
 //
 // Parameters:
-//   - driver: The database driver type ("postgre", "sqlite", or empty/default for MySQL)
-//   - source: The database connection source string (DSN)
+//   - ctx: Context bounding the connect phase
+//   - cfg: Database configuration
 //
 // Returns:
-//   - error: Error if initialization or connection fails
-func Init(driver, source string) error {
-	if source == "" {
-		return errors.New("database source cannot be empty")
-	}
-
-	var initErr error
-	gormLogger := NewGormLogger(logger.Error)
-
-	initDBOnce.Do(func() {
-		// Retry connection with exponential backoff
-		maxRetries := 10
-		retryDelay := 2 * time.Second
-
-		for attempt := 0; attempt < maxRetries; attempt++ {
-			switch driver {
-			case "postgre":
-				gdb, initErr = postgres.InitDB(source, gormLogger)
-			case "sqlite":
-				gdb, initErr = sqlite3.InitDB(source, gormLogger)
-			default:
-				// MySQL is the default driver
-				gdb, initErr = mysql.InitDB(source, gormLogger)
+//   - error: Error if initialization or connection fails, or ctx is cancelled
+func Init(ctx context.Context, cfg *Config) error {
+	return InitNamed(ctx, DefaultName, cfg)
+}
+
+// InitNamed initializes a named database connection, e.g. one of the "databases" section.
+// It behaves like Init; calling it again for an initialized name is a no-op.
+//
+// Parameters:
+//   - ctx: Context bounding the connect phase
+//   - name: The connection name used with Get
+//   - cfg: Database configuration of the connection
+//
+// Returns:
+//   - error: Error if initialization or connection fails, or ctx is cancelled
+func InitNamed(ctx context.Context, name string, cfg *Config) error {
+	if cfg == nil {
+		return errors.Errorf("database %q config cannot be nil", name)
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	if lookup(name) != nil {
+		return nil
+	}
+
+	level, err := parseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return errors.Wrapf(err, "database %q", name)
+	}
+
+	// An unknown driver is a configuration error, not worth retrying.
+	driverName := cfg.Driver
+	if driverName == "" {
+		driverName = defaultDriver
+	}
+	d, err := driver.Lookup(driverName)
+	if err != nil {
+		return errors.Wrapf(err, "database %q", name)
+	}
+	// The password is resolved once, so a failing secret source fails fast.
+	source, err := resolveSource(ctx, d, cfg)
+	if err != nil {
+		return errors.Wrapf(err, "database %q", name)
+	}
+
+	gormLogger := NewGormLogger(level)
+	state := StateUp
+	gormDB, connectErr := connect(ctx, d, source, cfg, gormLogger)
+	if connectErr != nil {
+		// Only a database that stays unreachable is tolerated, not an aborted startup.
+		if !cfg.DegradedStart || ctx.Err() != nil {
+			return errors.Wrapf(connectErr, "connect to db %q error", name)
+		}
+		if gormDB, err = openLazy(d, source, cfg, gormLogger); err != nil {
+			return errors.Wrapf(err, "open db %q error", name)
+		}
+		state = StateDown
+		fmt.Printf("database %q unavailable, starting degraded and reconnecting in background: %v\n", name, connectErr)
+	}
+
+	// The audit table of a degraded connection is created once it is up.
+	var pending []ConnectHook
+	if cfg.Audit {
+		err = gormDB.WithContext(ctx).Use(audit.New(audit.WithTable(cfg.AuditTable), audit.WithAutoMigrate(cfg.AuditAutoMigrate && state == StateUp)))
+		if err != nil {
+			if sqlDB, dbErr := gormDB.DB(); dbErr == nil {
+				_ = sqlDB.Close()
+			}
+			return errors.Wrapf(err, "database %q audit", name)
+		}
+		if cfg.AuditAutoMigrate && state == StateDown {
+			table := cfg.AuditTable
+			if table == "" {
+				table = audit.DefaultTable
+			}
+			pending = append(pending, func(ctx context.Context, db *gorm.DB) error {
+				return errors.Wrap(db.Table(table).AutoMigrate(&audit.Entry{}), "migrate audit table")
+			})
+		}
+	}
+	if cfg.Metrics {
+		if err = gormDB.Use(metrics.New(name)); err != nil {
+			if sqlDB, dbErr := gormDB.DB(); dbErr == nil {
+				_ = sqlDB.Close()
 			}
+			return errors.Wrapf(err, "database %q metrics", name)
+		}
+	}
+	m := newMonitor(name, gormDB, state, connectErr, valueOr(cfg.HealthCheckInterval, defaultHealthCheckInterval), pending...)
+
+	connsMu.Lock()
+	conns[name] = gormDB
+	monitors[name] = m
+	connOrder = append(connOrder, name)
+	connsMu.Unlock()
+	return nil
+}
+
+// Set registers an existing GORM database as a named connection, replacing the connection
+// of that name until restore is called. It is meant for tests (see dbtest), which register
+// a transaction so code using Get runs in it. The caller owns gormDB: it is not pinged in
+// the background and Close does not close it.
+//
+// Parameters:
+//   - name: The connection name used with Get
+//   - gormDB: The database or transaction to use
+//
+// Returns:
+//   - func(): Restores the previous connection of that name, or removes it
+func Set(name string, gormDB *gorm.DB) (restore func()) {
+	m := newExternalMonitor(name, gormDB)
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	connsMu.Lock()
+	prevDB, prevMonitor := conns[name], monitors[name]
+	conns[name] = gormDB
+	monitors[name] = m
+	if prevDB == nil {
+		connOrder = append(connOrder, name)
+	}
+	connsMu.Unlock()
 
-			if initErr == nil {
-				// Connection successful
+	return func() {
+		connsMu.Lock()
+		defer connsMu.Unlock()
+		if monitors[name] != m {
+			return
+		}
+		if prevDB != nil {
+			conns[name], monitors[name] = prevDB, prevMonitor
+			return
+		}
+		delete(conns, name)
+		delete(monitors, name)
+		for i, n := range connOrder {
+			if n == name {
+				connOrder = append(connOrder[:i:i], connOrder[i+1:]...)
 				break
 			}
+		}
+	}
+}
 
-			// Log retry attempt
-			if attempt < maxRetries-1 {
-				fmt.Printf("database connection attempt %d/%d failed: %v, retrying in %v...\n",
-					attempt+1, maxRetries, initErr, retryDelay)
-				time.Sleep(retryDelay)
-				retryDelay *= 2 // Exponential backoff
-			}
+// connect opens the database, retrying with exponential backoff.
+func connect(ctx context.Context, d driver.Driver, source string, cfg *Config, gormLogger logger.Interface) (*gorm.DB, error) {
+	ctx, cancel := context.WithTimeout(ctx, valueOr(cfg.ConnectTimeout, defaultConnectTimeout))
+	defer cancel()
+
+	maxRetries := valueOr(cfg.MaxRetries, defaultMaxRetries)
+	retryDelay := valueOr(cfg.RetryBackoff, defaultRetryBackoff)
+	maxBackoff := valueOr(cfg.MaxBackoff, defaultMaxBackoff)
+
+	for attempt := 0; ; attempt++ {
+		gormDB, err := open(ctx, d, source, cfg, gormLogger)
+		if err == nil {
+			return gormDB, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Wrapf(ctx.Err(), "connect aborted after %d attempt(s), last error: %v", attempt+1, err)
+		}
+		if attempt >= maxRetries {
+			return nil, err
 		}
-	})
 
-	if initErr != nil {
-		return errors.Wrap(initErr, "connect to db error")
+		// Log retry attempt
+		fmt.Printf("database connection attempt %d/%d failed: %v, retrying in %v...\n",
+			attempt+1, maxRetries+1, err, retryDelay)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "connect aborted after %d attempt(s), last error: %v", attempt+1, err)
+		case <-time.After(retryDelay):
+		}
+		retryDelay *= 2 // Exponential backoff
+		if retryDelay > maxBackoff {
+			retryDelay = maxBackoff
+		}
 	}
+}
 
-	if gdb == nil {
-		return errors.New("database instance is nil after initialization")
+// open makes one connection attempt, configures the pool and pings the database.
+func open(ctx context.Context, d driver.Driver, source string, cfg *Config, gormLogger logger.Interface) (*gorm.DB, error) {
+	gormDB, err := d.Open(source, gormLogger)
+	if err != nil {
+		return nil, err
 	}
 
 	// Configure connection pool settings
-	sqlDB, err := gdb.DB()
+	sqlDB, err := gormDB.DB()
 	if err != nil {
-		return errors.Wrap(err, "get sql db error")
+		return nil, errors.Wrap(err, "get sql db error")
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	configurePool(sqlDB, cfg)
 
 	// Test the connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 	if err := sqlDB.PingContext(ctx); err != nil {
-		return errors.Wrap(err, "ping database error")
+		_ = sqlDB.Close()
+		return nil, errors.Wrap(err, "ping database error")
 	}
 
-	return nil
+	return gormDB, nil
+}
+
+// openLazy opens the database without pinging it, for a degraded start.
+func openLazy(d driver.Driver, source string, cfg *Config, gormLogger logger.Interface) (*gorm.DB, error) {
+	opener := d.OpenLazy
+	if opener == nil {
+		opener = d.Open
+	}
+	gormDB, err := opener(source, gormLogger)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "get sql db error")
+	}
+	configurePool(sqlDB, cfg)
+	return gormDB, nil
 }
 
-// Get returns the global GORM database instance.
+// configurePool applies the pool settings of cfg to sqlDB.
+func configurePool(sqlDB *sql.DB, cfg *Config) {
+	sqlDB.SetMaxIdleConns(valueOr(cfg.MaxIdleConns, defaultMaxIdleConns))
+	sqlDB.SetMaxOpenConns(valueOr(cfg.MaxOpenConns, defaultMaxOpenConns))
+	sqlDB.SetConnMaxLifetime(valueOr(cfg.ConnMaxLifetime, defaultConnMaxLifetime))
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// valueOr returns v, or def if v is not positive.
+func valueOr[T int | time.Duration](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// Get returns an initialized database connection.
+//
+// Parameters:
+//   - name: Optional connection name; the default connection ("database" section) if omitted
 //
 // Returns:
 //   - *gorm.DB: The GORM database instance
 //
 // Panics:
-//   - If the database has not been initialized (nil instance)
+//   - If the connection has not been initialized
+//
+// Note: The database should be initialized using Init or InitNamed before calling this function.
+func Get(name ...string) *gorm.DB {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
+	}
+	gormDB := lookup(n)
+	if gormDB == nil {
+		panic(fmt.Sprintf("database %q is nil; please initialize it using db.Init()", n))
+	}
+
+	return gormDB
+}
+
+// Names returns the names of the initialized connections in initialization order.
+func Names() []string {
+	connsMu.RLock()
+	defer connsMu.RUnlock()
+	return append([]string(nil), connOrder...)
+}
+
+// Close stops the health checks and closes all connections in reverse initialization order.
 //
-// Note: The database should be initialized using Init before calling this function.
-func Get() *gorm.DB {
-	if gdb == nil {
-		panic("database is nil; please initialize it using db.Init()")
+// Returns:
+//   - error: The first error from closing a connection pool
+func Close() error {
+	connsMu.Lock()
+	order := connOrder
+	closing := conns
+	stopping := monitors
+	conns = map[string]*gorm.DB{}
+	monitors = map[string]*monitor{}
+	connOrder = nil
+	connsMu.Unlock()
+
+	var firstErr error
+	for i := len(order) - 1; i >= 0; i-- {
+		stopping[order[i]].stop()
+		if stopping[order[i]].external {
+			continue
+		}
+		sqlDB, err := closing[order[i]].DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "close database %q", order[i])
+		}
 	}
+	return firstErr
+}
 
-	return gdb
+// lookup returns the named connection, or nil if it is not initialized.
+func lookup(name string) *gorm.DB {
+	connsMu.RLock()
+	defer connsMu.RUnlock()
+	return conns[name]
+}
+
+// parseLogLevel converts a LogLevel setting to a GORM log level; empty means "error".
+func parseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent, nil
+	case "", "error":
+		return logger.Error, nil
+	case "warn":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	default:
+		return 0, errors.Errorf("unknown log level: %s", level)
+	}
 }
 
 // NewGormLogger creates a GORM logger adapter.
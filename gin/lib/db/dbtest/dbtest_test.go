@@ -0,0 +1,176 @@
+package dbtest_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mengbin92/example/lib/db"
+	"github.com/mengbin92/example/lib/db/dbtest"
+	"github.com/mengbin92/example/lib/factory"
+)
+
+type user struct {
+	ID   uint64
+	Name string
+	Tags string
+}
+
+type note struct {
+	ID   uint64
+	Body string
+}
+
+var testFS = fstest.MapFS{
+	"migrations/1_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, tags TEXT);")},
+	"migrations/1_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"fixtures/users.yml": {Data: []byte(`users:
+  - id: 1
+    name: alice
+    tags: [admin, ops]
+  - id: 2
+    name: bob
+  - id: 3
+    name: carol
+`)},
+}
+
+func migrations(t *testing.T) fs.FS {
+	t.Helper()
+	sub, err := fs.Sub(testFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []dbtest.Option
+		table string
+		want  int64
+	}{
+		{name: "migrations", opts: []dbtest.Option{dbtest.WithMigrations(migrations(t))}, table: "users", want: 0},
+		{
+			name:  "fixtures",
+			opts:  []dbtest.Option{dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/*.yml")},
+			table: "users",
+			want:  3,
+		},
+		{name: "models", opts: []dbtest.Option{dbtest.WithModels(&note{})}, table: "notes", want: 0},
+		{name: "file", opts: []dbtest.Option{dbtest.WithFile(), dbtest.WithModels(&note{})}, table: "notes", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.Open(t, tt.opts...)
+			var n int64
+			if err := d.DB.Table(tt.table).Count(&n).Error; err != nil {
+				t.Fatalf("count %s: %v", tt.table, err)
+			}
+			if n != tt.want {
+				t.Errorf("%s has %d rows, want %d", tt.table, n, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixturesJSON(t *testing.T) {
+	tx := dbtest.New(t, dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/users.yml"))
+	var u user
+	if err := tx.First(&u, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.Tags != `["admin","ops"]` {
+		t.Errorf("tags = %q, want the list as JSON", u.Tags)
+	}
+}
+
+func TestLoadFixturesInvalid(t *testing.T) {
+	tx := dbtest.New(t, dbtest.WithMigrations(migrations(t)))
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not yaml", data: "users: [", want: "parse fixtures"},
+		{name: "not a mapping", data: "- id: 1", want: "must map table names"},
+		{name: "rows not a list", data: "users: {id: 1}", want: "fixtures of table users"},
+		{name: "unknown table", data: "missing:\n  - id: 1", want: "insert fixture into missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dbtest.LoadFixtures(tx, []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadFixtures() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeginRollsBack(t *testing.T) {
+	d := dbtest.Open(t, dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/users.yml"))
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			tx := d.Begin(t)
+			if db.Get() != tx {
+				t.Fatal("db.Get() does not return the test transaction")
+			}
+			ctx := dbtest.Context(context.Background(), tx)
+			if err := factory.DB(ctx).Create(&user{ID: 10, Name: name}).Error; err != nil {
+				t.Fatalf("create in %s subtest: %v", name, err)
+			}
+			var n int64
+			if err := tx.Model(&user{}).Count(&n).Error; err != nil {
+				t.Fatal(err)
+			}
+			if n != 4 {
+				t.Errorf("users = %d, want the 3 fixtures and the new row", n)
+			}
+		})
+	}
+
+	var n int64
+	if err := d.DB.Model(&user{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("users after the subtests = %d, want 3", n)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	tx := dbtest.New(t, dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/users.yml"))
+	r := dbtest.Engine(tx)
+	r.GET("/users/:id", func(c *gin.Context) {
+		var u user
+		if err := factory.DB(c.Request.Context()).First(&u, c.Param("id")).Error; err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.String(http.StatusOK, u.Name)
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/users/2", code: http.StatusOK, body: "bob"},
+		{path: "/users/99", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.code || w.Body.String() != tt.body {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+			}
+		})
+	}
+}
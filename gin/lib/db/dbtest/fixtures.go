@@ -0,0 +1,103 @@
+package dbtest
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// LoadFixtures inserts the rows of a YAML fixture document. The document maps table
+// names to lists of rows; tables are filled in document order, so list parent tables
+// first. Nested maps and lists are stored as JSON.
+//
+//	users:
+//	  - id: 1
+//	    name: alice
+//	orders:
+//	  - id: 10
+//	    user_id: 1
+//	    items: [{sku: A1, qty: 2}]
+//
+// Parameters:
+//   - gormDB: The database to insert into
+//   - data: The YAML document
+//
+// Returns:
+//   - error: Error if the document is invalid or an insert fails
+func LoadFixtures(gormDB *gorm.DB, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return errors.Wrap(err, "parse fixtures")
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	tables := doc.Content[0]
+	if tables.Kind != yaml.MappingNode {
+		return errors.New("fixtures must map table names to rows")
+	}
+
+	for i := 0; i+1 < len(tables.Content); i += 2 {
+		table := tables.Content[i].Value
+		var rows []map[string]interface{}
+		if err := tables.Content[i+1].Decode(&rows); err != nil {
+			return errors.Wrapf(err, "fixtures of table %s", table)
+		}
+		for _, row := range rows {
+			for col, v := range row {
+				switch v.(type) {
+				case map[string]interface{}, []interface{}:
+					b, err := json.Marshal(v)
+					if err != nil {
+						return errors.Wrapf(err, "fixtures of table %s column %s", table, col)
+					}
+					row[col] = string(b)
+				}
+			}
+			if err := gormDB.Table(table).Create(row).Error; err != nil {
+				return errors.Wrapf(err, "insert fixture into %s", table)
+			}
+		}
+	}
+	return nil
+}
+
+// loadFixtureFiles loads the fixture files matching the patterns, from fsys or the
+// working directory if fsys is nil.
+func loadFixtureFiles(gormDB *gorm.DB, fsys fs.FS, patterns []string) error {
+	for _, pattern := range patterns {
+		var files []string
+		var err error
+		if fsys != nil {
+			files, err = fs.Glob(fsys, pattern)
+		} else {
+			files, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "fixtures %s", pattern)
+		}
+		if len(files) == 0 {
+			return errors.Errorf("fixtures %s: no such file", pattern)
+		}
+		for _, file := range files {
+			var data []byte
+			if fsys != nil {
+				data, err = fs.ReadFile(fsys, file)
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return errors.Wrap(err, "read fixtures")
+			}
+			if err := LoadFixtures(gormDB, data); err != nil {
+				return errors.Wrapf(err, "fixtures %s", file)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// TLS modes of DSN.TLS, named after the PostgreSQL sslmode values.
+const (
+	// TLSDisable connects without TLS
+	TLSDisable = "disable"
+	// TLSPrefer uses TLS if the server supports it, without verifying the certificate
+	TLSPrefer = "prefer"
+	// TLSRequire requires TLS without verifying the certificate
+	TLSRequire = "require"
+	// TLSVerifyCA requires TLS and a certificate signed by the CA, ignoring the host name
+	TLSVerifyCA = "verify-ca"
+	// TLSVerifyFull requires TLS and a certificate valid for the host
+	TLSVerifyFull = "verify-full"
+)
+
+// DSN is the structured form of a data source name, converted by Driver.DSN.
+type DSN struct {
+	// Host is the server host; for MySQL a path starting with "/" is a unix socket
+	Host string
+	// Port is the server port, 0 for the driver default
+	Port int
+	// User is the user name
+	User string
+	// Password is the resolved password
+	Password string
+	// DBName is the database name; for SQLite the database file
+	DBName string
+	// Params are driver specific parameters
+	Params map[string]string
+	// TLS configures the connection encryption
+	TLS TLS
+}
+
+// TLS configures the encryption of a DSN.
+type TLS struct {
+	// Mode is one of the TLS constants; empty means TLSDisable
+	Mode string
+	// CAFile is the CA certificate verifying the server
+	CAFile string
+	// CertFile is the client certificate
+	CertFile string
+	// KeyFile is the key of the client certificate
+	KeyFile string
+	// ServerName is checked against the server certificate instead of the host
+	ServerName string
+}
+
+// Config returns the crypto/tls configuration of t, for drivers that take one instead of
+// certificate file parameters.
+//
+// Parameters:
+//   - host: The server host, the default server name
+//
+// Returns:
+//   - *tls.Config: The TLS configuration, nil if t is TLSDisable
+//   - error: Error if the mode is unknown or a certificate cannot be loaded
+func (t TLS) Config(host string) (*tls.Config, error) {
+	switch t.Mode {
+	case "", TLSDisable:
+		return nil, nil
+	case TLSPrefer, TLSRequire, TLSVerifyCA, TLSVerifyFull:
+	default:
+		return nil, errors.Errorf("unknown tls mode %q", t.Mode)
+	}
+
+	cfg := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if t.ServerName != "" {
+		cfg.ServerName = t.ServerName
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read tls ca file")
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("tls ca file %s contains no certificate", t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load tls client certificate")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch t.Mode {
+	case TLSPrefer, TLSRequire:
+		cfg.InsecureSkipVerify = true
+	case TLSVerifyCA:
+		// The chain is verified by hand, as crypto/tls always checks the host name.
+		cfg.InsecureSkipVerify = true
+		roots := cfg.RootCAs
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server sent no certificate")
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+			for _, c := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(c)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mengbin92/example/lib/db/driver"
+	"github.com/pkg/errors"
+)
+
+// passwordCommandTimeout bounds the run of a password command.
+const passwordCommandTimeout = 10 * time.Second
+
+// DSNConfig is the structured form of Config.Source. At most one password source may be set.
+type DSNConfig struct {
+	// Host is the server host; for MySQL a path starting with "/" is a unix socket
+	Host string
+	// Port is the server port (default of the driver if 0)
+	Port int
+	// User is the user name
+	User string
+	// Password is the inline password; prefer one of the sources below
+	Password string
+	// PasswordEnv is the environment variable holding the password
+	PasswordEnv string
+	// PasswordFile is the file holding the password, e.g. a Docker or Kubernetes secret
+	PasswordFile string
+	// PasswordCommand is the command printing the password, run with sh -c
+	PasswordCommand string
+	// DBName is the database name; for SQLite the database file
+	DBName string
+	// Params are driver parameters, e.g. charset or TimeZone
+	Params map[string]string
+	// TLS configures the connection encryption
+	TLS driver.TLS
+}
+
+// resolveSource returns the data source name of a connection: Source if set, otherwise
+// the one built by the driver from DSN with the password resolved from its source.
+func resolveSource(ctx context.Context, d driver.Driver, cfg *Config) (string, error) {
+	if cfg.Source != "" {
+		return cfg.Source, nil
+	}
+	if cfg.DSN == nil {
+		return "", errors.New("source or dsn is required")
+	}
+	if d.DSN == nil {
+		return "", errors.Errorf("driver %s does not support dsn, configure source", d.Name)
+	}
+
+	password, err := resolvePassword(ctx, cfg.DSN)
+	if err != nil {
+		return "", err
+	}
+	return d.DSN(driver.DSN{
+		Host:     cfg.DSN.Host,
+		Port:     cfg.DSN.Port,
+		User:     cfg.DSN.User,
+		Password: password,
+		DBName:   cfg.DSN.DBName,
+		Params:   cfg.DSN.Params,
+		TLS:      cfg.DSN.TLS,
+	})
+}
+
+// resolvePassword returns the password of dsn from the one source that is set. Trailing
+// line breaks of a file or command output are removed.
+func resolvePassword(ctx context.Context, dsn *DSNConfig) (string, error) {
+	set := 0
+	for _, s := range []string{dsn.Password, dsn.PasswordEnv, dsn.PasswordFile, dsn.PasswordCommand} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", errors.New("set only one of password, password_env, password_file and password_command")
+	}
+
+	switch {
+	case dsn.PasswordEnv != "":
+		password, ok := os.LookupEnv(dsn.PasswordEnv)
+		if !ok {
+			return "", errors.Errorf("password environment variable %s is not set", dsn.PasswordEnv)
+		}
+		return password, nil
+	case dsn.PasswordFile != "":
+		b, err := os.ReadFile(dsn.PasswordFile)
+		if err != nil {
+			return "", errors.Wrap(err, "read password file")
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case dsn.PasswordCommand != "":
+		ctx, cancel := context.WithTimeout(ctx, passwordCommandTimeout)
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", dsn.PasswordCommand)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", errors.Wrapf(err, "password command failed: %s", msg)
+			}
+			return "", errors.Wrap(err, "password command failed")
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	default:
+		return dsn.Password, nil
+	}
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mengbin92/example/lib/db/driver"
+)
+
+func TestResolvePassword(t *testing.T) {
+	t.Setenv("DBTEST_PASSWORD", "from-env")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dsn     *DSNConfig
+		want    string
+		wantErr string
+	}{
+		{name: "none", dsn: &DSNConfig{}, want: ""},
+		{name: "inline", dsn: &DSNConfig{Password: "inline"}, want: "inline"},
+		{name: "env", dsn: &DSNConfig{PasswordEnv: "DBTEST_PASSWORD"}, want: "from-env"},
+		{name: "file trims line break", dsn: &DSNConfig{PasswordFile: file}, want: "from-file"},
+		{name: "command", dsn: &DSNConfig{PasswordCommand: "printf 'from-command\\n'"}, want: "from-command"},
+		{
+			name:    "several sources",
+			dsn:     &DSNConfig{Password: "inline", PasswordEnv: "DBTEST_PASSWORD"},
+			wantErr: "set only one of",
+		},
+		{
+			name:    "env not set",
+			dsn:     &DSNConfig{PasswordEnv: "DBTEST_PASSWORD_UNSET"},
+			wantErr: "DBTEST_PASSWORD_UNSET is not set",
+		},
+		{
+			name:    "file missing",
+			dsn:     &DSNConfig{PasswordFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: "read password file",
+		},
+		{
+			name:    "command fails",
+			dsn:     &DSNConfig{PasswordCommand: "echo denied >&2; exit 3"},
+			wantErr: "password command failed: denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePassword(context.Background(), tt.dsn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolvePassword() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePassword() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolvePassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSource(t *testing.T) {
+	t.Setenv("DBTEST_PASSWORD", "secret")
+	d := driver.Driver{
+		Name: "test",
+		DSN: func(dsn driver.DSN) (string, error) {
+			return dsn.User + ":" + dsn.Password + "@" + dsn.Host + "/" + dsn.DBName, nil
+		},
+	}
+	dsn := &DSNConfig{Host: "db", User: "app", PasswordEnv: "DBTEST_PASSWORD", DBName: "orders"}
+
+	tests := []struct {
+		name    string
+		d       driver.Driver
+		cfg     *Config
+		want    string
+		wantErr string
+	}{
+		{name: "dsn", d: d, cfg: &Config{DSN: dsn}, want: "app:secret@db/orders"},
+		{name: "source wins", d: d, cfg: &Config{Source: "override", DSN: dsn}, want: "override"},
+		{name: "neither", d: d, cfg: &Config{}, wantErr: "source or dsn is required"},
+		{name: "driver without dsn", d: driver.Driver{Name: "test"}, cfg: &Config{DSN: dsn}, wantErr: "does not support dsn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSource(context.Background(), tt.d, tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveSource() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSource() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
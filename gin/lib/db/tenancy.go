@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+
+	"github.com/mengbin92/example/lib/db/tenant"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Tenancy modes of TenancyConfig.Mode.
+const (
+	// TenancyColumn shares tables between tenants and isolates rows by a tenant column
+	TenancyColumn = "column"
+	// TenancyDatabase gives every tenant its own named connection
+	TenancyDatabase = "database"
+)
+
+// ErrUnknownTenant is returned when the tenant in context has no connection.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenancyConfig contains the tenant isolation settings.
+type TenancyConfig struct {
+	// Mode is "column" (default) or "database"
+	Mode string
+	// Column is the tenant column of the "column" mode (default "tenant_id")
+	Column string
+	// Databases maps tenant IDs to connection names of the "database" mode
+	Databases map[string]string
+}
+
+// gTenancy is the tenancy configuration set by InitTenancy, nil if tenancy is disabled.
+var gTenancy *TenancyConfig
+
+// InitTenancy sets up tenant isolation on the initialized connections.
+// In "column" mode the tenant plugin is registered on every connection; in "database"
+// mode TenantDB selects the connection of the tenant in context.
+// It must be called once during startup, before serving requests.
+//
+// Parameters:
+//   - cfg: Tenancy configuration
+//
+// Returns:
+//   - error: Error if the mode is unknown or a configured connection is not initialized
+func InitTenancy(cfg *TenancyConfig) error {
+	t := *cfg
+	if t.Mode == "" {
+		t.Mode = TenancyColumn
+	}
+
+	switch t.Mode {
+	case TenancyColumn:
+		for _, name := range Names() {
+			if err := Get(name).Use(tenant.New(tenant.WithColumn(t.Column))); err != nil {
+				return errors.Wrapf(err, "database %q tenancy", name)
+			}
+		}
+	case TenancyDatabase:
+		for id, name := range t.Databases {
+			if lookup(name) == nil {
+				return errors.Errorf("database %q of tenant %q is not initialized", name, id)
+			}
+		}
+	default:
+		return errors.Errorf("unsupported tenancy mode: %s", t.Mode)
+	}
+
+	gTenancy = &t
+	return nil
+}
+
+// TenantConnection returns the name of the connection holding the data of the tenant in ctx.
+// It is the default connection when tenancy is disabled or in "column" mode.
+//
+// Parameters:
+//   - ctx: The request context carrying the tenant
+//
+// Returns:
+//   - string: The connection name for Get
+//   - error: tenant.ErrMissingTenant if ctx has no tenant, ErrUnknownTenant if the tenant
+//     has no connection
+func TenantConnection(ctx context.Context) (string, error) {
+	if gTenancy == nil || gTenancy.Mode == TenancyColumn {
+		return DefaultName, nil
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", tenant.ErrMissingTenant
+	}
+	name, ok := gTenancy.Databases[id]
+	if !ok {
+		return "", errors.Wrapf(ErrUnknownTenant, "tenant %q", id)
+	}
+	return name, nil
+}
+
+// TenantDB returns a session bound to ctx on the connection of the tenant in ctx.
+// Errors resolving the tenant are reported by the session.
+//
+// Parameters:
+//   - ctx: The request context carrying the tenant
+//
+// Returns:
+//   - *gorm.DB: A session bound to ctx
+func TenantDB(ctx context.Context) *gorm.DB {
+	name, err := TenantConnection(ctx)
+	if err != nil {
+		tx := Get().Session(&gorm.Session{NewDB: true}).WithContext(ctx)
+		_ = tx.AddError(err)
+		return tx
+	}
+	return Get(name).WithContext(ctx)
+}
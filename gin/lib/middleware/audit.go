@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mengbin92/example/lib/db/audit"
+)
+
+// SetAuditActorMiddleware creates a middleware that records the actor of the request for the
+// audit trail. Changes made with db.WithContext(ctx.Request.Context()) are attributed to it.
+// Register it after the authentication middleware.
+//
+// Parameters:
+//   - actor: Returns the user or service making the request; an empty result keeps the default actor
+//
+// Returns:
+//   - gin.HandlerFunc: A Gin middleware function that adds the audit actor to context
+func SetAuditActorMiddleware(actor func(ctx *gin.Context) string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if a := actor(ctx); a != "" {
+			req := ctx.Request
+			ctx.Request = req.WithContext(audit.ContextWithActor(req.Context(), a))
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mengbin92/example/lib/db/tenant"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyHeader is the request header carrying the idempotency key.
+	IdempotencyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses that were replayed from the store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyInProgress = "in_progress"
+	idempotencyCompleted  = "completed"
+)
+
+// idempotencyReserveScript reserves a key or returns the existing record.
+var idempotencyReserveScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return false
+end
+return redis.call('GET', KEYS[1])
+`)
+
+// idempotencyOwnerScript completes or releases a record only if the caller still holds it.
+var idempotencyOwnerScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if not cur then
+	return 0
+end
+if cjson.decode(cur)['token'] ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1
+`)
+
+// IdempotencyConfig contains configuration for the idempotency middleware.
+type IdempotencyConfig struct {
+	// Prefix is the Redis key prefix (default "idempotency")
+	Prefix string
+	// TTL is how long completed responses are replayed (default 24h)
+	TTL time.Duration
+	// LockTTL is the max time a request may hold its key in flight (default 1m)
+	LockTTL time.Duration
+	// ClaimsKey is the gin context key of the verified JWT claims set by the authentication
+	// middleware; their "sub" claim scopes the keys (default "claims")
+	ClaimsKey string
+}
+
+// idempotencyRecord is the stored state of an idempotency key.
+type idempotencyRecord struct {
+	State       string `json:"state"`
+	Token       string `json:"token"`
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// responseRecorder captures the response body while writing it to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// SetIdempotencyMiddleware creates a middleware that enforces Idempotency-Key semantics
+// for POST, PUT, PATCH and DELETE requests.
+//
+// The first request with a key reserves it together with a fingerprint of the method,
+// route, query and body. A 2xx response is stored and replayed for retries with the same
+// key; other responses release the key so the client can retry. While the first request
+// is in flight, duplicates get 409 Conflict; a key reused with a different payload gets
+// 422 Unprocessable Entity. If Redis is unreachable, requests are processed normally.
+//
+// Keys are scoped by route, tenant and the subject of the verified JWT claims, so clients
+// reusing a key never get each other's responses. Register this middleware after the
+// tenant and authentication middleware.
+//
+// Parameters:
+//   - rdb: The Redis client used to store records
+//   - cfg: Idempotency configuration
+//   - logger: The zap logger instance used to report store failures
+//
+// Returns:
+//   - gin.HandlerFunc: A Gin middleware function that handles idempotency keys
+func SetIdempotencyMiddleware(rdb *redis.Client, cfg IdempotencyConfig, logger *zap.Logger) gin.HandlerFunc {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "idempotency"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = time.Minute
+	}
+	if cfg.ClaimsKey == "" {
+		cfg.ClaimsKey = "claims"
+	}
+	subject := TenantFromClaims(cfg.ClaimsKey, "sub")
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" || !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "read request body error"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		recordKey := cfg.Prefix + ":" + c.Request.Method + " " + route + ":" + idempotencyCaller(c, subject) + key
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body)
+
+		ctx := c.Request.Context()
+		rec, reserved, err := reserveIdempotencyKey(ctx, rdb, recordKey, fingerprint, cfg.LockTTL)
+		if err != nil {
+			logger.Warn("idempotency store unavailable, processing request without it", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+					"message": "Idempotency-Key was already used with a different request payload",
+				})
+			case rec.State == idempotencyInProgress:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+					"message": "a request with the same Idempotency-Key is still being processed",
+				})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(rec.Status, rec.ContentType, rec.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		bgCtx := context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if status < 200 || status >= 300 {
+			if err := finishIdempotencyKey(bgCtx, rdb, recordKey, rec, nil, 0); err != nil {
+				logger.Warn("release idempotency key failed", zap.Error(err))
+			}
+			return
+		}
+
+		rec.State = idempotencyCompleted
+		rec.Status = status
+		rec.ContentType = recorder.Header().Get("Content-Type")
+		rec.Body = recorder.body.Bytes()
+		if err := finishIdempotencyKey(bgCtx, rdb, recordKey, rec, rec, cfg.TTL); err != nil {
+			logger.Warn("store idempotent response failed", zap.Error(err))
+		}
+	}
+}
+
+// idempotencyCaller returns the key segment of the request's caller: a hash of its tenant
+// and subject followed by ":", or "" for an anonymous request without tenant.
+func idempotencyCaller(c *gin.Context, subject TenantResolver) string {
+	tenantID, _ := tenant.FromContext(c.Request.Context())
+	sub := subject(c)
+	if tenantID == "" && sub == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(tenantID + "\x00" + sub))
+	return hex.EncodeToString(h[:8]) + ":"
+}
+
+// reserveIdempotencyKey claims a key or returns its existing record.
+func reserveIdempotencyKey(ctx context.Context, rdb *redis.Client, key, fingerprint string, lockTTL time.Duration) (*idempotencyRecord, bool, error) {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+	rec := &idempotencyRecord{State: idempotencyInProgress, Token: hex.EncodeToString(token), Fingerprint: fingerprint}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, false, err
+	}
+
+	res, err := idempotencyReserveScript.Run(ctx, rdb, []string{key}, data, lockTTL.Milliseconds()).Result()
+	if errors.Is(err, redis.Nil) {
+		return rec, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	raw, _ := res.(string)
+	var existing idempotencyRecord
+	if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// finishIdempotencyKey stores next as the record of a held key, or deletes the key if next is nil.
+func finishIdempotencyKey(ctx context.Context, rdb *redis.Client, key string, held, next *idempotencyRecord, ttl time.Duration) error {
+	var data []byte
+	if next != nil {
+		var err error
+		if data, err = json.Marshal(next); err != nil {
+			return err
+		}
+	}
+	return idempotencyOwnerScript.Run(ctx, rdb, []string{key}, held.Token, string(data), ttl.Milliseconds()).Err()
+}
+
+// requestFingerprint hashes the parts of a request that identify its payload.
+func requestFingerprint(method, path, query string, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{method, path, query} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isMutatingMethod reports whether an HTTP method changes server state.
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mengbin92/example/lib/db"
+	"github.com/mengbin92/example/lib/db/tenant"
+	"github.com/mengbin92/example/lib/utils"
+	"gorm.io/gorm"
+)
+
+// TenantResolver extracts the tenant ID of a request; it returns "" if the request names none.
+type TenantResolver func(c *gin.Context) string
+
+// TenantFromHeader resolves the tenant from a request header, e.g. "X-Tenant-ID".
+func TenantFromHeader(name string) TenantResolver {
+	return func(c *gin.Context) string {
+		return strings.TrimSpace(c.GetHeader(name))
+	}
+}
+
+// TenantFromClaims resolves the tenant from a claim of the verified JWT claims that the
+// authentication middleware stored with c.Set(key, claims), e.g. a jwt.MapClaims.
+func TenantFromClaims(key, claim string) TenantResolver {
+	return func(c *gin.Context) string {
+		claims, ok := c.Get(key)
+		if !ok {
+			return ""
+		}
+		rv := reflect.ValueOf(claims)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return ""
+		}
+		v := rv.MapIndex(reflect.ValueOf(claim).Convert(rv.Type().Key()))
+		if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+			return ""
+		}
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// TenantFromSubdomain resolves the tenant from the first label of the request host below
+// domain, e.g. "acme" for "acme.example.com" with domain "example.com".
+func TenantFromSubdomain(domain string) TenantResolver {
+	suffix := "." + strings.TrimPrefix(strings.ToLower(domain), ".")
+	return func(c *gin.Context) string {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(host)
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if sub == "" || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// SetTenantMiddleware creates a middleware that stores the tenant of the request in the
+// request context. Resolvers are tried in order and the first non-empty tenant wins.
+// The database retrieved with factory.DB(ctx) is replaced by a session bound to the tenant:
+// its connection in "database" mode, otherwise the injected database scoped by the tenant
+// plugin. Register it after SetDBMiddleware.
+//
+// Parameters:
+//   - required: Reject requests without a tenant with 400 Bad Request
+//   - resolvers: The tenant sources, e.g. TenantFromHeader("X-Tenant-ID")
+//
+// Returns:
+//   - gin.HandlerFunc: A Gin middleware function that adds the tenant to context
+func SetTenantMiddleware(required bool, resolvers ...TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var id string
+		for _, resolve := range resolvers {
+			if id = resolve(c); id != "" {
+				break
+			}
+		}
+		if id == "" {
+			if required {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "the request does not identify a tenant"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		ctx := tenant.WithTenant(c.Request.Context(), id)
+		name, err := db.TenantConnection(ctx)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "unknown tenant"})
+			return
+		}
+		if name != db.DefaultName {
+			ctx = context.WithValue(ctx, utils.ContextKey("DB"), db.Get(name).WithContext(ctx))
+		} else if gormDB, ok := ctx.Value(utils.ContextKey("DB")).(*gorm.DB); ok {
+			ctx = context.WithValue(ctx, utils.ContextKey("DB"), gormDB.WithContext(ctx))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,437 @@
+// Package migrate provides versioned schema migrations.
+// Migrations are SQL files named "{version}_{name}.up.sql" / "{version}_{name}.down.sql"
+// loaded from an fs.FS (usually an embed.FS), or Go functions added with Register.
+// Applied versions are recorded in the schema_migrations table together with a checksum
+// of the migration, and a database lock ensures only one replica migrates at a time.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTable       = "schema_migrations"
+	defaultLockTimeout = time.Minute
+)
+
+// ErrChecksumMismatch is returned when an applied migration was modified afterwards.
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// fileName matches "{version}_{name}.up.sql" and "{version}_{name}.down.sql".
+var fileName = regexp.MustCompile(`^(\d+)_([a-zA-Z0-9_\-]+)\.(up|down)\.sql$`)
+
+// GoFunc is a migration step implemented in Go. It runs inside the migration transaction.
+type GoFunc func(ctx context.Context, tx *sql.Tx) error
+
+// Migration is one versioned schema change.
+type Migration struct {
+	// Version orders migrations; by convention a UTC timestamp such as 20260102150405
+	Version int64
+	// Name describes the change
+	Name string
+	// UpSQL and DownSQL hold the statements of a SQL migration
+	UpSQL, DownSQL string
+	// Up and Down implement a Go migration
+	Up, Down GoFunc
+	// Checksum identifies the content of the migration
+	Checksum string
+}
+
+// Status is the state of a migration in the database.
+type Status struct {
+	// Version and Name identify the migration
+	Version int64
+	Name    string
+	// Applied reports whether the migration has been applied
+	Applied bool
+	// AppliedAt is when the migration was applied
+	AppliedAt time.Time
+	// Modified reports whether the migration changed after it was applied
+	Modified bool
+	// Missing reports an applied version that no longer exists in the source
+	Missing bool
+}
+
+var (
+	// registry holds the Go migrations added with Register
+	registry   = map[int64]*Migration{}
+	registryMu sync.Mutex
+)
+
+// Register adds a Go migration. It is meant to be called from init functions of the
+// package holding the migrations, and panics on duplicate versions.
+//
+// Parameters:
+//   - version: The migration version
+//   - name: The migration name
+//   - up: The function applying the change
+//   - down: The function reverting the change (may be nil if the change is irreversible)
+func Register(version int64, name string, up, down GoFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[version]; ok {
+		panic("migrate: duplicate go migration version " + strconv.FormatInt(version, 10))
+	}
+	registry[version] = &Migration{
+		Version:  version,
+		Name:     name,
+		Up:       up,
+		Down:     down,
+		Checksum: "go:" + name,
+	}
+}
+
+// Migrator applies and reverts migrations on a database.
+type Migrator struct {
+	db          *sql.DB
+	dialect     dialect
+	migrations  []*Migration
+	table       string
+	lockTimeout time.Duration
+	log         *zap.Logger
+}
+
+// Option configures a Migrator.
+type Option func(*Migrator)
+
+// WithTable sets the name of the migrations table (default "schema_migrations").
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockTimeout sets how long to wait for another replica to finish migrating (default 1m).
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = d
+	}
+}
+
+// New creates a migrator.
+//
+// Parameters:
+//   - db: The database to migrate
+//   - dialectName: The database dialect: "mysql", "postgres" or "sqlite"
+//   - fsys: File system holding the SQL migration files at its root (may be nil)
+//   - logger: Logger instance for migration progress
+//   - opts: Optional settings
+//
+// Returns:
+//   - *Migrator: A new migrator with the SQL and registered Go migrations loaded
+//   - error: Error if the dialect is unsupported or the migrations are invalid
+func New(db *sql.DB, dialectName string, fsys fs.FS, logger *zap.Logger, opts ...Option) (*Migrator, error) {
+	d, err := newDialect(dialectName)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:          db,
+		dialect:     d,
+		table:       defaultTable,
+		lockTimeout: defaultLockTimeout,
+		log:         logger.With(zap.String("module", "migrate")),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.migrations, err = load(fsys); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Migrations returns the known migrations ordered by version.
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// Up applies all pending migrations in version order.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//
+// Returns:
+//   - []*Migration: The migrations applied by this call
+//   - error: Error if a migration fails or an applied migration was modified
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := m.locked(ctx, func(applied map[int64]appliedRow) error {
+		for _, mig := range m.migrations {
+			row, ok := applied[mig.Version]
+			if ok {
+				if row.checksum != mig.Checksum {
+					return errors.Wrapf(ErrChecksumMismatch, "version %d (%s)", mig.Version, mig.Name)
+				}
+				continue
+			}
+			m.log.Info("applying migration", zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+			if err := m.apply(ctx, mig, true); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the most recently applied migrations.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - steps: Number of migrations to revert (values < 1 revert one)
+//
+// Returns:
+//   - []*Migration: The migrations reverted by this call
+//   - error: Error if a migration fails, is irreversible or is missing from the source
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	if steps < 1 {
+		steps = 1
+	}
+
+	byVersion := make(map[int64]*Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var done []*Migration
+	err := m.locked(ctx, func(applied map[int64]appliedRow) error {
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mig, ok := byVersion[versions[i]]
+			if !ok {
+				return errors.Errorf("applied migration %d is missing from the source", versions[i])
+			}
+			if mig.DownSQL == "" && mig.Down == nil {
+				return errors.Errorf("migration %d_%s is irreversible", mig.Version, mig.Name)
+			}
+			m.log.Info("reverting migration", zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+			if err := m.apply(ctx, mig, false); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status reports the state of every known and applied migration ordered by version.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//
+// Returns:
+//   - []Status: One entry per migration
+//   - error: Error if the migrations table cannot be read
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = row.appliedAt
+			st.Modified = row.checksum != mig.Checksum
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, st)
+	}
+	for v, row := range applied {
+		statuses = append(statuses, Status{Version: v, Name: row.name, Applied: true, AppliedAt: row.appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// appliedRow is a row of the migrations table.
+type appliedRow struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// locked runs fn while holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(applied map[int64]appliedRow) error) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.dialect.lock(ctx, m.db, m.table, m.lockTimeout)
+	if err != nil {
+		return errors.Wrap(err, "acquire migration lock")
+	}
+	defer unlock()
+
+	// Read the state only after locking so changes made by another replica are seen.
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	return fn(applied)
+}
+
+// ensureTable creates the migrations table if needed.
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	return errors.Wrap(err, "create migrations table")
+}
+
+// applied reads the migrations table.
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedRow, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+m.table)
+	if err != nil {
+		return nil, errors.Wrap(err, "read migrations table")
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedRow)
+	for rows.Next() {
+		var (
+			version int64
+			row     appliedRow
+		)
+		if err := rows.Scan(&version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, errors.Wrap(err, "scan migrations table")
+		}
+		applied[version] = row
+	}
+	return applied, errors.Wrap(rows.Err(), "read migrations table")
+}
+
+// apply runs one migration step and records it in the same transaction.
+// MySQL commits DDL statements implicitly, so a failed MySQL migration may be partially applied.
+func (m *Migrator) apply(ctx context.Context, mig *Migration, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "begin migration transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	fn, script := mig.Down, mig.DownSQL
+	if up {
+		fn, script = mig.Up, mig.UpSQL
+	}
+	if fn != nil {
+		err = fn(ctx, tx)
+	} else {
+		for _, stmt := range splitStatements(script) {
+			if _, err = tx.ExecContext(ctx, stmt); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "migration %d_%s", mig.Version, mig.Name)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, m.dialect.rebind("INSERT INTO "+m.table+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+			mig.Version, mig.Name, mig.Checksum, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, m.dialect.rebind("DELETE FROM "+m.table+" WHERE version = ?"), mig.Version)
+	}
+	if err != nil {
+		return errors.Wrap(err, "record migration")
+	}
+	return errors.Wrap(tx.Commit(), "commit migration")
+}
+
+// load reads the SQL migrations from fsys and merges the registered Go migrations.
+func load(fsys fs.FS) ([]*Migration, error) {
+	byVersion := make(map[int64]*Migration)
+
+	if fsys != nil {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, errors.Wrap(err, "read migrations directory")
+		}
+		for _, entry := range entries {
+			match := fileName.FindStringSubmatch(entry.Name())
+			if entry.IsDir() || match == nil {
+				continue
+			}
+			version, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse version of %s", entry.Name())
+			}
+			data, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+			if err != nil {
+				return nil, errors.Wrapf(err, "read %s", entry.Name())
+			}
+
+			mig, ok := byVersion[version]
+			if !ok {
+				mig = &Migration{Version: version, Name: match[2]}
+				byVersion[version] = mig
+			} else if mig.Name != match[2] {
+				return nil, errors.Errorf("migration version %d is used by %s and %s", version, mig.Name, match[2])
+			}
+			if match[3] == "up" {
+				mig.UpSQL = string(data)
+			} else {
+				mig.DownSQL = string(data)
+			}
+		}
+	}
+
+	for version, mig := range byVersion {
+		if strings.TrimSpace(mig.UpSQL) == "" {
+			return nil, errors.Errorf("migration %d_%s has no up.sql", version, mig.Name)
+		}
+		sum := sha256.Sum256([]byte(mig.UpSQL))
+		mig.Checksum = hex.EncodeToString(sum[:])
+	}
+
+	registryMu.Lock()
+	for version, mig := range registry {
+		if _, ok := byVersion[version]; ok {
+			registryMu.Unlock()
+			return nil, errors.Errorf("migration version %d is defined in SQL and Go", version)
+		}
+		byVersion[version] = mig
+	}
+	registryMu.Unlock()
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
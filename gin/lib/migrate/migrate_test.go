@@ -0,0 +1,161 @@
+package migrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int64
+		names    []string
+		wantErr  string
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"20260102000000_add_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id INTEGER);")},
+				"20260102000000_add_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+				"20260101000000_add_users.up.sql":    {Data: []byte("CREATE TABLE users (id INTEGER);")},
+			},
+			versions: []int64{20260101000000, 20260102000000},
+			names:    []string{"add_users", "add_orders"},
+		},
+		{
+			name: "other files are ignored",
+			fsys: fstest.MapFS{
+				"1_init.up.sql":  {Data: []byte("SELECT 1;")},
+				"README.md":      {Data: []byte("# migrations")},
+				"2_init.sql":     {Data: []byte("SELECT 2;")},
+				"sub/3_x.up.sql": {Data: []byte("SELECT 3;")},
+			},
+			versions: []int64{1},
+			names:    []string{"init"},
+		},
+		{
+			name:    "down without up",
+			fsys:    fstest.MapFS{"1_init.down.sql": {Data: []byte("DROP TABLE t;")}},
+			wantErr: "has no up.sql",
+		},
+		{
+			name:    "empty up",
+			fsys:    fstest.MapFS{"1_init.up.sql": {Data: []byte("  \n")}},
+			wantErr: "has no up.sql",
+		},
+		{
+			name: "version used twice",
+			fsys: fstest.MapFS{
+				"1_init.up.sql":  {Data: []byte("SELECT 1;")},
+				"1_other.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "is used by",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			var versions []int64
+			var names []string
+			for _, mig := range migrations {
+				versions = append(versions, mig.Version)
+				names = append(names, mig.Name)
+				if mig.Checksum == "" {
+					t.Errorf("migration %d has no checksum", mig.Version)
+				}
+			}
+			if !reflect.DeepEqual(versions, tt.versions) || !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("load() = %v %v, want %v %v", versions, names, tt.versions, tt.names)
+			}
+		})
+	}
+}
+
+func TestLoadChecksum(t *testing.T) {
+	a, err := load(fstest.MapFS{"1_init.up.sql": {Data: []byte("SELECT 1;")}, "1_init.down.sql": {Data: []byte("SELECT 0;")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := load(fstest.MapFS{"1_init.up.sql": {Data: []byte("SELECT 1;")}, "1_init.down.sql": {Data: []byte("SELECT 2;")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := load(fstest.MapFS{"1_init.up.sql": {Data: []byte("SELECT 2;")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a[0].Checksum != b[0].Checksum {
+		t.Error("checksum changed with the down script")
+	}
+	if a[0].Checksum == c[0].Checksum {
+		t.Error("checksum did not change with the up script")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "single",
+			script: "CREATE TABLE t (id INTEGER)",
+			want:   []string{"CREATE TABLE t (id INTEGER)"},
+		},
+		{
+			name:   "several with blank statements",
+			script: "CREATE TABLE a (id INTEGER);\n\n;CREATE TABLE b (id INTEGER);\n",
+			want:   []string{"CREATE TABLE a (id INTEGER)", "CREATE TABLE b (id INTEGER)"},
+		},
+		{
+			name:   "semicolon in quotes",
+			script: `INSERT INTO t VALUES ('a;b', "c;d", ` + "`e;f`" + `); INSERT INTO t VALUES ('it''s;');`,
+			want:   []string{`INSERT INTO t VALUES ('a;b', "c;d", ` + "`e;f`" + `)`, `INSERT INTO t VALUES ('it''s;')`},
+		},
+		{
+			name:   "backslash escape",
+			script: `INSERT INTO t VALUES ('a\';b'); SELECT 1;`,
+			want:   []string{`INSERT INTO t VALUES ('a\';b')`, "SELECT 1"},
+		},
+		{
+			name:   "comments",
+			script: "-- first; table\nCREATE TABLE a (id INTEGER); /* drop; it */ SELECT 1;\n-- trailing;",
+			want:   []string{"-- first; table\nCREATE TABLE a (id INTEGER)", "/* drop; it */ SELECT 1"},
+		},
+		{
+			name:   "dollar quoted body",
+			script: "CREATE FUNCTION f() RETURNS void AS $$ BEGIN PERFORM 1; END; $$ LANGUAGE plpgsql; SELECT 1;",
+			want:   []string{"CREATE FUNCTION f() RETURNS void AS $$ BEGIN PERFORM 1; END; $$ LANGUAGE plpgsql", "SELECT 1"},
+		},
+		{
+			name:   "tagged dollar quote",
+			script: "DO $body$ BEGIN RAISE NOTICE '$$;'; END $body$; SELECT $1;",
+			want:   []string{"DO $body$ BEGIN RAISE NOTICE '$$;'; END $body$", "SELECT $1"},
+		},
+		{
+			name:   "only comments",
+			script: "-- nothing here;\n-- at all",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Package seed applies reference data to the database: roles, countries, default settings
+// and other rows an environment needs before it is usable.
+//
+// A seed set is either a YAML or JSON file listing rows per table, or a Go function added
+// with Register. Rows are upserted by their natural keys, so running the seeds again
+// updates the rows instead of duplicating them. Each set may be limited to environments
+// (e.g. only "dev"), and every set runs in its own transaction.
+//
+// File format (JSON uses the same structure):
+//
+//	envs: [dev, staging]        # optional; all environments if omitted
+//	database: default           # optional connection name
+//	tables:
+//	  - table: roles
+//	    keys: [code]            # natural key columns identifying a row
+//	    insert_only: false      # keep existing rows unchanged
+//	    rows:
+//	      - code: admin
+//	        name: Administrator
+package seed
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mengbin92/example/lib/db"
+	"github.com/mengbin92/example/lib/db/audit"
+	"github.com/mengbin92/example/lib/db/tenant"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// actor is recorded in the audit trail for seeded rows.
+const actor = "seed"
+
+// Func applies a seed set using tx, the transaction of the set. It must be idempotent;
+// Upsert helps with that.
+type Func func(ctx context.Context, tx *gorm.DB) error
+
+// Set is a named unit of seed data.
+type Set struct {
+	// Name identifies the set; sets run in name order, so prefix names with numbers
+	// (e.g. "010_roles") when one set depends on another
+	Name string
+	// Envs limits the set to these environments; empty means all environments
+	Envs []string
+	// Connection is the database connection of the set (default "default")
+	Connection string
+	// Run applies the set
+	Run Func
+}
+
+// Result reports the rows written by a set through Upsert.
+type Result struct {
+	// Set is the set name
+	Set string
+	// Inserted is the number of new rows
+	Inserted int
+	// Updated is the number of rows that already existed and were updated
+	Updated int
+	// Unchanged is the number of existing rows kept as they are (insert_only)
+	Unchanged int
+}
+
+var (
+	// registered holds the sets added with Register by name
+	registered = map[string]Set{}
+	// registeredMu protects registered
+	registeredMu sync.RWMutex
+)
+
+// Register adds a Go seed set. Call it from an init function of the seeds package.
+//
+// Parameters:
+//   - s: The seed set
+//
+// Panics:
+//   - If the name is empty or already registered, or Run is nil
+func Register(s Set) {
+	if s.Name == "" || s.Run == nil {
+		panic("seed: Register requires a name and a function")
+	}
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	if _, ok := registered[s.Name]; ok {
+		panic("seed: Register called twice for " + s.Name)
+	}
+	registered[s.Name] = s
+}
+
+// Seeder runs the registered seed sets and the sets of a file system.
+type Seeder struct {
+	sets []Set
+	log  *zap.Logger
+}
+
+// New creates a seeder from the registered Go sets and the YAML (.yml, .yaml) and JSON
+// files at the root of fsys. A file set is named after its file without the extension.
+//
+// Parameters:
+//   - fsys: File system with the seed files, e.g. seeds.FS(); nil for Go sets only
+//   - logger: Logger for seeding progress
+//
+// Returns:
+//   - *Seeder: A seeder with the sets in name order
+//   - error: Error if a file is invalid or two sets have the same name
+func New(fsys fs.FS, logger *zap.Logger) (*Seeder, error) {
+	byName := map[string]Set{}
+	registeredMu.RLock()
+	for name, s := range registered {
+		byName[name] = s
+	}
+	registeredMu.RUnlock()
+
+	if fsys != nil {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, errors.Wrap(err, "read seed files")
+		}
+		for _, entry := range entries {
+			ext := path.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".yml" && ext != ".yaml" && ext != ".json") {
+				continue
+			}
+			data, err := fs.ReadFile(fsys, entry.Name())
+			if err != nil {
+				return nil, errors.Wrap(err, "read seed file")
+			}
+			s, err := parseFile(strings.TrimSuffix(entry.Name(), ext), data)
+			if err != nil {
+				return nil, errors.Wrapf(err, "seed file %s", entry.Name())
+			}
+			if _, ok := byName[s.Name]; ok {
+				return nil, errors.Errorf("duplicate seed set %q", s.Name)
+			}
+			byName[s.Name] = s
+		}
+	}
+
+	sets := make([]Set, 0, len(byName))
+	for _, s := range byName {
+		if s.Connection == "" {
+			s.Connection = db.DefaultName
+		}
+		sets = append(sets, s)
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	return &Seeder{sets: sets, log: logger.With(zap.String("module", "seed"))}, nil
+}
+
+// Sets returns the sets applying to an environment in name order.
+//
+// Parameters:
+//   - env: The environment, e.g. "dev"; empty selects only sets without Envs
+//
+// Returns:
+//   - []Set: The applicable sets
+func (s *Seeder) Sets(env string) []Set {
+	var sets []Set
+	for _, set := range s.sets {
+		if len(set.Envs) == 0 || slices.Contains(set.Envs, env) {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// Run applies the sets of an environment, each in its own transaction.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - env: The environment selecting the sets
+//   - names: Optional set names; all sets of the environment if omitted
+//
+// Returns:
+//   - []Result: One result per applied set
+//   - error: Error if a named set does not apply to env or a set fails; earlier sets stay applied
+func (s *Seeder) Run(ctx context.Context, env string, names ...string) ([]Result, error) {
+	sets := s.Sets(env)
+	if len(names) > 0 {
+		selected := make([]Set, 0, len(names))
+		for _, name := range names {
+			i := slices.IndexFunc(sets, func(set Set) bool { return set.Name == name })
+			if i < 0 {
+				return nil, errors.Errorf("seed set %q does not exist or does not apply to environment %q", name, env)
+			}
+			selected = append(selected, sets[i])
+		}
+		sets = selected
+	}
+
+	ctx = audit.ContextWithActor(tenant.SkipTenant(ctx), actor)
+	results := make([]Result, 0, len(sets))
+	for _, set := range sets {
+		res := &Result{Set: set.Name}
+		gormDB := db.Get(set.Connection)
+		if gormDB == nil {
+			return results, errors.Errorf("seed set %q: database %q is not initialized", set.Name, set.Connection)
+		}
+		err := gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return set.Run(context.WithValue(ctx, resultKey{}, res), tx)
+		})
+		if err != nil {
+			return results, errors.Wrapf(err, "seed set %q", set.Name)
+		}
+		results = append(results, *res)
+		s.log.Info("applied seed set", zap.String("set", res.Set),
+			zap.Int("inserted", res.Inserted), zap.Int("updated", res.Updated), zap.Int("unchanged", res.Unchanged))
+	}
+	return results, nil
+}
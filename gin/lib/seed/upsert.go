@@ -0,0 +1,140 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// resultKey is the context key of the Result counting the rows of a set.
+type resultKey struct{}
+
+// Row is a row of seed data keyed by column name.
+type Row = map[string]interface{}
+
+// Upsert writes rows identified by natural key columns: a row whose keys match an existing
+// row updates it (or leaves it unchanged if insertOnly is set), other rows are inserted.
+// Matching uses a query instead of ON CONFLICT, so the key columns need no unique index
+// and the same code works with every driver. Nested maps and lists are stored as JSON.
+//
+// Parameters:
+//   - ctx: The context passed to the set's Func
+//   - tx: The transaction passed to the set's Func
+//   - table: The table name
+//   - keys: The natural key columns; every row must set them
+//   - insertOnly: Keep existing rows unchanged instead of updating them
+//   - rows: The rows to write
+//
+// Returns:
+//   - error: Error if a row lacks a key column or a statement fails
+func Upsert(ctx context.Context, tx *gorm.DB, table string, keys []string, insertOnly bool, rows ...Row) error {
+	if len(keys) == 0 {
+		return errors.Errorf("table %s: seed rows need at least one key column", table)
+	}
+	res, _ := ctx.Value(resultKey{}).(*Result)
+	if res == nil {
+		res = &Result{}
+	}
+	tx = tx.WithContext(ctx)
+
+	for i, row := range rows {
+		values := make(Row, len(row))
+		for col, v := range row {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+				b, err := json.Marshal(v)
+				if err != nil {
+					return errors.Wrapf(err, "table %s row %d column %s", table, i, col)
+				}
+				values[col] = string(b)
+			default:
+				values[col] = v
+			}
+		}
+
+		cond := make(Row, len(keys))
+		for _, k := range keys {
+			v, ok := values[k]
+			if !ok {
+				return errors.Errorf("table %s row %d: missing key column %s", table, i, k)
+			}
+			cond[k] = v
+		}
+
+		var count int64
+		if err := tx.Table(table).Where(cond).Count(&count).Error; err != nil {
+			return errors.Wrapf(err, "table %s row %d: find", table, i)
+		}
+		switch {
+		case count == 0:
+			if err := tx.Table(table).Create(values).Error; err != nil {
+				return errors.Wrapf(err, "table %s row %d: insert", table, i)
+			}
+			res.Inserted++
+		case count > 1:
+			return errors.Errorf("table %s row %d: keys %v match %d rows", table, i, keys, count)
+		case insertOnly || len(values) == len(cond):
+			res.Unchanged++
+		default:
+			updates := make(Row, len(values))
+			for col, v := range values {
+				if _, isKey := cond[col]; !isKey {
+					updates[col] = v
+				}
+			}
+			if err := tx.Table(table).Where(cond).Updates(updates).Error; err != nil {
+				return errors.Wrapf(err, "table %s row %d: update", table, i)
+			}
+			res.Updated++
+		}
+	}
+	return nil
+}
+
+// file is the structure of a seed file.
+type file struct {
+	Envs     []string    `yaml:"envs"`
+	Database string      `yaml:"database"`
+	Tables   []fileTable `yaml:"tables"`
+}
+
+// fileTable is the seed data of a table in a seed file.
+type fileTable struct {
+	Table      string   `yaml:"table"`
+	Keys       []string `yaml:"keys"`
+	InsertOnly bool     `yaml:"insert_only"`
+	Rows       []Row    `yaml:"rows"`
+}
+
+// parseFile parses a YAML or JSON seed file into a set.
+func parseFile(name string, data []byte) (Set, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return Set{}, errors.Wrap(err, "parse")
+	}
+	for _, t := range f.Tables {
+		if t.Table == "" || len(t.Keys) == 0 {
+			return Set{}, errors.New("every table needs a name and key columns")
+		}
+	}
+
+	return Set{
+		Name:       name,
+		Envs:       f.Envs,
+		Connection: f.Database,
+		Run: func(ctx context.Context, tx *gorm.DB) error {
+			for _, t := range f.Tables {
+				if err := Upsert(ctx, tx, t.Table, t.Keys, t.InsertOnly, t.Rows...); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}, nil
+}
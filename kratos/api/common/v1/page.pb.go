@@ -0,0 +1,473 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v6.33.1
+// source: common/v1/page.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Operator is the comparison applied by a Filter
+type Operator int32
+
+const (
+	// Equal; the default
+	Operator_OPERATOR_UNSPECIFIED Operator = 0
+	Operator_EQ                   Operator = 1
+	Operator_NE                   Operator = 2
+	Operator_GT                   Operator = 3
+	Operator_GTE                  Operator = 4
+	Operator_LT                   Operator = 5
+	Operator_LTE                  Operator = 6
+	// Value is one of values
+	Operator_IN Operator = 7
+	// Value contains the text (case sensitivity depends on the database collation)
+	Operator_CONTAINS Operator = 8
+)
+
+// Enum value maps for Operator.
+var (
+	Operator_name = map[int32]string{
+		0: "OPERATOR_UNSPECIFIED",
+		1: "EQ",
+		2: "NE",
+		3: "GT",
+		4: "GTE",
+		5: "LT",
+		6: "LTE",
+		7: "IN",
+		8: "CONTAINS",
+	}
+	Operator_value = map[string]int32{
+		"OPERATOR_UNSPECIFIED": 0,
+		"EQ":                   1,
+		"NE":                   2,
+		"GT":                   3,
+		"GTE":                  4,
+		"LT":                   5,
+		"LTE":                  6,
+		"IN":                   7,
+		"CONTAINS":             8,
+	}
+)
+
+func (x Operator) Enum() *Operator {
+	p := new(Operator)
+	*p = x
+	return p
+}
+
+func (x Operator) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Operator) Descriptor() protoreflect.EnumDescriptor {
+	return file_common_v1_page_proto_enumTypes[0].Descriptor()
+}
+
+func (Operator) Type() protoreflect.EnumType {
+	return &file_common_v1_page_proto_enumTypes[0]
+}
+
+func (x Operator) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Operator.Descriptor instead.
+func (Operator) EnumDescriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{0}
+}
+
+// Filter restricts a list to items whose field matches a value.
+// Only fields whitelisted by the endpoint can be filtered.
+type Filter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Field name, e.g. "status"
+	Field string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	// Comparison operator, EQ if unset
+	Op Operator `protobuf:"varint,2,opt,name=op,proto3,enum=api.common.v1.Operator" json:"op,omitempty"`
+	// Value compared with the field
+	Value string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// Values for the IN operator
+	Values        []string `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_common_v1_page_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Filter) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Filter) GetOp() Operator {
+	if x != nil {
+		return x.Op
+	}
+	return Operator_OPERATOR_UNSPECIFIED
+}
+
+func (x *Filter) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Filter) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// Sort orders a list by a field.
+// Only fields whitelisted by the endpoint can be sorted on.
+type Sort struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Field name, e.g. "created_at"
+	Field string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	// Descending order
+	Desc          bool `protobuf:"varint,2,opt,name=desc,proto3" json:"desc,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sort) Reset() {
+	*x = Sort{}
+	mi := &file_common_v1_page_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sort) ProtoMessage() {}
+
+func (x *Sort) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sort.ProtoReflect.Descriptor instead.
+func (*Sort) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Sort) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Sort) GetDesc() bool {
+	if x != nil {
+		return x.Desc
+	}
+	return false
+}
+
+// PageRequest selects a page of a list.
+// Use either page (offset pagination) or page_token (cursor pagination).
+type PageRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Max items per page; the endpoint applies its default and upper bound
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// 1-based page number for offset pagination
+	Page int32 `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	// Cursor from PageResponse.next_page_token; takes precedence over page
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Sort order; the endpoint's default order if empty
+	Sort []*Sort `protobuf:"bytes,4,rep,name=sort,proto3" json:"sort,omitempty"`
+	// Filters combined with AND
+	Filters       []*Filter `protobuf:"bytes,5,rep,name=filters,proto3" json:"filters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageRequest) Reset() {
+	*x = PageRequest{}
+	mi := &file_common_v1_page_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageRequest) ProtoMessage() {}
+
+func (x *PageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageRequest.ProtoReflect.Descriptor instead.
+func (*PageRequest) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PageRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *PageRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *PageRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *PageRequest) GetSort() []*Sort {
+	if x != nil {
+		return x.Sort
+	}
+	return nil
+}
+
+func (x *PageRequest) GetFilters() []*Filter {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+// PageResponse describes the returned page of a list.
+type PageResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Total number of matching items (offset pagination only)
+	Total int64 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	// Page number (offset pagination only)
+	Page int32 `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	// Items per page
+	PageSize int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// Cursor of the next page, empty on the last page
+	NextPageToken string `protobuf:"bytes,4,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageResponse) Reset() {
+	*x = PageResponse{}
+	mi := &file_common_v1_page_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageResponse) ProtoMessage() {}
+
+func (x *PageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_common_v1_page_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageResponse.ProtoReflect.Descriptor instead.
+func (*PageResponse) Descriptor() ([]byte, []int) {
+	return file_common_v1_page_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PageResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *PageResponse) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *PageResponse) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *PageResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+var File_common_v1_page_proto protoreflect.FileDescriptor
+
+const file_common_v1_page_proto_rawDesc = "" +
+	"\n" +
+	"\x14common/v1/page.proto\x12\rapi.common.v1\"u\n" +
+	"\x06Filter\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12'\n" +
+	"\x02op\x18\x02 \x01(\x0e2\x17.api.common.v1.OperatorR\x02op\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x16\n" +
+	"\x06values\x18\x04 \x03(\tR\x06values\"0\n" +
+	"\x04Sort\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x12\n" +
+	"\x04desc\x18\x02 \x01(\bR\x04desc\"\xb7\x01\n" +
+	"\vPageRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\x12'\n" +
+	"\x04sort\x18\x04 \x03(\v2\x13.api.common.v1.SortR\x04sort\x12/\n" +
+	"\afilters\x18\x05 \x03(\v2\x15.api.common.v1.FilterR\afilters\"}\n" +
+	"\fPageResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x03R\x05total\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12&\n" +
+	"\x0fnext_page_token\x18\x04 \x01(\tR\rnextPageToken*l\n" +
+	"\bOperator\x12\x18\n" +
+	"\x14OPERATOR_UNSPECIFIED\x10\x00\x12\x06\n" +
+	"\x02EQ\x10\x01\x12\x06\n" +
+	"\x02NE\x10\x02\x12\x06\n" +
+	"\x02GT\x10\x03\x12\a\n" +
+	"\x03GTE\x10\x04\x12\x06\n" +
+	"\x02LT\x10\x05\x12\a\n" +
+	"\x03LTE\x10\x06\x12\x06\n" +
+	"\x02IN\x10\a\x12\f\n" +
+	"\bCONTAINS\x10\bB;\n" +
+	"\rapi.common.v1P\x01Z(kratos-project-template/api/common/v1;v1b\x06proto3"
+
+var (
+	file_common_v1_page_proto_rawDescOnce sync.Once
+	file_common_v1_page_proto_rawDescData []byte
+)
+
+func file_common_v1_page_proto_rawDescGZIP() []byte {
+	file_common_v1_page_proto_rawDescOnce.Do(func() {
+		file_common_v1_page_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_common_v1_page_proto_rawDesc), len(file_common_v1_page_proto_rawDesc)))
+	})
+	return file_common_v1_page_proto_rawDescData
+}
+
+var file_common_v1_page_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_common_v1_page_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_common_v1_page_proto_goTypes = []any{
+	(Operator)(0),        // 0: api.common.v1.Operator
+	(*Filter)(nil),       // 1: api.common.v1.Filter
+	(*Sort)(nil),         // 2: api.common.v1.Sort
+	(*PageRequest)(nil),  // 3: api.common.v1.PageRequest
+	(*PageResponse)(nil), // 4: api.common.v1.PageResponse
+}
+var file_common_v1_page_proto_depIdxs = []int32{
+	0, // 0: api.common.v1.Filter.op:type_name -> api.common.v1.Operator
+	2, // 1: api.common.v1.PageRequest.sort:type_name -> api.common.v1.Sort
+	1, // 2: api.common.v1.PageRequest.filters:type_name -> api.common.v1.Filter
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_common_v1_page_proto_init() }
+func file_common_v1_page_proto_init() {
+	if File_common_v1_page_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_common_v1_page_proto_rawDesc), len(file_common_v1_page_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_common_v1_page_proto_goTypes,
+		DependencyIndexes: file_common_v1_page_proto_depIdxs,
+		EnumInfos:         file_common_v1_page_proto_enumTypes,
+		MessageInfos:      file_common_v1_page_proto_msgTypes,
+	}.Build()
+	File_common_v1_page_proto = out.File
+	file_common_v1_page_proto_goTypes = nil
+	file_common_v1_page_proto_depIdxs = nil
+}
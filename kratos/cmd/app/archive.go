@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/archive"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/storage"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// archiveUsage describes the archive subcommands.
+const archiveUsage = `usage: app -conf <path> archive <command>
+
+commands:
+  run [policy...]                               archive rows now (all policies by default)
+  list <policy> [-from date] [-to date]         list archived batches
+  restore <policy> [-from date] [-to date]      restore archived batches into the database
+
+dates are YYYY-MM-DD or RFC 3339 and bound the policy's time column`
+
+// runArchive executes an archive subcommand.
+//
+// Parameters:
+//   - ctx: Context for the database and storage operations
+//   - bc: The bootstrap configuration
+//   - logger: Logger instance for archival progress
+//   - args: Arguments after "archive"
+//
+// Returns:
+//   - error: Error if the command is unknown or fails
+func runArchive(ctx context.Context, bc *conf.Bootstrap, logger log.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(archiveUsage)
+	}
+	cmd := args[0]
+
+	var policy string
+	var from, to time.Time
+	switch cmd {
+	case "run":
+	case "list", "restore":
+		fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+		fromFlag := fs.String("from", "", "only batches with rows at or after this date")
+		toFlag := fs.String("to", "", "only batches with rows before this date")
+		if len(args) < 2 {
+			return errors.New(archiveUsage)
+		}
+		policy = args[1]
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		var err error
+		if from, err = parseArchiveDate(*fromFlag); err != nil {
+			return err
+		}
+		if to, err = parseArchiveDate(*toFlag); err != nil {
+			return err
+		}
+	default:
+		return errors.New(archiveUsage)
+	}
+
+	if err := initArchive(ctx, bc, logger); err != nil {
+		return err
+	}
+	defer db.Close()
+	archiver := archive.New(storage.Get(), bc.Data.GetArchive(), logger)
+
+	switch cmd {
+	case "run":
+		results, err := archiver.Run(ctx, args[1:]...)
+		for _, res := range results {
+			fmt.Printf("%s: archived %d rows in %d batches\n", res.Policy, res.Rows, res.Batches)
+		}
+		return err
+	case "list":
+		manifests, err := archiver.Manifests(ctx, policy, from, to)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "BATCH\tROWS\tFROM\tTO\tARCHIVED AT")
+		for _, m := range manifests {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", m.ID, m.Count,
+				m.MinTime.Format(time.RFC3339), m.MaxTime.Format(time.RFC3339), m.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+	default:
+		n, err := archiver.Restore(ctx, policy, from, to)
+		fmt.Printf("%s: restored %d rows\n", policy, n)
+		return err
+	}
+}
+
+// initArchive connects the databases and the object storage used by the policies.
+func initArchive(ctx context.Context, bc *conf.Bootstrap, logger log.Logger) error {
+	if err := initDatabases(ctx, bc, logger); err != nil {
+		return err
+	}
+	if err := storage.Init(ctx, bc.Data.GetObjectStorage(), logger); err != nil {
+		db.Close()
+		return errors.Wrap(err, "init object storage")
+	}
+	return nil
+}
+
+// initDatabases connects the default and the named database connections.
+func initDatabases(ctx context.Context, bc *conf.Bootstrap, logger log.Logger) error {
+	if err := db.Init(ctx, bc.Data.GetDatabase(), logger); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(bc.Data.GetDatabases()))
+	for name := range bc.Data.GetDatabases() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := db.InitNamed(ctx, name, bc.Data.GetDatabases()[name], logger); err != nil {
+			db.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// parseArchiveDate parses a -from or -to flag; an empty value is no bound.
+func parseArchiveDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid date %q, use YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
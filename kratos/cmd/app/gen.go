@@ -1,18 +1,49 @@
 package main
 
 import (
+	"context"
+	"time"
+
 	"kratos-project-template/internal/conf"
 	"kratos-project-template/internal/server"
+	"kratos-project-template/provider/cache"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/election"
+	"kratos-project-template/provider/eventbus"
 
 	"github.com/go-kratos/kratos/v2"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
 )
 
 // wireApp init kratos application.
 func wireApp(confServer *conf.Server, confData *conf.Data, logger log.Logger) (*kratos.App, func(), error) {
 	grpcServer := server.NewGRPCServer(confServer, logger)
 	httpServer := server.NewHTTPServer(confServer, logger)
-	app := newApp(logger, grpcServer, httpServer)
-	return app, nil, nil
+	servers := []transport.Server{grpcServer, httpServer, election.Default()}
+	if workerServer := server.NewWorkerServer(confData, logger); workerServer != nil {
+		servers = append(servers, workerServer)
+	}
+	if outboxRelay := server.NewOutboxRelay(confData, logger); outboxRelay != nil {
+		servers = append(servers, outboxRelay)
+	}
+	if archiveServer := server.NewArchiveServer(confData, logger); archiveServer != nil {
+		servers = append(servers, archiveServer)
+	}
+	app := newApp(logger, servers...)
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := eventbus.Default().Close(ctx); err != nil {
+			log.NewHelper(logger).Warnf("close event bus: %v", err)
+		}
+		if err := cache.Close(); err != nil {
+			log.NewHelper(logger).Warnf("close redis: %v", err)
+		}
+		if err := db.Close(); err != nil {
+			log.NewHelper(logger).Warnf("close database: %v", err)
+		}
+	}
+	return app, cleanup, nil
 }
 
@@ -3,9 +3,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"kratos-project-template/internal/conf"
 	"kratos-project-template/internal/global"
@@ -16,8 +19,7 @@ import (
 	"github.com/go-kratos/kratos/v2/config/env"
 	"github.com/go-kratos/kratos/v2/config/file"
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/transport/grpc"
-	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/go-kratos/kratos/v2/transport"
 
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
@@ -44,22 +46,18 @@ func init() {
 //
 // Parameters:
 //   - logger: The logger instance for application logging
-//   - gs: The gRPC server instance
-//   - hs: The HTTP server instance
+//   - servers: The servers started and stopped with the application (gRPC, HTTP, job worker)
 //
 // Returns:
 //   - *kratos.App: A configured kratos application ready to run
-func newApp(logger log.Logger, gs *grpc.Server, hs *khttp.Server) *kratos.App {
+func newApp(logger log.Logger, servers ...transport.Server) *kratos.App {
 	return kratos.New(
 		kratos.ID(id),
 		kratos.Name(Name),
 		kratos.Version(Version),
 		kratos.Metadata(map[string]string{}),
 		kratos.Logger(logger),
-		kratos.Server(
-			gs,
-			hs,
-		),
+		kratos.Server(servers...),
 	)
 }
 
@@ -69,6 +67,7 @@ func newApp(logger log.Logger, gs *grpc.Server, hs *khttp.Server) *kratos.App {
 //
 // The function will panic if critical initialization steps fail:
 //   - Configuration loading fails
+//   - Global initialization fails (a signal received during startup exits cleanly)
 //   - Application wiring fails
 //   - Application startup fails
 func main() {
@@ -115,8 +114,80 @@ func main() {
 		zap.String("service.version", Version),
 	)
 
+	// "app -conf <path> migrate ..." runs schema migrations instead of the servers.
+	if flag.Arg(0) == "migrate" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runMigrate(ctx, &bc, logger, flag.Args()[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "app -conf <path> archive ..." archives or restores cold data instead of the servers.
+	if flag.Arg(0) == "archive" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runArchive(ctx, &bc, logger, flag.Args()[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "archive: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "app -conf <path> seed ..." applies seed data instead of the servers.
+	if flag.Arg(0) == "seed" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runSeed(ctx, &bc, logger, flag.Args()[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "app -conf <path> reencrypt ..." rewrites encrypted columns with the primary key.
+	if flag.Arg(0) == "reencrypt" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runReencrypt(ctx, &bc, logger, flag.Args()[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reencrypt: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// "app -conf <path> reshard ..." moves rows between shards instead of the servers.
+	if flag.Arg(0) == "reshard" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runReshard(ctx, &bc, logger, flag.Args()[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reshard: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize global variables
-	global.Init(&bc, logger)
+	// SIGINT/SIGTERM while connecting to dependencies aborts startup cleanly.
+	initCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := global.Init(initCtx, &bc, logger)
+	interrupted := initCtx.Err() != nil
+	stop()
+	if err != nil {
+		if interrupted {
+			log.NewHelper(logger).Infof("startup interrupted: %v", err)
+			return
+		}
+		log.NewHelper(logger).Errorf("Failed to initialize application: %v", err)
+		os.Exit(1)
+	}
 
 	app, cleanup, err := wireApp(bc.Server, bc.Data, logger)
 	if err != nil {
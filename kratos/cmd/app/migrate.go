@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/internal/migrations"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/migrate"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// migrateUsage describes the migrate subcommands.
+const migrateUsage = `usage: app -conf <path> migrate <command>
+
+commands:
+  up                       apply all pending migrations
+  down [n]                 revert the last n migrations (default 1)
+  status                   show applied and pending migrations
+  create [-go] [-dir d] <name>  create a new migration (SQL by default)`
+
+// runMigrate executes a migrate subcommand.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - bc: The bootstrap configuration
+//   - logger: Logger instance for migration progress
+//   - args: Arguments after "migrate"
+//
+// Returns:
+//   - error: Error if the command is unknown or fails
+func runMigrate(ctx context.Context, bc *conf.Bootstrap, logger log.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	if args[0] == "create" {
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		goMigration := fs.Bool("go", false, "create a Go migration instead of SQL files")
+		dir := fs.String("dir", "internal/migrations/sql", "directory of the SQL migration files")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(migrateUsage)
+		}
+		files, err := migrate.Create(*dir, fs.Arg(0), *goMigration)
+		for _, f := range files {
+			fmt.Println("created", f)
+		}
+		return err
+	}
+
+	if err := db.Init(ctx, bc.Data.GetDatabase(), logger); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sqlDB, err := db.Get().DB()
+	if err != nil {
+		return errors.Wrap(err, "get sql db error")
+	}
+	m, err := migrate.New(sqlDB, db.Get().Dialector.Name(), migrations.FS(), logger)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx)
+		printMigrations("applied", done)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return errors.Wrap(err, "parse steps")
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		printMigrations("reverted", done)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, st := range statuses {
+			state, appliedAt := "pending", ""
+			switch {
+			case st.Missing:
+				state = "missing"
+			case st.Modified:
+				state = "modified"
+			case st.Applied:
+				state = "applied"
+			}
+			if st.Applied {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// printMigrations prints the migrations applied or reverted by a command.
+func printMigrations(action string, migrations []*migrate.Migration) {
+	if len(migrations) == 0 {
+		fmt.Println("no migrations", action)
+		return
+	}
+	for _, mig := range migrations {
+		fmt.Printf("%s %d_%s\n", action, mig.Version, mig.Name)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/encrypt"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// reencryptUsage describes the reencrypt command.
+const reencryptUsage = `usage: app -conf <path> reencrypt [-batch n] [-dry-run]
+
+rewrites the encrypted columns of the models registered with encrypt.Register that
+use a key other than data.encryption.primary_key; retired keys can be removed from
+the configuration once it reports no rewritten rows`
+
+// runReencrypt executes the reencrypt command.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - bc: The bootstrap configuration
+//   - logger: Logger instance for progress
+//   - args: Arguments after "reencrypt"
+//
+// Returns:
+//   - error: Error if the arguments are invalid or re-encryption fails
+func runReencrypt(ctx context.Context, bc *conf.Bootstrap, logger log.Logger, args []string) error {
+	fs := flag.NewFlagSet("reencrypt", flag.ContinueOnError)
+	batch := fs.Int("batch", 0, "rows per batch (default 500)")
+	dryRun := fs.Bool("dry-run", false, "only count the rows that need re-encryption")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New(reencryptUsage)
+	}
+
+	if err := encrypt.Init(bc.Data.GetEncryption(), logger); err != nil {
+		return err
+	}
+	if err := initDatabases(ctx, bc, logger); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	results, err := encrypt.Reencrypt(ctx, *batch, *dryRun, logger)
+	action := "rewritten"
+	if *dryRun {
+		action = "to rewrite"
+	}
+	for _, res := range results {
+		fmt.Printf("%s (%s): %d rows scanned, %d %s, %d skipped\n",
+			res.Table, strings.Join(res.Columns, ", "), res.Scanned, res.Rewritten, action, res.Skipped)
+	}
+	return err
+}
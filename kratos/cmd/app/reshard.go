@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// reshardUsage describes the reshard command.
+const reshardUsage = `usage: app -conf <path> reshard -table t -key column [-pk id] [-from conn,...] [-batch n] [-dry-run]
+
+moves the rows of a sharded table to the shard their key maps to under data.sharding;
+-from adds connections to drain, e.g. a shard removed from data.sharding.shards.
+pause writes to the table while it runs; an interrupted run is completed by running it again.
+the primary key must be unique across shards: a row whose key is used by another row on the
+destination shard stops the run and stays on its source shard`
+
+// runReshard executes the reshard command.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - bc: The bootstrap configuration
+//   - logger: Logger instance for database logging
+//   - args: Arguments after "reshard"
+//
+// Returns:
+//   - error: Error if the arguments are invalid or resharding fails
+func runReshard(ctx context.Context, bc *conf.Bootstrap, logger log.Logger, args []string) error {
+	fs := flag.NewFlagSet("reshard", flag.ContinueOnError)
+	opts := db.ReshardOptions{}
+	fs.StringVar(&opts.Table, "table", "", "sharded table")
+	fs.StringVar(&opts.KeyColumn, "key", "", "column holding the shard key")
+	fs.StringVar(&opts.PrimaryKey, "pk", "id", "single-column primary key of the table")
+	from := fs.String("from", "", "comma separated extra connections to drain")
+	fs.IntVar(&opts.BatchSize, "batch", 500, "rows per batch")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "only count the rows that would move")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opts.Table == "" || opts.KeyColumn == "" || fs.NArg() > 0 {
+		return errors.New(reshardUsage)
+	}
+	if *from != "" {
+		opts.Sources = strings.Split(*from, ",")
+	}
+
+	if err := initDatabases(ctx, bc, logger); err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.InitSharding(bc.Data.GetSharding()); err != nil {
+		return err
+	}
+
+	results, err := db.Reshard(ctx, opts)
+	action := "moved"
+	if opts.DryRun {
+		action = "to move"
+	}
+	for _, res := range results {
+		dests := make([]string, 0, len(res.Moved))
+		for dest, n := range res.Moved {
+			dests = append(dests, fmt.Sprintf("%d to %s", n, dest))
+		}
+		sort.Strings(dests)
+		if len(dests) == 0 {
+			dests = append(dests, "none")
+		}
+		fmt.Printf("%s: %d rows scanned, %s: %s\n", res.Shard, res.Scanned, action, strings.Join(dests, ", "))
+	}
+	return err
+}
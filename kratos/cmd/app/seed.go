@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/internal/seeds"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/encrypt"
+	"kratos-project-template/provider/seed"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// seedUsage describes the seed subcommands.
+const seedUsage = `usage: app -conf <path> seed <command>
+
+commands:
+  run [-env e] [set...]    apply the seed sets of the environment (all by default)
+  list [-env e]            list the seed sets of the environment
+
+the environment defaults to data.seed.env`
+
+// runSeed executes a seed subcommand.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - bc: The bootstrap configuration
+//   - logger: Logger instance for seeding progress
+//   - args: Arguments after "seed"
+//
+// Returns:
+//   - error: Error if the command is unknown or fails
+func runSeed(ctx context.Context, bc *conf.Bootstrap, logger log.Logger, args []string) error {
+	if len(args) == 0 || (args[0] != "run" && args[0] != "list") {
+		return errors.New(seedUsage)
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	env := fs.String("env", bc.Data.GetSeed().GetEnv(), "environment selecting the seed sets")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	seeder, err := seed.New(seeds.FS(), logger)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SET\tDATABASE\tENVIRONMENTS")
+		for _, s := range seeder.Sets(*env) {
+			envs := strings.Join(s.Envs, ",")
+			if envs == "" {
+				envs = "all"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Connection, envs)
+		}
+		return w.Flush()
+	}
+
+	// Go seed sets may write models with encrypted fields.
+	if err := encrypt.Init(bc.Data.GetEncryption(), logger); err != nil {
+		return err
+	}
+	if err := initDatabases(ctx, bc, logger); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	results, err := seeder.Run(ctx, *env, fs.Args()...)
+	for _, res := range results {
+		fmt.Printf("%s: %d inserted, %d updated, %d unchanged\n", res.Set, res.Inserted, res.Updated, res.Unchanged)
+	}
+	return err
+}
@@ -86,6 +86,10 @@ type Server struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Http          *Server_HTTP           `protobuf:"bytes,1,opt,name=http,proto3" json:"http,omitempty"`
 	Grpc          *Server_GRPC           `protobuf:"bytes,2,opt,name=grpc,proto3" json:"grpc,omitempty"`
+	Idempotency   *Server_Idempotency    `protobuf:"bytes,3,opt,name=idempotency,proto3" json:"idempotency,omitempty"`
+	Election      *Server_Election       `protobuf:"bytes,4,opt,name=election,proto3" json:"election,omitempty"`
+	Tenancy       *Server_Tenancy        `protobuf:"bytes,5,opt,name=tenancy,proto3" json:"tenancy,omitempty"`
+	Auth          *Server_Auth           `protobuf:"bytes,6,opt,name=auth,proto3" json:"auth,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -134,11 +138,47 @@ func (x *Server) GetGrpc() *Server_GRPC {
 	return nil
 }
 
+func (x *Server) GetIdempotency() *Server_Idempotency {
+	if x != nil {
+		return x.Idempotency
+	}
+	return nil
+}
+
+func (x *Server) GetElection() *Server_Election {
+	if x != nil {
+		return x.Election
+	}
+	return nil
+}
+
+func (x *Server) GetTenancy() *Server_Tenancy {
+	if x != nil {
+		return x.Tenancy
+	}
+	return nil
+}
+
+func (x *Server) GetAuth() *Server_Auth {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
 type Data struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Database      *Data_Database         `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
-	Redis         *Data_Redis            `protobuf:"bytes,2,opt,name=redis,proto3" json:"redis,omitempty"`
-	ObjectStorage *Data_ObjectStorage    `protobuf:"bytes,3,opt,name=object_storage,json=objectStorage,proto3" json:"object_storage,omitempty"`
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Database      *Data_Database            `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+	Redis         *Data_Redis               `protobuf:"bytes,2,opt,name=redis,proto3" json:"redis,omitempty"`
+	ObjectStorage *Data_ObjectStorage       `protobuf:"bytes,3,opt,name=object_storage,json=objectStorage,proto3" json:"object_storage,omitempty"`
+	Queue         *Data_Queue               `protobuf:"bytes,4,opt,name=queue,proto3" json:"queue,omitempty"`
+	EventBus      *Data_EventBus            `protobuf:"bytes,5,opt,name=event_bus,json=eventBus,proto3" json:"event_bus,omitempty"`
+	Databases     map[string]*Data_Database `protobuf:"bytes,6,rep,name=databases,proto3" json:"databases,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Additional named connections, see db.Get(name)
+	Outbox        *Data_Outbox              `protobuf:"bytes,7,opt,name=outbox,proto3" json:"outbox,omitempty"`
+	Archive       *Data_Archive             `protobuf:"bytes,8,opt,name=archive,proto3" json:"archive,omitempty"`
+	Seed          *Data_Seed                `protobuf:"bytes,9,opt,name=seed,proto3" json:"seed,omitempty"`
+	Encryption    *Data_Encryption          `protobuf:"bytes,10,opt,name=encryption,proto3" json:"encryption,omitempty"`
+	Sharding      *Data_Sharding            `protobuf:"bytes,11,opt,name=sharding,proto3" json:"sharding,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -194,6 +234,62 @@ func (x *Data) GetObjectStorage() *Data_ObjectStorage {
 	return nil
 }
 
+func (x *Data) GetQueue() *Data_Queue {
+	if x != nil {
+		return x.Queue
+	}
+	return nil
+}
+
+func (x *Data) GetEventBus() *Data_EventBus {
+	if x != nil {
+		return x.EventBus
+	}
+	return nil
+}
+
+func (x *Data) GetDatabases() map[string]*Data_Database {
+	if x != nil {
+		return x.Databases
+	}
+	return nil
+}
+
+func (x *Data) GetOutbox() *Data_Outbox {
+	if x != nil {
+		return x.Outbox
+	}
+	return nil
+}
+
+func (x *Data) GetArchive() *Data_Archive {
+	if x != nil {
+		return x.Archive
+	}
+	return nil
+}
+
+func (x *Data) GetSeed() *Data_Seed {
+	if x != nil {
+		return x.Seed
+	}
+	return nil
+}
+
+func (x *Data) GetEncryption() *Data_Encryption {
+	if x != nil {
+		return x.Encryption
+	}
+	return nil
+}
+
+func (x *Data) GetSharding() *Data_Sharding {
+	if x != nil {
+		return x.Sharding
+	}
+	return nil
+}
+
 type Log struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Log level: debug(-1), info(0), warn(1), error(2), dpanic(3), panic(4),
@@ -369,28 +465,30 @@ func (x *Server_GRPC) GetTimeout() *durationpb.Duration {
 	return nil
 }
 
-type Data_Database struct {
+type Server_Idempotency struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Driver        string                 `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
-	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`               // Enable Idempotency-Key handling (default false)
+	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`                  // Redis key prefix, default "idempotency"
+	Ttl           *durationpb.Duration   `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`                        // How long completed responses are replayed, default 24h
+	LockTtl       *durationpb.Duration   `protobuf:"bytes,4,opt,name=lock_ttl,json=lockTtl,proto3" json:"lock_ttl,omitempty"` // Max time a request may hold the key in flight, default 1m
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Data_Database) Reset() {
-	*x = Data_Database{}
+func (x *Server_Idempotency) Reset() {
+	*x = Server_Idempotency{}
 	mi := &file_conf_conf_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_Database) String() string {
+func (x *Server_Idempotency) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data_Database) ProtoMessage() {}
+func (*Server_Idempotency) ProtoMessage() {}
 
-func (x *Data_Database) ProtoReflect() protoreflect.Message {
+func (x *Server_Idempotency) ProtoReflect() protoreflect.Message {
 	mi := &file_conf_conf_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -402,51 +500,65 @@ func (x *Data_Database) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data_Database.ProtoReflect.Descriptor instead.
-func (*Data_Database) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0}
+// Deprecated: Use Server_Idempotency.ProtoReflect.Descriptor instead.
+func (*Server_Idempotency) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 2}
 }
 
-func (x *Data_Database) GetDriver() string {
+func (x *Server_Idempotency) GetEnabled() bool {
 	if x != nil {
-		return x.Driver
+		return x.Enabled
 	}
-	return ""
+	return false
 }
 
-func (x *Data_Database) GetSource() string {
+func (x *Server_Idempotency) GetPrefix() string {
 	if x != nil {
-		return x.Source
+		return x.Prefix
 	}
 	return ""
 }
 
-type Data_Redis struct {
+func (x *Server_Idempotency) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *Server_Idempotency) GetLockTtl() *durationpb.Duration {
+	if x != nil {
+		return x.LockTtl
+	}
+	return nil
+}
+
+type Server_Election struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Addr          string                 `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
-	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
-	Db            int32                  `protobuf:"varint,3,opt,name=db,proto3" json:"db,omitempty"`
-	PoolSize      int32                  `protobuf:"varint,4,opt,name=pool_size,json=poolSize,proto3" json:"pool_size,omitempty"`
-	ReadTimeout   *durationpb.Duration   `protobuf:"bytes,5,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"`
-	WriteTimeout  *durationpb.Duration   `protobuf:"bytes,6,opt,name=write_timeout,json=writeTimeout,proto3" json:"write_timeout,omitempty"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                 // Enable leader election (default false: every replica is leader)
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                                        // Election name, default "kratos-project-template"
+	Backend       string                 `protobuf:"bytes,3,opt,name=backend,proto3" json:"backend,omitempty"`                                  // "redis" (default) or "db" (Postgres/MySQL advisory lock)
+	Lease         *durationpb.Duration   `protobuf:"bytes,4,opt,name=lease,proto3" json:"lease,omitempty"`                                      // Lease duration, default 15s
+	RenewInterval *durationpb.Duration   `protobuf:"bytes,5,opt,name=renew_interval,json=renewInterval,proto3" json:"renew_interval,omitempty"` // Lease renewal interval, default 5s
+	RetryInterval *durationpb.Duration   `protobuf:"bytes,6,opt,name=retry_interval,json=retryInterval,proto3" json:"retry_interval,omitempty"` // Interval between acquire attempts, default 5s
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Data_Redis) Reset() {
-	*x = Data_Redis{}
+func (x *Server_Election) Reset() {
+	*x = Server_Election{}
 	mi := &file_conf_conf_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_Redis) String() string {
+func (x *Server_Election) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data_Redis) ProtoMessage() {}
+func (*Server_Election) ProtoMessage() {}
 
-func (x *Data_Redis) ProtoReflect() protoreflect.Message {
+func (x *Server_Election) ProtoReflect() protoreflect.Message {
 	mi := &file_conf_conf_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -458,82 +570,84 @@ func (x *Data_Redis) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data_Redis.ProtoReflect.Descriptor instead.
-func (*Data_Redis) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 1}
+// Deprecated: Use Server_Election.ProtoReflect.Descriptor instead.
+func (*Server_Election) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 3}
 }
 
-func (x *Data_Redis) GetAddr() string {
+func (x *Server_Election) GetEnabled() bool {
 	if x != nil {
-		return x.Addr
+		return x.Enabled
 	}
-	return ""
+	return false
 }
 
-func (x *Data_Redis) GetPassword() string {
+func (x *Server_Election) GetName() string {
 	if x != nil {
-		return x.Password
+		return x.Name
 	}
 	return ""
 }
 
-func (x *Data_Redis) GetDb() int32 {
+func (x *Server_Election) GetBackend() string {
 	if x != nil {
-		return x.Db
+		return x.Backend
 	}
-	return 0
+	return ""
 }
 
-func (x *Data_Redis) GetPoolSize() int32 {
+func (x *Server_Election) GetLease() *durationpb.Duration {
 	if x != nil {
-		return x.PoolSize
+		return x.Lease
 	}
-	return 0
+	return nil
 }
 
-func (x *Data_Redis) GetReadTimeout() *durationpb.Duration {
+func (x *Server_Election) GetRenewInterval() *durationpb.Duration {
 	if x != nil {
-		return x.ReadTimeout
+		return x.RenewInterval
 	}
 	return nil
 }
 
-func (x *Data_Redis) GetWriteTimeout() *durationpb.Duration {
+func (x *Server_Election) GetRetryInterval() *durationpb.Duration {
 	if x != nil {
-		return x.WriteTimeout
+		return x.RetryInterval
 	}
 	return nil
 }
 
-type Data_ObjectStorage struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Provider        string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`                                        // "s3", "oss", "cos", "minio"
-	Endpoint        string                 `protobuf:"bytes,2,opt,name=endpoint,proto3" json:"endpoint,omitempty"`                                        // Object storage endpoint
-	AccessKeyId     string                 `protobuf:"bytes,3,opt,name=access_key_id,json=accessKeyId,proto3" json:"access_key_id,omitempty"`             // Access key ID
-	SecretAccessKey string                 `protobuf:"bytes,4,opt,name=secret_access_key,json=secretAccessKey,proto3" json:"secret_access_key,omitempty"` // Secret access key
-	BucketName      string                 `protobuf:"bytes,5,opt,name=bucket_name,json=bucketName,proto3" json:"bucket_name,omitempty"`                  // Bucket name
-	Region          string                 `protobuf:"bytes,6,opt,name=region,proto3" json:"region,omitempty"`                                            // Region (required for S3/OSS)
-	UseSsl          bool                   `protobuf:"varint,7,opt,name=use_ssl,json=useSsl,proto3" json:"use_ssl,omitempty"`                             // Use SSL
-	PathPrefix      string                 `protobuf:"bytes,8,opt,name=path_prefix,json=pathPrefix,proto3" json:"path_prefix,omitempty"`                  // Path prefix, e.g., "data/"
-	Enabled         bool                   `protobuf:"varint,9,opt,name=enabled,proto3" json:"enabled,omitempty"`                                         // Enable object storage (default false)
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type Server_Tenancy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                                                               // Resolve the tenant of requests and isolate tenant data (default false)
+	Resolvers     []string               `protobuf:"bytes,2,rep,name=resolvers,proto3" json:"resolvers,omitempty"`                                                                            // Tenant sources tried in order: "header" (default), "jwt", "subdomain"
+	Header        string                 `protobuf:"bytes,3,opt,name=header,proto3" json:"header,omitempty"`                                                                                  // Header of the "header" resolver, default "X-Tenant-ID"
+	JwtClaim      string                 `protobuf:"bytes,4,opt,name=jwt_claim,json=jwtClaim,proto3" json:"jwt_claim,omitempty"`                                                              // Claim of the "jwt" resolver, default "tenant_id"
+	Domain        string                 `protobuf:"bytes,5,opt,name=domain,proto3" json:"domain,omitempty"`                                                                                  // Base domain of the "subdomain" resolver, e.g. "example.com"
+	Required      bool                   `protobuf:"varint,6,opt,name=required,proto3" json:"required,omitempty"`                                                                             // Reject requests that do not identify a tenant
+	Mode          string                 `protobuf:"bytes,7,opt,name=mode,proto3" json:"mode,omitempty"`                                                                                      // "column" (default), "schema" or "database"
+	Column        string                 `protobuf:"bytes,8,opt,name=column,proto3" json:"column,omitempty"`                                                                                  // Tenant column of the "column" mode, default "tenant_id"
+	Database      string                 `protobuf:"bytes,9,opt,name=database,proto3" json:"database,omitempty"`                                                                              // Connection whose pool the tenant schemas share in "schema" mode, default "default"
+	SchemaPrefix  string                 `protobuf:"bytes,10,opt,name=schema_prefix,json=schemaPrefix,proto3" json:"schema_prefix,omitempty"`                                                 // "schema" mode: the schema of a tenant is schema_prefix + tenant ID
+	Databases     map[string]string      `protobuf:"bytes,11,rep,name=databases,proto3" json:"databases,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // "database" mode: tenant ID -> connection name in data.databases
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Data_ObjectStorage) Reset() {
-	*x = Data_ObjectStorage{}
+func (x *Server_Tenancy) Reset() {
+	*x = Server_Tenancy{}
 	mi := &file_conf_conf_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Data_ObjectStorage) String() string {
+func (x *Server_Tenancy) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Data_ObjectStorage) ProtoMessage() {}
+func (*Server_Tenancy) ProtoMessage() {}
 
-func (x *Data_ObjectStorage) ProtoReflect() protoreflect.Message {
+func (x *Server_Tenancy) ProtoReflect() protoreflect.Message {
 	mi := &file_conf_conf_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -545,121 +659,1576 @@ func (x *Data_ObjectStorage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Data_ObjectStorage.ProtoReflect.Descriptor instead.
-func (*Data_ObjectStorage) Descriptor() ([]byte, []int) {
-	return file_conf_conf_proto_rawDescGZIP(), []int{2, 2}
+// Deprecated: Use Server_Tenancy.ProtoReflect.Descriptor instead.
+func (*Server_Tenancy) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 4}
 }
 
-func (x *Data_ObjectStorage) GetProvider() string {
+func (x *Server_Tenancy) GetEnabled() bool {
 	if x != nil {
-		return x.Provider
+		return x.Enabled
 	}
-	return ""
+	return false
 }
 
-func (x *Data_ObjectStorage) GetEndpoint() string {
+func (x *Server_Tenancy) GetResolvers() []string {
 	if x != nil {
-		return x.Endpoint
+		return x.Resolvers
+	}
+	return nil
+}
+
+func (x *Server_Tenancy) GetHeader() string {
+	if x != nil {
+		return x.Header
 	}
 	return ""
 }
 
-func (x *Data_ObjectStorage) GetAccessKeyId() string {
+func (x *Server_Tenancy) GetJwtClaim() string {
 	if x != nil {
-		return x.AccessKeyId
+		return x.JwtClaim
 	}
 	return ""
 }
 
-func (x *Data_ObjectStorage) GetSecretAccessKey() string {
+func (x *Server_Tenancy) GetDomain() string {
 	if x != nil {
-		return x.SecretAccessKey
+		return x.Domain
 	}
 	return ""
 }
 
-func (x *Data_ObjectStorage) GetBucketName() string {
+func (x *Server_Tenancy) GetRequired() bool {
 	if x != nil {
-		return x.BucketName
+		return x.Required
+	}
+	return false
+}
+
+func (x *Server_Tenancy) GetMode() string {
+	if x != nil {
+		return x.Mode
 	}
 	return ""
 }
 
-func (x *Data_ObjectStorage) GetRegion() string {
+func (x *Server_Tenancy) GetColumn() string {
 	if x != nil {
-		return x.Region
+		return x.Column
 	}
 	return ""
 }
 
-func (x *Data_ObjectStorage) GetUseSsl() bool {
+func (x *Server_Tenancy) GetDatabase() string {
 	if x != nil {
-		return x.UseSsl
+		return x.Database
 	}
-	return false
+	return ""
 }
 
-func (x *Data_ObjectStorage) GetPathPrefix() string {
+func (x *Server_Tenancy) GetSchemaPrefix() string {
 	if x != nil {
-		return x.PathPrefix
+		return x.SchemaPrefix
 	}
 	return ""
 }
 
-func (x *Data_ObjectStorage) GetEnabled() bool {
+func (x *Server_Tenancy) GetDatabases() map[string]string {
+	if x != nil {
+		return x.Databases
+	}
+	return nil
+}
+
+type Server_Auth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                 // Verify the JWT bearer token of every request with kratos jwt.Server (default false)
+	SigningMethod string                 `protobuf:"bytes,2,opt,name=signing_method,json=signingMethod,proto3" json:"signing_method,omitempty"` // HMAC signing method: "HS256" (default), "HS384" or "HS512"
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`                                          // HMAC key the tokens are signed with, e.g. ${JWT_KEY}
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Server_Auth) Reset() {
+	*x = Server_Auth{}
+	mi := &file_conf_conf_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Server_Auth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Server_Auth) ProtoMessage() {}
+
+func (x *Server_Auth) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Server_Auth.ProtoReflect.Descriptor instead.
+func (*Server_Auth) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{1, 5}
+}
+
+func (x *Server_Auth) GetEnabled() bool {
 	if x != nil {
 		return x.Enabled
 	}
 	return false
 }
 
-var File_conf_conf_proto protoreflect.FileDescriptor
+func (x *Server_Auth) GetSigningMethod() string {
+	if x != nil {
+		return x.SigningMethod
+	}
+	return ""
+}
 
-const file_conf_conf_proto_rawDesc = "" +
-	"\n" +
-	"\x0fconf/conf.proto\x12\n" +
-	"kratos.api\x1a\x1egoogle/protobuf/duration.proto\"\x80\x01\n" +
-	"\tBootstrap\x12*\n" +
-	"\x06server\x18\x01 \x01(\v2\x12.kratos.api.ServerR\x06server\x12$\n" +
-	"\x04data\x18\x02 \x01(\v2\x10.kratos.api.DataR\x04data\x12!\n" +
-	"\x03log\x18\x03 \x01(\v2\x0f.kratos.api.LogR\x03log\"\xb8\x02\n" +
-	"\x06Server\x12+\n" +
-	"\x04http\x18\x01 \x01(\v2\x17.kratos.api.Server.HTTPR\x04http\x12+\n" +
-	"\x04grpc\x18\x02 \x01(\v2\x17.kratos.api.Server.GRPCR\x04grpc\x1ai\n" +
-	"\x04HTTP\x12\x18\n" +
-	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
-	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
-	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x1ai\n" +
-	"\x04GRPC\x12\x18\n" +
-	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
-	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
-	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\"\xfa\x05\n" +
-	"\x04Data\x125\n" +
-	"\bdatabase\x18\x01 \x01(\v2\x19.kratos.api.Data.DatabaseR\bdatabase\x12,\n" +
-	"\x05redis\x18\x02 \x01(\v2\x16.kratos.api.Data.RedisR\x05redis\x12E\n" +
-	"\x0eobject_storage\x18\x03 \x01(\v2\x1e.kratos.api.Data.ObjectStorageR\robjectStorage\x1a:\n" +
-	"\bDatabase\x12\x16\n" +
-	"\x06driver\x18\x01 \x01(\tR\x06driver\x12\x16\n" +
-	"\x06source\x18\x02 \x01(\tR\x06source\x1a\xe2\x01\n" +
-	"\x05Redis\x12\x12\n" +
-	"\x04addr\x18\x01 \x01(\tR\x04addr\x12\x1a\n" +
-	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x0e\n" +
-	"\x02db\x18\x03 \x01(\x05R\x02db\x12\x1b\n" +
-	"\tpool_size\x18\x04 \x01(\x05R\bpoolSize\x12<\n" +
-	"\fread_timeout\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\vreadTimeout\x12>\n" +
-	"\rwrite_timeout\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\fwriteTimeout\x1a\xa4\x02\n" +
-	"\rObjectStorage\x12\x1a\n" +
-	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x1a\n" +
-	"\bendpoint\x18\x02 \x01(\tR\bendpoint\x12\"\n" +
-	"\raccess_key_id\x18\x03 \x01(\tR\vaccessKeyId\x12*\n" +
-	"\x11secret_access_key\x18\x04 \x01(\tR\x0fsecretAccessKey\x12\x1f\n" +
-	"\vbucket_name\x18\x05 \x01(\tR\n" +
-	"bucketName\x12\x16\n" +
-	"\x06region\x18\x06 \x01(\tR\x06region\x12\x17\n" +
-	"\ause_ssl\x18\a \x01(\bR\x06useSsl\x12\x1f\n" +
-	"\vpath_prefix\x18\b \x01(\tR\n" +
-	"pathPrefix\x12\x18\n" +
-	"\aenabled\x18\t \x01(\bR\aenabled\"3\n" +
+func (x *Server_Auth) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type Data_Database struct {
+	state                      protoimpl.MessageState `protogen:"open.v1"`
+	Driver                     string                 `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	Source                     string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`                                                                                // Data source name with the password inline; leave empty to use dsn
+	MaxIdleConns               int32                  `protobuf:"varint,3,opt,name=max_idle_conns,json=maxIdleConns,proto3" json:"max_idle_conns,omitempty"`                                             // Max idle connections in the pool, default 10
+	MaxOpenConns               int32                  `protobuf:"varint,4,opt,name=max_open_conns,json=maxOpenConns,proto3" json:"max_open_conns,omitempty"`                                             // Max open connections, default 100
+	ConnMaxLifetime            *durationpb.Duration   `protobuf:"bytes,5,opt,name=conn_max_lifetime,json=connMaxLifetime,proto3" json:"conn_max_lifetime,omitempty"`                                     // Max lifetime of a connection, default 1h
+	ConnMaxIdleTime            *durationpb.Duration   `protobuf:"bytes,6,opt,name=conn_max_idle_time,json=connMaxIdleTime,proto3" json:"conn_max_idle_time,omitempty"`                                   // Max idle time of a connection, default 0 (no limit)
+	MaxRetries                 int32                  `protobuf:"varint,7,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`                                                     // Connect retries after the first failed attempt, default 5
+	RetryBackoff               *durationpb.Duration   `protobuf:"bytes,8,opt,name=retry_backoff,json=retryBackoff,proto3" json:"retry_backoff,omitempty"`                                                // Initial delay between connect attempts, default 1s
+	MaxBackoff                 *durationpb.Duration   `protobuf:"bytes,9,opt,name=max_backoff,json=maxBackoff,proto3" json:"max_backoff,omitempty"`                                                      // Upper bound of the exponential backoff, default 30s
+	ConnectTimeout             *durationpb.Duration   `protobuf:"bytes,10,opt,name=connect_timeout,json=connectTimeout,proto3" json:"connect_timeout,omitempty"`                                         // Deadline for the whole connect phase, default 1m
+	Replicas                   []string               `protobuf:"bytes,11,rep,name=replicas,proto3" json:"replicas,omitempty"`                                                                           // Read replica sources (same driver as the primary)
+	ReplicaPolicy              string                 `protobuf:"bytes,12,opt,name=replica_policy,json=replicaPolicy,proto3" json:"replica_policy,omitempty"`                                            // "random" (default), "round_robin" or "least_conn"
+	ReplicaHealthCheckInterval *durationpb.Duration   `protobuf:"bytes,13,opt,name=replica_health_check_interval,json=replicaHealthCheckInterval,proto3" json:"replica_health_check_interval,omitempty"` // Replica ping interval, default 10s
+	LogLevel                   string                 `protobuf:"bytes,14,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`                                                           // GORM log level: "silent", "error" (default), "warn" or "info"
+	SlowThreshold              *durationpb.Duration   `protobuf:"bytes,15,opt,name=slow_threshold,json=slowThreshold,proto3" json:"slow_threshold,omitempty"`                                            // Queries slower than this are logged as warnings, default 1s
+	LogParams                  bool                   `protobuf:"varint,16,opt,name=log_params,json=logParams,proto3" json:"log_params,omitempty"`                                                       // Log SQL with parameter values; by default parameters are redacted
+	LogSampleRate              float64                `protobuf:"fixed64,17,opt,name=log_sample_rate,json=logSampleRate,proto3" json:"log_sample_rate,omitempty"`                                        // Fraction of ordinary queries logged at info level, default 1 (all)
+	Audit                      *Data_Database_Audit   `protobuf:"bytes,18,opt,name=audit,proto3" json:"audit,omitempty"`                                                                                 // Audit trail of models implementing audit.Auditable
+	DegradedStart              bool                   `protobuf:"varint,19,opt,name=degraded_start,json=degradedStart,proto3" json:"degraded_start,omitempty"`                                           // Start with the database down instead of failing; reconnects in background
+	HealthCheckInterval        *durationpb.Duration   `protobuf:"bytes,20,opt,name=health_check_interval,json=healthCheckInterval,proto3" json:"health_check_interval,omitempty"`                        // Background ping interval of the primary, default 10s
+	Metrics                    bool                   `protobuf:"varint,21,opt,name=metrics,proto3" json:"metrics,omitempty"`                                                                            // Record query latency and errors as Prometheus metrics (pool stats are always exported)
+	Dsn                        *Data_Database_Dsn     `protobuf:"bytes,22,opt,name=dsn,proto3" json:"dsn,omitempty"`                                                                                     // Structured connection settings, used if source is empty
+	unknownFields              protoimpl.UnknownFields
+	sizeCache                  protoimpl.SizeCache
+}
+
+func (x *Data_Database) Reset() {
+	*x = Data_Database{}
+	mi := &file_conf_conf_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Database) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Database) ProtoMessage() {}
+
+func (x *Data_Database) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Database.ProtoReflect.Descriptor instead.
+func (*Data_Database) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0}
+}
+
+func (x *Data_Database) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *Data_Database) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Data_Database) GetMaxIdleConns() int32 {
+	if x != nil {
+		return x.MaxIdleConns
+	}
+	return 0
+}
+
+func (x *Data_Database) GetMaxOpenConns() int32 {
+	if x != nil {
+		return x.MaxOpenConns
+	}
+	return 0
+}
+
+func (x *Data_Database) GetConnMaxLifetime() *durationpb.Duration {
+	if x != nil {
+		return x.ConnMaxLifetime
+	}
+	return nil
+}
+
+func (x *Data_Database) GetConnMaxIdleTime() *durationpb.Duration {
+	if x != nil {
+		return x.ConnMaxIdleTime
+	}
+	return nil
+}
+
+func (x *Data_Database) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *Data_Database) GetRetryBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.RetryBackoff
+	}
+	return nil
+}
+
+func (x *Data_Database) GetMaxBackoff() *durationpb.Duration {
+	if x != nil {
+		return x.MaxBackoff
+	}
+	return nil
+}
+
+func (x *Data_Database) GetConnectTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.ConnectTimeout
+	}
+	return nil
+}
+
+func (x *Data_Database) GetReplicas() []string {
+	if x != nil {
+		return x.Replicas
+	}
+	return nil
+}
+
+func (x *Data_Database) GetReplicaPolicy() string {
+	if x != nil {
+		return x.ReplicaPolicy
+	}
+	return ""
+}
+
+func (x *Data_Database) GetReplicaHealthCheckInterval() *durationpb.Duration {
+	if x != nil {
+		return x.ReplicaHealthCheckInterval
+	}
+	return nil
+}
+
+func (x *Data_Database) GetLogLevel() string {
+	if x != nil {
+		return x.LogLevel
+	}
+	return ""
+}
+
+func (x *Data_Database) GetSlowThreshold() *durationpb.Duration {
+	if x != nil {
+		return x.SlowThreshold
+	}
+	return nil
+}
+
+func (x *Data_Database) GetLogParams() bool {
+	if x != nil {
+		return x.LogParams
+	}
+	return false
+}
+
+func (x *Data_Database) GetLogSampleRate() float64 {
+	if x != nil {
+		return x.LogSampleRate
+	}
+	return 0
+}
+
+func (x *Data_Database) GetAudit() *Data_Database_Audit {
+	if x != nil {
+		return x.Audit
+	}
+	return nil
+}
+
+func (x *Data_Database) GetDegradedStart() bool {
+	if x != nil {
+		return x.DegradedStart
+	}
+	return false
+}
+
+func (x *Data_Database) GetHealthCheckInterval() *durationpb.Duration {
+	if x != nil {
+		return x.HealthCheckInterval
+	}
+	return nil
+}
+
+func (x *Data_Database) GetMetrics() bool {
+	if x != nil {
+		return x.Metrics
+	}
+	return false
+}
+
+func (x *Data_Database) GetDsn() *Data_Database_Dsn {
+	if x != nil {
+		return x.Dsn
+	}
+	return nil
+}
+
+type Data_Redis struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Addr                string                 `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	Password            string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Db                  int32                  `protobuf:"varint,3,opt,name=db,proto3" json:"db,omitempty"`
+	PoolSize            int32                  `protobuf:"varint,4,opt,name=pool_size,json=poolSize,proto3" json:"pool_size,omitempty"`
+	ReadTimeout         *durationpb.Duration   `protobuf:"bytes,5,opt,name=read_timeout,json=readTimeout,proto3" json:"read_timeout,omitempty"`
+	WriteTimeout        *durationpb.Duration   `protobuf:"bytes,6,opt,name=write_timeout,json=writeTimeout,proto3" json:"write_timeout,omitempty"`
+	DialTimeout         *durationpb.Duration   `protobuf:"bytes,7,opt,name=dial_timeout,json=dialTimeout,proto3" json:"dial_timeout,omitempty"`                           // Connect timeout, default 5s
+	HealthCheckInterval *durationpb.Duration   `protobuf:"bytes,8,opt,name=health_check_interval,json=healthCheckInterval,proto3" json:"health_check_interval,omitempty"` // Background ping interval, default 5s
+	FallbackMemory      bool                   `protobuf:"varint,9,opt,name=fallback_memory,json=fallbackMemory,proto3" json:"fallback_memory,omitempty"`                 // Serve cache.Get() from process memory while Redis is down
+	FallbackMaxEntries  int32                  `protobuf:"varint,10,opt,name=fallback_max_entries,json=fallbackMaxEntries,proto3" json:"fallback_max_entries,omitempty"`  // Max keys kept by the in-memory fallback, default 10000
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Data_Redis) Reset() {
+	*x = Data_Redis{}
+	mi := &file_conf_conf_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Redis) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Redis) ProtoMessage() {}
+
+func (x *Data_Redis) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Redis.ProtoReflect.Descriptor instead.
+func (*Data_Redis) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 1}
+}
+
+func (x *Data_Redis) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Data_Redis) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *Data_Redis) GetDb() int32 {
+	if x != nil {
+		return x.Db
+	}
+	return 0
+}
+
+func (x *Data_Redis) GetPoolSize() int32 {
+	if x != nil {
+		return x.PoolSize
+	}
+	return 0
+}
+
+func (x *Data_Redis) GetReadTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.ReadTimeout
+	}
+	return nil
+}
+
+func (x *Data_Redis) GetWriteTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.WriteTimeout
+	}
+	return nil
+}
+
+func (x *Data_Redis) GetDialTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.DialTimeout
+	}
+	return nil
+}
+
+func (x *Data_Redis) GetHealthCheckInterval() *durationpb.Duration {
+	if x != nil {
+		return x.HealthCheckInterval
+	}
+	return nil
+}
+
+func (x *Data_Redis) GetFallbackMemory() bool {
+	if x != nil {
+		return x.FallbackMemory
+	}
+	return false
+}
+
+func (x *Data_Redis) GetFallbackMaxEntries() int32 {
+	if x != nil {
+		return x.FallbackMaxEntries
+	}
+	return 0
+}
+
+type Data_ObjectStorage struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Provider        string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`                                        // "s3", "oss", "cos", "minio"
+	Endpoint        string                 `protobuf:"bytes,2,opt,name=endpoint,proto3" json:"endpoint,omitempty"`                                        // Object storage endpoint
+	AccessKeyId     string                 `protobuf:"bytes,3,opt,name=access_key_id,json=accessKeyId,proto3" json:"access_key_id,omitempty"`             // Access key ID
+	SecretAccessKey string                 `protobuf:"bytes,4,opt,name=secret_access_key,json=secretAccessKey,proto3" json:"secret_access_key,omitempty"` // Secret access key
+	BucketName      string                 `protobuf:"bytes,5,opt,name=bucket_name,json=bucketName,proto3" json:"bucket_name,omitempty"`                  // Bucket name
+	Region          string                 `protobuf:"bytes,6,opt,name=region,proto3" json:"region,omitempty"`                                            // Region (required for S3/OSS)
+	UseSsl          bool                   `protobuf:"varint,7,opt,name=use_ssl,json=useSsl,proto3" json:"use_ssl,omitempty"`                             // Use SSL
+	PathPrefix      string                 `protobuf:"bytes,8,opt,name=path_prefix,json=pathPrefix,proto3" json:"path_prefix,omitempty"`                  // Path prefix, e.g., "data/"
+	Enabled         bool                   `protobuf:"varint,9,opt,name=enabled,proto3" json:"enabled,omitempty"`                                         // Enable object storage (default false)
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Data_ObjectStorage) Reset() {
+	*x = Data_ObjectStorage{}
+	mi := &file_conf_conf_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_ObjectStorage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_ObjectStorage) ProtoMessage() {}
+
+func (x *Data_ObjectStorage) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_ObjectStorage.ProtoReflect.Descriptor instead.
+func (*Data_ObjectStorage) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 2}
+}
+
+func (x *Data_ObjectStorage) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetAccessKeyId() string {
+	if x != nil {
+		return x.AccessKeyId
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetSecretAccessKey() string {
+	if x != nil {
+		return x.SecretAccessKey
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetBucketName() string {
+	if x != nil {
+		return x.BucketName
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetUseSsl() bool {
+	if x != nil {
+		return x.UseSsl
+	}
+	return false
+}
+
+func (x *Data_ObjectStorage) GetPathPrefix() string {
+	if x != nil {
+		return x.PathPrefix
+	}
+	return ""
+}
+
+func (x *Data_ObjectStorage) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+type Data_Queue struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Enabled           bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                             // Enable the background job worker (default false)
+	Prefix            string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`                                                // Redis key prefix, default "queue"
+	Queues            []string               `protobuf:"bytes,3,rep,name=queues,proto3" json:"queues,omitempty"`                                                // Queues consumed by the worker, default ["default"]
+	Group             string                 `protobuf:"bytes,4,opt,name=group,proto3" json:"group,omitempty"`                                                  // Consumer group name, default "workers"
+	Concurrency       int32                  `protobuf:"varint,5,opt,name=concurrency,proto3" json:"concurrency,omitempty"`                                     // Number of concurrent handlers, default 10
+	MaxRetries        int32                  `protobuf:"varint,6,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`                     // Default retry budget per job, default 5
+	VisibilityTimeout *durationpb.Duration   `protobuf:"bytes,7,opt,name=visibility_timeout,json=visibilityTimeout,proto3" json:"visibility_timeout,omitempty"` // Idle time before a pending job is reclaimed, default 5m
+	PollInterval      *durationpb.Duration   `protobuf:"bytes,8,opt,name=poll_interval,json=pollInterval,proto3" json:"poll_interval,omitempty"`                // Scheduler/reclaimer tick, default 1s
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Data_Queue) Reset() {
+	*x = Data_Queue{}
+	mi := &file_conf_conf_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Queue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Queue) ProtoMessage() {}
+
+func (x *Data_Queue) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Queue.ProtoReflect.Descriptor instead.
+func (*Data_Queue) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 3}
+}
+
+func (x *Data_Queue) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Data_Queue) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Data_Queue) GetQueues() []string {
+	if x != nil {
+		return x.Queues
+	}
+	return nil
+}
+
+func (x *Data_Queue) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Data_Queue) GetConcurrency() int32 {
+	if x != nil {
+		return x.Concurrency
+	}
+	return 0
+}
+
+func (x *Data_Queue) GetMaxRetries() int32 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *Data_Queue) GetVisibilityTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.VisibilityTimeout
+	}
+	return nil
+}
+
+func (x *Data_Queue) GetPollInterval() *durationpb.Duration {
+	if x != nil {
+		return x.PollInterval
+	}
+	return nil
+}
+
+type Data_EventBus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transport     string                 `protobuf:"bytes,1,opt,name=transport,proto3" json:"transport,omitempty"`                              // "local" (default), "redis_pubsub" or "redis_stream"
+	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`                                    // Channel/stream name prefix, default "events"
+	StreamMaxLen  int64                  `protobuf:"varint,3,opt,name=stream_max_len,json=streamMaxLen,proto3" json:"stream_max_len,omitempty"` // Approximate max length of each topic stream, default 10000
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_EventBus) Reset() {
+	*x = Data_EventBus{}
+	mi := &file_conf_conf_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_EventBus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_EventBus) ProtoMessage() {}
+
+func (x *Data_EventBus) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_EventBus.ProtoReflect.Descriptor instead.
+func (*Data_EventBus) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 4}
+}
+
+func (x *Data_EventBus) GetTransport() string {
+	if x != nil {
+		return x.Transport
+	}
+	return ""
+}
+
+func (x *Data_EventBus) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Data_EventBus) GetStreamMaxLen() int64 {
+	if x != nil {
+		return x.StreamMaxLen
+	}
+	return 0
+}
+
+type Data_Outbox struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                 // Run the outbox relay (default false); Add works regardless
+	Database      string                 `protobuf:"bytes,2,opt,name=database,proto3" json:"database,omitempty"`                                // Connection holding the outbox table, default "default"
+	Table         string                 `protobuf:"bytes,3,opt,name=table,proto3" json:"table,omitempty"`                                      // Outbox table name, default "outbox_messages"
+	AutoMigrate   bool                   `protobuf:"varint,4,opt,name=auto_migrate,json=autoMigrate,proto3" json:"auto_migrate,omitempty"`      // Create the outbox table on startup instead of with a migration
+	Prefix        string                 `protobuf:"bytes,5,opt,name=prefix,proto3" json:"prefix,omitempty"`                                    // Redis stream prefix; events of a topic go to "<prefix>:<topic>", default "outbox"
+	StreamMaxLen  int64                  `protobuf:"varint,6,opt,name=stream_max_len,json=streamMaxLen,proto3" json:"stream_max_len,omitempty"` // Approximate max length of each topic stream, default 10000
+	PollInterval  *durationpb.Duration   `protobuf:"bytes,7,opt,name=poll_interval,json=pollInterval,proto3" json:"poll_interval,omitempty"`    // Interval between polls of the outbox table, default 1s
+	BatchSize     int32                  `protobuf:"varint,8,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`            // Max messages relayed per poll, default 100
+	MaxAttempts   int32                  `protobuf:"varint,9,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`      // Publish attempts before a message is marked failed, default 10
+	Retention     *durationpb.Duration   `protobuf:"bytes,10,opt,name=retention,proto3" json:"retention,omitempty"`                             // Delivered messages are deleted after this time, default 24h
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Outbox) Reset() {
+	*x = Data_Outbox{}
+	mi := &file_conf_conf_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Outbox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Outbox) ProtoMessage() {}
+
+func (x *Data_Outbox) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Outbox.ProtoReflect.Descriptor instead.
+func (*Data_Outbox) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 5}
+}
+
+func (x *Data_Outbox) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Data_Outbox) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *Data_Outbox) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *Data_Outbox) GetAutoMigrate() bool {
+	if x != nil {
+		return x.AutoMigrate
+	}
+	return false
+}
+
+func (x *Data_Outbox) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Data_Outbox) GetStreamMaxLen() int64 {
+	if x != nil {
+		return x.StreamMaxLen
+	}
+	return 0
+}
+
+func (x *Data_Outbox) GetPollInterval() *durationpb.Duration {
+	if x != nil {
+		return x.PollInterval
+	}
+	return nil
+}
+
+func (x *Data_Outbox) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *Data_Outbox) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *Data_Outbox) GetRetention() *durationpb.Duration {
+	if x != nil {
+		return x.Retention
+	}
+	return nil
+}
+
+type Data_Archive struct {
+	state         protoimpl.MessageState          `protogen:"open.v1"`
+	Enabled       bool                            `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                                                                               // Archive periodically on the elected leader (default false)
+	Prefix        string                          `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`                                                                                                  // Object key prefix, default "archive"
+	Interval      *durationpb.Duration            `protobuf:"bytes,3,opt,name=interval,proto3" json:"interval,omitempty"`                                                                                              // Interval between archival runs, default 1h
+	BatchSize     int32                           `protobuf:"varint,4,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`                                                                          // Rows per archive file, default 1000
+	OlderThan     map[string]*durationpb.Duration `protobuf:"bytes,5,rep,name=older_than,json=olderThan,proto3" json:"older_than,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Per policy override of the age of archived rows
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Archive) Reset() {
+	*x = Data_Archive{}
+	mi := &file_conf_conf_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Archive) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Archive) ProtoMessage() {}
+
+func (x *Data_Archive) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Archive.ProtoReflect.Descriptor instead.
+func (*Data_Archive) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 6}
+}
+
+func (x *Data_Archive) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Data_Archive) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *Data_Archive) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *Data_Archive) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *Data_Archive) GetOlderThan() map[string]*durationpb.Duration {
+	if x != nil {
+		return x.OlderThan
+	}
+	return nil
+}
+
+type Data_Seed struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Env           string                 `protobuf:"bytes,1,opt,name=env,proto3" json:"env,omitempty"` // Environment selecting the seed sets, overridden by "seed -env"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Seed) Reset() {
+	*x = Data_Seed{}
+	mi := &file_conf_conf_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Seed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Seed) ProtoMessage() {}
+
+func (x *Data_Seed) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Seed.ProtoReflect.Descriptor instead.
+func (*Data_Seed) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 7}
+}
+
+func (x *Data_Seed) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+type Data_Sharding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`                                   // Route sharded tables by the shard key in context (default false)
+	Shards        []string               `protobuf:"bytes,2,rep,name=shards,proto3" json:"shards,omitempty"`                                      // Shard connections in order: "default" or names in data.databases
+	Strategy      string                 `protobuf:"bytes,3,opt,name=strategy,proto3" json:"strategy,omitempty"`                                  // "hash" (default), "range" or a name registered with db.RegisterShardFunc
+	RangeBounds   []int64                `protobuf:"varint,4,rep,packed,name=range_bounds,json=rangeBounds,proto3" json:"range_bounds,omitempty"` // "range": shard i holds keys below range_bounds[i], the last shard the rest
+	Tables        []string               `protobuf:"bytes,5,rep,name=tables,proto3" json:"tables,omitempty"`                                      // Sharded tables; statements on them made with db.DB(ctx) or db.Get() run on the shard of the key in context
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Sharding) Reset() {
+	*x = Data_Sharding{}
+	mi := &file_conf_conf_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Sharding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Sharding) ProtoMessage() {}
+
+func (x *Data_Sharding) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Sharding.ProtoReflect.Descriptor instead.
+func (*Data_Sharding) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 8}
+}
+
+func (x *Data_Sharding) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Data_Sharding) GetShards() []string {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+func (x *Data_Sharding) GetStrategy() string {
+	if x != nil {
+		return x.Strategy
+	}
+	return ""
+}
+
+func (x *Data_Sharding) GetRangeBounds() []int64 {
+	if x != nil {
+		return x.RangeBounds
+	}
+	return nil
+}
+
+func (x *Data_Sharding) GetTables() []string {
+	if x != nil {
+		return x.Tables
+	}
+	return nil
+}
+
+type Data_Encryption struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrimaryKey    string                 `protobuf:"bytes,1,opt,name=primary_key,json=primaryKey,proto3" json:"primary_key,omitempty"`                                             // ID of the key encrypting new values; must be in keys
+	Keys          map[string]string      `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Base64 AES keys (16, 24 or 32 bytes) by ID; keep retired keys until re-encrypted
+	BlindIndexKey string                 `protobuf:"bytes,3,opt,name=blind_index_key,json=blindIndexKey,proto3" json:"blind_index_key,omitempty"`                                  // Base64 HMAC key (at least 32 bytes) of blind indexes; never rotate casually
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Encryption) Reset() {
+	*x = Data_Encryption{}
+	mi := &file_conf_conf_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Encryption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Encryption) ProtoMessage() {}
+
+func (x *Data_Encryption) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Encryption.ProtoReflect.Descriptor instead.
+func (*Data_Encryption) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 9}
+}
+
+func (x *Data_Encryption) GetPrimaryKey() string {
+	if x != nil {
+		return x.PrimaryKey
+	}
+	return ""
+}
+
+func (x *Data_Encryption) GetKeys() map[string]string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *Data_Encryption) GetBlindIndexKey() string {
+	if x != nil {
+		return x.BlindIndexKey
+	}
+	return ""
+}
+
+// Dsn is the structured form of source. At most one password source may be set.
+type Data_Database_Dsn struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Host            string                 `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`  // Server host; for MySQL a path starting with "/" is a unix socket
+	Port            int32                  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"` // Server port, default of the driver if 0
+	User            string                 `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	Password        string                 `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`                                                                       // Inline password; prefer one of the sources below
+	PasswordEnv     string                 `protobuf:"bytes,5,opt,name=password_env,json=passwordEnv,proto3" json:"password_env,omitempty"`                                              // Environment variable holding the password
+	PasswordFile    string                 `protobuf:"bytes,6,opt,name=password_file,json=passwordFile,proto3" json:"password_file,omitempty"`                                           // File holding the password, e.g. a Docker or Kubernetes secret
+	PasswordCommand string                 `protobuf:"bytes,7,opt,name=password_command,json=passwordCommand,proto3" json:"password_command,omitempty"`                                  // Command printing the password, run with sh -c
+	Dbname          string                 `protobuf:"bytes,8,opt,name=dbname,proto3" json:"dbname,omitempty"`                                                                           // Database name; for SQLite the database file
+	Params          map[string]string      `protobuf:"bytes,9,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Driver parameters, e.g. charset or TimeZone
+	Tls             *Data_Database_Dsn_Tls `protobuf:"bytes,10,opt,name=tls,proto3" json:"tls,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Data_Database_Dsn) Reset() {
+	*x = Data_Database_Dsn{}
+	mi := &file_conf_conf_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Database_Dsn) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Database_Dsn) ProtoMessage() {}
+
+func (x *Data_Database_Dsn) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Database_Dsn.ProtoReflect.Descriptor instead.
+func (*Data_Database_Dsn) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0, 0}
+}
+
+func (x *Data_Database_Dsn) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Data_Database_Dsn) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetPasswordEnv() string {
+	if x != nil {
+		return x.PasswordEnv
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetPasswordFile() string {
+	if x != nil {
+		return x.PasswordFile
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetPasswordCommand() string {
+	if x != nil {
+		return x.PasswordCommand
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetDbname() string {
+	if x != nil {
+		return x.Dbname
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *Data_Database_Dsn) GetTls() *Data_Database_Dsn_Tls {
+	if x != nil {
+		return x.Tls
+	}
+	return nil
+}
+
+type Data_Database_Audit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Table         string                 `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`                                 // Audit table name, default "audit_logs"
+	AutoMigrate   bool                   `protobuf:"varint,3,opt,name=auto_migrate,json=autoMigrate,proto3" json:"auto_migrate,omitempty"` // Create the audit table on startup instead of with a migration
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Database_Audit) Reset() {
+	*x = Data_Database_Audit{}
+	mi := &file_conf_conf_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Database_Audit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Database_Audit) ProtoMessage() {}
+
+func (x *Data_Database_Audit) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Database_Audit.ProtoReflect.Descriptor instead.
+func (*Data_Database_Audit) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0, 1}
+}
+
+func (x *Data_Database_Audit) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Data_Database_Audit) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *Data_Database_Audit) GetAutoMigrate() bool {
+	if x != nil {
+		return x.AutoMigrate
+	}
+	return false
+}
+
+type Data_Database_Dsn_Tls struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mode          string                 `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`                               // "disable" (default), "prefer", "require", "verify-ca" or "verify-full"
+	CaFile        string                 `protobuf:"bytes,2,opt,name=ca_file,json=caFile,proto3" json:"ca_file,omitempty"`             // CA certificate verifying the server
+	CertFile      string                 `protobuf:"bytes,3,opt,name=cert_file,json=certFile,proto3" json:"cert_file,omitempty"`       // Client certificate
+	KeyFile       string                 `protobuf:"bytes,4,opt,name=key_file,json=keyFile,proto3" json:"key_file,omitempty"`          // Client certificate key
+	ServerName    string                 `protobuf:"bytes,5,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"` // Name checked against the server certificate, default host (MySQL only)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Data_Database_Dsn_Tls) Reset() {
+	*x = Data_Database_Dsn_Tls{}
+	mi := &file_conf_conf_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Data_Database_Dsn_Tls) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Data_Database_Dsn_Tls) ProtoMessage() {}
+
+func (x *Data_Database_Dsn_Tls) ProtoReflect() protoreflect.Message {
+	mi := &file_conf_conf_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Data_Database_Dsn_Tls.ProtoReflect.Descriptor instead.
+func (*Data_Database_Dsn_Tls) Descriptor() ([]byte, []int) {
+	return file_conf_conf_proto_rawDescGZIP(), []int{2, 0, 0, 1}
+}
+
+func (x *Data_Database_Dsn_Tls) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn_Tls) GetCaFile() string {
+	if x != nil {
+		return x.CaFile
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn_Tls) GetCertFile() string {
+	if x != nil {
+		return x.CertFile
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn_Tls) GetKeyFile() string {
+	if x != nil {
+		return x.KeyFile
+	}
+	return ""
+}
+
+func (x *Data_Database_Dsn_Tls) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+var File_conf_conf_proto protoreflect.FileDescriptor
+
+const file_conf_conf_proto_rawDesc = "" +
+	"\n" +
+	"\x0fconf/conf.proto\x12\n" +
+	"kratos.api\x1a\x1egoogle/protobuf/duration.proto\"\x80\x01\n" +
+	"\tBootstrap\x12*\n" +
+	"\x06server\x18\x01 \x01(\v2\x12.kratos.api.ServerR\x06server\x12$\n" +
+	"\x04data\x18\x02 \x01(\v2\x10.kratos.api.DataR\x04data\x12!\n" +
+	"\x03log\x18\x03 \x01(\v2\x0f.kratos.api.LogR\x03log\"\xc1\v\n" +
+	"\x06Server\x12+\n" +
+	"\x04http\x18\x01 \x01(\v2\x17.kratos.api.Server.HTTPR\x04http\x12+\n" +
+	"\x04grpc\x18\x02 \x01(\v2\x17.kratos.api.Server.GRPCR\x04grpc\x12@\n" +
+	"\vidempotency\x18\x03 \x01(\v2\x1e.kratos.api.Server.IdempotencyR\vidempotency\x127\n" +
+	"\belection\x18\x04 \x01(\v2\x1b.kratos.api.Server.ElectionR\belection\x124\n" +
+	"\atenancy\x18\x05 \x01(\v2\x1a.kratos.api.Server.TenancyR\atenancy\x12+\n" +
+	"\x04auth\x18\x06 \x01(\v2\x17.kratos.api.Server.AuthR\x04auth\x1ai\n" +
+	"\x04HTTP\x12\x18\n" +
+	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
+	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x1ai\n" +
+	"\x04GRPC\x12\x18\n" +
+	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x123\n" +
+	"\atimeout\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\atimeout\x1a\xa2\x01\n" +
+	"\vIdempotency\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12+\n" +
+	"\x03ttl\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x124\n" +
+	"\block_ttl\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\alockTtl\x1a\x87\x02\n" +
+	"\bElection\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\abackend\x18\x03 \x01(\tR\abackend\x12/\n" +
+	"\x05lease\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x05lease\x12@\n" +
+	"\x0erenew_interval\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\rrenewInterval\x12@\n" +
+	"\x0eretry_interval\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\rretryInterval\x1a\x9e\x03\n" +
+	"\aTenancy\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x1c\n" +
+	"\tresolvers\x18\x02 \x03(\tR\tresolvers\x12\x16\n" +
+	"\x06header\x18\x03 \x01(\tR\x06header\x12\x1b\n" +
+	"\tjwt_claim\x18\x04 \x01(\tR\bjwtClaim\x12\x16\n" +
+	"\x06domain\x18\x05 \x01(\tR\x06domain\x12\x1a\n" +
+	"\brequired\x18\x06 \x01(\bR\brequired\x12\x12\n" +
+	"\x04mode\x18\a \x01(\tR\x04mode\x12\x16\n" +
+	"\x06column\x18\b \x01(\tR\x06column\x12\x1a\n" +
+	"\bdatabase\x18\t \x01(\tR\bdatabase\x12#\n" +
+	"\rschema_prefix\x18\n" +
+	" \x01(\tR\fschemaPrefix\x12G\n" +
+	"\tdatabases\x18\v \x03(\v2).kratos.api.Server.Tenancy.DatabasesEntryR\tdatabases\x1a<\n" +
+	"\x0eDatabasesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aY\n" +
+	"\x04Auth\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12%\n" +
+	"\x0esigning_method\x18\x02 \x01(\tR\rsigningMethod\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\"\xab$\n" +
+	"\x04Data\x125\n" +
+	"\bdatabase\x18\x01 \x01(\v2\x19.kratos.api.Data.DatabaseR\bdatabase\x12,\n" +
+	"\x05redis\x18\x02 \x01(\v2\x16.kratos.api.Data.RedisR\x05redis\x12E\n" +
+	"\x0eobject_storage\x18\x03 \x01(\v2\x1e.kratos.api.Data.ObjectStorageR\robjectStorage\x12,\n" +
+	"\x05queue\x18\x04 \x01(\v2\x16.kratos.api.Data.QueueR\x05queue\x126\n" +
+	"\tevent_bus\x18\x05 \x01(\v2\x19.kratos.api.Data.EventBusR\beventBus\x12=\n" +
+	"\tdatabases\x18\x06 \x03(\v2\x1f.kratos.api.Data.DatabasesEntryR\tdatabases\x12/\n" +
+	"\x06outbox\x18\a \x01(\v2\x17.kratos.api.Data.OutboxR\x06outbox\x122\n" +
+	"\aarchive\x18\b \x01(\v2\x18.kratos.api.Data.ArchiveR\aarchive\x12)\n" +
+	"\x04seed\x18\t \x01(\v2\x15.kratos.api.Data.SeedR\x04seed\x12;\n" +
+	"\n" +
+	"encryption\x18\n" +
+	" \x01(\v2\x1b.kratos.api.Data.EncryptionR\n" +
+	"encryption\x125\n" +
+	"\bsharding\x18\v \x01(\v2\x19.kratos.api.Data.ShardingR\bsharding\x1a\xbd\r\n" +
+	"\bDatabase\x12\x16\n" +
+	"\x06driver\x18\x01 \x01(\tR\x06driver\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12$\n" +
+	"\x0emax_idle_conns\x18\x03 \x01(\x05R\fmaxIdleConns\x12$\n" +
+	"\x0emax_open_conns\x18\x04 \x01(\x05R\fmaxOpenConns\x12E\n" +
+	"\x11conn_max_lifetime\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\x0fconnMaxLifetime\x12F\n" +
+	"\x12conn_max_idle_time\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x0fconnMaxIdleTime\x12\x1f\n" +
+	"\vmax_retries\x18\a \x01(\x05R\n" +
+	"maxRetries\x12>\n" +
+	"\rretry_backoff\x18\b \x01(\v2\x19.google.protobuf.DurationR\fretryBackoff\x12:\n" +
+	"\vmax_backoff\x18\t \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"maxBackoff\x12B\n" +
+	"\x0fconnect_timeout\x18\n" +
+	" \x01(\v2\x19.google.protobuf.DurationR\x0econnectTimeout\x12\x1a\n" +
+	"\breplicas\x18\v \x03(\tR\breplicas\x12%\n" +
+	"\x0ereplica_policy\x18\f \x01(\tR\rreplicaPolicy\x12\\\n" +
+	"\x1dreplica_health_check_interval\x18\r \x01(\v2\x19.google.protobuf.DurationR\x1areplicaHealthCheckInterval\x12\x1b\n" +
+	"\tlog_level\x18\x0e \x01(\tR\blogLevel\x12@\n" +
+	"\x0eslow_threshold\x18\x0f \x01(\v2\x19.google.protobuf.DurationR\rslowThreshold\x12\x1d\n" +
+	"\n" +
+	"log_params\x18\x10 \x01(\bR\tlogParams\x12&\n" +
+	"\x0flog_sample_rate\x18\x11 \x01(\x01R\rlogSampleRate\x125\n" +
+	"\x05audit\x18\x12 \x01(\v2\x1f.kratos.api.Data.Database.AuditR\x05audit\x12%\n" +
+	"\x0edegraded_start\x18\x13 \x01(\bR\rdegradedStart\x12M\n" +
+	"\x15health_check_interval\x18\x14 \x01(\v2\x19.google.protobuf.DurationR\x13healthCheckInterval\x12\x18\n" +
+	"\ametrics\x18\x15 \x01(\bR\ametrics\x12/\n" +
+	"\x03dsn\x18\x16 \x01(\v2\x1d.kratos.api.Data.Database.DsnR\x03dsn\x1a\xa9\x04\n" +
+	"\x03Dsn\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\x05R\x04port\x12\x12\n" +
+	"\x04user\x18\x03 \x01(\tR\x04user\x12\x1a\n" +
+	"\bpassword\x18\x04 \x01(\tR\bpassword\x12!\n" +
+	"\fpassword_env\x18\x05 \x01(\tR\vpasswordEnv\x12#\n" +
+	"\rpassword_file\x18\x06 \x01(\tR\fpasswordFile\x12)\n" +
+	"\x10password_command\x18\a \x01(\tR\x0fpasswordCommand\x12\x16\n" +
+	"\x06dbname\x18\b \x01(\tR\x06dbname\x12A\n" +
+	"\x06params\x18\t \x03(\v2).kratos.api.Data.Database.Dsn.ParamsEntryR\x06params\x123\n" +
+	"\x03tls\x18\n" +
+	" \x01(\v2!.kratos.api.Data.Database.Dsn.TlsR\x03tls\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a\x8b\x01\n" +
+	"\x03Tls\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12\x17\n" +
+	"\aca_file\x18\x02 \x01(\tR\x06caFile\x12\x1b\n" +
+	"\tcert_file\x18\x03 \x01(\tR\bcertFile\x12\x19\n" +
+	"\bkey_file\x18\x04 \x01(\tR\akeyFile\x12\x1f\n" +
+	"\vserver_name\x18\x05 \x01(\tR\n" +
+	"serverName\x1aZ\n" +
+	"\x05Audit\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x14\n" +
+	"\x05table\x18\x02 \x01(\tR\x05table\x12!\n" +
+	"\fauto_migrate\x18\x03 \x01(\bR\vautoMigrate\x1a\xca\x03\n" +
+	"\x05Redis\x12\x12\n" +
+	"\x04addr\x18\x01 \x01(\tR\x04addr\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x0e\n" +
+	"\x02db\x18\x03 \x01(\x05R\x02db\x12\x1b\n" +
+	"\tpool_size\x18\x04 \x01(\x05R\bpoolSize\x12<\n" +
+	"\fread_timeout\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\vreadTimeout\x12>\n" +
+	"\rwrite_timeout\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\fwriteTimeout\x12<\n" +
+	"\fdial_timeout\x18\a \x01(\v2\x19.google.protobuf.DurationR\vdialTimeout\x12M\n" +
+	"\x15health_check_interval\x18\b \x01(\v2\x19.google.protobuf.DurationR\x13healthCheckInterval\x12'\n" +
+	"\x0ffallback_memory\x18\t \x01(\bR\x0efallbackMemory\x120\n" +
+	"\x14fallback_max_entries\x18\n" +
+	" \x01(\x05R\x12fallbackMaxEntries\x1a\xa4\x02\n" +
+	"\rObjectStorage\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x1a\n" +
+	"\bendpoint\x18\x02 \x01(\tR\bendpoint\x12\"\n" +
+	"\raccess_key_id\x18\x03 \x01(\tR\vaccessKeyId\x12*\n" +
+	"\x11secret_access_key\x18\x04 \x01(\tR\x0fsecretAccessKey\x12\x1f\n" +
+	"\vbucket_name\x18\x05 \x01(\tR\n" +
+	"bucketName\x12\x16\n" +
+	"\x06region\x18\x06 \x01(\tR\x06region\x12\x17\n" +
+	"\ause_ssl\x18\a \x01(\bR\x06useSsl\x12\x1f\n" +
+	"\vpath_prefix\x18\b \x01(\tR\n" +
+	"pathPrefix\x12\x18\n" +
+	"\aenabled\x18\t \x01(\bR\aenabled\x1a\xb4\x02\n" +
+	"\x05Queue\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12\x16\n" +
+	"\x06queues\x18\x03 \x03(\tR\x06queues\x12\x14\n" +
+	"\x05group\x18\x04 \x01(\tR\x05group\x12 \n" +
+	"\vconcurrency\x18\x05 \x01(\x05R\vconcurrency\x12\x1f\n" +
+	"\vmax_retries\x18\x06 \x01(\x05R\n" +
+	"maxRetries\x12H\n" +
+	"\x12visibility_timeout\x18\a \x01(\v2\x19.google.protobuf.DurationR\x11visibilityTimeout\x12>\n" +
+	"\rpoll_interval\x18\b \x01(\v2\x19.google.protobuf.DurationR\fpollInterval\x1af\n" +
+	"\bEventBus\x12\x1c\n" +
+	"\ttransport\x18\x01 \x01(\tR\ttransport\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12$\n" +
+	"\x0estream_max_len\x18\x03 \x01(\x03R\fstreamMaxLen\x1a\xf0\x02\n" +
+	"\x06Outbox\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x1a\n" +
+	"\bdatabase\x18\x02 \x01(\tR\bdatabase\x12\x14\n" +
+	"\x05table\x18\x03 \x01(\tR\x05table\x12!\n" +
+	"\fauto_migrate\x18\x04 \x01(\bR\vautoMigrate\x12\x16\n" +
+	"\x06prefix\x18\x05 \x01(\tR\x06prefix\x12$\n" +
+	"\x0estream_max_len\x18\x06 \x01(\x03R\fstreamMaxLen\x12>\n" +
+	"\rpoll_interval\x18\a \x01(\v2\x19.google.protobuf.DurationR\fpollInterval\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\b \x01(\x05R\tbatchSize\x12!\n" +
+	"\fmax_attempts\x18\t \x01(\x05R\vmaxAttempts\x127\n" +
+	"\tretention\x18\n" +
+	" \x01(\v2\x19.google.protobuf.DurationR\tretention\x1a\xb2\x02\n" +
+	"\aArchive\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x125\n" +
+	"\binterval\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\binterval\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x04 \x01(\x05R\tbatchSize\x12F\n" +
+	"\n" +
+	"older_than\x18\x05 \x03(\v2'.kratos.api.Data.Archive.OlderThanEntryR\tolderThan\x1aW\n" +
+	"\x0eOlderThanEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x05value:\x028\x01\x1a\x18\n" +
+	"\x04Seed\x12\x10\n" +
+	"\x03env\x18\x01 \x01(\tR\x03env\x1a\x93\x01\n" +
+	"\bSharding\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x16\n" +
+	"\x06shards\x18\x02 \x03(\tR\x06shards\x12\x1a\n" +
+	"\bstrategy\x18\x03 \x01(\tR\bstrategy\x12!\n" +
+	"\frange_bounds\x18\x04 \x03(\x03R\vrangeBounds\x12\x16\n" +
+	"\x06tables\x18\x05 \x03(\tR\x06tables\x1a\xc9\x01\n" +
+	"\n" +
+	"Encryption\x12\x1f\n" +
+	"\vprimary_key\x18\x01 \x01(\tR\n" +
+	"primaryKey\x129\n" +
+	"\x04keys\x18\x02 \x03(\v2%.kratos.api.Data.Encryption.KeysEntryR\x04keys\x12&\n" +
+	"\x0fblind_index_key\x18\x03 \x01(\tR\rblindIndexKey\x1a7\n" +
+	"\tKeysEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aW\n" +
+	"\x0eDatabasesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\v2\x19.kratos.api.Data.DatabaseR\x05value:\x028\x01\"3\n" +
 	"\x03Log\x12\x14\n" +
 	"\x05level\x18\x01 \x01(\x05R\x05level\x12\x16\n" +
 	"\x06format\x18\x02 \x01(\tR\x06formatB,Z*kratos-project-template/internal/conf;confb\x06proto3"
@@ -676,18 +2245,37 @@ func file_conf_conf_proto_rawDescGZIP() []byte {
 	return file_conf_conf_proto_rawDescData
 }
 
-var file_conf_conf_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_conf_conf_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_conf_conf_proto_goTypes = []any{
-	(*Bootstrap)(nil),           // 0: kratos.api.Bootstrap
-	(*Server)(nil),              // 1: kratos.api.Server
-	(*Data)(nil),                // 2: kratos.api.Data
-	(*Log)(nil),                 // 3: kratos.api.Log
-	(*Server_HTTP)(nil),         // 4: kratos.api.Server.HTTP
-	(*Server_GRPC)(nil),         // 5: kratos.api.Server.GRPC
-	(*Data_Database)(nil),       // 6: kratos.api.Data.Database
-	(*Data_Redis)(nil),          // 7: kratos.api.Data.Redis
-	(*Data_ObjectStorage)(nil),  // 8: kratos.api.Data.ObjectStorage
-	(*durationpb.Duration)(nil), // 9: google.protobuf.Duration
+	(*Bootstrap)(nil),             // 0: kratos.api.Bootstrap
+	(*Server)(nil),                // 1: kratos.api.Server
+	(*Data)(nil),                  // 2: kratos.api.Data
+	(*Log)(nil),                   // 3: kratos.api.Log
+	(*Server_HTTP)(nil),           // 4: kratos.api.Server.HTTP
+	(*Server_GRPC)(nil),           // 5: kratos.api.Server.GRPC
+	(*Server_Idempotency)(nil),    // 6: kratos.api.Server.Idempotency
+	(*Server_Election)(nil),       // 7: kratos.api.Server.Election
+	(*Server_Tenancy)(nil),        // 8: kratos.api.Server.Tenancy
+	(*Server_Auth)(nil),           // 9: kratos.api.Server.Auth
+	nil,                           // 10: kratos.api.Server.Tenancy.DatabasesEntry
+	(*Data_Database)(nil),         // 11: kratos.api.Data.Database
+	(*Data_Redis)(nil),            // 12: kratos.api.Data.Redis
+	(*Data_ObjectStorage)(nil),    // 13: kratos.api.Data.ObjectStorage
+	(*Data_Queue)(nil),            // 14: kratos.api.Data.Queue
+	(*Data_EventBus)(nil),         // 15: kratos.api.Data.EventBus
+	(*Data_Outbox)(nil),           // 16: kratos.api.Data.Outbox
+	(*Data_Archive)(nil),          // 17: kratos.api.Data.Archive
+	(*Data_Seed)(nil),             // 18: kratos.api.Data.Seed
+	(*Data_Sharding)(nil),         // 19: kratos.api.Data.Sharding
+	(*Data_Encryption)(nil),       // 20: kratos.api.Data.Encryption
+	nil,                           // 21: kratos.api.Data.DatabasesEntry
+	(*Data_Database_Dsn)(nil),     // 22: kratos.api.Data.Database.Dsn
+	(*Data_Database_Audit)(nil),   // 23: kratos.api.Data.Database.Audit
+	nil,                           // 24: kratos.api.Data.Database.Dsn.ParamsEntry
+	(*Data_Database_Dsn_Tls)(nil), // 25: kratos.api.Data.Database.Dsn.Tls
+	nil,                           // 26: kratos.api.Data.Archive.OlderThanEntry
+	nil,                           // 27: kratos.api.Data.Encryption.KeysEntry
+	(*durationpb.Duration)(nil),   // 28: google.protobuf.Duration
 }
 var file_conf_conf_proto_depIdxs = []int32{
 	1,  // 0: kratos.api.Bootstrap.server:type_name -> kratos.api.Server
@@ -695,18 +2283,59 @@ var file_conf_conf_proto_depIdxs = []int32{
 	3,  // 2: kratos.api.Bootstrap.log:type_name -> kratos.api.Log
 	4,  // 3: kratos.api.Server.http:type_name -> kratos.api.Server.HTTP
 	5,  // 4: kratos.api.Server.grpc:type_name -> kratos.api.Server.GRPC
-	6,  // 5: kratos.api.Data.database:type_name -> kratos.api.Data.Database
-	7,  // 6: kratos.api.Data.redis:type_name -> kratos.api.Data.Redis
-	8,  // 7: kratos.api.Data.object_storage:type_name -> kratos.api.Data.ObjectStorage
-	9,  // 8: kratos.api.Server.HTTP.timeout:type_name -> google.protobuf.Duration
-	9,  // 9: kratos.api.Server.GRPC.timeout:type_name -> google.protobuf.Duration
-	9,  // 10: kratos.api.Data.Redis.read_timeout:type_name -> google.protobuf.Duration
-	9,  // 11: kratos.api.Data.Redis.write_timeout:type_name -> google.protobuf.Duration
-	12, // [12:12] is the sub-list for method output_type
-	12, // [12:12] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	6,  // 5: kratos.api.Server.idempotency:type_name -> kratos.api.Server.Idempotency
+	7,  // 6: kratos.api.Server.election:type_name -> kratos.api.Server.Election
+	8,  // 7: kratos.api.Server.tenancy:type_name -> kratos.api.Server.Tenancy
+	9,  // 8: kratos.api.Server.auth:type_name -> kratos.api.Server.Auth
+	11, // 9: kratos.api.Data.database:type_name -> kratos.api.Data.Database
+	12, // 10: kratos.api.Data.redis:type_name -> kratos.api.Data.Redis
+	13, // 11: kratos.api.Data.object_storage:type_name -> kratos.api.Data.ObjectStorage
+	14, // 12: kratos.api.Data.queue:type_name -> kratos.api.Data.Queue
+	15, // 13: kratos.api.Data.event_bus:type_name -> kratos.api.Data.EventBus
+	21, // 14: kratos.api.Data.databases:type_name -> kratos.api.Data.DatabasesEntry
+	16, // 15: kratos.api.Data.outbox:type_name -> kratos.api.Data.Outbox
+	17, // 16: kratos.api.Data.archive:type_name -> kratos.api.Data.Archive
+	18, // 17: kratos.api.Data.seed:type_name -> kratos.api.Data.Seed
+	20, // 18: kratos.api.Data.encryption:type_name -> kratos.api.Data.Encryption
+	19, // 19: kratos.api.Data.sharding:type_name -> kratos.api.Data.Sharding
+	28, // 20: kratos.api.Server.HTTP.timeout:type_name -> google.protobuf.Duration
+	28, // 21: kratos.api.Server.GRPC.timeout:type_name -> google.protobuf.Duration
+	28, // 22: kratos.api.Server.Idempotency.ttl:type_name -> google.protobuf.Duration
+	28, // 23: kratos.api.Server.Idempotency.lock_ttl:type_name -> google.protobuf.Duration
+	28, // 24: kratos.api.Server.Election.lease:type_name -> google.protobuf.Duration
+	28, // 25: kratos.api.Server.Election.renew_interval:type_name -> google.protobuf.Duration
+	28, // 26: kratos.api.Server.Election.retry_interval:type_name -> google.protobuf.Duration
+	10, // 27: kratos.api.Server.Tenancy.databases:type_name -> kratos.api.Server.Tenancy.DatabasesEntry
+	28, // 28: kratos.api.Data.Database.conn_max_lifetime:type_name -> google.protobuf.Duration
+	28, // 29: kratos.api.Data.Database.conn_max_idle_time:type_name -> google.protobuf.Duration
+	28, // 30: kratos.api.Data.Database.retry_backoff:type_name -> google.protobuf.Duration
+	28, // 31: kratos.api.Data.Database.max_backoff:type_name -> google.protobuf.Duration
+	28, // 32: kratos.api.Data.Database.connect_timeout:type_name -> google.protobuf.Duration
+	28, // 33: kratos.api.Data.Database.replica_health_check_interval:type_name -> google.protobuf.Duration
+	28, // 34: kratos.api.Data.Database.slow_threshold:type_name -> google.protobuf.Duration
+	23, // 35: kratos.api.Data.Database.audit:type_name -> kratos.api.Data.Database.Audit
+	28, // 36: kratos.api.Data.Database.health_check_interval:type_name -> google.protobuf.Duration
+	22, // 37: kratos.api.Data.Database.dsn:type_name -> kratos.api.Data.Database.Dsn
+	28, // 38: kratos.api.Data.Redis.read_timeout:type_name -> google.protobuf.Duration
+	28, // 39: kratos.api.Data.Redis.write_timeout:type_name -> google.protobuf.Duration
+	28, // 40: kratos.api.Data.Redis.dial_timeout:type_name -> google.protobuf.Duration
+	28, // 41: kratos.api.Data.Redis.health_check_interval:type_name -> google.protobuf.Duration
+	28, // 42: kratos.api.Data.Queue.visibility_timeout:type_name -> google.protobuf.Duration
+	28, // 43: kratos.api.Data.Queue.poll_interval:type_name -> google.protobuf.Duration
+	28, // 44: kratos.api.Data.Outbox.poll_interval:type_name -> google.protobuf.Duration
+	28, // 45: kratos.api.Data.Outbox.retention:type_name -> google.protobuf.Duration
+	28, // 46: kratos.api.Data.Archive.interval:type_name -> google.protobuf.Duration
+	26, // 47: kratos.api.Data.Archive.older_than:type_name -> kratos.api.Data.Archive.OlderThanEntry
+	27, // 48: kratos.api.Data.Encryption.keys:type_name -> kratos.api.Data.Encryption.KeysEntry
+	11, // 49: kratos.api.Data.DatabasesEntry.value:type_name -> kratos.api.Data.Database
+	24, // 50: kratos.api.Data.Database.Dsn.params:type_name -> kratos.api.Data.Database.Dsn.ParamsEntry
+	25, // 51: kratos.api.Data.Database.Dsn.tls:type_name -> kratos.api.Data.Database.Dsn.Tls
+	28, // 52: kratos.api.Data.Archive.OlderThanEntry.value:type_name -> google.protobuf.Duration
+	53, // [53:53] is the sub-list for method output_type
+	53, // [53:53] is the sub-list for method input_type
+	53, // [53:53] is the sub-list for extension type_name
+	53, // [53:53] is the sub-list for extension extendee
+	0,  // [0:53] is the sub-list for field type_name
 }
 
 func init() { file_conf_conf_proto_init() }
@@ -720,7 +2349,7 @@ func file_conf_conf_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_conf_conf_proto_rawDesc), len(file_conf_conf_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,262 @@
+// Package dbtest provides isolated SQLite databases for tests of database-backed code.
+//
+// Open creates a fresh in-memory (or temporary file) database, applies migrations and
+// loads YAML fixtures. Begin starts a transaction that is rolled back when the test ends
+// and registers it as a db connection, so code under test using db.Get, db.DB and
+// db.Transaction works unchanged and leaves nothing behind:
+//
+//	func TestCreateOrder(t *testing.T) {
+//		tx := dbtest.New(t,
+//			dbtest.WithMigrations(migrations.FS()),
+//			dbtest.WithFixtures("testdata/users.yml"),
+//		)
+//		// ... call the code under test, then assert with tx
+//	}
+//
+// To migrate once and share the database between subtests:
+//
+//	d := dbtest.Open(t, dbtest.WithMigrations(migrations.FS()))
+//	t.Run("a", func(t *testing.T) { tx := d.Begin(t); ... })
+//
+// The registered connection is process-global: tests using Begin must not run in parallel.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"kratos-project-template/internal/global"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/driver"
+	"kratos-project-template/provider/migrate"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// seq makes the names of in-memory databases unique within the process.
+var seq atomic.Int64
+
+// unsafeName matches the characters of a test name not allowed in a database name.
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// options holds the settings of Open.
+type options struct {
+	name      string
+	file      bool
+	logLevel  logger.LogLevel
+	fsys      fs.FS
+	models    []interface{}
+	fixtures  []string
+	fixtureFS fs.FS
+	logger    log.Logger
+}
+
+// Option configures Open.
+type Option func(*options)
+
+// WithName registers the test database as a named connection instead of the default one.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithFile stores the database in a file in the test's temporary directory instead of
+// memory, e.g. to inspect it with the sqlite3 shell.
+func WithFile() Option {
+	return func(o *options) {
+		o.file = true
+	}
+}
+
+// WithMigrations applies the SQL and registered Go migrations, e.g. migrations.FS().
+func WithMigrations(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
+// WithModels creates the tables of the models with AutoMigrate after the migrations.
+func WithModels(models ...interface{}) Option {
+	return func(o *options) {
+		o.models = append(o.models, models...)
+	}
+}
+
+// WithFixtures loads YAML fixture files (glob patterns are allowed) after the migrations,
+// in order; see LoadFixtures for the format.
+func WithFixtures(patterns ...string) Option {
+	return func(o *options) {
+		o.fixtures = append(o.fixtures, patterns...)
+	}
+}
+
+// WithFixturesFS reads the fixture files of WithFixtures from fsys instead of the
+// working directory, e.g. an embed.FS.
+func WithFixturesFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fixtureFS = fsys
+	}
+}
+
+// WithLogLevel sets the GORM log level (default logger.Warn).
+func WithLogLevel(level logger.LogLevel) Option {
+	return func(o *options) {
+		o.logLevel = level
+	}
+}
+
+// WithLogger sets the logger of the database and of global.Logger (default: the test log).
+func WithLogger(l log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// Database is an isolated test database.
+type Database struct {
+	// DB is the database outside any test transaction
+	DB *gorm.DB
+
+	name   string
+	logger log.Logger
+}
+
+// New opens a test database and begins a transaction on it; see Open and Begin.
+//
+// Parameters:
+//   - tb: The test
+//   - opts: Optional settings
+//
+// Returns:
+//   - *gorm.DB: The transaction, rolled back when the test ends
+func New(tb testing.TB, opts ...Option) *gorm.DB {
+	tb.Helper()
+	return Open(tb, opts...).Begin(tb)
+}
+
+// Open creates an empty SQLite database, applies the migrations and loads the fixtures.
+// The database is closed when the test ends. It fails the test on error.
+//
+// Parameters:
+//   - tb: The test owning the database
+//   - opts: Optional settings
+//
+// Returns:
+//   - *Database: The test database
+func Open(tb testing.TB, opts ...Option) *Database {
+	tb.Helper()
+	o := options{name: db.DefaultName, logLevel: logger.Warn}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = testLogger{tb}
+	}
+
+	source := fmt.Sprintf("file:%s_%d?mode=memory&cache=shared", unsafeName.ReplaceAllString(tb.Name(), "_"), seq.Add(1))
+	if o.file {
+		source = filepath.Join(tb.TempDir(), "test.db")
+	}
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		tb.Fatalf("dbtest: %v", err)
+	}
+	gormDB, err := d.Open(source, db.NewGormLogger(o.logger, o.logLevel))
+	if err != nil {
+		tb.Fatalf("dbtest: open sqlite: %v", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		tb.Fatalf("dbtest: %v", err)
+	}
+	// One connection keeps the in-memory database alive and serializes access to it.
+	sqlDB.SetMaxOpenConns(1)
+	tb.Cleanup(func() { _ = sqlDB.Close() })
+
+	ctx := context.Background()
+	if o.fsys != nil {
+		m, err := migrate.New(sqlDB, gormDB.Dialector.Name(), o.fsys, o.logger)
+		if err != nil {
+			tb.Fatalf("dbtest: %v", err)
+		}
+		if _, err := m.Up(ctx); err != nil {
+			tb.Fatalf("dbtest: migrate: %v", err)
+		}
+	}
+	if len(o.models) > 0 {
+		if err := gormDB.AutoMigrate(o.models...); err != nil {
+			tb.Fatalf("dbtest: auto migrate: %v", err)
+		}
+	}
+	if len(o.fixtures) > 0 {
+		if err := loadFixtureFiles(gormDB, o.fixtureFS, o.fixtures); err != nil {
+			tb.Fatalf("dbtest: %v", err)
+		}
+	}
+	return &Database{DB: gormDB, name: o.name, logger: o.logger}
+}
+
+// Begin starts a transaction that is rolled back when the test ends and registers it as
+// the db connection of the database, replacing the configured one until then. It also
+// sets global.Logger if it is nil, so services can log.
+//
+// Parameters:
+//   - tb: The test owning the transaction
+//
+// Returns:
+//   - *gorm.DB: The transaction
+func (d *Database) Begin(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	tx := d.DB.Begin()
+	if tx.Error != nil {
+		tb.Fatalf("dbtest: begin: %v", tx.Error)
+	}
+	restore := db.Set(d.name, tx, d.logger)
+
+	prevLogger := global.Logger
+	if prevLogger == nil {
+		global.Logger = log.NewHelper(d.logger)
+	}
+	tb.Cleanup(func() {
+		global.Logger = prevLogger
+		restore()
+		if err := tx.Rollback().Error; err != nil {
+			tb.Errorf("dbtest: rollback: %v", err)
+		}
+	})
+	return tx
+}
+
+// Fixtures loads YAML fixture files into gormDB, e.g. a transaction returned by Begin.
+// It fails the test on error.
+//
+// Parameters:
+//   - tb: The test
+//   - gormDB: The database to insert into
+//   - patterns: Fixture files or glob patterns
+func Fixtures(tb testing.TB, gormDB *gorm.DB, patterns ...string) {
+	tb.Helper()
+	if err := loadFixtureFiles(gormDB, nil, patterns); err != nil {
+		tb.Fatalf("dbtest: %v", err)
+	}
+}
+
+// testLogger writes log records to the test log.
+type testLogger struct {
+	tb testing.TB
+}
+
+// Log implements log.Logger.
+func (l testLogger) Log(level log.Level, keyvals ...interface{}) error {
+	l.tb.Helper()
+	l.tb.Log(append([]interface{}{level.String()}, keyvals...)...)
+	return nil
+}
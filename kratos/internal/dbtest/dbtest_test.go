@@ -0,0 +1,196 @@
+package dbtest_test
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"kratos-project-template/internal/dbtest"
+	"kratos-project-template/provider/db"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type user struct {
+	ID   uint64
+	Name string
+	Tags string
+}
+
+type note struct {
+	ID   uint64
+	Body string
+}
+
+var testFS = fstest.MapFS{
+	"migrations/1_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, tags TEXT);")},
+	"migrations/1_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"fixtures/users.yml": {Data: []byte(`users:
+  - id: 1
+    name: alice
+    tags: [admin, ops]
+  - id: 2
+    name: bob
+  - id: 3
+    name: carol
+`)},
+}
+
+func migrations(t *testing.T) fs.FS {
+	t.Helper()
+	sub, err := fs.Sub(testFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []dbtest.Option
+		table string
+		want  int64
+	}{
+		{name: "migrations", opts: []dbtest.Option{dbtest.WithMigrations(migrations(t))}, table: "users", want: 0},
+		{
+			name:  "fixtures",
+			opts:  []dbtest.Option{dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/*.yml")},
+			table: "users",
+			want:  3,
+		},
+		{name: "models", opts: []dbtest.Option{dbtest.WithModels(&note{})}, table: "notes", want: 0},
+		{name: "file", opts: []dbtest.Option{dbtest.WithFile(), dbtest.WithModels(&note{})}, table: "notes", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.Open(t, tt.opts...)
+			var n int64
+			if err := d.DB.Table(tt.table).Count(&n).Error; err != nil {
+				t.Fatalf("count %s: %v", tt.table, err)
+			}
+			if n != tt.want {
+				t.Errorf("%s has %d rows, want %d", tt.table, n, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixturesJSON(t *testing.T) {
+	tx := dbtest.New(t, dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/users.yml"))
+	var u user
+	if err := tx.First(&u, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.Tags != `["admin","ops"]` {
+		t.Errorf("tags = %q, want the list as JSON", u.Tags)
+	}
+}
+
+func TestLoadFixturesInvalid(t *testing.T) {
+	tx := dbtest.New(t, dbtest.WithMigrations(migrations(t)))
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "not yaml", data: "users: [", want: "parse fixtures"},
+		{name: "not a mapping", data: "- id: 1", want: "must map table names"},
+		{name: "rows not a list", data: "users: {id: 1}", want: "fixtures of table users"},
+		{name: "unknown table", data: "missing:\n  - id: 1", want: "insert fixture into missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dbtest.LoadFixtures(tx, []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadFixtures() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeginRollsBack(t *testing.T) {
+	d := dbtest.Open(t, dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/users.yml"))
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			tx := d.Begin(t)
+			if db.Get() != tx {
+				t.Fatal("db.Get() does not return the test transaction")
+			}
+			ctx := context.Background()
+			if err := db.DB(ctx).Create(&user{ID: 10, Name: name}).Error; err != nil {
+				t.Fatalf("create in %s subtest: %v", name, err)
+			}
+			var n int64
+			if err := tx.Model(&user{}).Count(&n).Error; err != nil {
+				t.Fatal(err)
+			}
+			if n != 4 {
+				t.Errorf("users = %d, want the 3 fixtures and the new row", n)
+			}
+		})
+	}
+
+	var n int64
+	if err := d.DB.Model(&user{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("users after the subtests = %d, want 3", n)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	d := dbtest.Open(t, dbtest.WithMigrations(migrations(t)), dbtest.WithFixturesFS(testFS), dbtest.WithFixtures("fixtures/users.yml"))
+	repo := db.NewRepository[user](db.SortFields("name"), db.PageSize(2, 2))
+	ctx := context.Background()
+
+	t.Run("keyset pages", func(t *testing.T) {
+		d.Begin(t)
+		q := &db.Query{Sorts: []db.Sort{{Field: "name", Desc: true}}}
+		var names []string
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatal("pagination does not end")
+			}
+			items, page, err := repo.List(ctx, q)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			for _, u := range items {
+				names = append(names, u.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+		if got := strings.Join(names, ","); got != "carol,bob,alice" {
+			t.Errorf("names = %s, want carol,bob,alice", got)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		d.Begin(t)
+		tests := []struct {
+			name    string
+			user    user
+			wantErr error
+		}{
+			{name: "changed", user: user{ID: 2, Name: "robert"}},
+			{name: "unchanged", user: user{ID: 3, Name: "carol"}},
+			{name: "missing", user: user{ID: 99, Name: "nobody"}, wantErr: gorm.ErrRecordNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				u := tt.user
+				if err := repo.Update(ctx, &u, "name"); !errors.Is(err, tt.wantErr) {
+					t.Errorf("Update() error = %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+}
@@ -4,13 +4,20 @@ package global
 
 import (
 	"context"
+	"sort"
 
 	"kratos-project-template/internal/conf"
 	"kratos-project-template/provider/cache"
 	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/encrypt"
+	"kratos-project-template/provider/election"
+	"kratos-project-template/provider/eventbus"
+	"kratos-project-template/provider/outbox"
+	"kratos-project-template/provider/queue"
 	"kratos-project-template/provider/storage"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -22,37 +29,111 @@ var (
 // It initializes database, cache, and object storage connections based on the bootstrap configuration.
 //
 // Parameters:
+//   - ctx: Context bounding startup; cancelling it (e.g. on SIGTERM) aborts connection retries
 //   - bc: The bootstrap configuration containing log, data, and other settings
 //   - logger: The logger instance to use for application logging
 //
-// The function will panic if critical initialization steps fail:
+// Returns:
+//   - error: Error if critical initialization steps fail:
 //   - Bootstrap configuration is nil
-//   - Database initialization fails
-func Init(bc *conf.Bootstrap, logger log.Logger) {
+//   - An encryption key is invalid or the primary key is not configured
+//   - The password of a database cannot be read from its env var, file or command
+//   - Initialization of a database connection fails or ctx is cancelled while connecting;
+//     with degraded_start an unreachable database is not an error
+//   - Sharding is enabled with an unknown strategy or a shard that is not initialized
+//   - The outbox connection is not configured or the outbox table cannot be migrated
+//   - Tenancy is enabled with an unknown mode or a connection that is not initialized
+//   - Leader election is enabled but its backend is not available
+func Init(ctx context.Context, bc *conf.Bootstrap, logger log.Logger) error {
 	if bc == nil {
-		panic("bootstrap config cannot be nil")
+		return errors.New("bootstrap config cannot be nil")
 	}
 
 	Logger = log.NewHelper(logger)
 	Logger.Infof("logger initialized: %v", bc.Log)
+	Logger.Debugf("bootstrap config: %v", conf.Redacted(bc))
 
-	Logger.Infof("database initialized")
-	err := db.Init(context.Background(), bc.Data.Database, logger)
+	// Invalid keys fail fast instead of on the first read of an encrypted column.
+	err := encrypt.Init(bc.Data.GetEncryption(), logger)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	err = cache.InitRedis(context.Background(), bc.Data.Redis, logger)
+	err = db.Init(ctx, bc.Data.Database, logger)
 	if err != nil {
-		Logger.Warnf("redis initialization failed: %v", err)
+		return err
+	}
+	logDatabaseState(db.DefaultName)
+
+	// Named connections are initialized in name order so startup is deterministic.
+	names := make([]string, 0, len(bc.Data.GetDatabases()))
+	for name := range bc.Data.GetDatabases() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == db.DefaultName {
+			return errors.Errorf("data.databases cannot contain %q, configure it in data.database", name)
+		}
+		if err := db.InitNamed(ctx, name, bc.Data.GetDatabases()[name], logger); err != nil {
+			return err
+		}
+		logDatabaseState(name)
+	}
+
+	err = db.InitSharding(bc.Data.GetSharding())
+	if err != nil {
+		return err
+	}
+
+	err = outbox.Init(ctx, bc.Data.GetOutbox(), logger)
+	if err != nil {
+		return err
+	}
+
+	err = db.InitTenancy(bc.Server.GetTenancy())
+	if err != nil {
+		return err
+	}
+
+	// A failed ping is not fatal: the client keeps reconnecting in the background.
+	err = cache.InitRedis(ctx, bc.Data.Redis, logger)
+	if err != nil {
+		Logger.Warnf("redis initialization failed, running degraded: %v", err)
+	}
+
+	err = queue.Init(ctx, bc.Data.GetQueue(), logger)
+	if err != nil {
+		Logger.Warnf("job queue initialization failed: %v", err)
+	}
+
+	err = eventbus.Init(ctx, bc.Data.GetEventBus(), logger)
+	if err != nil {
+		Logger.Warnf("event bus initialization failed, falling back to local dispatch: %v", err)
+	}
+
+	// Running without a working backend could make every replica leader, so fail fast.
+	err = election.Init(ctx, bc.Server.GetElection(), logger)
+	if err != nil {
+		return err
 	}
 
 	Logger.Infof("object storage initialized")
 	if bc.Data != nil {
-		err = storage.Init(context.Background(), bc.Data.GetObjectStorage(), logger)
+		err = storage.Init(ctx, bc.Data.GetObjectStorage(), logger)
 		if err != nil {
 			Logger.Warnf("object storage initialization failed: %v", err)
 		}
 	}
+
+	return nil
 }
 
+// logDatabaseState logs whether a database connection is up after initialization.
+func logDatabaseState(name string) {
+	if st := db.GetStatus(name); st.State != db.StateUp {
+		Logger.Warnf("database %s started degraded: %v", name, st.Err)
+		return
+	}
+	Logger.Infof("database %s initialized", name)
+}
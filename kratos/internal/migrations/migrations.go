@@ -0,0 +1,25 @@
+// Package migrations holds the versioned database migrations of the application.
+// SQL migrations live in the sql directory and are embedded into the binary; Go
+// migrations are files in this package that call migrate.Register from init.
+// Create new migrations with "app migrate create <name>".
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed sql
+var files embed.FS
+
+// FS returns the embedded SQL migration files.
+//
+// Returns:
+//   - fs.FS: File system with the migration files at its root
+func FS() fs.FS {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,25 @@
+// Package seeds holds the seed data of the application: reference rows such as roles or
+// default settings. YAML and JSON seed files live in the data directory and are embedded
+// into the binary; Go seed sets are files in this package that call seed.Register from
+// init. Apply them with "app seed run".
+package seeds
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed data
+var files embed.FS
+
+// FS returns the embedded seed files.
+//
+// Returns:
+//   - fs.FS: File system with the seed files at its root
+func FS() fs.FS {
+	sub, err := fs.Sub(files, "data")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/archive"
+	"kratos-project-template/provider/election"
+	"kratos-project-template/provider/storage"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// NewArchiveServer creates the archival server.
+// It periodically moves the rows selected by the policies registered with
+// archive.Register to object storage; only the elected leader archives.
+//
+// Parameters:
+//   - c: Data configuration containing archive settings
+//   - logger: Logger instance for archival logging
+//
+// Returns:
+//   - *archive.Server: A configured server, or nil if archival is disabled
+//     or object storage is not enabled
+func NewArchiveServer(c *conf.Data, logger log.Logger) *archive.Server {
+	if !c.GetArchive().GetEnabled() {
+		return nil
+	}
+	if _, ok := storage.Get().(*storage.NoOpStorage); ok {
+		log.NewHelper(logger).Warnf("object storage is not enabled, archive disabled")
+		return nil
+	}
+
+	archiver := archive.New(storage.Get(), c.GetArchive(), logger)
+	return archive.NewServer(archiver, election.Default(), c.GetArchive().GetInterval().AsDuration(), logger)
+}
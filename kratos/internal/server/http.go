@@ -7,9 +7,9 @@ import (
 	"kratos-project-template/internal/service"
 
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/middleware/recovery"
 	khttp "github.com/go-kratos/kratos/v2/transport/http"
 	"github.com/gorilla/handlers"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // NewHTTPServer creates and configures a new HTTP server instance.
@@ -44,9 +44,11 @@ func NewHTTPServer(c *conf.Server, logger log.Logger) *khttp.Server {
 			"Content-Type",
 			"Authorization",
 			"X-Requested-With",
+			"Idempotency-Key",
 			// Note: Accept, Origin, and Access-Control-Request-* headers are simple headers
 			// and don't need to be explicitly allowed
 		}),
+		handlers.ExposedHeaders([]string{"Idempotent-Replayed"}),
 		handlers.MaxAge(86400), // Cache preflight requests for 24 hours
 	)
 
@@ -55,9 +57,7 @@ func NewHTTPServer(c *conf.Server, logger log.Logger) *khttp.Server {
 	}
 
 	var opts = []khttp.ServerOption{
-		khttp.Middleware(
-			recovery.Recovery(),
-		),
+		khttp.Middleware(serverMiddleware(c, logger)...),
 	}
 
 	// Create CORS middleware factory (reusable)
@@ -78,6 +78,8 @@ func NewHTTPServer(c *conf.Server, logger log.Logger) *khttp.Server {
 		opts = append(opts, khttp.Timeout(c.Http.Timeout.AsDuration()))
 	}
 	srv := khttp.NewServer(opts...)
+	// Prometheus metrics of the process, including database queries and connection pools.
+	srv.Handle("/metrics", promhttp.Handler())
 
 	demoService := service.NewDemoService()
 	v1.RegisterDemoHTTPServer(srv, demoService)
@@ -0,0 +1,129 @@
+// Package server provides server initialization for both gRPC and HTTP servers.
+package server
+
+import (
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/cache"
+	"kratos-project-template/provider/db/tenant"
+	"kratos-project-template/provider/idempotency"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth/jwt"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// serverMiddleware returns the middleware chain shared by the HTTP and gRPC servers.
+//
+// Parameters:
+//   - c: Server configuration
+//   - logger: Logger instance for middleware logging
+//
+// Returns:
+//   - []middleware.Middleware: The middleware chain, outermost first
+func serverMiddleware(c *conf.Server, logger log.Logger) []middleware.Middleware {
+	chain := []middleware.Middleware{
+		recovery.Recovery(),
+	}
+
+	// The tenant and idempotency middleware read the claims of the verified token.
+	if c.GetAuth().GetEnabled() {
+		chain = append(chain, authMiddleware(c.GetAuth(), logger))
+	}
+
+	if c.GetTenancy().GetEnabled() {
+		chain = append(chain, tenant.Server(c.GetTenancy().GetRequired(),
+			tenantResolvers(c.GetTenancy(), c.GetAuth().GetEnabled(), logger)...))
+	}
+
+	if c.GetIdempotency().GetEnabled() {
+		if rdb := cache.GetRedisClient(); rdb != nil {
+			store := idempotency.NewStore(rdb, c.GetIdempotency())
+			chain = append(chain, idempotency.Server(store, logger))
+		} else {
+			log.NewHelper(logger).Warnf("redis is not configured, idempotency middleware disabled")
+		}
+	}
+
+	return chain
+}
+
+// authMiddleware returns the kratos jwt.Server middleware verifying the bearer token of
+// every request with the HMAC key of server.auth. Without a key or with an unknown
+// signing method every request is rejected.
+//
+// Parameters:
+//   - c: Auth configuration
+//   - logger: Logger instance for reporting configuration errors
+//
+// Returns:
+//   - middleware.Middleware: The jwt.Server middleware
+func authMiddleware(c *conf.Server_Auth, logger log.Logger) middleware.Middleware {
+	name := c.GetSigningMethod()
+	if name == "" {
+		name = jwtv5.SigningMethodHS256.Alg()
+	}
+	method, ok := jwtv5.GetSigningMethod(name).(*jwtv5.SigningMethodHMAC)
+	if !ok {
+		log.NewHelper(logger).Errorf("unsupported jwt signing method %q, all requests are rejected", name)
+		method = jwtv5.SigningMethodHS256
+	}
+	if c.GetKey() == "" {
+		log.NewHelper(logger).Errorf("server.auth.key is empty, all requests are rejected")
+	}
+
+	key := []byte(c.GetKey())
+	return jwt.Server(func(token *jwtv5.Token) (interface{}, error) {
+		if !ok || len(key) == 0 {
+			return nil, errors.New("jwt authentication is not configured")
+		}
+		return key, nil
+	}, jwt.WithSigningMethod(method), jwt.WithClaims(func() jwtv5.Claims { return jwtv5.MapClaims{} }))
+}
+
+// tenantResolvers returns the tenant resolvers configured in server.tenancy.resolvers.
+// The "jwt" resolver reads the claims verified by the auth middleware, so it is ignored
+// unless server.auth is enabled.
+//
+// Parameters:
+//   - c: Tenancy configuration
+//   - auth: Whether the auth middleware verifies the JWT of requests
+//   - logger: Logger instance for reporting ignored resolvers
+//
+// Returns:
+//   - []tenant.Resolver: The resolvers in configuration order; the header resolver by default
+func tenantResolvers(c *conf.Server_Tenancy, auth bool, logger log.Logger) []tenant.Resolver {
+	names := c.GetResolvers()
+	if len(names) == 0 {
+		names = []string{"header"}
+	}
+
+	resolvers := make([]tenant.Resolver, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "header":
+			header := c.GetHeader()
+			if header == "" {
+				header = "X-Tenant-ID"
+			}
+			resolvers = append(resolvers, tenant.FromHeader(header))
+		case "jwt":
+			if !auth {
+				log.NewHelper(logger).Errorf("tenant resolver \"jwt\" ignored, it requires server.auth to be enabled")
+				continue
+			}
+			claim := c.GetJwtClaim()
+			if claim == "" {
+				claim = "tenant_id"
+			}
+			resolvers = append(resolvers, tenant.FromClaim(claim))
+		case "subdomain":
+			resolvers = append(resolvers, tenant.FromSubdomain(c.GetDomain()))
+		default:
+			log.NewHelper(logger).Errorf("unknown tenant resolver %q ignored", name)
+		}
+	}
+	return resolvers
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+)
+
+// headerCarrier adapts http.Header to transport.Header.
+type headerCarrier http.Header
+
+func (h headerCarrier) Get(key string) string      { return http.Header(h).Get(key) }
+func (h headerCarrier) Set(key, value string)      { http.Header(h).Set(key, value) }
+func (h headerCarrier) Add(key, value string)      { http.Header(h).Add(key, value) }
+func (h headerCarrier) Values(key string) []string { return http.Header(h).Values(key) }
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// testTransport is a server transport carrying request headers.
+type testTransport struct {
+	header headerCarrier
+}
+
+func (t *testTransport) Kind() transport.Kind            { return transport.KindHTTP }
+func (t *testTransport) Endpoint() string                { return "" }
+func (t *testTransport) Operation() string               { return "/demo.v1.Demo/Hello" }
+func (t *testTransport) RequestHeader() transport.Header { return t.header }
+func (t *testTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+
+// signToken returns a bearer token signed with key.
+func signToken(t *testing.T, method jwtv5.SigningMethod, key string, claims jwtv5.MapClaims) string {
+	t.Helper()
+	token, err := jwtv5.NewWithClaims(method, claims).SignedString([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestServerMiddlewareJWTTenant(t *testing.T) {
+	tenancy := &conf.Server_Tenancy{Enabled: true, Resolvers: []string{"jwt", "header"}}
+	claims := jwtv5.MapClaims{"sub": "u1", "tenant_id": "acme"}
+
+	tests := []struct {
+		name          string
+		auth          *conf.Server_Auth
+		authorization string
+		tenantHeader  string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "tenant from verified token",
+			auth:          &conf.Server_Auth{Enabled: true, Key: "secret"},
+			authorization: signToken(t, jwtv5.SigningMethodHS256, "secret", claims),
+			tenantHeader:  "other",
+			want:          "acme",
+		},
+		{
+			name:          "configured signing method",
+			auth:          &conf.Server_Auth{Enabled: true, SigningMethod: "HS512", Key: "secret"},
+			authorization: signToken(t, jwtv5.SigningMethodHS512, "secret", claims),
+			want:          "acme",
+		},
+		{
+			name:          "token signed with another key",
+			auth:          &conf.Server_Auth{Enabled: true, Key: "secret"},
+			authorization: signToken(t, jwtv5.SigningMethodHS256, "guess", claims),
+			wantErr:       true,
+		},
+		{
+			name:         "missing token",
+			auth:         &conf.Server_Auth{Enabled: true, Key: "secret"},
+			tenantHeader: "other",
+			wantErr:      true,
+		},
+		{
+			name:          "empty key rejects every token",
+			auth:          &conf.Server_Auth{Enabled: true},
+			authorization: signToken(t, jwtv5.SigningMethodHS256, "", claims),
+			wantErr:       true,
+		},
+		{
+			name:          "jwt resolver ignored without auth",
+			authorization: signToken(t, jwtv5.SigningMethodHS256, "secret", claims),
+			tenantHeader:  "other",
+			want:          "other",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &conf.Server{Tenancy: tenancy, Auth: tt.auth}
+			var got string
+			handler := middleware.Chain(serverMiddleware(c, log.NewStdLogger(io.Discard))...)(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					got, _ = tenant.FromContext(ctx)
+					return nil, nil
+				})
+
+			header := headerCarrier{}
+			if tt.authorization != "" {
+				header.Set("Authorization", tt.authorization)
+			}
+			if tt.tenantHeader != "" {
+				header.Set("X-Tenant-ID", tt.tenantHeader)
+			}
+			ctx := transport.NewServerContext(context.Background(), &testTransport{header: header})
+			_, err := handler(ctx, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("request was not rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("tenant = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package server provides server initialization for both gRPC and HTTP servers.
+package server
+
+import (
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/cache"
+	"kratos-project-template/provider/election"
+	"kratos-project-template/provider/outbox"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// NewOutboxRelay creates the outbox relay server.
+// The relay publishes the events stored with outbox.Add to Redis Streams; only the
+// elected leader relays, so enable leader election when running several replicas.
+//
+// Parameters:
+//   - c: Data configuration containing outbox settings
+//   - logger: Logger instance for relay logging
+//
+// Returns:
+//   - *outbox.Relay: A configured relay, or nil if the relay is disabled
+//     or Redis is not configured
+func NewOutboxRelay(c *conf.Data, logger log.Logger) *outbox.Relay {
+	if !c.GetOutbox().GetEnabled() {
+		return nil
+	}
+
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		log.NewHelper(logger).Warnf("redis is not configured, outbox relay disabled")
+		return nil
+	}
+
+	// Replace the publisher to relay to another broker, e.g.:
+	//   outbox.NewRelay(outbox.PublisherFunc(kafkaPublish), election.Default(), c.GetOutbox(), logger)
+	publisher := outbox.NewRedisStreamPublisher(rdb, c.GetOutbox())
+	return outbox.NewRelay(publisher, election.Default(), c.GetOutbox(), logger)
+}
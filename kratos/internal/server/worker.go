@@ -0,0 +1,39 @@
+// Package server provides server initialization for both gRPC and HTTP servers.
+package server
+
+import (
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/cache"
+	"kratos-project-template/provider/queue"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// NewWorkerServer creates the background job worker server.
+// The worker consumes the configured queues and runs the registered job handlers.
+//
+// Parameters:
+//   - c: Data configuration containing queue settings
+//   - logger: Logger instance for worker logging
+//
+// Returns:
+//   - *queue.Server: A configured worker server, or nil if the worker is disabled
+//     or Redis is not configured
+func NewWorkerServer(c *conf.Data, logger log.Logger) *queue.Server {
+	if !c.GetQueue().GetEnabled() {
+		return nil
+	}
+
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		log.NewHelper(logger).Warnf("redis is not configured, job worker disabled")
+		return nil
+	}
+
+	srv := queue.NewServer(rdb, c.GetQueue(), logger)
+
+	// Register job handlers here, e.g.:
+	//   srv.Handle("email:send", queue.TypedHandler(mailService.SendEmail))
+
+	return srv
+}
@@ -81,72 +81,84 @@ func (s *DemoService) CheckHealthy(ctx context.Context, req *pb.CheckHealthyRequ
 	healthStatus := "healthy"
 	details := make(map[string]*pb.HealthDetails)
 
-	// Check database connection
-	dbHealthy := true
-	startTime := time.Now()
-	if db.Get() != nil {
-		sqlDB, err := db.Get().DB()
-		if err == nil {
-			pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-			err := sqlDB.PingContext(pingCtx)
-			latency := time.Since(startTime).Milliseconds()
-			cancel()
-			if err != nil {
-				dbHealthy = false
-				healthStatus = "degraded"
-				details["database"] = &pb.HealthDetails{
-					Status:    "unhealthy",
-					Error:     err.Error(),
-					LatencyMs: float64(latency),
-				}
+	// Check database connections; the default connection is critical, named ones
+	// (data.databases) only degrade the service.
+	dbHealthy := false
+	for _, st := range db.Health(ctx) {
+		key := "database"
+		if st.Name != db.DefaultName {
+			key = "database_" + st.Name
+		}
+		if st.State != db.StateUp {
+			healthStatus = "degraded"
+			detail := &pb.HealthDetails{
+				Status:    "unhealthy",
+				LatencyMs: float64(st.Latency.Milliseconds()),
+			}
+			if st.Err != nil {
+				detail.Error = fmt.Sprintf("database %s since %s: %v", st.State, st.Since.Format(time.RFC3339), st.Err)
 			} else {
-				details["database"] = &pb.HealthDetails{
-					Status:    "healthy",
-					LatencyMs: float64(latency),
-				}
+				detail.Error = "database " + st.State.String()
 			}
+			details[key] = detail
 		} else {
-			dbHealthy = false
-			healthStatus = "degraded"
-			details["database"] = &pb.HealthDetails{
-				Status: "unhealthy",
-				Error:  "failed to get database instance",
+			if st.Name == db.DefaultName {
+				dbHealthy = true
+			}
+			details[key] = &pb.HealthDetails{
+				Status:    "healthy",
+				LatencyMs: float64(st.Latency.Milliseconds()),
 			}
 		}
-	} else {
-		dbHealthy = false
-		healthStatus = "unhealthy"
+
+		// Check read replicas (if configured); unhealthy replicas are out of rotation
+		for _, replica := range st.Replicas {
+			detail := &pb.HealthDetails{
+				Status:    "healthy",
+				LatencyMs: float64(replica.Latency.Milliseconds()),
+			}
+			if !replica.Healthy {
+				healthStatus = "degraded"
+				detail.Status = "unhealthy"
+				if replica.Err != nil {
+					detail.Error = replica.Err.Error()
+				}
+			}
+			details[fmt.Sprintf("%s_replica_%d", key, replica.Index)] = detail
+		}
+	}
+	if _, ok := details["database"]; !ok {
 		details["database"] = &pb.HealthDetails{
 			Status: "unavailable",
-			Error:  "database instance is nil",
+			Error:  "database is not initialized",
 		}
 	}
 
 	// Check Redis connection (if available)
-	redisStartTime := time.Now()
-	redisClient := cache.GetRedisClient()
-	if redisClient != nil {
-		redisCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-		_, err := redisClient.Ping(redisCtx).Result()
-		redisLatency := time.Since(redisStartTime).Milliseconds()
-		cancel()
-		if err != nil {
-			healthStatus = "degraded"
-			details["redis"] = &pb.HealthDetails{
-				Status:    "unhealthy",
-				Error:     err.Error(),
-				LatencyMs: float64(redisLatency),
-			}
-		} else {
-			details["redis"] = &pb.HealthDetails{
-				Status:    "healthy",
-				LatencyMs: float64(redisLatency),
-			}
-		}
-	} else {
+	// The state comes from the background monitor in the cache provider.
+	redisStatus := cache.GetStatus()
+	switch redisStatus.State {
+	case cache.StateNotConfigured:
 		details["redis"] = &pb.HealthDetails{
 			Status: "not_configured",
 		}
+	case cache.StateUp:
+		details["redis"] = &pb.HealthDetails{
+			Status:    "healthy",
+			LatencyMs: float64(redisStatus.Latency.Milliseconds()),
+		}
+	default:
+		healthStatus = "degraded"
+		detail := &pb.HealthDetails{
+			Status:    "unhealthy",
+			LatencyMs: float64(redisStatus.Latency.Milliseconds()),
+		}
+		if redisStatus.Err != nil {
+			detail.Error = redisStatus.Err.Error()
+		} else {
+			detail.Error = "redis " + redisStatus.State.String()
+		}
+		details["redis"] = detail
 	}
 
 	// If database is critical and unhealthy, mark as unhealthy
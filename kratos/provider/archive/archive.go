@@ -0,0 +1,705 @@
+package archive
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/audit"
+	"kratos-project-template/provider/db/tenant"
+	"kratos-project-template/provider/election"
+	"kratos-project-template/provider/storage"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultPrefix    = "archive"
+	defaultBatchSize = 1000
+	// restoreBatchSize bounds the rows per INSERT when restoring.
+	restoreBatchSize = 100
+	// formatJSONLines is the format of the data files.
+	formatJSONLines = "jsonl.gz"
+	// actor is recorded in the audit trail for archived and restored rows.
+	actor = "archive"
+	// lockRetryInterval is the wait between attempts to take the index lock.
+	lockRetryInterval = 100 * time.Millisecond
+)
+
+// indexMu serializes index updates within the process; databases without advisory
+// locks have no other protection.
+var indexMu sync.Mutex
+
+// ErrUnknownPolicy is returned for a policy name that is not registered.
+var ErrUnknownPolicy = errors.New("unknown archive policy")
+
+// Manifest describes an archived batch.
+type Manifest struct {
+	// ID identifies the batch
+	ID string `json:"id"`
+	// Policy is the name of the policy that archived the batch
+	Policy string `json:"policy"`
+	// Table is the table the rows were deleted from
+	Table string `json:"table"`
+	// Format is the format of the data file, "jsonl.gz"
+	Format string `json:"format"`
+	// DataKey is the object key of the data file
+	DataKey string `json:"data_key"`
+	// SHA256 is the hex encoded checksum of the data file
+	SHA256 string `json:"sha256"`
+	// Count is the number of rows in the batch
+	Count int `json:"count"`
+	// Column is the time column of the policy; MinTime and MaxTime bound its values in the batch
+	Column  string    `json:"column"`
+	MinTime time.Time `json:"min_time"`
+	MaxTime time.Time `json:"max_time"`
+	// Keys are the primary keys of the rows; they are only loaded by Lookup
+	Keys []string `json:"keys,omitempty"`
+	// MinKey and MaxKey bound the primary keys of the batch, compared numerically for
+	// integer keys; Lookup only downloads the manifests whose range holds the key
+	MinKey string `json:"min_key,omitempty"`
+	MaxKey string `json:"max_key,omitempty"`
+	// CreatedAt is when the batch was archived
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// index lists the manifests of a policy.
+type index struct {
+	Manifests []Manifest `json:"manifests"`
+}
+
+// Result reports an archival run of a policy.
+type Result struct {
+	// Policy is the policy name
+	Policy string
+	// Batches is the number of files written
+	Batches int
+	// Rows is the number of rows archived
+	Rows int
+}
+
+// Archiver archives, looks up and restores rows of the registered policies.
+// Runs of the same policy must not overlap; the Server runs them on the elected leader only.
+// Updates of a policy index are serialized by an advisory lock on the policy's database
+// (Postgres or MySQL), so a restore command does not lose the batches archived by the
+// server meanwhile.
+type Archiver struct {
+	storage   storage.Storage
+	prefix    string
+	batchSize int
+	olderThan map[string]time.Duration
+	log       *log.Helper
+}
+
+// New creates an archiver.
+//
+// Parameters:
+//   - st: The object storage holding the archive, e.g. storage.Get()
+//   - cfg: Archive configuration (may be nil to use defaults)
+//   - logger: Logger instance for archival progress
+//
+// Returns:
+//   - *Archiver: A new archiver
+func New(st storage.Storage, cfg *conf.Data_Archive, logger log.Logger) *Archiver {
+	a := &Archiver{
+		storage:   st,
+		prefix:    defaultPrefix,
+		batchSize: defaultBatchSize,
+		olderThan: make(map[string]time.Duration),
+		log:       log.NewHelper(log.With(logger, "module", "archive")),
+	}
+	if cfg.GetPrefix() != "" {
+		a.prefix = cfg.GetPrefix()
+	}
+	if cfg.GetBatchSize() > 0 {
+		a.batchSize = int(cfg.GetBatchSize())
+	}
+	for name, d := range cfg.GetOlderThan() {
+		a.olderThan[name] = d.AsDuration()
+	}
+	return a
+}
+
+// Run archives the rows of the given policies, or of all registered policies.
+//
+// Parameters:
+//   - ctx: Context for the database and storage operations
+//   - names: Optional policy names
+//
+// Returns:
+//   - []Result: One result per policy run, including the failed one
+//   - error: Error of the first failed policy; earlier batches stay archived
+func (a *Archiver) Run(ctx context.Context, names ...string) ([]Result, error) {
+	if _, ok := a.storage.(*storage.NoOpStorage); ok || a.storage == nil {
+		return nil, errors.New("archive requires object storage, enable data.object_storage")
+	}
+	if len(names) == 0 {
+		names = Policies()
+	}
+
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		p := lookupPolicy(name)
+		if p == nil {
+			return results, errors.Wrapf(ErrUnknownPolicy, "policy %q", name)
+		}
+		res, err := a.archive(ctx, p)
+		results = append(results, res)
+		if err != nil {
+			return results, errors.Wrapf(err, "archive policy %q", name)
+		}
+		if res.Rows > 0 {
+			a.log.Infof("[archive] policy %s archived %d rows in %d batches", name, res.Rows, res.Batches)
+		}
+	}
+	return results, nil
+}
+
+// archive moves the rows of a policy older than its cutoff, one batch per transaction.
+func (a *Archiver) archive(ctx context.Context, p *Policy) (Result, error) {
+	res := Result{Policy: p.Name}
+	sch, err := a.schema(p)
+	if err != nil {
+		return res, err
+	}
+	olderThan := p.OlderThan
+	if d, ok := a.olderThan[p.Name]; ok && d > 0 {
+		olderThan = d
+	}
+	cutoff := time.Now().Add(-olderThan)
+	ctx = audit.ContextWithActor(tenant.SkipTenant(ctx), actor)
+
+	for ctx.Err() == nil {
+		n, err := a.archiveBatch(ctx, p, sch, cutoff)
+		if err != nil {
+			return res, err
+		}
+		if n == 0 {
+			break
+		}
+		res.Batches++
+		res.Rows += n
+		if n < a.batchSize {
+			break
+		}
+	}
+	return res, ctx.Err()
+}
+
+// archiveBatch uploads the oldest rows before the cutoff and deletes them.
+// Files are written before the delete so a failure never loses rows; a failed commit
+// leaves a batch whose rows are still in the database, which restore tolerates.
+func (a *Archiver) archiveBatch(ctx context.Context, p *Policy, sch *schema.Schema, cutoff time.Time) (int, error) {
+	pk := sch.PrioritizedPrimaryField
+	n := 0
+	err := db.Transaction(ctx, func(ctx context.Context) error {
+		rows := reflect.New(reflect.SliceOf(sch.ModelType))
+		err := db.DB(ctx, p.Connection).Unscoped().Model(p.Model).Scopes(p.Scopes...).
+			Where(clause.Lt{Column: clause.Column{Table: clause.CurrentTable, Name: p.Column}, Value: cutoff}).
+			Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}}).
+			Limit(a.batchSize).
+			Find(rows.Interface()).Error
+		if err != nil {
+			return errors.Wrap(err, "select rows")
+		}
+		slice := rows.Elem()
+		if slice.Len() == 0 {
+			return nil
+		}
+
+		m, data, keys, err := a.encode(ctx, p, sch, slice)
+		if err != nil {
+			return err
+		}
+		if err := a.storage.PutObject(ctx, m.DataKey, data); err != nil {
+			return errors.Wrap(err, "upload data file")
+		}
+		if ok, err := a.storage.Exists(ctx, m.DataKey); err != nil || !ok {
+			return errors.Errorf("data file %s not found after upload: %v", m.DataKey, err)
+		}
+		if err := a.putJSON(ctx, a.manifestKey(p.Name, m.ID), m); err != nil {
+			return errors.Wrap(err, "upload manifest")
+		}
+		if err := a.updateIndex(ctx, p, func(idx *index) {
+			entry := *m
+			entry.Keys = nil
+			idx.Manifests = append(idx.Manifests, entry)
+		}); err != nil {
+			return err
+		}
+
+		del := db.DB(ctx, p.Connection).Unscoped().
+			Where(clause.IN{Column: clause.Column{Name: pk.DBName}, Values: keys}).
+			Delete(reflect.New(sch.ModelType).Interface())
+		if del.Error != nil {
+			return errors.Wrap(del.Error, "delete archived rows")
+		}
+		if del.RowsAffected != int64(len(keys)) {
+			return errors.Errorf("deleted %d of %d archived rows, rows changed concurrently", del.RowsAffected, len(keys))
+		}
+		n = len(keys)
+		return nil
+	}, db.TxOn(p.Connection))
+	return n, err
+}
+
+// encode writes the rows as gzip compressed JSON lines of column values.
+// It returns the manifest, the file and the primary key values.
+func (a *Archiver) encode(ctx context.Context, p *Policy, sch *schema.Schema, rows reflect.Value) (*Manifest, []byte, []interface{}, error) {
+	id := newBatchID()
+	m := &Manifest{
+		ID:        id,
+		Policy:    p.Name,
+		Table:     sch.Table,
+		Format:    formatJSONLines,
+		DataKey:   fmt.Sprintf("%s/%s/data/%s.%s", a.prefix, p.Name, id, formatJSONLines),
+		Count:     rows.Len(),
+		Column:    p.Column,
+		CreatedAt: time.Now().UTC(),
+	}
+	timeField := sch.LookUpField(p.Column)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	keys := make([]interface{}, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		record := make(map[string]interface{}, len(sch.DBNames))
+		for _, col := range sch.DBNames {
+			record[col], _ = sch.FieldsByDBName[col].ValueOf(ctx, row)
+		}
+		if err := enc.Encode(record); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "encode row")
+		}
+
+		key, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, row)
+		keys = append(keys, key)
+		k := fmt.Sprint(key)
+		m.Keys = append(m.Keys, k)
+		if i == 0 || compareKeys(sch.PrioritizedPrimaryField, k, m.MinKey) < 0 {
+			m.MinKey = k
+		}
+		if i == 0 || compareKeys(sch.PrioritizedPrimaryField, k, m.MaxKey) > 0 {
+			m.MaxKey = k
+		}
+		if timeField != nil {
+			if t, ok := asTime(timeField, ctx, row); ok {
+				if m.MinTime.IsZero() || t.Before(m.MinTime) {
+					m.MinTime = t
+				}
+				if t.After(m.MaxTime) {
+					m.MaxTime = t
+				}
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "compress rows")
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	m.SHA256 = hex.EncodeToString(sum[:])
+	return m, buf.Bytes(), keys, nil
+}
+
+// Manifests returns the archived batches of a policy whose time range overlaps
+// [from, to), oldest first. Zero bounds are open. Keys are not loaded.
+//
+// Parameters:
+//   - ctx: Context for the storage operations
+//   - name: The policy name
+//   - from: Lower bound of the policy's time column
+//   - to: Upper bound of the policy's time column
+//
+// Returns:
+//   - []Manifest: The matching batches
+//   - error: Error if the index cannot be read
+func (a *Archiver) Manifests(ctx context.Context, name string, from, to time.Time) ([]Manifest, error) {
+	if lookupPolicy(name) == nil {
+		return nil, errors.Wrapf(ErrUnknownPolicy, "policy %q", name)
+	}
+	idx, err := a.loadIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var manifests []Manifest
+	for _, m := range idx.Manifests {
+		if (!from.IsZero() && m.MaxTime.Before(from)) || (!to.IsZero() && !m.MinTime.Before(to)) {
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// Read loads the rows of an archived batch.
+//
+// Parameters:
+//   - ctx: Context for the storage operations
+//   - m: The batch, from Manifests
+//   - dest: Pointer to a slice of the policy's model, e.g. *[]models.Order
+//
+// Returns:
+//   - error: Error if the file is missing, corrupted or does not match dest
+func (a *Archiver) Read(ctx context.Context, m *Manifest, dest interface{}) error {
+	p := lookupPolicy(m.Policy)
+	if p == nil {
+		return errors.Wrapf(ErrUnknownPolicy, "policy %q", m.Policy)
+	}
+	sch, err := a.schema(p)
+	if err != nil {
+		return err
+	}
+	out := reflect.ValueOf(dest)
+	if out.Kind() != reflect.Ptr || out.Elem().Kind() != reflect.Slice || out.Elem().Type().Elem() != sch.ModelType {
+		return errors.Errorf("archive: dest must be *[]%s", sch.ModelType)
+	}
+
+	data, err := a.storage.GetObject(ctx, m.DataKey)
+	if err != nil {
+		return errors.Wrapf(err, "download %s", m.DataKey)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != m.SHA256 {
+		return errors.Errorf("archive file %s is corrupted: checksum mismatch", m.DataKey)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "decompress %s", m.DataKey)
+	}
+	defer zr.Close()
+
+	slice := out.Elem()
+	dec := json.NewDecoder(zr)
+	for {
+		var record map[string]json.RawMessage
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrapf(err, "decode %s", m.DataKey)
+		}
+		row := reflect.New(sch.ModelType).Elem()
+		for col, raw := range record {
+			field := sch.LookUpField(col)
+			if field == nil {
+				continue
+			}
+			v := reflect.New(field.FieldType)
+			if err := json.Unmarshal(raw, v.Interface()); err != nil {
+				return errors.Wrapf(err, "decode column %s of %s", col, m.DataKey)
+			}
+			if err := field.Set(ctx, row, v.Elem().Interface()); err != nil {
+				return errors.Wrapf(err, "set column %s", col)
+			}
+		}
+		slice = reflect.Append(slice, row)
+	}
+	out.Elem().Set(slice)
+	return nil
+}
+
+// Lookup finds an archived row by primary key, searching the newest batches first.
+// Only the batches whose key range holds the key are downloaded.
+//
+// Parameters:
+//   - ctx: Context for the storage operations
+//   - name: The policy name
+//   - key: The primary key formatted as a string
+//   - dest: Pointer to the policy's model receiving the row
+//
+// Returns:
+//   - bool: True if the row was found
+//   - error: Error if the archive cannot be read
+func (a *Archiver) Lookup(ctx context.Context, name, key string, dest interface{}) (bool, error) {
+	p := lookupPolicy(name)
+	if p == nil {
+		return false, errors.Wrapf(ErrUnknownPolicy, "policy %q", name)
+	}
+	sch, err := a.schema(p)
+	if err != nil {
+		return false, err
+	}
+	out := reflect.ValueOf(dest)
+	if out.Kind() != reflect.Ptr || out.Elem().Type() != sch.ModelType {
+		return false, errors.Errorf("archive: dest must be *%s", sch.ModelType)
+	}
+
+	idx, err := a.loadIndex(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	pk := sch.PrioritizedPrimaryField
+	for i := len(idx.Manifests) - 1; i >= 0; i-- {
+		entry := &idx.Manifests[i]
+		// Batches archived before key ranges were recorded have none and are searched.
+		if entry.MaxKey != "" && (compareKeys(pk, key, entry.MinKey) < 0 || compareKeys(pk, key, entry.MaxKey) > 0) {
+			continue
+		}
+		var m Manifest
+		if err := a.getJSON(ctx, a.manifestKey(name, entry.ID), &m); err != nil {
+			return false, errors.Wrap(err, "download manifest")
+		}
+		found := false
+		for _, k := range m.Keys {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		rows := reflect.New(reflect.SliceOf(sch.ModelType))
+		if err := a.Read(ctx, &m, rows.Interface()); err != nil {
+			return false, err
+		}
+		for j := 0; j < rows.Elem().Len(); j++ {
+			row := rows.Elem().Index(j)
+			if v, _ := pk.ValueOf(ctx, row); fmt.Sprint(v) == key {
+				out.Elem().Set(row)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Restore inserts the archived rows of a policy whose batches overlap [from, to) back
+// into the database and removes the batches from the archive. Rows that already exist
+// are left unchanged. Restored rows still older than the policy are archived again by
+// the next run unless the policy is changed.
+//
+// Parameters:
+//   - ctx: Context for the database and storage operations
+//   - name: The policy name
+//   - from: Lower bound of the policy's time column (zero for no bound)
+//   - to: Upper bound of the policy's time column (zero for no bound)
+//
+// Returns:
+//   - int: The number of rows read from the restored batches
+//   - error: Error of the first failed batch; earlier batches stay restored
+func (a *Archiver) Restore(ctx context.Context, name string, from, to time.Time) (int, error) {
+	manifests, err := a.Manifests(ctx, name, from, to)
+	if err != nil {
+		return 0, err
+	}
+	p := lookupPolicy(name)
+	sch, err := a.schema(p)
+	if err != nil {
+		return 0, err
+	}
+	ctx = audit.ContextWithActor(tenant.SkipTenant(ctx), actor)
+
+	restored := 0
+	for i := range manifests {
+		m := &manifests[i]
+		rows := reflect.New(reflect.SliceOf(sch.ModelType))
+		if err := a.Read(ctx, m, rows.Interface()); err != nil {
+			return restored, err
+		}
+		err := db.Transaction(ctx, func(ctx context.Context) error {
+			return db.DB(ctx, p.Connection).Session(&gorm.Session{SkipHooks: true}).
+				Clauses(clause.OnConflict{DoNothing: true}).
+				CreateInBatches(rows.Interface(), restoreBatchSize).Error
+		}, db.TxOn(p.Connection))
+		if err != nil {
+			return restored, errors.Wrapf(err, "restore batch %s", m.ID)
+		}
+		restored += m.Count
+
+		if err := a.updateIndex(ctx, p, func(idx *index) {
+			kept := idx.Manifests[:0]
+			for _, entry := range idx.Manifests {
+				if entry.ID != m.ID {
+					kept = append(kept, entry)
+				}
+			}
+			idx.Manifests = kept
+		}); err != nil {
+			return restored, err
+		}
+		for _, key := range []string{m.DataKey, a.manifestKey(name, m.ID)} {
+			if err := a.storage.DeleteObject(ctx, key); err != nil {
+				a.log.Warnf("[archive] delete restored object %s failed: %v", key, err)
+			}
+		}
+		a.log.Infof("[archive] policy %s restored batch %s (%d rows)", name, m.ID, m.Count)
+	}
+	return restored, nil
+}
+
+// schema parses the model of a policy and checks its primary key.
+func (a *Archiver) schema(p *Policy) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db.Get(p.Connection)}
+	if err := stmt.Parse(p.Model); err != nil {
+		return nil, errors.Wrapf(err, "parse model of policy %q", p.Name)
+	}
+	if len(stmt.Schema.PrimaryFields) != 1 {
+		return nil, errors.Errorf("model of policy %q must have a single-column primary key", p.Name)
+	}
+	if stmt.Schema.LookUpField(p.Column) == nil {
+		return nil, errors.Errorf("model of policy %q has no column %q", p.Name, p.Column)
+	}
+	return stmt.Schema, nil
+}
+
+// manifestKey returns the object key of a batch manifest.
+func (a *Archiver) manifestKey(policy, id string) string {
+	return fmt.Sprintf("%s/%s/manifests/%s.json", a.prefix, policy, id)
+}
+
+// indexKey returns the object key of a policy index.
+func (a *Archiver) indexKey(policy string) string {
+	return fmt.Sprintf("%s/%s/index.json", a.prefix, policy)
+}
+
+// loadIndex reads the index of a policy; a missing index is empty.
+func (a *Archiver) loadIndex(ctx context.Context, policy string) (*index, error) {
+	idx := &index{}
+	ok, err := a.storage.Exists(ctx, a.indexKey(policy))
+	if err != nil {
+		return nil, errors.Wrap(err, "check archive index")
+	}
+	if !ok {
+		return idx, nil
+	}
+	if err := a.getJSON(ctx, a.indexKey(policy), idx); err != nil {
+		return nil, errors.Wrap(err, "download archive index")
+	}
+	return idx, nil
+}
+
+// updateIndex applies fn to the index of a policy and uploads it while holding the
+// index lock.
+func (a *Archiver) updateIndex(ctx context.Context, p *Policy, fn func(idx *index)) error {
+	unlock, err := a.lockIndex(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	idx, err := a.loadIndex(ctx, p.Name)
+	if err != nil {
+		return err
+	}
+	fn(idx)
+	return errors.Wrap(a.putJSON(ctx, a.indexKey(p.Name), idx), "upload archive index")
+}
+
+// lockIndex takes the lock of a policy index: an advisory lock on the policy's database,
+// so every process archiving or restoring the policy takes the same lock. SQLite has no
+// advisory locks and is only locked within the process.
+func (a *Archiver) lockIndex(ctx context.Context, p *Policy) (func(), error) {
+	indexMu.Lock()
+	gormDB := db.Get(p.Connection)
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		indexMu.Unlock()
+		return nil, errors.Wrap(err, "get sql db error")
+	}
+	backend, err := election.NewSQLBackend(sqlDB, gormDB.Dialector.Name(), "archive:"+a.indexKey(p.Name))
+	if err != nil {
+		return indexMu.Unlock, nil
+	}
+
+	for {
+		_, acquired, err := backend.TryAcquire(ctx, "", 0)
+		if acquired {
+			break
+		}
+		if err != nil && ctx.Err() == nil {
+			a.log.Warnf("[archive] lock index of policy %s failed: %v", p.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			indexMu.Unlock()
+			return nil, errors.Wrap(ctx.Err(), "lock archive index")
+		case <-time.After(lockRetryInterval):
+		}
+	}
+	return func() {
+		// The lock is released even if ctx was cancelled during the update.
+		_ = backend.Release(context.WithoutCancel(ctx), "")
+		indexMu.Unlock()
+	}, nil
+}
+
+// putJSON uploads v encoded as JSON.
+func (a *Archiver) putJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return a.storage.PutObject(ctx, key, data)
+}
+
+// getJSON downloads and decodes a JSON object.
+func (a *Archiver) getJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := a.storage.GetObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// compareKeys orders primary keys formatted as strings: numerically for integer keys,
+// bytewise otherwise.
+func compareKeys(field *schema.Field, a, b string) int {
+	switch field.DataType {
+	case schema.Int:
+		x, errX := strconv.ParseInt(a, 10, 64)
+		y, errY := strconv.ParseInt(b, 10, 64)
+		if errX == nil && errY == nil {
+			return cmp.Compare(x, y)
+		}
+	case schema.Uint:
+		x, errX := strconv.ParseUint(a, 10, 64)
+		y, errY := strconv.ParseUint(b, 10, 64)
+		if errX == nil && errY == nil {
+			return cmp.Compare(x, y)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// asTime returns the value of a time column of a row.
+func asTime(field *schema.Field, ctx context.Context, row reflect.Value) (time.Time, bool) {
+	v, zero := field.ValueOf(ctx, row)
+	if zero {
+		return time.Time{}, false
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		return *t, t != nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// newBatchID returns a sortable unique batch identifier.
+func newBatchID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,429 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/driver"
+	"kratos-project-template/provider/storage"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// memStorage keeps objects in memory and counts the downloads.
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	gets    map[string]int
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{objects: make(map[string][]byte), gets: make(map[string]int)}
+}
+
+func (s *memStorage) PutObject(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memStorage) PutObjectFromReader(ctx context.Context, key string, reader io.Reader, _ int64) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return s.PutObject(ctx, key, data)
+}
+
+func (s *memStorage) GetObject(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.Errorf("object %s not found", key)
+	}
+	s.gets[key]++
+	return append([]byte(nil), data...), nil
+}
+
+func (s *memStorage) GetObjectReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, err := s.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStorage) DeleteObject(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memStorage) Exists(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+func (s *memStorage) GetObjectURL(context.Context, string, int64) (string, error) {
+	return "", nil
+}
+
+// manifestGets returns the number of manifest downloads.
+func (s *memStorage) manifestGets() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key, gets := range s.gets {
+		if strings.Contains(key, "/manifests/") {
+			n += gets
+		}
+	}
+	return n
+}
+
+// event is the model archived by the tests.
+type event struct {
+	ID        uint64 `gorm:"primaryKey"`
+	Name      string
+	CreatedAt time.Time
+}
+
+// usePolicy registers an in-memory SQLite database as the default connection and a
+// policy archiving its events table until the test ends.
+func usePolicy(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:archive_%s?mode=memory&cache=shared", name), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := gormDB.AutoMigrate(&event{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Set(db.DefaultName, gormDB, log.NewStdLogger(io.Discard)))
+
+	Register(Policy{Name: name, Model: &event{}, OlderThan: time.Hour})
+	t.Cleanup(func() {
+		policiesMu.Lock()
+		delete(policies, name)
+		policiesMu.Unlock()
+	})
+	return gormDB
+}
+
+// insertEvents stores events with the given IDs created two hours ago.
+func insertEvents(t *testing.T, gormDB *gorm.DB, ids ...uint64) {
+	t.Helper()
+	for _, id := range ids {
+		e := event{ID: id, Name: fmt.Sprintf("event %d", id), CreatedAt: time.Now().Add(-2 * time.Hour)}
+		if err := gormDB.Create(&e).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// eventIDs returns the IDs of the events in the database.
+func eventIDs(t *testing.T, gormDB *gorm.DB) []uint64 {
+	t.Helper()
+	ids := []uint64{}
+	if err := gormDB.Model(&event{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// readIDs returns the IDs of the events in the batches.
+func readIDs(t *testing.T, a *Archiver, manifests []Manifest) []uint64 {
+	t.Helper()
+	ids := []uint64{}
+	for i := range manifests {
+		var events []event
+		if err := a.Read(context.Background(), &manifests[i], &events); err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if len(events) != manifests[i].Count {
+			t.Errorf("batch %s has %d rows, manifest says %d", manifests[i].ID, len(events), manifests[i].Count)
+		}
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids
+}
+
+func TestRun(t *testing.T) {
+	gormDB := usePolicy(t, "run")
+	st := newMemStorage()
+	a := New(st, nil, log.NewStdLogger(io.Discard))
+	a.batchSize = 2
+	ctx := context.Background()
+
+	insertEvents(t, gormDB, 1, 2, 3, 4, 5)
+	if err := gormDB.Create(&event{ID: 6, Name: "recent", CreatedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := a.Run(ctx, "run")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []Result{{Policy: "run", Batches: 3, Rows: 5}}; !reflect.DeepEqual(results, want) {
+		t.Errorf("Run() = %+v, want %+v", results, want)
+	}
+	if got := eventIDs(t, gormDB); !reflect.DeepEqual(got, []uint64{6}) {
+		t.Errorf("events left = %v, want only the recent one", got)
+	}
+
+	manifests, err := a.Manifests(ctx, "run", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Manifests() error = %v", err)
+	}
+	if len(manifests) != 3 {
+		t.Fatalf("Manifests() returned %d batches, want 3", len(manifests))
+	}
+	if m := manifests[0]; m.Table != "events" || m.Column != "created_at" || m.MinKey != "1" || m.MaxKey != "2" || m.Keys != nil {
+		t.Errorf("first manifest = %+v", m)
+	}
+	if got := readIDs(t, a, manifests); !reflect.DeepEqual(got, []uint64{1, 2, 3, 4, 5}) {
+		t.Errorf("archived events = %v, want [1 2 3 4 5]", got)
+	}
+	var e event
+	if found, err := a.Lookup(ctx, "run", "3", &e); err != nil || !found || e.Name != "event 3" {
+		t.Errorf("Lookup() = %+v, %v, %v, want event 3", e, found, err)
+	}
+
+	// Nothing is left to archive.
+	if results, err := a.Run(ctx); err != nil || len(results) != 1 || results[0].Rows != 0 {
+		t.Errorf("second Run() = %+v, %v, want no rows", results, err)
+	}
+	if _, err := a.Run(ctx, "missing"); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("Run() of an unknown policy error = %v, want ErrUnknownPolicy", err)
+	}
+	if _, err := New(&storage.NoOpStorage{}, nil, log.NewStdLogger(io.Discard)).Run(ctx, "run"); err == nil {
+		t.Error("Run() without object storage did not fail")
+	}
+}
+
+func TestReadCorrupted(t *testing.T) {
+	gormDB := usePolicy(t, "corrupted")
+	st := newMemStorage()
+	a := New(st, nil, log.NewStdLogger(io.Discard))
+	ctx := context.Background()
+
+	insertEvents(t, gormDB, 1)
+	if _, err := a.Run(ctx, "corrupted"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	manifests, err := a.Manifests(ctx, "corrupted", time.Time{}, time.Time{})
+	if err != nil || len(manifests) != 1 {
+		t.Fatalf("Manifests() = %+v, %v, want one batch", manifests, err)
+	}
+	m := manifests[0]
+
+	if err := a.Read(ctx, &m, &[]Manifest{}); err == nil {
+		t.Error("Read() into a slice of another type did not fail")
+	}
+	if err := st.PutObject(ctx, m.DataKey, []byte("tampered")); err != nil {
+		t.Fatal(err)
+	}
+	var events []event
+	if err := a.Read(ctx, &m, &events); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Read() of a changed file error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestManifestsTimeRange(t *testing.T) {
+	gormDB := usePolicy(t, "range")
+	st := newMemStorage()
+	a := New(st, nil, log.NewStdLogger(io.Discard))
+	a.batchSize = 1
+	ctx := context.Background()
+
+	now := time.Now()
+	for id, age := range map[uint64]time.Duration{1: 5 * time.Hour, 2: 3 * time.Hour} {
+		if err := gormDB.Create(&event{ID: id, CreatedAt: now.Add(-age)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := a.Run(ctx, "range"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []uint64
+	}{
+		{name: "open", want: []uint64{1, 2}},
+		{name: "from", from: now.Add(-4 * time.Hour), want: []uint64{2}},
+		{name: "to", to: now.Add(-4 * time.Hour), want: []uint64{1}},
+		{name: "to is exclusive", to: now.Add(-5 * time.Hour), want: []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifests, err := a.Manifests(ctx, "range", tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Manifests() error = %v", err)
+			}
+			if got := readIDs(t, a, manifests); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batches hold %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := a.Manifests(ctx, "missing", time.Time{}, time.Time{}); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("Manifests() of an unknown policy error = %v, want ErrUnknownPolicy", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	gormDB := usePolicy(t, "restore")
+	st := newMemStorage()
+	a := New(st, nil, log.NewStdLogger(io.Discard))
+	a.batchSize = 2
+	ctx := context.Background()
+
+	insertEvents(t, gormDB, 1, 2, 3)
+	if _, err := a.Run(ctx, "restore"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// A row that exists again is left unchanged.
+	if err := gormDB.Create(&event{ID: 2, Name: "recreated", CreatedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := a.Restore(ctx, "restore", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Restore() = %d rows, want 3", n)
+	}
+	if got := eventIDs(t, gormDB); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Errorf("events = %v, want [1 2 3]", got)
+	}
+	var e event
+	if err := gormDB.First(&e, 2).Error; err != nil || e.Name != "recreated" {
+		t.Errorf("event 2 = %+v, %v, want the recreated row", e, err)
+	}
+
+	// Restored batches are removed from the archive, leaving only the empty index.
+	if manifests, err := a.Manifests(ctx, "restore", time.Time{}, time.Time{}); err != nil || len(manifests) != 0 {
+		t.Errorf("Manifests() after restore = %+v, %v, want none", manifests, err)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for key := range st.objects {
+		if key != a.indexKey("restore") {
+			t.Errorf("object %s left after restore", key)
+		}
+	}
+}
+
+func TestUpdateIndexConcurrent(t *testing.T) {
+	usePolicy(t, "concurrent")
+	st := newMemStorage()
+	p := lookupPolicy("concurrent")
+	ctx := context.Background()
+
+	// Archivers of separate commands share the storage; no update may be lost.
+	const updates = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		a := New(st, nil, log.NewStdLogger(io.Discard))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- a.updateIndex(ctx, p, func(idx *index) {
+				idx.Manifests = append(idx.Manifests, Manifest{ID: fmt.Sprint(i)})
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("updateIndex() error = %v", err)
+		}
+	}
+
+	idx, err := New(st, nil, log.NewStdLogger(io.Discard)).loadIndex(ctx, "concurrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != updates {
+		t.Errorf("index has %d manifests, want %d", len(idx.Manifests), updates)
+	}
+}
+
+func TestLookupKeyRange(t *testing.T) {
+	gormDB := usePolicy(t, "lookup")
+	st := newMemStorage()
+	a := New(st, nil, log.NewStdLogger(io.Discard))
+	a.batchSize = 3
+	ctx := context.Background()
+
+	// The batches hold the keys 2-10, 11-100 and 101. Keys compare as numbers, so 9 is
+	// in the first batch.
+	insertEvents(t, gormDB, 2, 9, 10, 11, 12, 100, 101)
+	if _, err := a.Run(ctx, "lookup"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	tests := []struct {
+		key   string
+		found bool
+		gets  int
+	}{
+		{key: "11", found: true, gets: 1},
+		{key: "9", found: true, gets: 1},
+		{key: "101", found: true, gets: 1},
+		{key: "50", found: false, gets: 1},
+		{key: "1", found: false, gets: 0},
+		{key: "1000", found: false, gets: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			before := st.manifestGets()
+			var got event
+			found, err := a.Lookup(ctx, "lookup", tt.key, &got)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if found != tt.found {
+				t.Fatalf("Lookup() found = %v, want %v", found, tt.found)
+			}
+			if found && fmt.Sprint(got.ID) != tt.key {
+				t.Errorf("Lookup() = event %d, want %s", got.ID, tt.key)
+			}
+			if gets := st.manifestGets() - before; gets != tt.gets {
+				t.Errorf("Lookup() downloaded %d manifests, want %d", gets, tt.gets)
+			}
+		})
+	}
+}
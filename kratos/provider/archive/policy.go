@@ -0,0 +1,96 @@
+// Package archive moves cold rows out of the database into object storage.
+// A Policy selects the rows of a GORM model older than a cutoff; the Archiver writes them
+// in batches to gzip compressed JSON lines files with a manifest per batch, then deletes
+// them from the database in the same transaction. Archived rows can be browsed, looked
+// up by primary key and restored.
+//
+// Object layout under the configured prefix:
+//
+//	<prefix>/<policy>/index.json                 manifests of the policy, oldest first, without keys
+//	<prefix>/<policy>/manifests/<batch>.json     manifest of a batch
+//	<prefix>/<policy>/data/<batch>.jsonl.gz      rows of a batch
+package archive
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"kratos-project-template/provider/db"
+
+	"gorm.io/gorm"
+)
+
+// defaultColumn is the default time column of a policy.
+const defaultColumn = "created_at"
+
+// Policy selects the rows of a model to archive.
+type Policy struct {
+	// Name identifies the policy in configuration, commands and object keys
+	Name string
+	// Model is a pointer to the GORM model, e.g. &models.Order{}; it must have a
+	// single-column primary key
+	Model interface{}
+	// Column is the time column compared with the cutoff (default "created_at")
+	Column string
+	// OlderThan is the age of archived rows; data.archive.older_than overrides it
+	OlderThan time.Duration
+	// Connection is the database connection of the model (default "default")
+	Connection string
+	// Scopes narrow the archived rows, e.g. to completed orders
+	Scopes []func(*gorm.DB) *gorm.DB
+}
+
+var (
+	// policies holds the registered policies by name
+	policies = map[string]*Policy{}
+	// policiesMu protects policies
+	policiesMu sync.RWMutex
+)
+
+// Register adds an archival policy. Call it from an init function of the package
+// defining the model.
+//
+// Parameters:
+//   - p: The policy
+//
+// Panics:
+//   - If the name is empty or already registered, Model is nil or OlderThan is not positive
+func Register(p Policy) {
+	if p.Name == "" || p.Model == nil || p.OlderThan <= 0 {
+		panic(fmt.Sprintf("archive: invalid policy %q: name, model and older_than are required", p.Name))
+	}
+	if p.Column == "" {
+		p.Column = defaultColumn
+	}
+	if p.Connection == "" {
+		p.Connection = db.DefaultName
+	}
+
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	if _, ok := policies[p.Name]; ok {
+		panic(fmt.Sprintf("archive: policy %q registered twice", p.Name))
+	}
+	policies[p.Name] = &p
+}
+
+// Policies returns the names of the registered policies in sorted order.
+func Policies() []string {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupPolicy returns the registered policy, or nil.
+func lookupPolicy(name string) *Policy {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	return policies[name]
+}
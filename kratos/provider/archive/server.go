@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kratos-project-template/provider/election"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Server = (*Server)(nil)
+
+// defaultInterval is the default time between archival runs.
+const defaultInterval = time.Hour
+
+// Server runs the archiver periodically.
+// It implements transport.Server so it starts and stops with the kratos application.
+// Only the leader of the elector archives, so runs never overlap across processes.
+type Server struct {
+	archiver *Archiver
+	elector  *election.Elector
+	interval time.Duration
+	log      *log.Helper
+
+	// lifecycle protects cancel and done, which are set by Start and read by Stop
+	lifecycle sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewServer creates an archival server.
+//
+// Parameters:
+//   - archiver: The archiver to run
+//   - elector: Elects the archiving process; nil archives in every process
+//   - interval: Time between runs (default 1h)
+//   - logger: Logger instance for server logging
+//
+// Returns:
+//   - *Server: A new server
+func NewServer(archiver *Archiver, elector *election.Elector, interval time.Duration, logger log.Logger) *Server {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Server{
+		archiver: archiver,
+		elector:  elector,
+		interval: interval,
+		log:      log.NewHelper(log.With(logger, "module", "archive")),
+	}
+}
+
+// Start runs the archiver until Stop is called.
+func (s *Server) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer close(done)
+
+	s.lifecycle.Lock()
+	s.cancel = cancel
+	s.done = done
+	s.lifecycle.Unlock()
+
+	s.log.Infof("[archive] server started: interval=%v, policies=%v", s.interval, Policies())
+	if s.elector == nil {
+		s.run(ctx)
+	} else {
+		err := s.elector.RunWhenLeader(ctx, func(ctx context.Context) error {
+			s.run(ctx)
+			return nil
+		})
+		// A nil error means the elector stopped before the application.
+		if err == nil {
+			<-ctx.Done()
+		}
+	}
+
+	s.log.Infof("[archive] server stopped")
+	return nil
+}
+
+// Stop signals the server to stop and waits for the current batch or ctx to expire.
+func (s *Server) Stop(ctx context.Context) error {
+	s.lifecycle.Lock()
+	cancel, done := s.cancel, s.done
+	s.lifecycle.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run archives every interval until ctx is done.
+func (s *Server) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.archiver.Run(ctx); err != nil && ctx.Err() == nil {
+			s.log.Warnf("[archive] run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
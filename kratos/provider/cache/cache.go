@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMiss is returned by Cache.Get when the key does not exist.
+var ErrMiss = errors.New("cache: key not found")
+
+// ErrUnavailable is returned by the cache from Get when Redis has not been initialized.
+var ErrUnavailable = errors.New("cache: redis is unavailable")
+
+// Cache is a key-value cache with expiration.
+// A ttl of 0 means the key does not expire.
+type Cache interface {
+	// Get returns the value of key, or ErrMiss if it does not exist.
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores value under key.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// SetNX stores value under key only if the key does not exist.
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	// Incr increments the integer value of key by one and returns the new value.
+	Incr(ctx context.Context, key string) (int64, error)
+	// Expire sets a ttl on key and reports whether the key exists; a ttl <= 0 deletes the key.
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Exists returns how many of the keys exist.
+	Exists(ctx context.Context, keys ...string) (int64, error)
+	// Delete removes keys and returns how many were removed.
+	Delete(ctx context.Context, keys ...string) (int64, error)
+}
+
+var (
+	_ Cache = (*RedisCache)(nil)
+	_ Cache = (*MemoryCache)(nil)
+	_ Cache = (*managedCache)(nil)
+)
+
+var (
+	// fallback is the in-memory cache used while Redis is down, nil if disabled
+	fallback *MemoryCache
+	// unavailableCache is returned by Get before InitRedis
+	unavailableCache = &managedCache{}
+	// gCache is the global managed cache
+	gCache     *managedCache
+	gCacheOnce sync.Once
+)
+
+// Get returns the global cache.
+// Calls go to Redis while it is up. While it is down they go to the in-memory fallback
+// if data.redis.fallback_memory is enabled, otherwise they return the Redis error.
+// Values written to the fallback are local to the process and are not copied to Redis
+// when it recovers.
+//
+// Returns:
+//   - Cache: The global cache; never nil
+func Get() Cache {
+	if getManaged() == nil {
+		return unavailableCache
+	}
+	gCacheOnce.Do(func() {
+		gCache = &managedCache{redis: NewRedisCache(GetRedisClient()), memory: fallback}
+	})
+	return gCache
+}
+
+// managedCache routes calls to Redis or the in-memory fallback based on the Redis state.
+type managedCache struct {
+	redis  *RedisCache
+	memory *MemoryCache
+}
+
+// pick returns the cache to use for the next call.
+func (c *managedCache) pick() (Cache, error) {
+	if c.redis != nil && (IsAvailable() || c.memory == nil) {
+		return c.redis, nil
+	}
+	if c.memory != nil {
+		return c.memory, nil
+	}
+	return nil, ErrUnavailable
+}
+
+func (c *managedCache) Get(ctx context.Context, key string) (string, error) {
+	cc, err := c.pick()
+	if err != nil {
+		return "", err
+	}
+	return cc.Get(ctx, key)
+}
+
+func (c *managedCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	cc, err := c.pick()
+	if err != nil {
+		return err
+	}
+	return cc.Set(ctx, key, value, ttl)
+}
+
+func (c *managedCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	cc, err := c.pick()
+	if err != nil {
+		return false, err
+	}
+	return cc.SetNX(ctx, key, value, ttl)
+}
+
+func (c *managedCache) Incr(ctx context.Context, key string) (int64, error) {
+	cc, err := c.pick()
+	if err != nil {
+		return 0, err
+	}
+	return cc.Incr(ctx, key)
+}
+
+func (c *managedCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	cc, err := c.pick()
+	if err != nil {
+		return false, err
+	}
+	return cc.Expire(ctx, key, ttl)
+}
+
+func (c *managedCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	cc, err := c.pick()
+	if err != nil {
+		return 0, err
+	}
+	return cc.Exists(ctx, keys...)
+}
+
+func (c *managedCache) Delete(ctx context.Context, keys ...string) (int64, error) {
+	cc, err := c.pick()
+	if err != nil {
+		return 0, err
+	}
+	return cc.Delete(ctx, keys...)
+}
+
+// RedisCache implements Cache on a Redis client.
+type RedisCache struct {
+	rdb redis.UniversalClient
+}
+
+// NewRedisCache creates a Redis-backed cache.
+//
+// Parameters:
+//   - rdb: The Redis client
+//
+// Returns:
+//   - *RedisCache: A new cache
+func NewRedisCache(rdb redis.UniversalClient) *RedisCache {
+	return &RedisCache{rdb: rdb}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	val, err := c.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+	return val, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (c *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.rdb.Incr(ctx, key).Result()
+}
+
+func (c *RedisCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.rdb.Expire(ctx, key, ttl).Result()
+}
+
+func (c *RedisCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return c.rdb.Exists(ctx, keys...).Result()
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) (int64, error) {
+	return c.rdb.Del(ctx, keys...).Result()
+}
@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultMemoryMaxEntries = 10000
+
+// memoryEntry is a value stored by MemoryCache.
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// expired reports whether the entry has expired at now.
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache implements Cache in process memory.
+// Values are stored as strings using the same formatting as the Redis client, so
+// callers see the same results from both implementations.
+type MemoryCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryCache creates an in-memory cache.
+//
+// Parameters:
+//   - maxEntries: Maximum number of keys; when full, expired keys and then arbitrary
+//     keys are evicted (0 uses the default of 10000)
+//
+// Returns:
+//   - *MemoryCache: A new cache
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryMaxEntries
+	}
+	return &MemoryCache{maxEntries: maxEntries, entries: make(map[string]memoryEntry)}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.lookup(key, time.Now())
+	if !ok {
+		return "", ErrMiss
+	}
+	return e.value, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, formatValue(value), ttl, time.Now())
+	return nil
+}
+
+func (c *MemoryCache) SetNX(_ context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.lookup(key, now); ok {
+		return false, nil
+	}
+	c.store(key, formatValue(value), ttl, now)
+	return true, nil
+}
+
+func (c *MemoryCache) Incr(_ context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	e, ok := c.lookup(key, now)
+	var n int64
+	if ok {
+		var err error
+		if n, err = strconv.ParseInt(e.value, 10, 64); err != nil {
+			return 0, errors.New("ERR value is not an integer or out of range")
+		}
+	}
+	n++
+	e.value = strconv.FormatInt(n, 10)
+	if !ok {
+		c.evict(now)
+	}
+	c.entries[key] = e
+	return n, nil
+}
+
+func (c *MemoryCache) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.lookup(key, time.Now())
+	if !ok {
+		return false, nil
+	}
+	if ttl <= 0 {
+		delete(c.entries, key)
+		return true, nil
+	}
+	e.expiresAt = time.Now().Add(ttl)
+	c.entries[key] = e
+	return true, nil
+}
+
+func (c *MemoryCache) Exists(_ context.Context, keys ...string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var n int64
+	for _, key := range keys {
+		if _, ok := c.lookup(key, now); ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, keys ...string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var n int64
+	for _, key := range keys {
+		if _, ok := c.lookup(key, now); ok {
+			delete(c.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// lookup returns a live entry, dropping it if it has expired. c.mu must be held.
+func (c *MemoryCache) lookup(key string, now time.Time) (memoryEntry, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if e.expired(now) {
+		delete(c.entries, key)
+		return memoryEntry{}, false
+	}
+	return e, true
+}
+
+// store writes an entry, evicting others if the cache is full. c.mu must be held.
+func (c *MemoryCache) store(key, value string, ttl time.Duration, now time.Time) {
+	if _, ok := c.entries[key]; !ok {
+		c.evict(now)
+	}
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	c.entries[key] = e
+}
+
+// evict makes room for one new key. c.mu must be held.
+func (c *MemoryCache) evict(now time.Time) {
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	for key, e := range c.entries {
+		if e.expired(now) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			return
+		}
+		delete(c.entries, key)
+	}
+}
+
+// formatValue converts a value to its string form the way the Redis client does.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return strconv.FormatInt(v.Nanoseconds(), 10)
+	case encoding.BinaryMarshaler:
+		if b, err := v.MarshalBinary(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
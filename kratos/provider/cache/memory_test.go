@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(0)
+
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get() of a missing key error = %v, want ErrMiss", err)
+	}
+	if err := c.Set(ctx, "k", 42, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Get(ctx, "k"); err != nil || got != "42" {
+		t.Errorf("Get() = %q, %v, want 42", got, err)
+	}
+
+	if ok, _ := c.SetNX(ctx, "k", "other", 0); ok {
+		t.Error("SetNX() of an existing key succeeded")
+	}
+	if ok, _ := c.SetNX(ctx, "nx", "v", 0); !ok {
+		t.Error("SetNX() of a new key failed")
+	}
+
+	for want := int64(43); want <= 44; want++ {
+		if got, err := c.Incr(ctx, "k"); err != nil || got != want {
+			t.Errorf("Incr() = %d, %v, want %d", got, err, want)
+		}
+	}
+	if got, err := c.Incr(ctx, "counter"); err != nil || got != 1 {
+		t.Errorf("Incr() of a new key = %d, %v, want 1", got, err)
+	}
+	if _, err := c.Incr(ctx, "nx"); err == nil {
+		t.Error("Incr() of a non-integer value did not fail")
+	}
+
+	if n, _ := c.Exists(ctx, "k", "nx", "missing"); n != 2 {
+		t.Errorf("Exists() = %d, want 2", n)
+	}
+	if n, _ := c.Delete(ctx, "k", "missing"); n != 1 {
+		t.Errorf("Delete() = %d, want 1", n)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get() of a deleted key error = %v, want ErrMiss", err)
+	}
+}
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(0)
+
+	if err := c.Set(ctx, "short", "v", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "long", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.Expire(ctx, "long", 20*time.Millisecond); !ok {
+		t.Error("Expire() of an existing key returned false")
+	}
+	if ok, _ := c.Expire(ctx, "missing", time.Second); ok {
+		t.Error("Expire() of a missing key returned true")
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	for _, key := range []string{"short", "long"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, ErrMiss) {
+			t.Errorf("Get(%q) after expiry error = %v, want ErrMiss", key, err)
+		}
+	}
+	// An expired key can be set again with SetNX.
+	if ok, _ := c.SetNX(ctx, "short", "again", 0); !ok {
+		t.Error("SetNX() of an expired key failed")
+	}
+
+	// A non-positive ttl deletes the key, like Redis.
+	if ok, _ := c.Expire(ctx, "short", 0); !ok {
+		t.Error("Expire() with ttl 0 returned false")
+	}
+	if n, _ := c.Exists(ctx, "short"); n != 0 {
+		t.Error("Expire() with ttl 0 did not delete the key")
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+
+	if err := c.Set(ctx, "expired", "v", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "a", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Expired keys are evicted first.
+	if err := c.Set(ctx, "b", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Exists(ctx, "a", "b"); n != 2 {
+		t.Errorf("Exists(a, b) = %d, want 2", n)
+	}
+
+	// Without expired keys an arbitrary key makes room.
+	if err := c.Set(ctx, "c", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Exists(ctx, "a", "b", "c"); n != 2 {
+		t.Errorf("Exists(a, b, c) = %d, want 2", n)
+	}
+	// Overwriting a key does not evict another.
+	if err := c.Set(ctx, "c", "w", 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Exists(ctx, "a", "b", "c"); n != 2 {
+		t.Errorf("Exists(a, b, c) after overwrite = %d, want 2", n)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: nil, want: ""},
+		{value: "s", want: "s"},
+		{value: []byte("b"), want: "b"},
+		{value: -7, want: "-7"},
+		{value: uint8(7), want: "7"},
+		{value: 1.5, want: "1.5"},
+		{value: float32(0.25), want: "0.25"},
+		{value: true, want: "1"},
+		{value: false, want: "0"},
+		{value: at, want: "2024-01-02T03:04:05Z"},
+		{value: time.Second, want: "1000000000"},
+		{value: struct{ A int }{1}, want: "{1}"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.value); got != tt.want {
+			t.Errorf("formatValue(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 // Package cache provides Redis cache client initialization and management.
+// The Redis client is created even if Redis is unreachable at startup; a background
+// monitor pings it, tracks the connection state and logs when it goes down or recovers,
+// so dependent components start working as soon as Redis comes back.
 package cache
 
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"kratos-project-template/internal/conf"
 
@@ -12,66 +17,268 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	defaultDialTimeout         = 5 * time.Second
+	defaultHealthCheckInterval = 5 * time.Second
+	pingTimeout                = 2 * time.Second
+)
+
+// State is the connection state of the managed Redis client.
+type State int32
+
+const (
+	// StateNotConfigured means InitRedis has not been called.
+	StateNotConfigured State = iota
+	// StateConnecting means the first ping has not completed yet.
+	StateConnecting
+	// StateUp means the last ping succeeded.
+	StateUp
+	// StateDown means the last ping failed; the client keeps reconnecting.
+	StateDown
+)
+
+// String returns the state name used in logs and health checks.
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateUp:
+		return "up"
+	case StateDown:
+		return "down"
+	default:
+		return "not_configured"
+	}
+}
+
+// Status is a snapshot of the managed Redis client health.
+type Status struct {
+	// State is the current connection state
+	State State
+	// Err is the error of the last failed ping, nil when up
+	Err error
+	// Latency is the round trip of the last ping
+	Latency time.Duration
+	// Since is when the current state was entered
+	Since time.Time
+}
+
+// managedRedis owns the Redis client and its health monitor.
+type managedRedis struct {
+	client   *redis.Client
+	interval time.Duration
+	log      *log.Helper
+
+	state atomic.Int32
+
+	mu        sync.RWMutex
+	status    Status
+	listeners []func(State)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
 var (
 	// redisc is the global Redis client instance
 	redisc *redis.Client
-	// initRedisOnce ensures Redis is initialized only once (thread-safe)
-	initRedisOnce sync.Once
+	// managed is the global managed Redis instance
+	managed *managedRedis
+	// managedMu protects redisc and managed
+	managedMu sync.RWMutex
 )
 
-// InitRedis initializes the Redis client connection.
-// It uses sync.Once to ensure the client is initialized only once, even if called multiple times.
+// InitRedis initializes the Redis client and starts its health monitor.
+// The client is kept even if the initial ping fails, so GetRedisClient never returns
+// nil after InitRedis; calling InitRedis again is a no-op.
 //
 // Parameters:
-//   - ctx: Context for the initialization operation (used for ping)
-//   - cfg: Redis configuration containing address, password, database, and pool settings
-//   - logger: Logger instance for logging initialization messages
+//   - ctx: Context for the initial ping
+//   - cfg: Redis configuration containing address, password, database, pool and health settings
+//   - logger: Logger instance for logging connection state changes
 //
 // Returns:
-//   - error: Error if initialization or connection test fails
+//   - error: Error if the configuration is nil or the initial ping fails; in the latter
+//     case the client keeps reconnecting in the background
 func InitRedis(ctx context.Context, cfg *conf.Data_Redis, logger log.Logger) error {
 	if cfg == nil {
 		return errors.New("redis config cannot be nil")
 	}
 
-	var initErr error
-	initRedisOnce.Do(func() {
-		redisc = redis.NewClient(&redis.Options{
-			Addr:         cfg.Addr,
-			Password:     cfg.Password,
-			DB:           int(cfg.Db),
-			PoolSize:     int(cfg.PoolSize),
-			ReadTimeout:  cfg.ReadTimeout.AsDuration(),
-			WriteTimeout: cfg.WriteTimeout.AsDuration(),
-		})
-
-		// Test connection
-		_, err := redisc.Ping(ctx).Result()
-		if err != nil {
-			initErr = errors.Wrap(err, "redis ping error")
-			redisc = nil // Reset client on error
-			return
-		}
-	})
+	managedMu.Lock()
+	if managed != nil {
+		managedMu.Unlock()
+		return nil
+	}
 
-	if initErr != nil {
-		return initErr
+	dialTimeout := defaultDialTimeout
+	if cfg.GetDialTimeout() != nil {
+		dialTimeout = cfg.GetDialTimeout().AsDuration()
+	}
+	interval := defaultHealthCheckInterval
+	if cfg.GetHealthCheckInterval() != nil {
+		interval = cfg.GetHealthCheckInterval().AsDuration()
 	}
 
-	if redisc == nil {
-		return errors.New("redis client is nil after initialization")
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           int(cfg.Db),
+		PoolSize:     int(cfg.PoolSize),
+		DialTimeout:  dialTimeout,
+		ReadTimeout:  cfg.ReadTimeout.AsDuration(),
+		WriteTimeout: cfg.WriteTimeout.AsDuration(),
+	})
+
+	m := &managedRedis{
+		client:   client,
+		interval: interval,
+		log:      log.NewHelper(log.With(logger, "module", "cache")),
+		status:   Status{State: StateConnecting, Since: time.Now()},
 	}
+	m.state.Store(int32(StateConnecting))
+	if cfg.GetFallbackMemory() {
+		fallback = NewMemoryCache(int(cfg.GetFallbackMaxEntries()))
+	}
+
+	monitorCtx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
 
+	redisc = client
+	managed = m
+	managedMu.Unlock()
+
+	// Test connection
+	err := m.check(ctx)
+	go m.monitor(monitorCtx)
+
+	if err != nil {
+		return errors.Wrap(err, "redis ping error")
+	}
 	return nil
 }
 
 // GetRedisClient returns the global Redis client instance.
+// After InitRedis the client is never nil, but commands fail while Redis is down;
+// use IsAvailable or Get when a degraded mode is needed.
 //
 // Returns:
-//   - *redis.Client: The Redis client instance, or nil if not initialized
-//
-// Note: The client should be initialized using InitRedis before calling this function.
+//   - *redis.Client: The Redis client instance, or nil if InitRedis has not been called
 func GetRedisClient() *redis.Client {
+	managedMu.RLock()
+	defer managedMu.RUnlock()
 	return redisc
 }
 
+// GetStatus returns the health of the managed Redis client.
+//
+// Returns:
+//   - Status: The current status; State is StateNotConfigured before InitRedis
+func GetStatus() Status {
+	m := getManaged()
+	if m == nil {
+		return Status{State: StateNotConfigured}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// IsAvailable reports whether the last ping to Redis succeeded.
+func IsAvailable() bool {
+	m := getManaged()
+	return m != nil && State(m.state.Load()) == StateUp
+}
+
+// OnStateChange registers a callback invoked when Redis goes down or recovers.
+// Callbacks run on the monitor goroutine and should return quickly.
+func OnStateChange(fn func(State)) {
+	m := getManaged()
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Close stops the health monitor and closes the Redis client.
+//
+// Returns:
+//   - error: Error if closing the client fails
+func Close() error {
+	m := getManaged()
+	if m == nil {
+		return nil
+	}
+	m.cancel()
+	<-m.done
+	return m.client.Close()
+}
+
+// getManaged returns the managed Redis instance, or nil before InitRedis.
+func getManaged() *managedRedis {
+	managedMu.RLock()
+	defer managedMu.RUnlock()
+	return managed
+}
+
+// monitor pings Redis until ctx is cancelled.
+func (m *managedRedis) monitor(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = m.check(ctx)
+		}
+	}
+}
+
+// check pings Redis once and records the result.
+func (m *managedRedis) check(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	start := time.Now()
+	err := m.client.Ping(pingCtx).Err()
+	latency := time.Since(start)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	next := StateUp
+	if err != nil {
+		next = StateDown
+	}
+
+	m.mu.Lock()
+	prev := m.status.State
+	m.status.Err = err
+	m.status.Latency = latency
+	if prev != next {
+		m.status.State = next
+		m.status.Since = time.Now()
+	}
+	listeners := append([]func(State){}, m.listeners...)
+	m.mu.Unlock()
+	m.state.Store(int32(next))
+
+	if prev == next {
+		return err
+	}
+	switch {
+	case next == StateDown:
+		m.log.Warnf("redis unavailable, reconnecting in background: %v", err)
+	case prev == StateDown:
+		m.log.Infof("redis connection recovered")
+	}
+	for _, fn := range listeners {
+		fn(next)
+	}
+	return err
+}
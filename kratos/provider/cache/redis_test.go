@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// errRefused is returned by fakeRedis while it is down.
+var errRefused = errors.New("dial tcp: connection refused")
+
+// fakeRedis answers PING, GET and SET in place of a Redis server. GET returns "redis".
+type fakeRedis struct {
+	down atomic.Bool
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if f.down.Load() {
+			cmd.SetErr(errRefused)
+			return errRefused
+		}
+		switch c := cmd.(type) {
+		case *redis.StatusCmd:
+			c.SetVal("OK")
+		case *redis.StringCmd:
+			c.SetVal("redis")
+		}
+		return nil
+	}
+}
+
+// useRedis installs a managed Redis client answered by a fakeRedis, with or without the
+// in-memory fallback, until the test ends.
+func useRedis(t *testing.T, withFallback bool) (*managedRedis, *fakeRedis) {
+	t.Helper()
+	f := &fakeRedis{}
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	client.AddHook(f)
+	m := &managedRedis{
+		client:   client,
+		interval: time.Hour,
+		log:      log.NewHelper(log.NewStdLogger(io.Discard)),
+		status:   Status{State: StateConnecting, Since: time.Now()},
+	}
+	m.state.Store(int32(StateConnecting))
+
+	managedMu.Lock()
+	redisc, managed = client, m
+	managedMu.Unlock()
+	if withFallback {
+		fallback = NewMemoryCache(0)
+	}
+	t.Cleanup(func() {
+		managedMu.Lock()
+		redisc, managed = nil, nil
+		managedMu.Unlock()
+		fallback, gCache, gCacheOnce = nil, nil, sync.Once{}
+		_ = client.Close()
+	})
+	return m, f
+}
+
+func TestManagedRedisState(t *testing.T) {
+	m, f := useRedis(t, false)
+	var changes []State
+	OnStateChange(func(s State) { changes = append(changes, s) })
+	ctx := context.Background()
+
+	if got := GetStatus().State; got != StateConnecting {
+		t.Errorf("state before the first ping = %v, want connecting", got)
+	}
+
+	f.down.Store(true)
+	if err := m.check(ctx); err == nil {
+		t.Fatal("check() of a down Redis did not fail")
+	}
+	if st := GetStatus(); st.State != StateDown || st.Err == nil || IsAvailable() {
+		t.Errorf("status = %+v, available %v, want down with the ping error", st, IsAvailable())
+	}
+	since := GetStatus().Since
+
+	// Failed pings while down do not notify again.
+	_ = m.check(ctx)
+	if GetStatus().Since != since {
+		t.Error("Since changed without a state change")
+	}
+
+	f.down.Store(false)
+	if err := m.check(ctx); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if st := GetStatus(); st.State != StateUp || st.Err != nil || !IsAvailable() {
+		t.Errorf("status = %+v, available %v, want up", st, IsAvailable())
+	}
+	if len(changes) != 2 || changes[0] != StateDown || changes[1] != StateUp {
+		t.Errorf("state changes = %v, want [down up]", changes)
+	}
+}
+
+func TestGetRoutesByState(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("with fallback", func(t *testing.T) {
+		m, f := useRedis(t, true)
+		f.down.Store(true)
+		_ = m.check(ctx)
+
+		// While Redis is down the fallback serves the calls.
+		if err := Get().Set(ctx, "k", "memory", 0); err != nil {
+			t.Fatalf("Set() while down error = %v", err)
+		}
+		if got, err := Get().Get(ctx, "k"); err != nil || got != "memory" {
+			t.Errorf("Get() while down = %q, %v, want memory", got, err)
+		}
+
+		f.down.Store(false)
+		_ = m.check(ctx)
+		if got, err := Get().Get(ctx, "k"); err != nil || got != "redis" {
+			t.Errorf("Get() after recovery = %q, %v, want redis", got, err)
+		}
+	})
+
+	t.Run("without fallback", func(t *testing.T) {
+		m, f := useRedis(t, false)
+		f.down.Store(true)
+		_ = m.check(ctx)
+
+		if _, err := Get().Get(ctx, "k"); !errors.Is(err, errRefused) {
+			t.Errorf("Get() while down error = %v, want the Redis error", err)
+		}
+	})
+}
+
+func TestGetBeforeInit(t *testing.T) {
+	if GetRedisClient() != nil {
+		t.Skip("redis is initialized")
+	}
+	if st := GetStatus(); st.State != StateNotConfigured {
+		t.Errorf("state = %v, want not_configured", st.State)
+	}
+	if _, err := Get().Get(context.Background(), "k"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Get() error = %v, want ErrUnavailable", err)
+	}
+	if err := Get().Set(context.Background(), "k", "v", 0); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Set() error = %v, want ErrUnavailable", err)
+	}
+}
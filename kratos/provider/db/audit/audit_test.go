@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"kratos-project-template/provider/db/driver"
+	_ "kratos-project-template/provider/db/sqlite3"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("audit_test_secret", secretSerializer{})
+}
+
+// secretSerializer stores values as they are and marks them as sensitive.
+type secretSerializer struct{}
+
+func (secretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var s string
+	switch v := dbValue.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	}
+	return field.Set(ctx, dst, s)
+}
+
+func (secretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	return fieldValue, nil
+}
+
+func (secretSerializer) Sensitive() bool {
+	return true
+}
+
+// user is an audited model.
+type user struct {
+	ID       uint64 `gorm:"primaryKey"`
+	Name     string
+	Password string
+	SSN      string `gorm:"serializer:audit_test_secret"`
+}
+
+func (user) AuditExclude() []string {
+	return []string{"password"}
+}
+
+// note is a model that is not audited.
+type note struct {
+	ID   uint64 `gorm:"primaryKey"`
+	Text string
+}
+
+// openDB opens an in-memory SQLite database with the audit plugin.
+func openDB(t *testing.T, opts ...Option) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:audit_%s?mode=memory&cache=shared", t.Name()), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := gormDB.AutoMigrate(&user{}, &note{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Use(New(append([]Option{WithAutoMigrate(true)}, opts...)...)); err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+// entries returns the recorded entries, oldest first.
+func entries(t *testing.T, gormDB *gorm.DB) []Entry {
+	t.Helper()
+	var out []Entry
+	if err := gormDB.Order("id").Find(&out).Error; err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestPlugin(t *testing.T) {
+	gormDB := openDB(t)
+	ctx := ContextWithActor(context.Background(), "alice")
+	db := gormDB.WithContext(ctx)
+
+	u := user{ID: 1, Name: "bob", Password: "hash", SSN: "123"}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&u).Update("name", "robert").Error; err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged values and excluded columns are not recorded.
+	if err := db.Model(&u).Updates(user{Name: "robert", Password: "new hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&u).Update("ssn", "456").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&note{ID: 1, Text: "not audited"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{Table: "users", PrimaryKey: "1", Action: ActionCreate, Actor: "alice", After: `{"id":1,"name":"bob","ssn":"[redacted]"}`},
+		{Table: "users", PrimaryKey: "1", Action: ActionUpdate, Actor: "alice", Before: `{"name":"bob"}`, After: `{"name":"robert"}`},
+		{Table: "users", PrimaryKey: "1", Action: ActionUpdate, Actor: "alice", Before: `{"ssn":"[redacted]"}`, After: `{"ssn":"[redacted]"}`},
+		{Table: "users", PrimaryKey: "1", Action: ActionDelete, Actor: "alice", Before: `{"id":1,"name":"robert","ssn":"[redacted]"}`},
+	}
+	got := entries(t, gormDB)
+	if len(got) != len(want) {
+		t.Fatalf("recorded %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g := got[i]
+		g.ID, g.CreatedAt = 0, want[i].CreatedAt
+		if g != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, g, want[i])
+		}
+	}
+}
+
+func TestPluginBatchUpdate(t *testing.T) {
+	gormDB := openDB(t)
+	for _, u := range []user{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}} {
+		if err := gormDB.Create(&u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gormDB.Model(&user{}).Where("id IN ?", []int{1, 2}).Update("name", "z").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, e := range entries(t, gormDB) {
+		if e.Action == ActionUpdate {
+			keys = append(keys, e.PrimaryKey)
+			if e.Actor != defaultActor {
+				t.Errorf("actor = %q, want %q without an actor in the context", e.Actor, defaultActor)
+			}
+		}
+	}
+	if !reflect.DeepEqual(keys, []string{"1", "2"}) {
+		t.Errorf("updated rows recorded = %v, want [1 2]", keys)
+	}
+}
+
+func TestPluginRollback(t *testing.T) {
+	gormDB := openDB(t, ActorFunc(func(context.Context) string { return "service" }))
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user{ID: 1, Name: "a"}).Error; err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatal("Transaction() error = nil")
+	}
+	if got := entries(t, gormDB); len(got) != 0 {
+		t.Errorf("rolled back change left %d entries", len(got))
+	}
+
+	if err := gormDB.Create(&user{ID: 2, Name: "b"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := entries(t, gormDB); len(got) != 1 || got[0].Actor != "service" {
+		t.Errorf("entries = %+v, want one by the ActorFunc actor", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	gormDB := openDB(t)
+	for i, actor := range []string{"alice", "bob", "alice", "alice"} {
+		ctx := ContextWithActor(context.Background(), actor)
+		if err := gormDB.WithContext(ctx).Create(&user{ID: uint64(i + 1), Name: actor}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	page, err := History(ctx, gormDB, HistoryQuery{Actor: "alice", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].PrimaryKey != "4" || page[1].PrimaryKey != "3" {
+		t.Fatalf("first page = %+v, want rows 4 and 3", page)
+	}
+	page, err = History(ctx, gormDB, HistoryQuery{Actor: "alice", Limit: 2, BeforeID: page[1].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].PrimaryKey != "1" {
+		t.Errorf("second page = %+v, want row 1", page)
+	}
+
+	row, err := History(ctx, gormDB, HistoryQuery{Table: "users", PrimaryKey: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(row) != 1 || row[0].Actor != "bob" {
+		t.Errorf("history of row 2 = %+v, want the create by bob", row)
+	}
+}
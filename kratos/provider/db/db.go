@@ -4,123 +4,462 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"kratos-project-template/internal/conf"
-	"kratos-project-template/provider/db/mysql"
-	"kratos-project-template/provider/db/postgres"
-	"kratos-project-template/provider/db/sqlite3"
+	"kratos-project-template/provider/db/audit"
+	"kratos-project-template/provider/db/driver"
+	"kratos-project-template/provider/db/metrics"
+
+	// Built-in drivers register themselves with the driver registry.
+	_ "kratos-project-template/provider/db/mysql"
+	_ "kratos-project-template/provider/db/postgres"
+	_ "kratos-project-template/provider/db/sqlite3"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/pkg/errors"
 
+	"google.golang.org/protobuf/types/known/durationpb"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const (
+	defaultDriver          = "mysql"
+	defaultMaxIdleConns    = 10
+	defaultMaxOpenConns    = 100
+	defaultConnMaxLifetime = time.Hour
+	defaultMaxRetries      = 5
+	defaultRetryBackoff    = time.Second
+	defaultMaxBackoff      = 30 * time.Second
+	defaultConnectTimeout  = time.Minute
+	pingTimeout            = 2 * time.Second
+)
+
+// DefaultName is the name of the connection configured in data.database.
+const DefaultName = "default"
+
+// conn is an initialized named database connection.
+type conn struct {
+	name     string
+	db       *gorm.DB
+	driver   driver.Driver
+	replicas *replicaSet
+	monitor  *monitor
+	log      *log.Helper
+	// external is set for connections registered with Set, whose pool is not closed by Close
+	external bool
+}
+
 var (
-	// gdb is the global GORM database instance
-	gdb *gorm.DB
-	// initDBOnce ensures the database is initialized only once (thread-safe)
-	initDBOnce sync.Once
+	// conns holds the initialized connections by name
+	conns = map[string]*conn{}
+	// connOrder lists the connection names in initialization order, for Close
+	connOrder []string
+	// connsMu protects conns and connOrder
+	connsMu sync.RWMutex
+	// initMu serializes initialization so a name is never connected twice
+	initMu sync.Mutex
 )
 
-// Init initializes the database connection.
-// It uses sync.Once to ensure the database is initialized only once, even if called multiple times.
+// Init initializes the default database connection.
+// Calling it again after a successful initialization is a no-op.
+//
+// When replicas are configured, reads are routed to healthy replicas and writes and
+// transactions to the primary; see WithPrimary to force a read to the primary.
 //
-// Supported drivers:
-//   - "postgre": PostgreSQL database
-//   - "sqlite": SQLite database
-//   - Default: MySQL database
+// Failed connection attempts are retried with exponential backoff until max_retries is
+// exhausted or connect_timeout expires. Cancelling ctx (e.g. on SIGTERM) aborts the
+// retries immediately. A failed Init leaves nothing behind, so it can be called again.
+//
+// With degraded_start, a database that is still unreachable after the retries does not fail
+// Init: the connection is registered in StateDown and Get returns it, but queries fail until
+// the background health check sees the database come back; see GetStatus and OnConnect.
+//
+// The data source name is source, or is built by the driver from dsn with the password
+// taken inline or from password_env, password_file or password_command.
+//
+// The driver is looked up in the driver registry; built-in drivers are:
+//   - "mysql" (alias "mariadb"): MySQL database, also used when driver is empty
+//   - "postgres" (aliases "postgresql", "postgre", "pg"): PostgreSQL database
+//   - "sqlite" (alias "sqlite3"): SQLite database
+//
+// Other drivers can be added with driver.Register.
 //
 // Parameters:
-//   - ctx: Context for the initialization operation (currently unused but reserved for future use)
-//   - cfg: Database configuration containing driver type and connection source
+//   - ctx: Context bounding the connect phase
+//   - cfg: Database configuration containing driver, connection source or dsn, pool and retry settings
 //   - logKratos: Logger instance for database logging
 //
 // Returns:
-//   - error: Error if initialization or connection fails
+//   - error: Error if initialization or connection fails, or ctx is cancelled
 func Init(ctx context.Context, cfg *conf.Data_Database, logKratos log.Logger) error {
+	return InitNamed(ctx, DefaultName, cfg, logKratos)
+}
+
+// InitNamed initializes a named database connection, e.g. one of data.databases.
+// It behaves like Init; calling it again for an initialized name is a no-op.
+//
+// Parameters:
+//   - ctx: Context bounding the connect phase
+//   - name: The connection name used with Get
+//   - cfg: Database configuration of the connection
+//   - logKratos: Logger instance for database logging
+//
+// Returns:
+//   - error: Error if initialization or connection fails, or ctx is cancelled
+func InitNamed(ctx context.Context, name string, cfg *conf.Data_Database, logKratos log.Logger) error {
 	if cfg == nil {
-		return errors.New("database config cannot be nil")
-	}
-
-	var initErr error
-	gormLogger := NewGormLogger(logKratos, logger.Error)
-
-	initDBOnce.Do(func() {
-		// Retry connection with exponential backoff
-		maxRetries := 10
-		retryDelay := 2 * time.Second
-
-		for attempt := 0; attempt < maxRetries; attempt++ {
-			switch cfg.Driver {
-			case "postgre":
-				gdb, initErr = postgres.InitDB(cfg.Source, gormLogger)
-			case "sqlite":
-				gdb, initErr = sqlite3.InitDB(cfg.Source, gormLogger)
-			default:
-				// MySQL is the default driver
-				gdb, initErr = mysql.InitDB(cfg.Source, gormLogger)
+		return errors.Errorf("database %q config cannot be nil", name)
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	if lookup(name) != nil {
+		return nil
+	}
+
+	level, err := parseLogLevel(cfg.GetLogLevel())
+	if err != nil {
+		return errors.Wrapf(err, "database %q", name)
+	}
+	logKratos = log.With(logKratos, "module", "db", "database", name)
+	logHelper := log.NewHelper(logKratos)
+
+	// An unknown driver is a configuration error, not worth retrying.
+	d, err := lookupDriver(cfg.GetDriver())
+	if err != nil {
+		return errors.Wrapf(err, "database %q", name)
+	}
+	// The password is resolved once, so a failing secret source fails fast.
+	source, err := resolveSource(ctx, d, cfg)
+	if err != nil {
+		return errors.Wrapf(err, "database %q", name)
+	}
+
+	gormLogger := NewGormLogger(logKratos, level,
+		SlowThreshold(durationOr(cfg.GetSlowThreshold(), defaultSlowThreshold)),
+		LogParams(cfg.GetLogParams()),
+		SampleRate(sampleRateOr(cfg.GetLogSampleRate())),
+	)
+	state := StateUp
+	gormDB, connectErr := connect(ctx, d, source, cfg, gormLogger, logHelper)
+	if connectErr != nil {
+		// Only a database that stays unreachable is tolerated, not an aborted startup.
+		if !cfg.GetDegradedStart() || ctx.Err() != nil {
+			return errors.Wrapf(connectErr, "connect to db %q error", name)
+		}
+		if gormDB, err = openLazy(d, source, cfg, gormLogger); err != nil {
+			return errors.Wrapf(err, "open db %q error", name)
+		}
+		state = StateDown
+		logHelper.Warnf("database unavailable, starting degraded and reconnecting in background: %v", connectErr)
+	}
+	if a := cfg.GetAudit(); a.GetEnabled() {
+		migrateAudit := a.GetAutoMigrate() && state == StateUp
+		err = gormDB.WithContext(ctx).Use(audit.New(audit.WithTable(a.GetTable()), audit.WithAutoMigrate(migrateAudit)))
+		if err != nil {
+			if sqlDB, dbErr := gormDB.DB(); dbErr == nil {
+				_ = sqlDB.Close()
+			}
+			return errors.Wrapf(err, "database %q audit", name)
+		}
+	}
+	if cfg.GetMetrics() {
+		if err = gormDB.Use(metrics.New(name)); err != nil {
+			if sqlDB, dbErr := gormDB.DB(); dbErr == nil {
+				_ = sqlDB.Close()
 			}
+			return errors.Wrapf(err, "database %q metrics", name)
+		}
+	}
+	rs, err := setupReplicas(ctx, gormDB, d, cfg, logHelper)
+	if err != nil {
+		if sqlDB, dbErr := gormDB.DB(); dbErr == nil {
+			_ = sqlDB.Close()
+		}
+		return errors.Wrapf(err, "connect to db %q error", name)
+	}
+
+	// The audit table of a degraded connection is created once it is up.
+	var pending []ConnectHook
+	if a := cfg.GetAudit(); a.GetEnabled() && a.GetAutoMigrate() && state == StateDown {
+		table := a.GetTable()
+		if table == "" {
+			table = audit.DefaultTable
+		}
+		pending = append(pending, func(ctx context.Context, db *gorm.DB) error {
+			return errors.Wrap(db.Table(table).AutoMigrate(&audit.Entry{}), "migrate audit table")
+		})
+	}
+	interval := durationOr(cfg.GetHealthCheckInterval(), defaultHealthCheckInterval)
+	m := newMonitor(name, gormDB, state, connectErr, interval, logHelper, pending...)
+
+	connsMu.Lock()
+	conns[name] = &conn{name: name, db: gormDB, driver: d, replicas: rs, monitor: m, log: logHelper}
+	connOrder = append(connOrder, name)
+	connsMu.Unlock()
+	return nil
+}
+
+// Set registers an existing GORM database as a named connection, replacing the connection
+// of that name until restore is called. It is meant for tests (see internal/dbtest), which
+// register a transaction so code using Get, DB and Transaction runs in it; Transaction
+// then uses savepoints. The caller owns gormDB: it is not pinged in the background and
+// Close does not close it.
+//
+// Parameters:
+//   - name: The connection name used with Get
+//   - gormDB: The database or transaction to use
+//   - logKratos: Logger instance for database logging
+//
+// Returns:
+//   - func(): Restores the previous connection of that name, or removes it
+func Set(name string, gormDB *gorm.DB, logKratos log.Logger) (restore func()) {
+	logHelper := log.NewHelper(log.With(logKratos, "module", "db", "database", name))
+	c := &conn{name: name, db: gormDB, monitor: newExternalMonitor(name, gormDB, logHelper), log: logHelper, external: true}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	connsMu.Lock()
+	prev := conns[name]
+	conns[name] = c
+	if prev == nil {
+		connOrder = append(connOrder, name)
+	}
+	connsMu.Unlock()
 
-			if initErr == nil {
-				// Connection successful
+	return func() {
+		connsMu.Lock()
+		defer connsMu.Unlock()
+		if conns[name] != c {
+			return
+		}
+		if prev != nil {
+			conns[name] = prev
+			return
+		}
+		delete(conns, name)
+		for i, n := range connOrder {
+			if n == name {
+				connOrder = append(connOrder[:i:i], connOrder[i+1:]...)
 				break
 			}
+		}
+	}
+}
 
-			// Log retry attempt
-			logHelper := log.NewHelper(logKratos)
-			if attempt < maxRetries-1 {
-				logHelper.Warnf("database connection attempt %d/%d failed: %v, retrying in %v...",
-					attempt+1, maxRetries, initErr, retryDelay)
-				time.Sleep(retryDelay)
-				retryDelay *= 2 // Exponential backoff
-			}
+// connect opens the database, retrying with exponential backoff.
+func connect(ctx context.Context, d driver.Driver, source string, cfg *conf.Data_Database, gormLogger logger.Interface, logHelper *log.Helper) (*gorm.DB, error) {
+	connectTimeout := durationOr(cfg.GetConnectTimeout(), defaultConnectTimeout)
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	maxRetries := int(cfg.GetMaxRetries())
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	retryDelay := durationOr(cfg.GetRetryBackoff(), defaultRetryBackoff)
+	maxBackoff := durationOr(cfg.GetMaxBackoff(), defaultMaxBackoff)
+
+	for attempt := 0; ; attempt++ {
+		gormDB, err := open(ctx, d, source, cfg, gormLogger)
+		if err == nil {
+			return gormDB, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Wrapf(ctx.Err(), "connect aborted after %d attempt(s), last error: %v", attempt+1, err)
+		}
+		if attempt >= maxRetries {
+			return nil, err
 		}
-	})
 
-	if initErr != nil {
-		return errors.Wrap(initErr, "connect to db error")
+		// Log retry attempt
+		logHelper.Warnf("database connection attempt %d/%d failed: %v, retrying in %v...",
+			attempt+1, maxRetries+1, err, retryDelay)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "connect aborted after %d attempt(s), last error: %v", attempt+1, err)
+		case <-time.After(retryDelay):
+		}
+		retryDelay *= 2 // Exponential backoff
+		if retryDelay > maxBackoff {
+			retryDelay = maxBackoff
+		}
 	}
+}
 
-	if gdb == nil {
-		return errors.New("database instance is nil after initialization")
+// open makes one connection attempt, configures the pool and pings the database.
+func open(ctx context.Context, d driver.Driver, source string, cfg *conf.Data_Database, gormLogger logger.Interface) (*gorm.DB, error) {
+	gormDB, err := d.Open(source, gormLogger)
+	if err != nil {
+		return nil, err
 	}
 
 	// Configure connection pool settings
-	sqlDB, err := gdb.DB()
+	sqlDB, err := gormDB.DB()
 	if err != nil {
-		return errors.Wrap(err, "get sql db error")
+		return nil, errors.Wrap(err, "get sql db error")
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	configurePool(sqlDB, cfg)
 
 	// Test the connection
 	if err := sqlDB.PingContext(ctx); err != nil {
-		return errors.Wrap(err, "ping database error")
+		_ = sqlDB.Close()
+		return nil, errors.Wrap(err, "ping database error")
 	}
 
-	return nil
+	return gormDB, nil
+}
+
+// openLazy opens the database without pinging it, for a degraded start.
+func openLazy(d driver.Driver, source string, cfg *conf.Data_Database, gormLogger logger.Interface) (*gorm.DB, error) {
+	opener := d.OpenLazy
+	if opener == nil {
+		opener = d.Open
+	}
+	gormDB, err := opener(source, gormLogger)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "get sql db error")
+	}
+	configurePool(sqlDB, cfg)
+	return gormDB, nil
 }
 
-// Get returns the global GORM database instance.
+// lookupDriver returns the registered driver for name; an empty name selects MySQL.
+func lookupDriver(name string) (driver.Driver, error) {
+	if name == "" {
+		name = defaultDriver
+	}
+	return driver.Lookup(name)
+}
+
+// configurePool applies the pool settings of cfg to sqlDB.
+func configurePool(sqlDB *sql.DB, cfg *conf.Data_Database) {
+	maxIdleConns := int(cfg.GetMaxIdleConns())
+	if maxIdleConns <= 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+	maxOpenConns := int(cfg.GetMaxOpenConns())
+	if maxOpenConns <= 0 {
+		maxOpenConns = defaultMaxOpenConns
+	}
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetConnMaxLifetime(durationOr(cfg.GetConnMaxLifetime(), defaultConnMaxLifetime))
+	sqlDB.SetConnMaxIdleTime(cfg.GetConnMaxIdleTime().AsDuration())
+}
+
+// durationOr returns d as a time.Duration, or def if d is not set.
+func durationOr(d *durationpb.Duration, def time.Duration) time.Duration {
+	if d == nil {
+		return def
+	}
+	return d.AsDuration()
+}
+
+// Get returns an initialized database connection.
+//
+// Parameters:
+//   - name: Optional connection name; the default connection (data.database) if omitted
 //
 // Returns:
 //   - *gorm.DB: The GORM database instance
 //
 // Panics:
-//   - If the database has not been initialized (nil instance)
+//   - If the connection has not been initialized
 //
-// Note: The database should be initialized using Init before calling this function.
-func Get() *gorm.DB {
-	if gdb == nil {
-		panic("database is nil; please initialize it using db.Init()")
+// Note: The database should be initialized using Init or InitNamed before calling this function.
+func Get(name ...string) *gorm.DB {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
 	}
+	c := lookup(n)
+	if c == nil {
+		panic("database " + strconv.Quote(n) + " is nil; please initialize it using db.Init()")
+	}
+
+	return c.db
+}
 
-	return gdb
+// Names returns the names of the initialized connections in initialization order.
+func Names() []string {
+	connsMu.RLock()
+	defer connsMu.RUnlock()
+	return append([]string(nil), connOrder...)
 }
 
+// Close stops the health checks and closes all connections in reverse
+// initialization order.
+//
+// Returns:
+//   - error: The first error from closing a connection pool
+func Close() error {
+	connsMu.Lock()
+	order := connOrder
+	closing := conns
+	conns = map[string]*conn{}
+	connOrder = nil
+	connsMu.Unlock()
+
+	var firstErr error
+	for i := len(order) - 1; i >= 0; i-- {
+		c := closing[order[i]]
+		c.monitor.stop()
+		if c.replicas != nil {
+			c.replicas.close()
+		}
+		if c.external {
+			continue
+		}
+		sqlDB, err := c.db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "close database %q", c.name)
+		}
+	}
+	return firstErr
+}
+
+// lookup returns the named connection, or nil if it is not initialized.
+func lookup(name string) *conn {
+	connsMu.RLock()
+	defer connsMu.RUnlock()
+	return conns[name]
+}
+
+// sampleRateOr returns the log sample rate, or 1 (log all) if it is not in (0, 1].
+func sampleRateOr(rate float64) float64 {
+	if rate <= 0 || rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+// parseLogLevel converts a log_level setting to a GORM log level; empty means "error".
+func parseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent, nil
+	case "", "error":
+		return logger.Error, nil
+	case "warn":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	default:
+		return 0, errors.Errorf("unknown log level: %s", level)
+	}
+}
@@ -0,0 +1,114 @@
+// Package driver is the registry of database drivers used by the db package.
+// Each driver package registers itself from an init function under a canonical name and
+// optional aliases, so applications can add drivers such as SQL Server or ClickHouse
+// without editing the db package:
+//
+//	func init() {
+//		driver.Register("sqlserver", driver.Driver{
+//			Open: func(source string, l logger.Interface) (*gorm.DB, error) {
+//				return gorm.Open(sqlserver.Open(source), &gorm.Config{Logger: l, DisableAutomaticPing: true})
+//			},
+//		}, "mssql")
+//	}
+package driver
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Opener opens a GORM database from a data source name.
+// It should set gorm.Config.DisableAutomaticPing, since db.Init pings with its startup context.
+type Opener func(source string, logger logger.Interface) (*gorm.DB, error)
+
+// DialectorFunc returns a dialector for a data source name or an existing connection pool.
+type DialectorFunc func(source string, conn gorm.ConnPool) gorm.Dialector
+
+// Driver describes a registered database driver.
+type Driver struct {
+	// Name is the canonical driver name, set by Register
+	Name string
+	// Open opens the primary database
+	Open Opener
+	// OpenLazy opens the primary database without contacting the server, for a degraded
+	// start while the server is down; nil if Open does not contact the server either
+	OpenLazy Opener
+	// Dialector opens read replicas without contacting the server; nil if the driver
+	// does not support read replicas
+	Dialector DialectorFunc
+	// Retryable reports whether a transaction failed with a deadlock or serialization
+	// failure and can be retried; nil if such errors are never retried
+	Retryable func(err error) bool
+	// DSN builds a data source name from structured settings; nil if the driver only
+	// accepts data source names
+	DSN func(dsn DSN) (string, error)
+}
+
+var (
+	// drivers maps canonical names and aliases to drivers
+	drivers = map[string]*Driver{}
+	// driversMu protects drivers
+	driversMu sync.RWMutex
+)
+
+// Register adds a driver under a canonical name and optional aliases.
+// Names are case-insensitive. It panics if Open is nil or a name is already registered,
+// as registration happens from init functions.
+//
+// Parameters:
+//   - name: The canonical driver name, e.g., "mysql"
+//   - d: The driver implementation
+//   - aliases: Other names accepted in the configuration, e.g., "mariadb"
+func Register(name string, d Driver, aliases ...string) {
+	if d.Open == nil {
+		panic("driver: Register opener is nil for " + name)
+	}
+
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	d.Name = strings.ToLower(name)
+	for _, n := range append([]string{name}, aliases...) {
+		n = strings.ToLower(n)
+		if _, ok := drivers[n]; ok {
+			panic("driver: Register called twice for " + n)
+		}
+		drivers[n] = &d
+	}
+}
+
+// Lookup returns the driver registered under a name or alias.
+//
+// Parameters:
+//   - name: The driver name or alias (case-insensitive)
+//
+// Returns:
+//   - Driver: The registered driver; Name holds its canonical name
+//   - error: Error listing the registered drivers if the name is unknown
+func Lookup(name string) (Driver, error) {
+	driversMu.RLock()
+	d, ok := drivers[strings.ToLower(name)]
+	driversMu.RUnlock()
+	if !ok {
+		return Driver{}, errors.Errorf("unknown database driver %q (registered: %s)", name, strings.Join(Names(), ", "))
+	}
+	return *d, nil
+}
+
+// Names returns the sorted names and aliases of all registered drivers.
+func Names() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for n := range drivers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
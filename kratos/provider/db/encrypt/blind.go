@@ -0,0 +1,41 @@
+package encrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// BlindIndex returns a keyed hash of a value for equality lookups on an encrypted column.
+// Store it in a separate indexed column when the row is saved and query that column:
+//
+//	idx, err := encrypt.BlindIndex("users.email", strings.ToLower(email))
+//	err = db.DB(ctx).Where("email_index = ?", idx).First(&user).Error
+//
+// Normalize the value (case, whitespace) the same way when saving and looking up. The scope
+// separates the indexes of different columns, so equal values in two columns do not reveal
+// each other. Changing data.encryption.blind_index_key invalidates all stored indexes.
+//
+// Parameters:
+//   - scope: The column the index belongs to, e.g. "users.email"
+//   - value: The plaintext value
+//
+// Returns:
+//   - string: 64 hex characters
+//   - error: ErrNotConfigured, or an error if no blind index key is configured
+func BlindIndex(scope, value string) (string, error) {
+	k, err := current()
+	if err != nil {
+		return "", err
+	}
+	if k.blindKey == nil {
+		return "", errors.New("blind index key is not configured")
+	}
+	mac := hmac.New(sha256.New, k.blindKey)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
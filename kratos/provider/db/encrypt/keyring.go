@@ -0,0 +1,206 @@
+// Package encrypt provides field-level encryption of sensitive columns with AES-GCM.
+//
+// Model fields opt in with a GORM serializer; the field keeps its plain Go type:
+//
+//	type User struct {
+//		ID         uint64
+//		Email      string            `gorm:"type:text;serializer:encrypted"`
+//		EmailIndex string            `gorm:"size:64;index"`
+//		Address    map[string]string `gorm:"type:text;serializer:encrypted_json"`
+//	}
+//
+// Stored values are "<key id>:<base64 nonce and ciphertext>". New values are encrypted
+// with the primary key of the keyring; values of retired keys stay readable as long as
+// their key is configured, and the reencrypt command rewrites them with the primary key.
+// Empty strings and NULL are stored as they are.
+//
+// Encrypted columns cannot be searched; store a BlindIndex of the value in a separate
+// column for equality lookups.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// separator separates the key ID from the ciphertext of a stored value.
+const separator = ":"
+
+// ErrNotConfigured is returned when encrypted columns are used before a keyring is set.
+var ErrNotConfigured = errors.New("encryption keyring is not configured")
+
+// Keyring holds the encryption keys by ID.
+type Keyring struct {
+	primary  string
+	ciphers  map[string]cipher.AEAD
+	blindKey []byte
+}
+
+var (
+	// keyring is the global keyring set by Init or SetKeyring
+	keyring *Keyring
+	// keyringMu protects keyring
+	keyringMu sync.RWMutex
+)
+
+// NewKeyring creates a keyring.
+//
+// Parameters:
+//   - primary: ID of the key encrypting new values; it must be in keys
+//   - keys: AES keys (16, 24 or 32 bytes) by ID; IDs must not contain ":"
+//   - blindKey: HMAC key of BlindIndex, at least 32 bytes (may be nil if blind indexes are not used)
+//
+// Returns:
+//   - *Keyring: The keyring
+//   - error: Error if a key is invalid or the primary key is missing
+func NewKeyring(primary string, keys map[string][]byte, blindKey []byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, errors.Errorf("primary encryption key %q is not configured", primary)
+	}
+	if blindKey != nil && len(blindKey) < 32 {
+		return nil, errors.New("blind index key must be at least 32 bytes")
+	}
+	k := &Keyring{primary: primary, ciphers: make(map[string]cipher.AEAD, len(keys)), blindKey: blindKey}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, separator) {
+			return nil, errors.Errorf("invalid encryption key ID %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encryption key %q", id)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encryption key %q", id)
+		}
+		k.ciphers[id] = gcm
+	}
+	return k, nil
+}
+
+// Init creates the global keyring from the configuration. Keys are base64 encoded;
+// without keys encrypted columns return ErrNotConfigured.
+//
+// Parameters:
+//   - cfg: Encryption configuration (may be nil)
+//   - logger: Logger instance for logging
+//
+// Returns:
+//   - error: Error if a key cannot be decoded or is invalid
+func Init(cfg *conf.Data_Encryption, logger log.Logger) error {
+	if len(cfg.GetKeys()) == 0 {
+		return nil
+	}
+	keys := make(map[string][]byte, len(cfg.GetKeys()))
+	for id, encoded := range cfg.GetKeys() {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return errors.Wrapf(err, "decode encryption key %q", id)
+		}
+		keys[id] = key
+	}
+	var blindKey []byte
+	if cfg.GetBlindIndexKey() != "" {
+		var err error
+		if blindKey, err = base64.StdEncoding.DecodeString(cfg.GetBlindIndexKey()); err != nil {
+			return errors.Wrap(err, "decode blind index key")
+		}
+	}
+
+	k, err := NewKeyring(cfg.GetPrimaryKey(), keys, blindKey)
+	if err != nil {
+		return err
+	}
+	SetKeyring(k)
+	log.NewHelper(logger).Infof("encryption initialized: primary_key=%s, keys=%d", k.primary, len(k.ciphers))
+	return nil
+}
+
+// SetKeyring replaces the global keyring, e.g. with a test keyring.
+//
+// Parameters:
+//   - k: The keyring; nil disables encryption
+func SetKeyring(k *Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	keyring = k
+}
+
+// current returns the global keyring.
+func current() (*Keyring, error) {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	if keyring == nil {
+		return nil, ErrNotConfigured
+	}
+	return keyring, nil
+}
+
+// Primary returns the ID of the key encrypting new values.
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Encrypt encrypts plaintext with the primary key.
+//
+// Parameters:
+//   - plaintext: The value to encrypt
+//
+// Returns:
+//   - string: The stored form "<key id>:<base64 nonce and ciphertext>"
+//   - error: Error if reading random bytes fails
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	gcm := k.ciphers[k.primary]
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "generate nonce")
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return k.primary + separator + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value created by Encrypt with any key of the keyring.
+//
+// Parameters:
+//   - value: The stored form of the value
+//
+// Returns:
+//   - []byte: The plaintext
+//   - error: Error if the key is unknown or the value is malformed or was tampered with
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	id, encoded, ok := strings.Cut(value, separator)
+	if !ok {
+		return nil, errors.New("value is not encrypted")
+	}
+	gcm, ok := k.ciphers[id]
+	if !ok {
+		return nil, errors.Errorf("unknown encryption key %q", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypt with key %q", id)
+	}
+	return plaintext, nil
+}
+
+// KeyID returns the ID of the key that encrypted a stored value, or "" if it is not encrypted.
+func KeyID(value string) string {
+	id, _, ok := strings.Cut(value, separator)
+	if !ok {
+		return ""
+	}
+	return id
+}
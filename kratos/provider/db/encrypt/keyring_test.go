@@ -0,0 +1,182 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	testKeyV1 = bytes.Repeat([]byte{1}, 32)
+	testKeyV2 = bytes.Repeat([]byte{2}, 16)
+	testBlind = bytes.Repeat([]byte{3}, 32)
+)
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name     string
+		primary  string
+		keys     map[string][]byte
+		blindKey []byte
+		wantErr  string
+	}{
+		{name: "valid", primary: "v1", keys: map[string][]byte{"v1": testKeyV1, "v2": testKeyV2}, blindKey: testBlind},
+		{name: "no blind key", primary: "v1", keys: map[string][]byte{"v1": testKeyV1}},
+		{name: "primary missing", primary: "v3", keys: map[string][]byte{"v1": testKeyV1}, wantErr: `primary encryption key "v3"`},
+		{name: "short blind key", primary: "v1", keys: map[string][]byte{"v1": testKeyV1}, blindKey: []byte("short"), wantErr: "at least 32 bytes"},
+		{name: "bad key size", primary: "v1", keys: map[string][]byte{"v1": []byte("12345")}, wantErr: `encryption key "v1"`},
+		{name: "separator in id", primary: "v:1", keys: map[string][]byte{"v:1": testKeyV1}, wantErr: "invalid encryption key ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.primary, tt.keys, tt.blindKey)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewKeyring() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewKeyring() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, err := NewKeyring("v1", map[string][]byte{"v1": testKeyV1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewKeyring("v2", map[string][]byte{"v1": testKeyV1, "v2": testKeyV2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := old.Encrypt([]byte("alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyID(stored) != "v1" {
+		t.Fatalf("KeyID() = %q, want v1", KeyID(stored))
+	}
+	again, err := old.Encrypt([]byte("alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == stored {
+		t.Error("Encrypt() reused a nonce")
+	}
+
+	plain, err := rotated.Decrypt(stored)
+	if err != nil {
+		t.Fatalf("Decrypt() with a retired key error = %v", err)
+	}
+	if string(plain) != "alice@example.com" {
+		t.Errorf("Decrypt() = %q", plain)
+	}
+
+	fresh, err := rotated.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyID(fresh) != "v2" || rotated.Primary() != "v2" {
+		t.Errorf("KeyID() = %q, want the primary key v2", KeyID(fresh))
+	}
+	if _, err := old.Decrypt(fresh); err == nil || !strings.Contains(err.Error(), `unknown encryption key "v2"`) {
+		t.Errorf("Decrypt() without the key error = %v", err)
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	k, err := NewKeyring("v1", map[string][]byte{"v1": testKeyV1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := k.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, "v1:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	tampered := "v1:" + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain value", value: "secret", want: "not encrypted"},
+		{name: "unknown key", value: "v9:AAAA", want: "unknown encryption key"},
+		{name: "not base64", value: "v1:***", want: "malformed encrypted value"},
+		{name: "too short", value: "v1:AAAA", want: "malformed encrypted value"},
+		{name: "tampered", value: tampered, want: `decrypt with key "v1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.Decrypt(tt.value); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decrypt(%q) error = %v, want %q", tt.value, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	SetKeyring(nil)
+	if _, err := BlindIndex("users.email", "a"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("BlindIndex() without keyring error = %v, want ErrNotConfigured", err)
+	}
+
+	noBlind, err := NewKeyring("v1", map[string][]byte{"v1": testKeyV1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeyring(noBlind)
+	t.Cleanup(func() { SetKeyring(nil) })
+	if _, err := BlindIndex("users.email", "a"); err == nil {
+		t.Fatal("BlindIndex() without blind index key succeeded")
+	}
+
+	k, err := NewKeyring("v1", map[string][]byte{"v1": testKeyV1}, testBlind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeyring(k)
+	index := func(scope, value string) string {
+		t.Helper()
+		idx, err := BlindIndex(scope, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return idx
+	}
+
+	base := index("users.email", "alice@example.com")
+	if len(base) != 64 {
+		t.Errorf("BlindIndex() length = %d, want 64", len(base))
+	}
+	tests := []struct {
+		name  string
+		scope string
+		value string
+		equal bool
+	}{
+		{name: "same value", scope: "users.email", value: "alice@example.com", equal: true},
+		{name: "other value", scope: "users.email", value: "bob@example.com"},
+		{name: "other scope", scope: "orders.email", value: "alice@example.com"},
+		{name: "scope boundary", scope: "users.emailalice", value: "@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := index(tt.scope, tt.value); (got == base) != tt.equal {
+				t.Errorf("BlindIndex(%q, %q) equal = %v, want %v", tt.scope, tt.value, got == base, tt.equal)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package encrypt
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultBatchSize is the default number of rows read per batch by Reencrypt.
+const defaultBatchSize = 500
+
+// target is a model with encrypted fields.
+type target struct {
+	model      interface{}
+	connection string
+}
+
+var (
+	// targets holds the models registered for re-encryption
+	targets []target
+	// targetsMu protects targets
+	targetsMu sync.RWMutex
+)
+
+// Register adds models with encrypted fields on the default connection to Reencrypt.
+// Call it from an init function of the package defining the models.
+//
+// Parameters:
+//   - models: Pointers to GORM models, e.g. &models.User{}; each needs a single-column primary key
+func Register(models ...interface{}) {
+	RegisterOn(db.DefaultName, models...)
+}
+
+// RegisterOn adds models with encrypted fields on a named connection to Reencrypt.
+//
+// Parameters:
+//   - connection: The database connection of the models
+//   - models: Pointers to GORM models
+func RegisterOn(connection string, models ...interface{}) {
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+	for _, model := range models {
+		targets = append(targets, target{model: model, connection: connection})
+	}
+}
+
+// Result reports the re-encryption of a table.
+type Result struct {
+	// Table is the table name
+	Table string
+	// Columns are the encrypted columns
+	Columns []string
+	// Scanned is the number of rows read
+	Scanned int
+	// Rewritten is the number of rows whose values were re-encrypted with the primary key
+	Rewritten int
+	// Skipped is the number of rows changed concurrently; they were written with the
+	// primary key already unless an old key is still in use somewhere
+	Skipped int
+}
+
+// Reencrypt rewrites the encrypted columns of the registered models that use a key other
+// than the primary key. Rows are read in primary key order and each batch is updated in
+// its own transaction, so the command can be interrupted and run again. An update only
+// applies if the row still holds the values that were read.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - batchSize: Rows per batch; 0 uses the default of 500
+//   - dryRun: Only count the rows that need re-encryption
+//   - logger: Logger instance for progress
+//
+// Returns:
+//   - []Result: One result per registered model
+//   - error: Error if a value cannot be decrypted or a statement fails
+func Reencrypt(ctx context.Context, batchSize int, dryRun bool, logger log.Logger) ([]Result, error) {
+	k, err := current()
+	if err != nil {
+		return nil, err
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	helper := log.NewHelper(log.With(logger, "module", "encrypt"))
+	ctx = tenant.SkipTenant(ctx)
+
+	targetsMu.RLock()
+	list := append([]target(nil), targets...)
+	targetsMu.RUnlock()
+
+	results := make([]Result, 0, len(list))
+	for _, t := range list {
+		res, err := reencryptTarget(ctx, k, t, batchSize, dryRun)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+		helper.Infof("[reencrypt] %s: %d rows scanned, %d rewritten, %d skipped", res.Table, res.Scanned, res.Rewritten, res.Skipped)
+	}
+	return results, nil
+}
+
+// reencryptTarget re-encrypts the rows of a model.
+func reencryptTarget(ctx context.Context, k *Keyring, t target, batchSize int, dryRun bool) (Result, error) {
+	gormDB := db.Get(t.connection)
+	if gormDB == nil {
+		return Result{}, errors.Errorf("database %q is not initialized", t.connection)
+	}
+	stmt := &gorm.Statement{DB: gormDB}
+	if err := stmt.Parse(t.model); err != nil {
+		return Result{}, errors.Wrapf(err, "parse model %T", t.model)
+	}
+	sch := stmt.Schema
+	if len(sch.PrimaryFields) != 1 {
+		return Result{}, errors.Errorf("table %s: re-encryption needs a single-column primary key", sch.Table)
+	}
+	pk := sch.PrimaryFields[0].DBName
+
+	res := Result{Table: sch.Table}
+	for _, field := range sch.Fields {
+		switch strings.ToLower(field.TagSettings["SERIALIZER"]) {
+		case "encrypted", "encrypted_json":
+			res.Columns = append(res.Columns, field.DBName)
+		}
+	}
+	sort.Strings(res.Columns)
+	if len(res.Columns) == 0 {
+		return res, errors.Errorf("table %s has no encrypted columns", sch.Table)
+	}
+
+	var last interface{}
+	for {
+		var rows []map[string]interface{}
+		query := gormDB.WithContext(ctx).Table(sch.Table).Select(append([]string{pk}, res.Columns...)).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: pk}}).Limit(batchSize)
+		if last != nil {
+			query = query.Where(clause.Gt{Column: clause.Column{Name: pk}, Value: last})
+		}
+		if err := query.Find(&rows).Error; err != nil {
+			return res, errors.Wrapf(err, "table %s: read batch", sch.Table)
+		}
+		if len(rows) == 0 {
+			return res, nil
+		}
+		last = rows[len(rows)-1][pk]
+		res.Scanned += len(rows)
+
+		err := gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				current, updates, err := rotateRow(k, row, res.Columns)
+				if err != nil {
+					return errors.Wrapf(err, "table %s row %v", sch.Table, row[pk])
+				}
+				if len(updates) == 0 {
+					continue
+				}
+				if dryRun {
+					res.Rewritten++
+					continue
+				}
+				result := tx.Table(sch.Table).Where(clause.Eq{Column: clause.Column{Name: pk}, Value: row[pk]}).
+					Where(current).UpdateColumns(updates)
+				if result.Error != nil {
+					return errors.Wrapf(result.Error, "table %s row %v: update", sch.Table, row[pk])
+				}
+				if result.RowsAffected == 0 {
+					res.Skipped++
+				} else {
+					res.Rewritten++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return res, err
+		}
+		if len(rows) < batchSize {
+			return res, nil
+		}
+	}
+}
+
+// rotateRow returns the stored values of the columns to rewrite and their new values.
+func rotateRow(k *Keyring, row map[string]interface{}, columns []string) (map[string]interface{}, map[string]interface{}, error) {
+	current := map[string]interface{}{}
+	updates := map[string]interface{}{}
+	for _, col := range columns {
+		var value string
+		switch v := row[col].(type) {
+		case nil:
+			continue
+		case []byte:
+			value = string(v)
+		case string:
+			value = v
+		default:
+			return nil, nil, errors.Errorf("column %s: unsupported value %T", col, v)
+		}
+		if value == "" || KeyID(value) == k.primary {
+			continue
+		}
+		plaintext, err := k.Decrypt(value)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "column %s", col)
+		}
+		rotated, err := k.Encrypt(plaintext)
+		if err != nil {
+			return nil, nil, err
+		}
+		current[col] = value
+		updates[col] = rotated
+	}
+	return current, updates, nil
+}
@@ -0,0 +1,156 @@
+package encrypt
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", StringSerializer{})
+	schema.RegisterSerializer("encrypted_json", JSONSerializer{})
+}
+
+// StringSerializer encrypts string, *string and []byte fields; register name "encrypted".
+type StringSerializer struct{}
+
+// Scan implements schema.SerializerInterface.
+func (StringSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	plaintext, err := decryptColumn(field, dbValue)
+	if err != nil {
+		return err
+	}
+
+	fieldValue := reflect.New(field.FieldType).Elem()
+	switch {
+	case field.FieldType.Kind() == reflect.String:
+		fieldValue.SetString(string(plaintext))
+	case field.FieldType.Kind() == reflect.Slice && field.FieldType.Elem().Kind() == reflect.Uint8:
+		if plaintext != nil {
+			fieldValue.SetBytes(plaintext)
+		}
+	case field.FieldType.Kind() == reflect.Ptr && field.FieldType.Elem().Kind() == reflect.String:
+		if plaintext != nil {
+			s := reflect.New(field.FieldType.Elem())
+			s.Elem().SetString(string(plaintext))
+			fieldValue.Set(s)
+		}
+	default:
+		return errors.Errorf("field %s: serializer encrypted needs a string, *string or []byte field", field.Name)
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue)
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface.
+func (StringSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext []byte
+	switch v := fieldValue.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		plaintext = []byte(v)
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = []byte(*v)
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = v
+	default:
+		rv := reflect.ValueOf(fieldValue)
+		if rv.Kind() != reflect.String {
+			return nil, errors.Errorf("field %s: serializer encrypted needs a string, *string or []byte field", field.Name)
+		}
+		plaintext = []byte(rv.String())
+	}
+	return encryptColumn(field, plaintext)
+}
+
+// Sensitive marks the column for audit.Plugin, which records changes without the value.
+func (StringSerializer) Sensitive() bool {
+	return true
+}
+
+// JSONSerializer encrypts the JSON encoding of any field; register name "encrypted_json".
+type JSONSerializer struct{}
+
+// Scan implements schema.SerializerInterface.
+func (JSONSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	plaintext, err := decryptColumn(field, dbValue)
+	if err != nil {
+		return err
+	}
+	fieldValue := reflect.New(field.FieldType)
+	if len(plaintext) > 0 {
+		if err := json.Unmarshal(plaintext, fieldValue.Interface()); err != nil {
+			return errors.Wrapf(err, "field %s: decode decrypted JSON", field.Name)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface.
+func (JSONSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, errors.Wrapf(err, "field %s: encode JSON", field.Name)
+	}
+	if string(plaintext) == "null" {
+		if field.TagSettings["NOT NULL"] != "" {
+			return "", nil
+		}
+		return nil, nil
+	}
+	return encryptColumn(field, plaintext)
+}
+
+// Sensitive marks the column for audit.Plugin, which records changes without the value.
+func (JSONSerializer) Sensitive() bool {
+	return true
+}
+
+// encryptColumn encrypts the value of a column; empty values are stored as they are.
+func encryptColumn(field *schema.Field, plaintext []byte) (interface{}, error) {
+	if len(plaintext) == 0 {
+		return "", nil
+	}
+	k, err := current()
+	if err != nil {
+		return nil, err
+	}
+	value, err := k.Encrypt(plaintext)
+	return value, errors.Wrapf(err, "field %s", field.Name)
+}
+
+// decryptColumn decrypts the value of a column; NULL returns nil and an empty value an
+// empty plaintext.
+func decryptColumn(field *schema.Field, dbValue interface{}) ([]byte, error) {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return nil, errors.Errorf("field %s: unsupported encrypted column value %T", field.Name, dbValue)
+	}
+	if value == "" {
+		return []byte{}, nil
+	}
+	k, err := current()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := k.Decrypt(value)
+	return plaintext, errors.Wrapf(err, "field %s", field.Name)
+}
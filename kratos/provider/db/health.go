@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"kratos-project-template/provider/db/metrics"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// defaultHealthCheckInterval is the default ping interval of a primary connection.
+const defaultHealthCheckInterval = 10 * time.Second
+
+func init() {
+	// Pool statistics are read at scrape time, so connections added later are exported too.
+	prometheus.MustRegister(metrics.NewStatsCollector(poolStats))
+}
+
+// State is the connection state of a database connection.
+type State int32
+
+const (
+	// StateNotInitialized means the connection has not been initialized.
+	StateNotInitialized State = iota
+	// StateUp means the last ping succeeded.
+	StateUp
+	// StateDown means the last ping failed; the pool keeps reconnecting.
+	StateDown
+)
+
+// String returns the state name used in logs and health checks.
+func (s State) String() string {
+	switch s {
+	case StateUp:
+		return "up"
+	case StateDown:
+		return "down"
+	default:
+		return "not_initialized"
+	}
+}
+
+// Status is the health of a database connection.
+type Status struct {
+	// Name is the connection name
+	Name string
+	// State is the current connection state
+	State State
+	// Err is the error of the last failed ping, nil when up
+	Err error
+	// Latency is the round trip of the last ping
+	Latency time.Duration
+	// Since is when the current state was entered
+	Since time.Time
+	// Stats are the connection pool statistics of the primary
+	Stats sql.DBStats
+	// Replicas is the health of the connection's read replicas
+	Replicas []ReplicaStatus
+}
+
+// ConnectHook runs once a connection is up, see OnConnect.
+type ConnectHook func(ctx context.Context, db *gorm.DB) error
+
+// monitor pings the primary of a connection, tracks its state and runs the
+// hooks waiting for it to come up.
+type monitor struct {
+	db       *gorm.DB
+	interval time.Duration
+	log      *log.Helper
+
+	mu      sync.Mutex
+	status  Status
+	pending []ConnectHook
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newMonitor creates the monitor of a connection in the given state and starts it.
+func newMonitor(name string, gormDB *gorm.DB, state State, err error, interval time.Duration, logHelper *log.Helper, pending ...ConnectHook) *monitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &monitor{
+		db:       gormDB,
+		interval: interval,
+		log:      logHelper,
+		status:   Status{Name: name, State: state, Err: err, Since: time.Now()},
+		pending:  pending,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go m.run(ctx)
+	return m
+}
+
+// newExternalMonitor creates the monitor of a connection registered with Set. It is not
+// pinged in the background, only by Health.
+func newExternalMonitor(name string, gormDB *gorm.DB, logHelper *log.Helper) *monitor {
+	done := make(chan struct{})
+	close(done)
+	return &monitor{
+		db:     gormDB,
+		log:    logHelper,
+		status: Status{Name: name, State: StateUp, Since: time.Now()},
+		cancel: func() {},
+		done:   done,
+	}
+}
+
+// GetStatus returns the last known health of a connection without pinging it.
+//
+// Parameters:
+//   - name: Optional connection name; the default connection if omitted
+//
+// Returns:
+//   - Status: The current status; State is StateNotInitialized for an unknown connection
+func GetStatus(name ...string) Status {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
+	}
+	c := lookup(n)
+	if c == nil {
+		return Status{Name: n, State: StateNotInitialized}
+	}
+	st := c.monitor.snapshot()
+	st.Name = n
+	st.Replicas = c.replicas.status()
+	return st
+}
+
+// IsAvailable reports whether the last ping of a connection succeeded.
+//
+// Parameters:
+//   - name: Optional connection name; the default connection if omitted
+func IsAvailable(name ...string) bool {
+	return GetStatus(name...).State == StateUp
+}
+
+// OnConnect runs fn once the connection is up: immediately if it is, otherwise on the
+// monitor goroutine when it comes up, e.g. to create tables after a degraded start.
+// A deferred fn that fails is logged and retried at the next successful ping.
+//
+// Parameters:
+//   - ctx: Context for an immediate run
+//   - name: The connection name
+//   - fn: The function to run
+//
+// Returns:
+//   - error: Error if the connection is not initialized, or the error of an immediate run
+func OnConnect(ctx context.Context, name string, fn ConnectHook) error {
+	c := lookup(name)
+	if c == nil {
+		return errors.Errorf("database %q is not initialized", name)
+	}
+	m := c.monitor
+	m.mu.Lock()
+	if m.status.State != StateUp {
+		m.pending = append(m.pending, fn)
+		m.mu.Unlock()
+		return nil
+	}
+	m.mu.Unlock()
+	return fn(ctx, m.db.WithContext(ctx))
+}
+
+// Health pings every initialized connection and updates its state.
+//
+// Parameters:
+//   - ctx: Context bounding the pings
+//
+// Returns:
+//   - []Status: One entry per connection in initialization order
+func Health(ctx context.Context) []Status {
+	names := Names()
+	statuses := make([]Status, 0, len(names))
+	for _, name := range names {
+		c := lookup(name)
+		if c == nil {
+			continue
+		}
+		c.monitor.check(ctx)
+		st := c.monitor.snapshot()
+		st.Replicas = c.replicas.status()
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
+// poolStats returns the pool statistics of every connection for the metrics collector.
+func poolStats() []metrics.PoolStats {
+	var stats []metrics.PoolStats
+	for _, name := range Names() {
+		st := GetStatus(name)
+		if st.State == StateNotInitialized {
+			continue
+		}
+		stats = append(stats, metrics.PoolStats{Connection: name, Pool: "primary", Stats: st.Stats})
+		for _, r := range st.Replicas {
+			stats = append(stats, metrics.PoolStats{Connection: name, Pool: fmt.Sprintf("replica%d", r.Index), Stats: r.Stats})
+		}
+	}
+	return stats
+}
+
+// snapshot returns the status with the current pool statistics.
+func (m *monitor) snapshot() Status {
+	m.mu.Lock()
+	st := m.status
+	m.mu.Unlock()
+	if sqlDB, err := m.db.DB(); err == nil {
+		st.Stats = sqlDB.Stats()
+	}
+	return st
+}
+
+// run pings the primary until ctx is cancelled.
+func (m *monitor) run(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+// check pings the primary once, records the result and runs the pending hooks when up.
+func (m *monitor) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	start := time.Now()
+	err := m.ping(pingCtx)
+	latency := time.Since(start)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	next := StateUp
+	if err != nil {
+		next = StateDown
+	}
+
+	m.mu.Lock()
+	prev := m.status.State
+	m.status.Err = err
+	m.status.Latency = latency
+	if prev != next {
+		m.status.State = next
+		m.status.Since = time.Now()
+	}
+	var hooks []ConnectHook
+	if next == StateUp {
+		hooks, m.pending = m.pending, nil
+	}
+	m.mu.Unlock()
+
+	switch {
+	case prev == next:
+	case next == StateDown:
+		m.log.Warnf("database unavailable, reconnecting in background: %v", err)
+	default:
+		m.log.Infof("database connection recovered")
+	}
+
+	for _, fn := range hooks {
+		if err := fn(ctx, m.db.WithContext(ctx)); err != nil {
+			m.log.Warnf("database connect hook failed, retrying at next check: %v", err)
+			m.mu.Lock()
+			m.pending = append(m.pending, fn)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// ping checks the primary; a database bound to a transaction (see Set) is checked with a query.
+func (m *monitor) ping(ctx context.Context) error {
+	if _, ok := m.db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return m.db.WithContext(ctx).Exec("SELECT 1").Error
+	}
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "get sql db error")
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// stop stops the monitor goroutine.
+func (m *monitor) stop() {
+	m.cancel()
+	<-m.done
+}
@@ -2,75 +2,223 @@ package db
 
 import (
 	"context"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const defaultSlowThreshold = time.Second
+
+// requestIDHeader is the header read by RequestFields.
+const requestIDHeader = "X-Request-ID"
+
+// GormLogger adapts GORM logging to a kratos logger with structured key/values.
+// Each query is logged with sql, rows, elapsed_ms and caller, plus the fields returned
+// by its context extractor (trace_id, span_id and request_id by default).
+//
+// Failed queries are logged at error level and queries slower than the slow threshold at
+// warn level; ordinary queries are logged at info level and can be sampled. SQL parameters
+// are redacted unless LogParams is enabled.
 type GormLogger struct {
-	log      *log.Helper
-	logLevel logger.LogLevel
+	logger        log.Logger
+	log           *log.Helper
+	logLevel      logger.LogLevel
+	slowThreshold time.Duration
+	logParams     bool
+	sampleRate    float64
+	fields        func(ctx context.Context) []any
+}
+
+// GormLoggerOption configures a GormLogger.
+type GormLoggerOption func(*GormLogger)
+
+// SlowThreshold sets the duration above which queries are logged as slow (default 1s,
+// a negative value disables slow query logging).
+func SlowThreshold(d time.Duration) GormLoggerOption {
+	return func(gl *GormLogger) {
+		gl.slowThreshold = d
+	}
+}
+
+// LogParams includes parameter values in logged SQL. They may contain personal data or
+// secrets, so it is off by default.
+func LogParams(enabled bool) GormLoggerOption {
+	return func(gl *GormLogger) {
+		gl.logParams = enabled
+	}
+}
+
+// SampleRate logs only a fraction (0, 1] of ordinary queries at info level; failed and
+// slow queries are always logged.
+func SampleRate(rate float64) GormLoggerOption {
+	return func(gl *GormLogger) {
+		gl.sampleRate = rate
+	}
 }
 
-func NewGormLogger(l log.Logger, level logger.LogLevel) *GormLogger {
-	return &GormLogger{
-		log:      log.NewHelper(l),
-		logLevel: level,
+// ContextFields replaces the function extracting key/values from the query context.
+func ContextFields(fn func(ctx context.Context) []any) GormLoggerOption {
+	return func(gl *GormLogger) {
+		gl.fields = fn
 	}
 }
 
+// NewGormLogger creates a GORM logger writing to a kratos logger.
+//
+// Parameters:
+//   - l: The kratos logger
+//   - level: The GORM log level
+//   - opts: Optional settings such as the slow threshold and sampling
+//
+// Returns:
+//   - *GormLogger: A GORM logger interface implementation
+func NewGormLogger(l log.Logger, level logger.LogLevel, opts ...GormLoggerOption) *GormLogger {
+	gl := &GormLogger{
+		logger:        l,
+		log:           log.NewHelper(l),
+		logLevel:      level,
+		slowThreshold: defaultSlowThreshold,
+		sampleRate:    1,
+		fields:        RequestFields,
+	}
+	for _, opt := range opts {
+		opt(gl)
+	}
+	return gl
+}
+
+// RequestFields returns the trace_id and span_id of the OpenTelemetry span and the
+// X-Request-ID header of the kratos transport in ctx, when present.
+//
+// Parameters:
+//   - ctx: The query context
+//
+// Returns:
+//   - []any: Key/value pairs to add to the log entry
+func RequestFields(ctx context.Context) []any {
+	var kv []any
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		kv = append(kv, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		if id := tr.RequestHeader().Get(requestIDHeader); id != "" {
+			kv = append(kv, "request_id", id)
+		}
+	}
+	return kv
+}
+
+// LogMode returns a copy of the logger with another log level.
 func (gl *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
 	newlogger := *gl
 	newlogger.logLevel = level
 	return &newlogger
 }
 
+// Info logs a GORM info message.
 func (gl *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
 	if gl.logLevel >= logger.Info {
 		gl.log.Infof(msg, data...)
 	}
 }
 
+// Warn logs a GORM warning.
 func (gl *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
 	if gl.logLevel >= logger.Warn {
 		gl.log.Warnf(msg, data...)
 	}
 }
 
+// Error logs a GORM error.
 func (gl *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
 	if gl.logLevel >= logger.Error {
 		gl.log.Errorf(msg, data...)
 	}
 }
 
+// ParamsFilter redacts the parameters of logged SQL unless LogParams is enabled.
+// GORM calls it before rendering the SQL passed to Trace.
+func (gl *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if gl.logParams {
+		return sql, params
+	}
+	return sql, nil
+}
+
+// Trace logs a finished query.
 func (gl *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
 	if gl.logLevel <= logger.Silent {
 		return
 	}
 
 	elapsed := time.Since(begin)
-	sql, rows := fc()
-
 	switch {
-	case err != nil:
-		// Record not found is a normal case, don't log it as error
-		if err == gorm.ErrRecordNotFound {
-			// Skip logging record not found errors as they are expected in normal operations
-			// Only log at debug level if log level is Info or higher
-			if gl.logLevel >= logger.Info {
-				gl.log.Debugf("SQL: %s | rows: %d | err: record not found | took: %v", sql, rows, elapsed)
-			}
-			// Otherwise, just skip logging
-			return
-		}
-		// Other errors are logged at error level
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
 		if gl.logLevel >= logger.Error {
-			gl.log.Errorf("SQL Error: %s | rows: %d | err: %v | took: %v", sql, rows, err, elapsed)
+			gl.trace(ctx, log.LevelError, "sql error", elapsed, fc, "error", err.Error())
+		}
+	case gl.slowThreshold > 0 && elapsed > gl.slowThreshold:
+		if gl.logLevel >= logger.Warn {
+			gl.trace(ctx, log.LevelWarn, "slow sql", elapsed, fc, "slow_threshold_ms", gl.slowThreshold.Milliseconds())
 		}
 	case gl.logLevel >= logger.Info:
-		gl.log.Infof("SQL: %s | rows: %d | took: %v", sql, rows, elapsed)
+		if gl.sampleRate < 1 && rand.Float64() >= gl.sampleRate {
+			return
+		}
+		// Record not found is a normal case, don't log it as error
+		if err != nil {
+			gl.trace(ctx, log.LevelDebug, "sql", elapsed, fc, "error", err.Error())
+			return
+		}
+		gl.trace(ctx, log.LevelInfo, "sql", elapsed, fc)
+	}
+}
+
+// trace writes one query log entry.
+func (gl *GormLogger) trace(ctx context.Context, level log.Level, msg string, elapsed time.Duration, fc func() (string, int64), extra ...any) {
+	sql, rows := fc()
+	kv := []any{
+		log.DefaultMessageKey, msg,
+		"sql", sql,
+		"rows", rows,
+		"elapsed_ms", float64(elapsed.Microseconds()) / 1000,
+		"caller", queryCaller(),
+	}
+	kv = append(kv, extra...)
+	if gl.fields != nil {
+		kv = append(kv, gl.fields(ctx)...)
 	}
+	_ = gl.logger.Log(level, kv...)
 }
 
+// packageDir is the source directory of this package, skipped when finding the caller.
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// queryCaller returns file:line of the first caller outside GORM and this package,
+// i.e. the repository or service code that ran the query.
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && filepath.Dir(frame.File) != packageDir {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
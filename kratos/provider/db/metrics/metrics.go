@@ -0,0 +1,198 @@
+// Package metrics exports database metrics to Prometheus: a GORM plugin recording the
+// latency and errors of queries by connection, operation and table, and a collector of
+// the connection pool statistics (sql.DBStats) of each connection.
+//
+// Query metrics:
+//
+//	db_query_duration_seconds{connection,operation,table}   histogram; _count is the query count
+//	db_query_errors_total{connection,operation,table}       failed queries (record not found is not an error)
+//
+// Operations are "create", "query", "update", "delete", "row" and "raw"; raw SQL has no table.
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// startKey is the statement instance key holding the start time of a query.
+const startKey = "metrics:start"
+
+var (
+	// queryDuration records the latency of queries
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of database queries.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"connection", "operation", "table"})
+	// queryErrors counts failed queries
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "db",
+		Name:      "query_errors_total",
+		Help:      "Number of failed database queries.",
+	}, []string{"connection", "operation", "table"})
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration, queryErrors)
+}
+
+// Plugin is a GORM plugin recording query metrics of a connection.
+type Plugin struct {
+	connection string
+}
+
+// New creates the query metrics plugin.
+//
+// Parameters:
+//   - connection: The connection name used as the "connection" label
+//
+// Returns:
+//   - *Plugin: A plugin to register with gorm.DB.Use
+func New(connection string) *Plugin {
+	return &Plugin{connection: connection}
+}
+
+// Name implements gorm.Plugin.
+func (p *Plugin) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin by registering the callbacks around every operation.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("*").Register("metrics:before_create", before),
+		cb.Create().After("*").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("*").Register("metrics:before_query", before),
+		cb.Query().After("*").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("*").Register("metrics:before_update", before),
+		cb.Update().After("*").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", before),
+		cb.Delete().After("*").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", before),
+		cb.Row().After("*").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", before),
+		cb.Raw().After("*").Register("metrics:after_raw", p.after("raw")),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register metrics callback")
+		}
+	}
+	return nil
+}
+
+// before records the start time of a statement.
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+// after returns the callback recording the latency and error of an operation.
+func (p *Plugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		queryDuration.WithLabelValues(p.connection, operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(p.connection, operation, table).Inc()
+		}
+	}
+}
+
+// PoolStats are the statistics of a connection pool.
+type PoolStats struct {
+	// Connection is the connection name
+	Connection string
+	// Pool is "primary" or "replica<index>"
+	Pool string
+	// Stats are the pool statistics
+	Stats sql.DBStats
+}
+
+// statsCollector exports pool statistics collected at scrape time.
+type statsCollector struct {
+	stats func() []PoolStats
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewStatsCollector creates a collector of connection pool statistics.
+//
+// Parameters:
+//   - stats: Returns the statistics of every pool; called on each scrape
+//
+// Returns:
+//   - prometheus.Collector: The collector exporting db_pool_* metrics labeled by connection and pool
+func NewStatsCollector(stats func() []PoolStats) prometheus.Collector {
+	labels := []string{"connection", "pool"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("db", "pool", name), help, labels, nil)
+	}
+	return &statsCollector{
+		stats:             stats,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections."),
+		open:              desc("open_connections", "Number of established connections, in use and idle."),
+		inUse:             desc("in_use_connections", "Number of connections in use."),
+		idle:              desc("idle_connections", "Number of idle connections."),
+		waitCount:         desc("wait_count_total", "Number of times a connection was waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "Total time blocked waiting for a connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "Connections closed due to max_idle_conns."),
+		maxIdleTimeClosed: desc("max_idle_time_closed_total", "Connections closed due to conn_max_idle_time."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "Connections closed due to conn_max_lifetime."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector.
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, p := range c.stats() {
+		s := p.Stats
+		gauge := func(desc *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, p.Connection, p.Pool)
+		}
+		counter := func(desc *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, p.Connection, p.Pool)
+		}
+		gauge(c.maxOpen, float64(s.MaxOpenConnections))
+		gauge(c.open, float64(s.OpenConnections))
+		gauge(c.inUse, float64(s.InUse))
+		gauge(c.idle, float64(s.Idle))
+		counter(c.waitCount, float64(s.WaitCount))
+		counter(c.waitDuration, s.WaitDuration.Seconds())
+		counter(c.maxIdleClosed, float64(s.MaxIdleClosed))
+		counter(c.maxIdleTimeClosed, float64(s.MaxIdleTimeClosed))
+		counter(c.maxLifetimeClosed, float64(s.MaxLifetimeClosed))
+	}
+}
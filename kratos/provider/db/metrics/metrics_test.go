@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"kratos-project-template/provider/db/driver"
+	_ "kratos-project-template/provider/db/sqlite3"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// item is the model queried by the tests.
+type item struct {
+	ID   uint64 `gorm:"primaryKey"`
+	Name string
+}
+
+// openDB opens an in-memory SQLite database with the metrics plugin labeled with the
+// test name as connection.
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:metrics_%s?mode=memory&cache=shared", t.Name()), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := gormDB.AutoMigrate(&item{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Use(New(t.Name())); err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+// queries returns the number of queries recorded for the labels.
+func queries(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := queryDuration.WithLabelValues(labels...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestPlugin(t *testing.T) {
+	gormDB := openDB(t)
+	conn := t.Name()
+
+	if err := gormDB.Create(&item{ID: 1, Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Find(&[]item{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Model(&item{ID: 1}).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	if err := gormDB.Table("items").Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Exec("DELETE FROM items WHERE id = ?", 2).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Delete(&item{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		operation, table string
+		want             uint64
+	}{
+		{"create", "items", 1},
+		{"query", "items", 2},
+		{"update", "items", 1},
+		{"delete", "items", 1},
+		{"raw", "", 1},
+	}
+	for _, tt := range tests {
+		if got := queries(t, conn, tt.operation, tt.table); got != tt.want {
+			t.Errorf("%s queries of table %q = %d, want %d", tt.operation, tt.table, got, tt.want)
+		}
+	}
+	if got := testutil.ToFloat64(queryErrors.WithLabelValues(conn, "query", "items")); got != 0 {
+		t.Errorf("query errors = %v, want 0", got)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	gormDB := openDB(t)
+	conn := t.Name()
+
+	// Record not found is not an error.
+	if err := gormDB.First(&item{}, 1).Error; err == nil {
+		t.Fatal("First() of a missing row error = nil")
+	}
+	if err := gormDB.Table("missing").Find(&[]item{}).Error; err == nil {
+		t.Fatal("Find() of a missing table error = nil")
+	}
+
+	if got := testutil.ToFloat64(queryErrors.WithLabelValues(conn, "query", "items")); got != 0 {
+		t.Errorf("errors of record not found = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(queryErrors.WithLabelValues(conn, "query", "missing")); got != 1 {
+		t.Errorf("errors of the missing table = %v, want 1", got)
+	}
+	if got := queries(t, conn, "query", "missing"); got != 1 {
+		t.Errorf("failed queries recorded = %d, want 1", got)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	c := NewStatsCollector(func() []PoolStats {
+		return []PoolStats{
+			{Connection: "default", Pool: "primary", Stats: sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 2, Idle: 1, WaitCount: 4, WaitDuration: 1500 * time.Millisecond}},
+			{Connection: "default", Pool: "replica0", Stats: sql.DBStats{MaxOpenConnections: 5}},
+		}
+	})
+
+	if n := testutil.CollectAndCount(c); n != 18 {
+		t.Errorf("collected %d metrics, want 9 per pool", n)
+	}
+	want := `
+# HELP db_pool_in_use_connections Number of connections in use.
+# TYPE db_pool_in_use_connections gauge
+db_pool_in_use_connections{connection="default",pool="primary"} 2
+db_pool_in_use_connections{connection="default",pool="replica0"} 0
+# HELP db_pool_max_open_connections Maximum number of open connections.
+# TYPE db_pool_max_open_connections gauge
+db_pool_max_open_connections{connection="default",pool="primary"} 10
+db_pool_max_open_connections{connection="default",pool="replica0"} 5
+# HELP db_pool_wait_duration_seconds_total Total time blocked waiting for a connection.
+# TYPE db_pool_wait_duration_seconds_total counter
+db_pool_wait_duration_seconds_total{connection="default",pool="primary"} 1.5
+db_pool_wait_duration_seconds_total{connection="default",pool="replica0"} 0
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"db_pool_in_use_connections", "db_pool_max_open_connections", "db_pool_wait_duration_seconds_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
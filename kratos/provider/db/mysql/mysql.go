@@ -2,11 +2,33 @@
 package mysql
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"kratos-project-template/provider/db/driver"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// init registers the MySQL driver as "mysql" (alias "mariadb").
+func init() {
+	driver.Register("mysql", driver.Driver{
+		Open:      InitDB,
+		OpenLazy:  OpenLazy,
+		Dialector: Dialector,
+		Retryable: Retryable,
+		DSN:       DSN,
+	}, "mariadb")
+}
+
 // InitDB initializes a MySQL database connection using GORM.
 //
 // Parameters:
@@ -17,11 +39,142 @@ import (
 //   - *gorm.DB: A GORM database instance connected to MySQL
 //   - error: Error if connection fails
 func InitDB(source string, logger logger.Interface) (*gorm.DB, error) {
-	return gorm.Open(mysql.Open(source), &gorm.Config{
+	return gorm.Open(mysql.Open(source), gormConfig(logger))
+}
+
+// OpenLazy opens a MySQL database like InitDB but skips server version detection, so it
+// succeeds while the server is down. Version dependent migration features assume a
+// current server.
+//
+// Parameters:
+//   - source: The MySQL data source name (DSN)
+//   - logger: The GORM logger interface for logging database operations
+//
+// Returns:
+//   - *gorm.DB: A GORM database instance that connects on first use
+//   - error: Error if the DSN is invalid
+func OpenLazy(source string, logger logger.Interface) (*gorm.DB, error) {
+	return gorm.Open(Dialector(source, nil), gormConfig(logger))
+}
+
+// gormConfig returns the GORM settings of MySQL connections.
+func gormConfig(logger logger.Interface) *gorm.Config {
+	return &gorm.Config{
 		SkipDefaultTransaction:                   true,
 		AllowGlobalUpdate:                        false,
 		DisableForeignKeyConstraintWhenMigrating: true,
 		Logger:                                   logger,
+		// db.Init pings with its startup context so connecting can be cancelled
+		DisableAutomaticPing: true,
+	}
+}
+
+// Dialector returns a MySQL dialector that skips server version detection, so opening it
+// does not need the server to be reachable. It is used for read replicas.
+//
+// Parameters:
+//   - source: The MySQL data source name (DSN); ignored if conn is set
+//   - conn: An existing connection pool to reuse, or nil to open one from source
+//
+// Returns:
+//   - gorm.Dialector: The MySQL dialector
+func Dialector(source string, conn gorm.ConnPool) gorm.Dialector {
+	return mysql.New(mysql.Config{
+		DSN:                       source,
+		Conn:                      conn,
+		SkipInitializeWithVersion: true,
 	})
 }
 
+// Retryable reports whether err is a deadlock (1213) or lock wait timeout (1205);
+// the transaction was rolled back and can be retried.
+//
+// Parameters:
+//   - err: The error returned by the transaction
+//
+// Returns:
+//   - bool: True if the transaction can be retried
+func Retryable(err error) bool {
+	var myErr *mysqldriver.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	return myErr.Number == 1213 || myErr.Number == 1205
+}
+
+// DSN builds a MySQL data source name from structured settings. Params take the
+// go-sql-driver/mysql parameter names, e.g. charset, parseTime and loc. TLS modes that
+// need certificates register a TLS configuration with the MySQL driver.
+//
+// Parameters:
+//   - dsn: The structured settings; the port defaults to 3306
+//
+// Returns:
+//   - string: The data source name
+//   - error: Error if a parameter or the TLS settings are invalid
+func DSN(dsn driver.DSN) (string, error) {
+	params := url.Values{}
+	for k, v := range dsn.Params {
+		params.Set(k, v)
+	}
+	// Parsing the parameters alone applies them the way the driver does for a DSN string.
+	cfg, err := mysqldriver.ParseDSN("/?" + params.Encode())
+	if err != nil {
+		return "", errors.Wrap(err, "invalid mysql params")
+	}
+	cfg.User = dsn.User
+	cfg.Passwd = dsn.Password
+	cfg.DBName = dsn.DBName
+	switch {
+	case strings.HasPrefix(dsn.Host, "/"):
+		cfg.Net = "unix"
+		cfg.Addr = dsn.Host
+	case dsn.Host != "":
+		port := dsn.Port
+		if port == 0 {
+			port = 3306
+		}
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(dsn.Host, strconv.Itoa(port))
+	}
+
+	tlsName, err := tlsConfigName(dsn)
+	if err != nil {
+		return "", err
+	}
+	if tlsName != "" {
+		cfg.TLSConfig = tlsName
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// tlsConfigName returns the value of the tls parameter for the TLS settings of dsn,
+// registering a custom configuration if needed; empty keeps the parameter as is.
+func tlsConfigName(dsn driver.DSN) (string, error) {
+	t := dsn.TLS
+	custom := t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" || t.ServerName != ""
+	switch {
+	case t.Mode == "":
+		return "", nil
+	case t.Mode == driver.TLSDisable:
+		return "false", nil
+	case t.Mode == driver.TLSPrefer && !custom:
+		return "preferred", nil
+	case t.Mode == driver.TLSRequire && !custom:
+		return "skip-verify", nil
+	case t.Mode == driver.TLSVerifyFull && !custom:
+		return "true", nil
+	}
+
+	tlsCfg, err := t.Config(dsn.Host)
+	if err != nil {
+		return "", err
+	}
+	// The name identifies the settings, so building the same DSN again reuses it.
+	sum := sha256.Sum256([]byte(strings.Join([]string{dsn.Host, t.Mode, t.CAFile, t.CertFile, t.KeyFile, t.ServerName}, "\x00")))
+	name := "dsn-" + hex.EncodeToString(sum[:8])
+	if err := mysqldriver.RegisterTLSConfig(name, tlsCfg); err != nil {
+		return "", errors.Wrap(err, "register mysql tls config")
+	}
+	return name, nil
+}
@@ -0,0 +1,204 @@
+package mysql
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kratos-project-template/provider/db/driver"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// writeTestCA writes a self-signed CA certificate to a temporary file.
+func writeTestCA(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     driver.DSN
+		want    string
+		wantErr string
+	}{
+		{
+			name: "tcp with default port",
+			dsn:  driver.DSN{Host: "db", User: "app", Password: "p@ss:word", DBName: "orders"},
+			want: "app:p@ss:word@tcp(db:3306)/orders",
+		},
+		{
+			name: "ipv6 host and port",
+			dsn:  driver.DSN{Host: "::1", Port: 3307, User: "app", DBName: "orders"},
+			want: "app@tcp([::1]:3307)/orders",
+		},
+		{
+			name: "unix socket",
+			dsn:  driver.DSN{Host: "/var/run/mysqld/mysqld.sock", User: "app", DBName: "orders"},
+			want: "app@unix(/var/run/mysqld/mysqld.sock)/orders",
+		},
+		{
+			name: "params",
+			dsn: driver.DSN{Host: "db", User: "app", DBName: "orders", Params: map[string]string{
+				"charset": "utf8mb4", "parseTime": "true", "loc": "Local", "sql_mode": "'STRICT_ALL_TABLES'",
+			}},
+			want: "app@tcp(db:3306)/orders?loc=Local&parseTime=true&charset=utf8mb4&sql_mode=%27STRICT_ALL_TABLES%27",
+		},
+		{
+			name: "tls param kept without mode",
+			dsn:  driver.DSN{Host: "db", User: "app", DBName: "orders", Params: map[string]string{"tls": "skip-verify"}},
+			want: "app@tcp(db:3306)/orders?tls=skip-verify",
+		},
+		{
+			name: "tls mode overrides param",
+			dsn: driver.DSN{Host: "db", User: "app", DBName: "orders", Params: map[string]string{"tls": "skip-verify"},
+				TLS: driver.TLS{Mode: driver.TLSVerifyFull}},
+			want: "app@tcp(db:3306)/orders?tls=true",
+		},
+		{
+			name: "disable",
+			dsn:  driver.DSN{Host: "db", User: "app", DBName: "orders", TLS: driver.TLS{Mode: driver.TLSDisable}},
+			want: "app@tcp(db:3306)/orders?tls=false",
+		},
+		{
+			name: "prefer",
+			dsn:  driver.DSN{Host: "db", User: "app", DBName: "orders", TLS: driver.TLS{Mode: driver.TLSPrefer}},
+			want: "app@tcp(db:3306)/orders?tls=preferred",
+		},
+		{
+			name: "require",
+			dsn:  driver.DSN{Host: "db", User: "app", DBName: "orders", TLS: driver.TLS{Mode: driver.TLSRequire}},
+			want: "app@tcp(db:3306)/orders?tls=skip-verify",
+		},
+		{
+			name:    "invalid param",
+			dsn:     driver.DSN{Host: "db", Params: map[string]string{"parseTime": "maybe"}},
+			wantErr: "invalid mysql params",
+		},
+		{
+			name:    "unknown tls mode",
+			dsn:     driver.DSN{Host: "db", TLS: driver.TLS{Mode: "strict", ServerName: "db"}},
+			wantErr: `unknown tls mode "strict"`,
+		},
+		{
+			name:    "missing ca file",
+			dsn:     driver.DSN{Host: "db", TLS: driver.TLS{Mode: driver.TLSVerifyCA, CAFile: "/nonexistent/ca.pem"}},
+			wantErr: "read tls ca file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DSN(tt.dsn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DSN() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DSN() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DSN() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDSNCustomTLS(t *testing.T) {
+	ca := writeTestCA(t)
+	tests := []struct {
+		name       string
+		tls        driver.TLS
+		serverName string
+		skipVerify bool
+		rootCAs    bool
+	}{
+		{
+			name:       "verify-full with server name",
+			tls:        driver.TLS{Mode: driver.TLSVerifyFull, ServerName: "mysql.internal"},
+			serverName: "mysql.internal",
+		},
+		{
+			name:       "verify-full with ca",
+			tls:        driver.TLS{Mode: driver.TLSVerifyFull, CAFile: ca},
+			serverName: "db.example.com",
+			rootCAs:    true,
+		},
+		{
+			// The chain is checked by VerifyConnection instead of crypto/tls.
+			name:       "verify-ca",
+			tls:        driver.TLS{Mode: driver.TLSVerifyCA, CAFile: ca},
+			serverName: "db.example.com",
+			skipVerify: true,
+			rootCAs:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn := driver.DSN{Host: "db.example.com", User: "app", DBName: "orders", TLS: tt.tls}
+			got, err := DSN(dsn)
+			if err != nil {
+				t.Fatalf("DSN() error = %v", err)
+			}
+			again, err := DSN(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != got {
+				t.Errorf("DSN() is not stable: %s, %s", got, again)
+			}
+			if !strings.HasPrefix(got, "app@tcp(db.example.com:3306)/orders?tls=dsn-") {
+				t.Fatalf("DSN() = %s, want a registered tls config", got)
+			}
+
+			cfg, err := mysqldriver.ParseDSN(got)
+			if err != nil {
+				t.Fatalf("parse %s: %v", got, err)
+			}
+			if cfg.TLS == nil {
+				t.Fatal("tls config is not registered")
+			}
+			if cfg.TLS.ServerName != tt.serverName {
+				t.Errorf("ServerName = %q, want %q", cfg.TLS.ServerName, tt.serverName)
+			}
+			if cfg.TLS.InsecureSkipVerify != tt.skipVerify {
+				t.Errorf("InsecureSkipVerify = %v, want %v", cfg.TLS.InsecureSkipVerify, tt.skipVerify)
+			}
+			if (cfg.TLS.RootCAs != nil) != tt.rootCAs {
+				t.Errorf("RootCAs set = %v, want %v", cfg.TLS.RootCAs != nil, tt.rootCAs)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// defaultConflictAttempts is the default number of attempts of RetryOnConflict.
+	defaultConflictAttempts = 3
+	// conflictBackoff is the base delay between attempts of RetryOnConflict.
+	conflictBackoff = 10 * time.Millisecond
+)
+
+// ErrConflict is the kratos error of an optimistic lock conflict, encoded by the servers
+// as HTTP 409 and gRPC Aborted. Check for it with errors.Is.
+var ErrConflict = kerrors.Conflict("OPTIMISTIC_LOCK_CONFLICT", "the record was modified by another request, reload it and try again")
+
+// Version is the type of a model's optimistic lock column. Updates with UpdateVersioned
+// (and Repository.Update) only apply if the stored version is still the one that was
+// read, and increment it:
+//
+//	type Article struct {
+//		ID      uint64
+//		Title   string
+//		Version db.Version `gorm:"not null;default:0"`
+//	}
+type Version int64
+
+// ConflictError is returned when a versioned row was changed since it was read.
+// It wraps ErrConflict, so it is encoded as a conflict without exposing its details.
+type ConflictError struct {
+	// Table is the table of the row
+	Table string
+	// Key is the primary key of the row; composite keys are joined with ","
+	Key string
+	// Version is the version the update expected
+	Version int64
+}
+
+// Error implements error.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("optimistic lock conflict: %s %s is no longer at version %d", e.Table, e.Key, e.Version)
+}
+
+// Unwrap returns ErrConflict.
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// UpdateVersioned saves a row loaded earlier if nobody changed it in between. The update
+// matches the primary key and the version of the row and increments the version, which
+// is set on the model on success.
+//
+// Parameters:
+//   - tx: The database, e.g. DB(ctx)
+//   - model: Pointer to the row; the model needs a Version field
+//   - columns: Columns to update; all columns, including zero values, if empty
+//
+// Returns:
+//   - error: *ConflictError if the row has another version, gorm.ErrRecordNotFound if it
+//     no longer exists, or the query error
+func UpdateVersioned(tx *gorm.DB, model interface{}, columns ...string) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return errors.Wrapf(err, "parse model %T", model)
+	}
+	field := versionField(stmt.Schema)
+	if field == nil {
+		return errors.Errorf("model %T has no db.Version field", model)
+	}
+
+	ctx := tx.Statement.Context
+	rv := reflect.ValueOf(model)
+	version := field.ReflectValueOf(ctx, rv)
+	current := version.Int()
+	version.SetInt(current + 1)
+
+	q := tx.Model(model).Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: current})
+	if len(columns) > 0 {
+		q = q.Select(append(append([]string(nil), columns...), field.DBName))
+	} else {
+		q = q.Select("*")
+	}
+	res := q.Updates(model)
+	if res.Error != nil || res.RowsAffected > 0 {
+		if res.Error != nil {
+			version.SetInt(current)
+		}
+		return res.Error
+	}
+	version.SetInt(current)
+
+	// Nothing matched: the row was either changed or deleted.
+	keys, exists, err := rowExists(tx, stmt.Schema, model)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+	return &ConflictError{Table: stmt.Schema.Table, Key: strings.Join(keys, ","), Version: current}
+}
+
+// versionField returns the Version field of a model, or nil.
+func versionField(s *schema.Schema) *schema.Field {
+	versionType := reflect.TypeOf(Version(0))
+	for _, f := range s.Fields {
+		if f.FieldType == versionType && f.DBName != "" {
+			return f
+		}
+	}
+	return nil
+}
+
+// RetryOnConflict runs a read-modify-write function again when it fails with an
+// optimistic lock conflict, after a short random delay. fn must reload the row on every
+// call; do not call RetryOnConflict inside a transaction, whose snapshot would return the
+// same stale row, but start the transaction in fn instead.
+//
+//	err := db.RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+//		article, err := repo.Get(ctx, id)
+//		if err != nil {
+//			return err
+//		}
+//		article.Views++
+//		return repo.Update(ctx, article)
+//	})
+//
+// Parameters:
+//   - ctx: Context passed to fn; cancelling it stops retrying
+//   - attempts: Maximum number of calls of fn; 0 uses the default of 3
+//   - fn: The read-modify-write function
+//
+// Returns:
+//   - error: The error of the last attempt, or ctx.Err() if ctx is done while waiting
+func RetryOnConflict(ctx context.Context, attempts int, fn func(ctx context.Context) error) error {
+	if attempts <= 0 {
+		attempts = defaultConflictAttempts
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(ctx); err == nil || !errors.Is(err, ErrConflict) {
+			return err
+		}
+		if i == attempts-1 {
+			break
+		}
+		// Full jitter spreads out competing writers.
+		delay := time.Duration(rand.Int64N(int64(conflictBackoff << i)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return err
+}
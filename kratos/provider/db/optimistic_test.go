@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// article is a versioned model.
+type article struct {
+	ID      uint64 `gorm:"primaryKey"`
+	Title   string
+	Views   int
+	Version Version `gorm:"not null;default:0"`
+}
+
+// openArticles opens an in-memory SQLite database holding article 1 at version 0.
+func openArticles(t *testing.T) *gorm.DB {
+	t.Helper()
+	gormDB := openSQLite(t, "optimistic_"+t.Name())
+	if err := gormDB.AutoMigrate(&article{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Create(&article{ID: 1, Title: "draft"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+// loadArticle reads article 1.
+func loadArticle(t *testing.T, gormDB *gorm.DB) article {
+	t.Helper()
+	var a article
+	if err := gormDB.First(&a, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	gormDB := openArticles(t)
+
+	a := loadArticle(t, gormDB)
+	stale := a
+	a.Title, a.Views = "published", 0
+	if err := UpdateVersioned(gormDB, &a); err != nil {
+		t.Fatalf("UpdateVersioned() error = %v", err)
+	}
+	if a.Version != 1 {
+		t.Errorf("model version = %d, want 1", a.Version)
+	}
+
+	// Only the given columns are written.
+	a.Title, a.Views = "ignored", 7
+	if err := UpdateVersioned(gormDB, &a, "views"); err != nil {
+		t.Fatalf("UpdateVersioned() of a column error = %v", err)
+	}
+	if got := loadArticle(t, gormDB); got.Title != "published" || got.Views != 7 || got.Version != 2 {
+		t.Errorf("stored article = %+v, want published with 7 views at version 2", got)
+	}
+
+	// A row changed since it was read is not overwritten.
+	stale.Title = "stale"
+	err := UpdateVersioned(gormDB, &stale)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("UpdateVersioned() of a stale row error = %v, want a ConflictError", err)
+	}
+	if *conflict != (ConflictError{Table: "articles", Key: "1", Version: 0}) {
+		t.Errorf("conflict = %+v", conflict)
+	}
+	if stale.Version != 0 {
+		t.Errorf("model version after a conflict = %d, want it unchanged", stale.Version)
+	}
+	if got := loadArticle(t, gormDB); got.Title != "published" {
+		t.Errorf("stored title = %q after a conflict", got.Title)
+	}
+
+	if err := gormDB.Delete(&article{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateVersioned(gormDB, &a); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("UpdateVersioned() of a deleted row error = %v, want ErrRecordNotFound", err)
+	}
+
+	type unversioned struct{ ID uint64 }
+	if err := UpdateVersioned(gormDB, &unversioned{ID: 1}); err == nil {
+		t.Error("UpdateVersioned() of a model without a Version field did not fail")
+	}
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	gormDB := openArticles(t)
+	ctx := context.Background()
+
+	// Another writer wins the first attempt; the retry reloads the row and succeeds.
+	calls := 0
+	err := RetryOnConflict(ctx, 0, func(ctx context.Context) error {
+		calls++
+		a := loadArticle(t, gormDB)
+		if calls == 1 {
+			other := a
+			other.Views = 10
+			if err := UpdateVersioned(gormDB, &other); err != nil {
+				return err
+			}
+		}
+		a.Views++
+		return UpdateVersioned(gormDB, &a)
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("RetryOnConflict() = %v after %d calls, want success after 2", err, calls)
+	}
+	if got := loadArticle(t, gormDB); got.Views != 11 || got.Version != 2 {
+		t.Errorf("stored article = %+v, want 11 views at version 2", got)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		attempts int
+		err      error
+		calls    int
+		want     error
+	}{
+		{name: "default attempts", ctx: ctx, err: &ConflictError{}, calls: 3, want: ErrConflict},
+		{name: "attempts", ctx: ctx, attempts: 5, err: &ConflictError{}, calls: 5, want: ErrConflict},
+		{name: "other errors", ctx: ctx, attempts: 5, err: gorm.ErrRecordNotFound, calls: 1, want: gorm.ErrRecordNotFound},
+		{name: "cancelled", ctx: cancelled(), attempts: 5, err: &ConflictError{}, calls: 1, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := RetryOnConflict(tt.ctx, tt.attempts, func(context.Context) error {
+				calls++
+				return tt.err
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("RetryOnConflict() error = %v, want %v", err, tt.want)
+			}
+			if calls != tt.calls {
+				t.Errorf("fn ran %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+// cancelled returns a cancelled context.
+func cancelled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
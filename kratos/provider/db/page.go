@@ -0,0 +1,69 @@
+package db
+
+import (
+	commonv1 "kratos-project-template/api/common/v1"
+)
+
+// operators maps the API filter operators to repository operators.
+var operators = map[commonv1.Operator]Op{
+	commonv1.Operator_OPERATOR_UNSPECIFIED: OpEq,
+	commonv1.Operator_EQ:                   OpEq,
+	commonv1.Operator_NE:                   OpNe,
+	commonv1.Operator_GT:                   OpGt,
+	commonv1.Operator_GTE:                  OpGte,
+	commonv1.Operator_LT:                   OpLt,
+	commonv1.Operator_LTE:                  OpLte,
+	commonv1.Operator_IN:                   OpIn,
+	commonv1.Operator_CONTAINS:             OpContains,
+}
+
+// QueryFromPageRequest converts the shared API page request to a repository query,
+// so HTTP and gRPC list endpoints expose filtering, sorting and pagination the same way.
+// Field whitelisting happens in Repository.List.
+//
+// Parameters:
+//   - req: The page request of a list endpoint (may be nil)
+//
+// Returns:
+//   - *Query: The repository query
+func QueryFromPageRequest(req *commonv1.PageRequest) *Query {
+	q := &Query{
+		Page:     int(req.GetPage()),
+		PageSize: int(req.GetPageSize()),
+		Cursor:   req.GetPageToken(),
+	}
+	for _, st := range req.GetSort() {
+		q.Sorts = append(q.Sorts, Sort{Field: st.GetField(), Desc: st.GetDesc()})
+	}
+	for _, f := range req.GetFilters() {
+		op, ok := operators[f.GetOp()]
+		if !ok {
+			op = Op(f.GetOp().String())
+		}
+		var value any = f.GetValue()
+		if op == OpIn {
+			value = f.GetValues()
+		}
+		q.Filters = append(q.Filters, Filter{Field: f.GetField(), Op: op, Value: value})
+	}
+	return q
+}
+
+// PageResponse converts the page returned by Repository.List to the shared API message.
+//
+// Parameters:
+//   - p: The page description (may be nil)
+//
+// Returns:
+//   - *commonv1.PageResponse: The page response of a list endpoint
+func PageResponse(p *Page) *commonv1.PageResponse {
+	if p == nil {
+		return &commonv1.PageResponse{}
+	}
+	return &commonv1.PageResponse{
+		Total:         p.Total,
+		Page:          int32(p.Page),
+		PageSize:      int32(p.PageSize),
+		NextPageToken: p.NextCursor,
+	}
+}
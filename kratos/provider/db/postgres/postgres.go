@@ -2,11 +2,29 @@
 package postgres
 
 import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"kratos-project-template/provider/db/driver"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// init registers the PostgreSQL driver as "postgres" (aliases "postgresql", "postgre", "pg").
+func init() {
+	driver.Register("postgres", driver.Driver{
+		Open:      InitDB,
+		Dialector: Dialector,
+		Retryable: Retryable,
+		DSN:       DSN,
+	}, "postgresql", "postgre", "pg")
+}
+
 // InitDB initializes a PostgreSQL database connection using GORM.
 //
 // Parameters:
@@ -21,6 +39,93 @@ func InitDB(source string, logger logger.Interface) (*gorm.DB, error) {
 		&gorm.Config{
 			SkipDefaultTransaction: true,
 			Logger:                 logger,
+			// db.Init pings with its startup context so connecting can be cancelled
+			DisableAutomaticPing: true,
 		})
 }
 
+// Dialector returns a PostgreSQL dialector. It is used for read replicas.
+//
+// Parameters:
+//   - source: The PostgreSQL data source name (DSN); ignored if conn is set
+//   - conn: An existing connection pool to reuse, or nil to open one from source
+//
+// Returns:
+//   - gorm.Dialector: The PostgreSQL dialector
+func Dialector(source string, conn gorm.ConnPool) gorm.Dialector {
+	return postgres.New(postgres.Config{
+		DSN:  source,
+		Conn: conn,
+	})
+}
+
+// Retryable reports whether err is a serialization failure (40001) or deadlock (40P01);
+// the transaction was rolled back and can be retried.
+//
+// Parameters:
+//   - err: The error returned by the transaction
+//
+// Returns:
+//   - bool: True if the transaction can be retried
+func Retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// DSN builds a PostgreSQL keyword/value data source name from structured settings.
+// Params take libpq parameter names, e.g. TimeZone or application_name; the TLS mode
+// maps to sslmode and the certificate files to sslrootcert, sslcert and sslkey.
+//
+// Parameters:
+//   - dsn: The structured settings; an empty host or port uses the pgx defaults
+//
+// Returns:
+//   - string: The data source name
+//   - error: Error if the TLS settings are invalid
+func DSN(dsn driver.DSN) (string, error) {
+	t := dsn.TLS
+	switch t.Mode {
+	case "", driver.TLSDisable, driver.TLSPrefer, driver.TLSRequire, driver.TLSVerifyCA, driver.TLSVerifyFull:
+	default:
+		return "", errors.Errorf("unknown tls mode %q", t.Mode)
+	}
+	if t.ServerName != "" {
+		return "", errors.New("tls server_name is not supported by postgres")
+	}
+
+	var pairs []string
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, key+"="+quote(value))
+		}
+	}
+	add("host", dsn.Host)
+	if dsn.Port != 0 {
+		add("port", strconv.Itoa(dsn.Port))
+	}
+	add("user", dsn.User)
+	add("password", dsn.Password)
+	add("dbname", dsn.DBName)
+	add("sslmode", t.Mode)
+	add("sslrootcert", t.CAFile)
+	add("sslcert", t.CertFile)
+	add("sslkey", t.KeyFile)
+
+	keys := make([]string, 0, len(dsn.Params))
+	for k := range dsn.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, dsn.Params[k])
+	}
+	return strings.Join(pairs, " "), nil
+}
+
+// quote quotes a keyword/value parameter value, escaping quotes and backslashes.
+func quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"kratos-project-template/provider/db/driver"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     driver.DSN
+		want    string
+		wantErr string
+	}{
+		{
+			name: "basic",
+			dsn:  driver.DSN{Host: "db", Port: 5432, User: "app", Password: "secret", DBName: "orders"},
+			want: "host='db' port='5432' user='app' password='secret' dbname='orders'",
+		},
+		{
+			name: "quoted values",
+			dsn:  driver.DSN{Host: "db", User: "app", Password: `it's a \ secret`, DBName: "orders"},
+			want: `host='db' user='app' password='it\'s a \\ secret' dbname='orders'`,
+		},
+		{
+			name: "socket directory and sorted params",
+			dsn:  driver.DSN{Host: "/var/run/postgresql", DBName: "orders", Params: map[string]string{"TimeZone": "UTC", "application_name": "api"}},
+			want: "host='/var/run/postgresql' dbname='orders' TimeZone='UTC' application_name='api'",
+		},
+		{
+			name: "verify-full",
+			dsn: driver.DSN{Host: "db", DBName: "orders", TLS: driver.TLS{
+				Mode: driver.TLSVerifyFull, CAFile: "/etc/ssl/ca.pem", CertFile: "/etc/ssl/client.pem", KeyFile: "/etc/ssl/client.key",
+			}},
+			want: "host='db' dbname='orders' sslmode='verify-full' sslrootcert='/etc/ssl/ca.pem' sslcert='/etc/ssl/client.pem' sslkey='/etc/ssl/client.key'",
+		},
+		{
+			name: "disable",
+			dsn:  driver.DSN{Host: "db", DBName: "orders", TLS: driver.TLS{Mode: driver.TLSDisable}},
+			want: "host='db' dbname='orders' sslmode='disable'",
+		},
+		{
+			name:    "unknown tls mode",
+			dsn:     driver.DSN{Host: "db", TLS: driver.TLS{Mode: "strict"}},
+			wantErr: `unknown tls mode "strict"`,
+		},
+		{
+			name:    "server name",
+			dsn:     driver.DSN{Host: "db", TLS: driver.TLS{Mode: driver.TLSVerifyFull, ServerName: "pg.internal"}},
+			wantErr: "server_name is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DSN(tt.dsn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DSN() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DSN() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DSN() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
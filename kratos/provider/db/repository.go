@@ -0,0 +1,584 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultPageSize = 20
+	defaultMaxPage  = 100
+)
+
+// ErrInvalidQuery is the kratos error returned by List for filters or sorts on fields that
+// are not whitelisted, unknown operators and malformed cursors, encoded by the servers as
+// HTTP 400 and gRPC InvalidArgument. It is wrapped with the details, check for it with
+// errors.Is.
+var ErrInvalidQuery = kerrors.BadRequest("INVALID_QUERY", "invalid query")
+
+// Op is a filter comparison operator.
+type Op string
+
+// Filter operators.
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpIn       Op = "in"
+	OpContains Op = "contains"
+)
+
+// Filter restricts a list to rows whose field matches a value.
+type Filter struct {
+	// Field is the column name
+	Field string
+	// Op is the comparison, OpEq if empty
+	Op Op
+	// Value is compared with the field; a slice for OpIn
+	Value any
+}
+
+// Sort orders a list by a field.
+type Sort struct {
+	// Field is the column name
+	Field string
+	// Desc selects descending order
+	Desc bool
+}
+
+// Query describes a list request: filters, sort order and page.
+// Use Cursor for keyset pagination or Page for offset pagination.
+type Query struct {
+	// Filters are combined with AND; fields must be whitelisted with FilterFields
+	Filters []Filter
+	// Sorts is the order; fields must be whitelisted with SortFields
+	Sorts []Sort
+	// Page is the 1-based page number for offset pagination
+	Page int
+	// PageSize is the max number of items, capped by the repository
+	PageSize int
+	// Cursor is the NextCursor of the previous page; takes precedence over Page
+	Cursor string
+	// Scopes add trusted conditions, e.g. restricting a list to the current user
+	Scopes []func(*gorm.DB) *gorm.DB
+}
+
+// Page describes the page returned by List.
+type Page struct {
+	// Total is the number of matching rows (offset pagination only)
+	Total int64
+	// Page is the page number (offset pagination only)
+	Page int
+	// PageSize is the applied page size
+	PageSize int
+	// NextCursor fetches the next page with keyset pagination, empty on the last page
+	NextCursor string
+}
+
+// Repository implements CRUD and list queries for a GORM model.
+// It uses DB(ctx), so calls made inside Transaction join the transaction.
+type Repository[T any] struct {
+	conn        string
+	sharded     bool
+	filters     map[string]bool
+	sorts       map[string]bool
+	defaultSort []Sort
+	pageSize    int
+	maxPageSize int
+
+	schemaOnce sync.Once
+	schema     *schema.Schema
+	schemaErr  error
+}
+
+// RepositoryOption configures a Repository.
+type RepositoryOption func(*repositoryOptions)
+
+// repositoryOptions holds the settings of a Repository.
+type repositoryOptions struct {
+	conn        string
+	sharded     bool
+	filters     []string
+	sorts       []string
+	defaultSort []Sort
+	pageSize    int
+	maxPageSize int
+}
+
+// RepoConnection uses a named connection instead of the default one.
+func RepoConnection(name string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.conn = name
+	}
+}
+
+// RepoSharded routes every call to the shard of the key in ctx (see ShardDB). Queries on
+// the table outside the repository must use ShardDB, or DB(ctx) with the table listed in
+// data.sharding.tables.
+func RepoSharded() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.sharded = true
+	}
+}
+
+// FilterFields whitelists the columns that List may filter on.
+func FilterFields(columns ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.filters = append(o.filters, columns...)
+	}
+}
+
+// SortFields whitelists the columns that List may sort on.
+func SortFields(columns ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.sorts = append(o.sorts, columns...)
+	}
+}
+
+// DefaultSort sets the order used when a query has no sorts (default: primary key ascending).
+func DefaultSort(sorts ...Sort) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.defaultSort = sorts
+	}
+}
+
+// PageSize sets the default and maximum page size (default 20 and 100).
+func PageSize(def, max int) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.pageSize = def
+		o.maxPageSize = max
+	}
+}
+
+// NewRepository creates a repository for the model T.
+//
+// Parameters:
+//   - opts: Optional settings, e.g. the filter and sort whitelists
+//
+// Returns:
+//   - *Repository[T]: A new repository
+func NewRepository[T any](opts ...RepositoryOption) *Repository[T] {
+	o := repositoryOptions{conn: DefaultName, pageSize: defaultPageSize, maxPageSize: defaultMaxPage}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Repository[T]{
+		conn:        o.conn,
+		sharded:     o.sharded,
+		filters:     make(map[string]bool, len(o.filters)),
+		sorts:       make(map[string]bool, len(o.sorts)),
+		defaultSort: o.defaultSort,
+		pageSize:    o.pageSize,
+		maxPageSize: o.maxPageSize,
+	}
+	for _, f := range o.filters {
+		r.filters[f] = true
+	}
+	for _, f := range o.sorts {
+		r.sorts[f] = true
+	}
+	return r
+}
+
+// DB returns the database of the repository bound to ctx, joining a transaction in ctx.
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	if r.sharded {
+		return ShardDB(ctx)
+	}
+	return DB(ctx, r.conn)
+}
+
+// Create inserts a row.
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - v: The row to insert; generated fields such as the primary key are set on it
+//
+// Returns:
+//   - error: Error if the insert fails
+func (r *Repository[T]) Create(ctx context.Context, v *T) error {
+	return r.DB(ctx).Create(v).Error
+}
+
+// Get loads a row by primary key.
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - id: The primary key
+//
+// Returns:
+//   - *T: The row
+//   - error: gorm.ErrRecordNotFound if no row matches, or the query error
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	v := new(T)
+	if err := r.DB(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Take(v).Error; err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Update saves a row by its primary key. Models with a Version field are updated with
+// UpdateVersioned, so concurrent changes are reported instead of overwritten.
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - v: The row with its primary key set
+//   - columns: Columns to update; all columns, including zero values, if empty
+//
+// Returns:
+//   - error: *ConflictError if a versioned row was changed since it was read,
+//     gorm.ErrRecordNotFound if no row matches, or the query error
+func (r *Repository[T]) Update(ctx context.Context, v *T, columns ...string) error {
+	s, err := r.modelSchema(ctx)
+	if err != nil {
+		return err
+	}
+	if versionField(s) != nil {
+		return UpdateVersioned(r.DB(ctx), v, columns...)
+	}
+
+	tx := r.DB(ctx).Model(v)
+	if len(columns) > 0 {
+		tx = tx.Select(columns)
+	} else {
+		tx = tx.Select("*")
+	}
+	tx = tx.Updates(v)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		// MySQL counts changed rows, so an update that changes nothing affects none.
+		_, exists, err := rowExists(r.DB(ctx), s, v)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// rowExists reports whether the row with the primary key of model exists, for updates
+// that affected no rows.
+//
+// Parameters:
+//   - tx: The database, e.g. DB(ctx)
+//   - s: The schema of the model
+//   - model: Pointer to the row with its primary key set
+//
+// Returns:
+//   - []string: The primary key values, for error messages
+//   - bool: True if the row exists
+//   - error: The query error
+func rowExists(tx *gorm.DB, s *schema.Schema, model interface{}) ([]string, bool, error) {
+	ctx := tx.Statement.Context
+	rv := reflect.ValueOf(model)
+	keys := make([]string, 0, len(s.PrimaryFields))
+	q := tx.Session(&gorm.Session{NewDB: true}).WithContext(ctx).Model(reflect.New(s.ModelType).Interface())
+	for _, pf := range s.PrimaryFields {
+		v, _ := pf.ValueOf(ctx, rv)
+		keys = append(keys, fmt.Sprint(v))
+		q = q.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pf.DBName}, Value: v})
+	}
+	var n int64
+	if err := q.Count(&n).Error; err != nil {
+		return keys, false, err
+	}
+	return keys, n > 0, nil
+}
+
+// Delete deletes a row by primary key (a soft delete for models with gorm.DeletedAt).
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - id: The primary key
+//
+// Returns:
+//   - error: gorm.ErrRecordNotFound if no row matches, or the query error
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	tx := r.DB(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// List returns a page of rows matching the query.
+// The primary key is appended to the sort order so pages are stable. Keyset pagination
+// skips rows with NULL in a sort column, so whitelist only NOT NULL columns for sorting.
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - q: The filters, sort order and page; nil lists the first page
+//
+// Returns:
+//   - []*T: The rows of the page
+//   - *Page: The page description with the total (offset) or next cursor (keyset)
+//   - error: ErrInvalidQuery for a query that is not allowed, or the query error
+func (r *Repository[T]) List(ctx context.Context, q *Query) ([]*T, *Page, error) {
+	if q == nil {
+		q = &Query{}
+	}
+	s, err := r.modelSchema(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx := r.DB(ctx).Model(new(T))
+	for _, scope := range q.Scopes {
+		tx = scope(tx)
+	}
+	for _, f := range q.Filters {
+		expr, err := r.filterExpr(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		tx = tx.Where(expr)
+	}
+
+	sorts, err := r.sortOrder(s, q.Sorts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := q.PageSize
+	if size <= 0 {
+		size = r.pageSize
+	}
+	if size > r.maxPageSize {
+		size = r.maxPageSize
+	}
+	page := &Page{PageSize: size}
+
+	if q.Cursor != "" || q.Page <= 0 {
+		// Keyset pagination
+		if q.Cursor != "" {
+			expr, err := keysetExpr(s, sorts, q.Cursor)
+			if err != nil {
+				return nil, nil, err
+			}
+			tx = tx.Where(expr)
+		}
+	} else {
+		// Offset pagination
+		if err := tx.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+			return nil, nil, err
+		}
+		page.Page = q.Page
+		tx = tx.Offset((q.Page - 1) * size)
+	}
+
+	for _, st := range sorts {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: st.Field}, Desc: st.Desc})
+	}
+
+	var items []*T
+	if err := tx.Limit(size + 1).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(items) > size {
+		items = items[:size]
+		if page.Page == 0 {
+			if page.NextCursor, err = encodeCursor(ctx, s, sorts, items[size-1]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return items, page, nil
+}
+
+// modelSchema parses the schema of T once.
+func (r *Repository[T]) modelSchema(ctx context.Context) (*schema.Schema, error) {
+	r.schemaOnce.Do(func() {
+		stmt := &gorm.Statement{DB: r.DB(ctx)}
+		r.schemaErr = stmt.Parse(new(T))
+		r.schema = stmt.Schema
+	})
+	if r.schemaErr != nil {
+		return nil, errors.Wrap(r.schemaErr, "parse model schema")
+	}
+	return r.schema, nil
+}
+
+// filterExpr converts a whitelisted filter to a clause expression.
+func (r *Repository[T]) filterExpr(f Filter) (clause.Expression, error) {
+	if !r.filters[f.Field] {
+		return nil, errors.Wrapf(ErrInvalidQuery, "filter on field %q is not allowed", f.Field)
+	}
+
+	col := clause.Column{Name: f.Field}
+	switch f.Op {
+	case "", OpEq:
+		return clause.Eq{Column: col, Value: f.Value}, nil
+	case OpNe:
+		return clause.Neq{Column: col, Value: f.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: col, Value: f.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: col, Value: f.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: col, Value: f.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: col, Value: f.Value}, nil
+	case OpIn:
+		rv := reflect.ValueOf(f.Value)
+		if rv.Kind() != reflect.Slice {
+			return nil, errors.Wrapf(ErrInvalidQuery, "filter %q: in needs a list of values", f.Field)
+		}
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return clause.IN{Column: col, Values: values}, nil
+	case OpContains:
+		s, ok := f.Value.(string)
+		if !ok {
+			return nil, errors.Wrapf(ErrInvalidQuery, "filter %q: contains needs a string", f.Field)
+		}
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []any{col, "%" + likeEscaper.Replace(s) + "%"}}, nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidQuery, "unknown filter operator %q", f.Op)
+	}
+}
+
+// likeEscaper escapes LIKE wildcards with "!", which needs no quoting in any dialect.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// sortOrder validates the sorts and appends the primary key as tie-breaker.
+func (r *Repository[T]) sortOrder(s *schema.Schema, sorts []Sort) ([]Sort, error) {
+	if len(sorts) == 0 {
+		sorts = r.defaultSort
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, errors.Errorf("model %s has no primary key", s.Name)
+	}
+
+	order := make([]Sort, 0, len(sorts)+1)
+	hasPK := false
+	for _, st := range sorts {
+		if st.Field == pk.DBName {
+			hasPK = true
+		} else if !r.sorts[st.Field] && !isDefaultSort(r.defaultSort, st.Field) {
+			return nil, errors.Wrapf(ErrInvalidQuery, "sort on field %q is not allowed", st.Field)
+		}
+		if s.LookUpField(st.Field) == nil {
+			return nil, errors.Wrapf(ErrInvalidQuery, "unknown sort field %q", st.Field)
+		}
+		order = append(order, st)
+	}
+	if !hasPK {
+		order = append(order, Sort{Field: pk.DBName})
+	}
+	return order, nil
+}
+
+// isDefaultSort reports whether field is part of the default order.
+func isDefaultSort(sorts []Sort, field string) bool {
+	for _, st := range sorts {
+		if st.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// cursor is the decoded keyset pagination cursor.
+type cursor struct {
+	// Order is the sort order the cursor was created with
+	Order string `json:"o"`
+	// Values are the sort column values of the last row of the page
+	Values []json.RawMessage `json:"v"`
+}
+
+// orderKey identifies a sort order, so a cursor is not reused with another order.
+func orderKey(sorts []Sort) string {
+	parts := make([]string, len(sorts))
+	for i, st := range sorts {
+		parts[i] = st.Field
+		if st.Desc {
+			parts[i] += " desc"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// encodeCursor builds the cursor pointing after item.
+func encodeCursor(ctx context.Context, s *schema.Schema, sorts []Sort, item any) (string, error) {
+	c := cursor{Order: orderKey(sorts)}
+	rv := reflect.ValueOf(item)
+	for _, st := range sorts {
+		v, _ := s.LookUpField(st.Field).ValueOf(ctx, rv)
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrapf(err, "encode cursor field %s", st.Field)
+		}
+		c.Values = append(c.Values, raw)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "encode cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// keysetExpr decodes a cursor into the condition selecting the rows after it:
+// (a > va) OR (a = va AND b > vb) OR ..., with < for descending columns.
+func keysetExpr(s *schema.Schema, sorts []Sort, token string) (clause.Expression, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidQuery, "malformed cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || len(c.Values) != len(sorts) {
+		return nil, errors.Wrap(ErrInvalidQuery, "malformed cursor")
+	}
+	if c.Order != orderKey(sorts) {
+		return nil, errors.Wrap(ErrInvalidQuery, "cursor was created with a different sort order")
+	}
+
+	values := make([]any, len(sorts))
+	for i, st := range sorts {
+		v := reflect.New(s.LookUpField(st.Field).FieldType)
+		if err := json.Unmarshal(c.Values[i], v.Interface()); err != nil {
+			return nil, errors.Wrap(ErrInvalidQuery, "malformed cursor")
+		}
+		values[i] = v.Elem().Interface()
+	}
+
+	var or []clause.Expression
+	for i, st := range sorts {
+		and := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, clause.Eq{Column: clause.Column{Name: sorts[j].Field}, Value: values[j]})
+		}
+		col := clause.Column{Name: st.Field}
+		if st.Desc {
+			and = append(and, clause.Lt{Column: col, Value: values[i]})
+		} else {
+			and = append(and, clause.Gt{Column: col, Value: values[i]})
+		}
+		or = append(or, clause.And(and...))
+	}
+	return clause.Or(or...), nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/pkg/errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type cursorItem struct {
+	ID        uint64
+	Name      string
+	Score     float64
+	CreatedAt time.Time
+}
+
+// dryRunMySQL returns a MySQL database that renders statements without a server.
+func dryRunMySQL(t *testing.T) *gorm.DB {
+	t.Helper()
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/d", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	gormDB := dryRunMySQL(t)
+	s, err := schema.Parse(&cursorItem{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 600, time.UTC)
+	item := &cursorItem{ID: 42, Name: "alice", Score: 1.5, CreatedAt: created}
+
+	tests := []struct {
+		name  string
+		sorts []Sort
+		sql   string
+		vars  []any
+	}{
+		{
+			name:  "primary key",
+			sorts: []Sort{{Field: "id"}},
+			sql:   "`id` > ?",
+			vars:  []any{uint64(42)},
+		},
+		{
+			name:  "descending string then key",
+			sorts: []Sort{{Field: "name", Desc: true}, {Field: "id"}},
+			sql:   "(`name` < ? OR (`name` = ? AND `id` > ?))",
+			vars:  []any{"alice", "alice", uint64(42)},
+		},
+		{
+			name:  "float and time",
+			sorts: []Sort{{Field: "score"}, {Field: "created_at", Desc: true}},
+			sql:   "(`score` > ? OR (`score` = ? AND `created_at` < ?))",
+			vars:  []any{1.5, 1.5, created},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := encodeCursor(context.Background(), s, tt.sorts, item)
+			if err != nil {
+				t.Fatalf("encodeCursor() error = %v", err)
+			}
+			expr, err := keysetExpr(s, tt.sorts, token)
+			if err != nil {
+				t.Fatalf("keysetExpr() error = %v", err)
+			}
+			stmt := gormDB.Where(expr).Find(&[]cursorItem{}).Statement
+			want := "SELECT * FROM `cursor_items` WHERE " + tt.sql
+			if got := stmt.SQL.String(); got != want {
+				t.Errorf("SQL = %s, want %s", got, want)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.vars) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestKeysetExprInvalid(t *testing.T) {
+	s, err := schema.Parse(&cursorItem{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorts := []Sort{{Field: "name"}, {Field: "id"}}
+	valid, err := encodeCursor(context.Background(), s, sorts, &cursorItem{ID: 1, Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		sorts []Sort
+		token string
+		want  string
+	}{
+		{name: "not base64", sorts: sorts, token: "!!!", want: "malformed cursor"},
+		{name: "not json", sorts: sorts, token: encode("{"), want: "malformed cursor"},
+		{name: "value count", sorts: sorts, token: encode(`{"o":"name,id","v":["a"]}`), want: "malformed cursor"},
+		{name: "value type", sorts: sorts, token: encode(`{"o":"name,id","v":["a","x"]}`), want: "malformed cursor"},
+		{name: "other order", sorts: []Sort{{Field: "name", Desc: true}, {Field: "id"}}, token: valid, want: "different sort order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := keysetExpr(s, tt.sorts, tt.token)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("keysetExpr() error = %v, want ErrInvalidQuery", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("keysetExpr() error = %v, want %q", err, tt.want)
+			}
+			if se := kerrors.FromError(err); se.Code != 400 || se.Reason != "INVALID_QUERY" {
+				t.Errorf("keysetExpr() error is encoded as %d %s, want 400 INVALID_QUERY", se.Code, se.Reason)
+			}
+		})
+	}
+}
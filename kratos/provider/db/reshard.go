@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultReshardBatch is the default number of rows read per batch by Reshard.
+const defaultReshardBatch = 500
+
+// ReshardOptions selects the rows moved by Reshard.
+type ReshardOptions struct {
+	// Table is the sharded table
+	Table string
+	// KeyColumn is the column holding the shard key
+	KeyColumn string
+	// PrimaryKey is the single-column primary key of the table (default "id"); it must be
+	// unique across all shards, e.g. a UUID or snowflake ID rather than a per-database
+	// auto-increment
+	PrimaryKey string
+	// Sources are extra connections to drain, e.g. a shard being removed from data.sharding.shards
+	Sources []string
+	// BatchSize is the number of rows read per batch (default 500)
+	BatchSize int
+	// DryRun only counts the rows that would move
+	DryRun bool
+}
+
+// ReshardResult reports the rows of a source shard.
+type ReshardResult struct {
+	// Shard is the source connection
+	Shard string
+	// Scanned is the number of rows read
+	Scanned int
+	// Moved is the number of rows on the wrong shard, by destination shard
+	Moved map[string]int
+}
+
+// Reshard moves the rows of a table to the shard their key maps to under the current
+// sharding configuration, e.g. after adding a shard or changing range bounds. Every
+// batch is first inserted into the destination shard, skipping rows that exist there
+// with the same values, and then deleted from the source, so an interrupted run is
+// completed by running it again. A destination row with the same primary key but other
+// values stops the run before the source row is deleted: primary keys must be unique
+// across shards. Writes to the table should be paused while it runs.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - opts: The table and key column to reshard
+//
+// Returns:
+//   - []ReshardResult: One result per source shard
+//   - error: Error if sharding is disabled, a key cannot be mapped, a primary key is used
+//     by another row on the destination shard or a statement fails
+func Reshard(ctx context.Context, opts ReshardOptions) ([]ReshardResult, error) {
+	s := currentSharding()
+	if s == nil {
+		return nil, errors.New("sharding is not enabled")
+	}
+	if opts.Table == "" || opts.KeyColumn == "" {
+		return nil, errors.New("reshard needs a table and a key column")
+	}
+	if opts.PrimaryKey == "" {
+		opts.PrimaryKey = "id"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultReshardBatch
+	}
+	ctx = tenant.SkipTenant(ctx)
+
+	sources := append([]string(nil), s.shards...)
+	for _, name := range opts.Sources {
+		if lookup(name) == nil {
+			return nil, errors.Errorf("source database %q is not initialized", name)
+		}
+		if !slices.Contains(sources, name) {
+			sources = append(sources, name)
+		}
+	}
+
+	results := make([]ReshardResult, 0, len(sources))
+	for _, source := range sources {
+		res, err := reshardSource(ctx, s, source, opts)
+		results = append(results, res)
+		if err != nil {
+			return results, errors.Wrapf(err, "shard %q", source)
+		}
+	}
+	return results, nil
+}
+
+// reshardSource moves the misplaced rows of one source shard.
+func reshardSource(ctx context.Context, s *sharding, source string, opts ReshardOptions) (ReshardResult, error) {
+	res := ReshardResult{Shard: source, Moved: map[string]int{}}
+	src := Get(source).WithContext(pinShard(ctx, source))
+	pk := clause.Column{Name: opts.PrimaryKey}
+
+	var last interface{}
+	for {
+		var rows []map[string]interface{}
+		query := src.Table(opts.Table).Order(clause.OrderByColumn{Column: pk}).Limit(opts.BatchSize)
+		if last != nil {
+			query = query.Where(clause.Gt{Column: pk, Value: last})
+		}
+		if err := query.Find(&rows).Error; err != nil {
+			return res, errors.Wrap(err, "read batch")
+		}
+		if len(rows) == 0 {
+			return res, nil
+		}
+		last = rows[len(rows)-1][opts.PrimaryKey]
+		res.Scanned += len(rows)
+
+		moves := map[string][]map[string]interface{}{}
+		for _, row := range rows {
+			key := row[opts.KeyColumn]
+			if b, ok := key.([]byte); ok {
+				key = string(b)
+			}
+			dest, err := s.shardFor(fmt.Sprint(key))
+			if err != nil {
+				return res, errors.Wrapf(err, "row %v", row[opts.PrimaryKey])
+			}
+			if dest != source {
+				moves[dest] = append(moves[dest], row)
+			}
+		}
+
+		for dest, batch := range moves {
+			res.Moved[dest] += len(batch)
+			if opts.DryRun {
+				continue
+			}
+			if err := copyRows(Get(dest).WithContext(pinShard(ctx, dest)), opts.Table, opts.PrimaryKey, batch); err != nil {
+				return res, errors.Wrapf(err, "copy %d rows to %q", len(batch), dest)
+			}
+			ids := make([]interface{}, len(batch))
+			for i, row := range batch {
+				ids[i] = row[opts.PrimaryKey]
+			}
+			err := src.Session(&gorm.Session{NewDB: true}).Table(opts.Table).
+				Where(clause.IN{Column: pk, Values: ids}).Delete(nil).Error
+			if err != nil {
+				return res, errors.Wrapf(err, "delete %d moved rows", len(batch))
+			}
+		}
+		if len(rows) < opts.BatchSize {
+			return res, nil
+		}
+	}
+}
+
+// copyRows inserts rows into table. Rows whose primary key exists are skipped if the
+// existing row has the same values, i.e. it was copied by an interrupted run; otherwise
+// the keys collide and nothing is inserted, so the caller keeps the source rows.
+func copyRows(tx *gorm.DB, table, primaryKey string, rows []map[string]interface{}) error {
+	pk := clause.Column{Name: primaryKey}
+	ids := make([]interface{}, len(rows))
+	for i, row := range rows {
+		ids[i] = row[primaryKey]
+	}
+	var existing []map[string]interface{}
+	err := tx.Session(&gorm.Session{NewDB: true}).Table(table).
+		Where(clause.IN{Column: pk, Values: ids}).Find(&existing).Error
+	if err != nil {
+		return errors.Wrap(err, "read existing rows")
+	}
+	byID := make(map[string]map[string]interface{}, len(existing))
+	for _, row := range existing {
+		byID[columnString(row[primaryKey])] = row
+	}
+
+	missing := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		cur, ok := byID[columnString(row[primaryKey])]
+		if !ok {
+			missing = append(missing, row)
+			continue
+		}
+		if !sameRow(row, cur) {
+			return errors.Errorf("%s %v exists with other values; primary keys must be unique across shards",
+				primaryKey, row[primaryKey])
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Table(table).Create(&missing).Error
+}
+
+// sameRow reports whether two rows read from a table have the same column values.
+func sameRow(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for col, v := range a {
+		w, ok := b[col]
+		if !ok || columnString(v) != columnString(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnString formats a column value for comparison; drivers return text as []byte
+// or string depending on the column type.
+func columnString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "\x00null"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"kratos-project-template/provider/db/driver"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openSQLite opens an in-memory SQLite database closed when the test ends.
+func openSQLite(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return gormDB
+}
+
+// useShards registers the databases as shard connections with key mod n routing until
+// the test ends.
+func useShards(t *testing.T, shards map[string]*gorm.DB, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Cleanup(Set(name, shards[name], log.NewStdLogger(io.Discard)))
+	}
+	gShardingMu.Lock()
+	gSharding = &sharding{shards: names, fn: func(key string, n int) (int, error) {
+		k, err := strconv.Atoi(key)
+		return k % n, err
+	}}
+	gShardingMu.Unlock()
+	t.Cleanup(func() {
+		gShardingMu.Lock()
+		gSharding = nil
+		gShardingMu.Unlock()
+	})
+}
+
+// orderRows returns the "id:customer_id" pairs of the orders table.
+func orderRows(t *testing.T, gormDB *gorm.DB) []string {
+	t.Helper()
+	var rows []struct{ ID, CustomerID int }
+	if err := gormDB.Table("orders").Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := []string{}
+	for _, r := range rows {
+		out = append(out, fmt.Sprintf("%d:%d", r.ID, r.CustomerID))
+	}
+	return out
+}
+
+func TestReshard(t *testing.T) {
+	tests := []struct {
+		name    string
+		shard0  string
+		shard1  string
+		dryRun  bool
+		want0   []string
+		want1   []string
+		moved   int
+		wantErr string
+	}{
+		{
+			name:   "moves misplaced rows",
+			shard0: "(1, 1), (2, 2), (3, 3)",
+			want0:  []string{"2:2"},
+			want1:  []string{"1:1", "3:3"},
+			moved:  2,
+		},
+		{
+			name:   "dry run",
+			shard0: "(1, 1), (2, 2)",
+			dryRun: true,
+			want0:  []string{"1:1", "2:2"},
+			want1:  []string{},
+			moved:  1,
+		},
+		{
+			name:   "completes an interrupted run",
+			shard0: "(1, 1), (2, 2)",
+			shard1: "(1, 1)",
+			want0:  []string{"2:2"},
+			want1:  []string{"1:1"},
+			moved:  1,
+		},
+		{
+			name:    "primary key used by another row",
+			shard0:  "(1, 1), (2, 2)",
+			shard1:  "(1, 7)",
+			want0:   []string{"1:1", "2:2"},
+			want1:   []string{"1:7"},
+			moved:   1,
+			wantErr: "primary keys must be unique across shards",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shards := map[string]*gorm.DB{}
+			for j, rows := range []string{tt.shard0, tt.shard1} {
+				gormDB := openSQLite(t, fmt.Sprintf("reshard_%d_%d", i, j))
+				if err := gormDB.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL)").Error; err != nil {
+					t.Fatal(err)
+				}
+				if rows != "" {
+					if err := gormDB.Exec("INSERT INTO orders (id, customer_id) VALUES " + rows).Error; err != nil {
+						t.Fatal(err)
+					}
+				}
+				shards[fmt.Sprintf("shard%d", j)] = gormDB
+			}
+			useShards(t, shards, "shard0", "shard1")
+
+			results, err := Reshard(context.Background(), ReshardOptions{Table: "orders", KeyColumn: "customer_id", DryRun: tt.dryRun})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Reshard() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Reshard() error = %v", err)
+			}
+			if got := results[0].Moved["shard1"]; got != tt.moved {
+				t.Errorf("moved to shard1 = %d, want %d", got, tt.moved)
+			}
+			if got := orderRows(t, shards["shard0"]); !reflect.DeepEqual(got, tt.want0) {
+				t.Errorf("shard0 = %v, want %v", got, tt.want0)
+			}
+			if got := orderRows(t, shards["shard1"]); !reflect.DeepEqual(got, tt.want1) {
+				t.Errorf("shard1 = %v, want %v", got, tt.want1)
+			}
+		})
+	}
+}
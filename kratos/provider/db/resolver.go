@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db/driver"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	defaultReplicaHealthCheckInterval = 10 * time.Second
+	replicaPingTimeout                = 2 * time.Second
+	forcePrimaryCallback              = "db:force_primary"
+)
+
+// primaryKey is the context key set by WithPrimary.
+type primaryKey struct{}
+
+// WithPrimary returns a context that routes every query made with it to the primary.
+// Use it for reads that must see a write made just before (read-your-writes).
+//
+// Parameters:
+//   - ctx: The parent context
+//
+// Returns:
+//   - context.Context: A context for db.Get().WithContext(ctx)
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReplicaStatus is the health of a read replica.
+type ReplicaStatus struct {
+	// Index is the position of the replica in data.database.replicas
+	Index int
+	// Healthy reports whether the replica is in rotation
+	Healthy bool
+	// Err is the error of the last failed ping
+	Err error
+	// Latency is the round trip of the last ping
+	Latency time.Duration
+	// Stats are the connection pool statistics of the replica
+	Stats sql.DBStats
+}
+
+// replica is a read replica connection pool with its health.
+type replica struct {
+	index   int
+	db      *sql.DB
+	healthy atomic.Bool
+
+	mu      sync.RWMutex
+	err     error
+	latency time.Duration
+}
+
+// replicaSet owns the replica pools and their health checks.
+type replicaSet struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	byPool   map[gorm.ConnPool]*replica
+	balance  func([]gorm.ConnPool) gorm.ConnPool
+	interval time.Duration
+	log      *log.Helper
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// setupReplicas registers the read/write splitting plugin on gormDB.
+// Writes, transactions and locking reads use the primary; other reads are balanced over
+// healthy replicas and fall back to the primary when none is healthy.
+func setupReplicas(ctx context.Context, gormDB *gorm.DB, d driver.Driver, cfg *conf.Data_Database, logHelper *log.Helper) (*replicaSet, error) {
+	if len(cfg.GetReplicas()) == 0 {
+		return nil, nil
+	}
+
+	primary, err := gormDB.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "get sql db error")
+	}
+
+	dialect := d.Dialector
+	if dialect == nil {
+		return nil, errors.Errorf("read replicas are not supported for driver %s", d.Name)
+	}
+
+	balance, err := newBalancer(cfg.GetReplicaPolicy())
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &replicaSet{
+		primary:  primary,
+		byPool:   make(map[gorm.ConnPool]*replica),
+		balance:  balance,
+		interval: durationOr(cfg.GetReplicaHealthCheckInterval(), defaultReplicaHealthCheckInterval),
+		log:      logHelper,
+	}
+
+	// Replicas are opened lazily so an unreachable replica does not block startup;
+	// it joins the rotation once a health check succeeds.
+	var dialectors []gorm.Dialector
+	for i, source := range cfg.GetReplicas() {
+		replicaDB, err := gorm.Open(dialect(source, nil), &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			rs.close()
+			return nil, errors.Wrapf(err, "open replica %d", i)
+		}
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			rs.close()
+			return nil, errors.Wrapf(err, "get replica %d sql db", i)
+		}
+		configurePool(sqlDB, cfg)
+
+		r := &replica{index: i, db: sqlDB}
+		rs.replicas = append(rs.replicas, r)
+		rs.byPool[sqlDB] = r
+		dialectors = append(dialectors, dialect("", sqlDB))
+	}
+	// The primary is the last replica candidate; the policy only picks it when no
+	// replica is healthy.
+	dialectors = append(dialectors, dialect("", primary))
+
+	rs.checkAll(ctx)
+
+	err = gormDB.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.PolicyFunc(rs.resolve),
+	}))
+	if err != nil {
+		rs.close()
+		return nil, errors.Wrap(err, "register db resolver")
+	}
+
+	// Registered after the resolver, "*" callbacks run first, so the write mode is set
+	// before the resolver picks a pool.
+	forcePrimary := func(tx *gorm.DB) {
+		if v, _ := tx.Statement.Context.Value(primaryKey{}).(bool); v {
+			dbresolver.Write.ModifyStatement(tx.Statement)
+		}
+	}
+	for _, err := range []error{
+		gormDB.Callback().Query().Before("*").Register(forcePrimaryCallback, forcePrimary),
+		gormDB.Callback().Row().Before("*").Register(forcePrimaryCallback, forcePrimary),
+		gormDB.Callback().Raw().Before("*").Register(forcePrimaryCallback, forcePrimary),
+	} {
+		if err != nil {
+			rs.close()
+			return nil, errors.Wrap(err, "register force primary callback")
+		}
+	}
+
+	monitorCtx, cancel := context.WithCancel(context.Background())
+	rs.cancel = cancel
+	rs.done = make(chan struct{})
+	go rs.monitor(monitorCtx)
+
+	logHelper.Infof("database read replicas enabled: count=%d, policy=%s", len(rs.replicas), cfg.GetReplicaPolicy())
+	return rs, nil
+}
+
+// GetReplicaStatus returns the health of every configured read replica of a connection.
+//
+// Parameters:
+//   - name: Optional connection name; the default connection if omitted
+//
+// Returns:
+//   - []ReplicaStatus: One entry per replica, empty if no replicas are configured
+func GetReplicaStatus(name ...string) []ReplicaStatus {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
+	}
+	c := lookup(n)
+	if c == nil {
+		return nil
+	}
+	return c.replicas.status()
+}
+
+// status returns the health of the replicas; rs may be nil.
+func (rs *replicaSet) status() []ReplicaStatus {
+	if rs == nil {
+		return nil
+	}
+	statuses := make([]ReplicaStatus, 0, len(rs.replicas))
+	for _, r := range rs.replicas {
+		r.mu.RLock()
+		statuses = append(statuses, ReplicaStatus{
+			Index:   r.index,
+			Healthy: r.healthy.Load(),
+			Err:     r.err,
+			Latency: r.latency,
+			Stats:   r.db.Stats(),
+		})
+		r.mu.RUnlock()
+	}
+	return statuses
+}
+
+// resolve picks a healthy replica, or the primary when none is healthy.
+func (rs *replicaSet) resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	for _, pool := range pools {
+		if r, ok := rs.byPool[pool]; ok && r.healthy.Load() {
+			healthy = append(healthy, pool)
+		}
+	}
+	if len(healthy) == 0 {
+		return rs.primary
+	}
+	return rs.balance(healthy)
+}
+
+// monitor pings the replicas until ctx is cancelled.
+func (rs *replicaSet) monitor(ctx context.Context) {
+	defer close(rs.done)
+
+	ticker := time.NewTicker(rs.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rs.checkAll(ctx)
+		}
+	}
+}
+
+// checkAll pings every replica concurrently and updates its health.
+func (rs *replicaSet) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range rs.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			rs.check(ctx, r)
+		}(r)
+	}
+	wg.Wait()
+}
+
+// check pings one replica and logs when it leaves or rejoins the rotation.
+func (rs *replicaSet) check(ctx context.Context, r *replica) {
+	pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+	start := time.Now()
+	err := r.db.PingContext(pingCtx)
+	latency := time.Since(start)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.err = err
+	r.latency = latency
+	r.mu.Unlock()
+
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		rs.log.Infof("database replica %d is healthy, added to rotation", r.index)
+	} else {
+		rs.log.Warnf("database replica %d is unhealthy, removed from rotation: %v", r.index, err)
+	}
+}
+
+// close stops the health checks and closes the replica pools.
+func (rs *replicaSet) close() {
+	if rs.cancel != nil {
+		rs.cancel()
+		<-rs.done
+	}
+	for _, r := range rs.replicas {
+		_ = r.db.Close()
+	}
+}
+
+// newBalancer returns the load-balancing policy for healthy replicas.
+func newBalancer(policy string) (func([]gorm.ConnPool) gorm.ConnPool, error) {
+	switch policy {
+	case "", "random":
+		return func(pools []gorm.ConnPool) gorm.ConnPool {
+			return pools[rand.Intn(len(pools))]
+		}, nil
+	case "round_robin":
+		var next atomic.Uint64
+		return func(pools []gorm.ConnPool) gorm.ConnPool {
+			return pools[int(next.Add(1)%uint64(len(pools)))]
+		}, nil
+	case "least_conn":
+		return func(pools []gorm.ConnPool) gorm.ConnPool {
+			best, bestInUse := pools[0], -1
+			for _, pool := range pools {
+				sqlDB, ok := pool.(*sql.DB)
+				if !ok {
+					continue
+				}
+				if inUse := sqlDB.Stats().InUse; bestInUse < 0 || inUse < bestInUse {
+					best, bestInUse = pool, inUse
+				}
+			}
+			return best
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported replica policy: %s", policy)
+	}
+}
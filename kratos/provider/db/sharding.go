@@ -0,0 +1,416 @@
+package db
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Built-in shard strategies of data.sharding.strategy.
+const (
+	// ShardHash spreads keys evenly by a hash of the key
+	ShardHash = "hash"
+	// ShardRange assigns ranges of integer keys to shards by data.sharding.range_bounds
+	ShardRange = "range"
+
+	// shardRouterCallback is the name of the callbacks routing sharded tables.
+	shardRouterCallback = "db:shard_router"
+)
+
+// ErrMissingShardKey is returned when sharding is enabled and the context has no shard key.
+var ErrMissingShardKey = errors.New("missing shard key")
+
+// ShardFunc maps a shard key to the index of a shard.
+type ShardFunc func(key string, shards int) (int, error)
+
+// shardKey is the context key of the shard key.
+type shardKey struct{}
+
+// shardPinKey is the context key of the shard connection a context is pinned to by
+// ShardDB, ScatterGather and Reshard.
+type shardPinKey struct{}
+
+// sharding holds the sharding settings set by InitSharding.
+type sharding struct {
+	shards []string
+	fn     ShardFunc
+	// tables are the sharded tables routed by DB(ctx) and Get()
+	tables map[string]bool
+}
+
+var (
+	// gSharding is the sharding configuration, nil if sharding is disabled
+	gSharding *sharding
+	// gShardingMu protects gSharding
+	gShardingMu sync.RWMutex
+
+	// shardFuncs holds the shard strategies by name
+	shardFuncs = map[string]ShardFunc{ShardHash: HashShard}
+	// shardFuncsMu protects shardFuncs
+	shardFuncsMu sync.RWMutex
+)
+
+// RegisterShardFunc adds a shard strategy selectable with data.sharding.strategy,
+// e.g. a lookup table of tenants. Call it from an init function.
+//
+// Parameters:
+//   - name: The strategy name
+//   - fn: The shard function
+//
+// Panics:
+//   - If the name is already registered or fn is nil
+func RegisterShardFunc(name string, fn ShardFunc) {
+	if fn == nil {
+		panic("db: RegisterShardFunc fn is nil")
+	}
+	shardFuncsMu.Lock()
+	defer shardFuncsMu.Unlock()
+	if _, ok := shardFuncs[name]; ok || name == ShardRange {
+		panic("db: RegisterShardFunc called twice for " + name)
+	}
+	shardFuncs[name] = fn
+}
+
+// HashShard is the "hash" strategy: the FNV-1a hash of the key modulo the number of shards.
+// Changing the number of shards moves most keys; run the reshard command afterwards.
+func HashShard(key string, shards int) (int, error) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum64() % uint64(shards)), nil
+}
+
+// RangeShard returns the "range" strategy for integer keys: shard i holds the keys below
+// bounds[i] (and at or above bounds[i-1]), the last shard holds the keys at or above the
+// last bound.
+//
+// Parameters:
+//   - bounds: Ascending upper bounds, one less than the number of shards
+//
+// Returns:
+//   - ShardFunc: The shard function
+func RangeShard(bounds ...int64) ShardFunc {
+	return func(key string, shards int) (int, error) {
+		if len(bounds) != shards-1 {
+			return 0, errors.Errorf("range sharding needs %d bounds for %d shards, got %d", shards-1, shards, len(bounds))
+		}
+		k, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "range shard key %q", key)
+		}
+		return sort.Search(len(bounds), func(i int) bool { return k < bounds[i] }), nil
+	}
+}
+
+// InitSharding sets up sharding over initialized connections. Rows of sharded tables are
+// routed to a shard by a key in context; see WithShardKey. Statements on the tables of
+// data.sharding.tables made with DB(ctx) or Get() on the default connection are routed
+// by callbacks, ShardDB returns a session on the shard itself.
+//
+// Parameters:
+//   - cfg: Sharding configuration (may be nil to disable sharding)
+//
+// Returns:
+//   - error: Error if the strategy is unknown, range bounds are invalid or a shard
+//     connection is not initialized
+func InitSharding(cfg *conf.Data_Sharding) error {
+	if !cfg.GetEnabled() {
+		return nil
+	}
+	fn, err := shardFunc(cfg)
+	if err != nil {
+		return err
+	}
+	s := &sharding{shards: cfg.GetShards(), fn: fn, tables: make(map[string]bool)}
+	if len(s.shards) == 0 {
+		return errors.New("sharding needs at least one shard")
+	}
+	for _, name := range s.shards {
+		if lookup(name) == nil {
+			return errors.Errorf("shard database %q is not initialized", name)
+		}
+	}
+	for _, table := range cfg.GetTables() {
+		s.tables[table] = true
+	}
+	if len(s.tables) > 0 {
+		c := lookup(DefaultName)
+		if c == nil {
+			return errors.New("sharded tables are routed on the default connection, which is not initialized")
+		}
+		// Routed statements are built by the default connection's dialect.
+		for _, name := range s.shards {
+			if dialect := lookup(name).db.Dialector.Name(); dialect != c.db.Dialector.Name() {
+				return errors.Errorf("shard %q uses %s, sharded tables need the driver of the default connection (%s)", name, dialect, c.db.Dialector.Name())
+			}
+		}
+		if err := registerShardRouter(c.db); err != nil {
+			return err
+		}
+	}
+
+	gShardingMu.Lock()
+	gSharding = s
+	gShardingMu.Unlock()
+	return nil
+}
+
+// shardFunc returns the shard function of the configured strategy.
+func shardFunc(cfg *conf.Data_Sharding) (ShardFunc, error) {
+	strategy := cfg.GetStrategy()
+	if strategy == "" {
+		strategy = ShardHash
+	}
+	if strategy == ShardRange {
+		bounds := cfg.GetRangeBounds()
+		if len(bounds) != len(cfg.GetShards())-1 {
+			return nil, errors.Errorf("range sharding needs %d range_bounds for %d shards", len(cfg.GetShards())-1, len(cfg.GetShards()))
+		}
+		for i := 1; i < len(bounds); i++ {
+			if bounds[i] <= bounds[i-1] {
+				return nil, errors.New("range_bounds must be ascending")
+			}
+		}
+		return RangeShard(bounds...), nil
+	}
+	shardFuncsMu.RLock()
+	defer shardFuncsMu.RUnlock()
+	fn, ok := shardFuncs[strategy]
+	if !ok {
+		return nil, errors.Errorf("unsupported shard strategy: %s", strategy)
+	}
+	return fn, nil
+}
+
+// currentSharding returns the settings set by InitSharding, nil if sharding is disabled.
+func currentSharding() *sharding {
+	gShardingMu.RLock()
+	defer gShardingMu.RUnlock()
+	return gSharding
+}
+
+// WithShardKey returns a context routing the sharded tables to the shard of key, e.g. a
+// tenant or customer ID. ShardDB, ShardConnection and RepoSharded repositories read the
+// key, as do statements on data.sharding.tables made with DB(ctx) or Get().
+func WithShardKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, shardKey{}, key)
+}
+
+// ShardKeyFromContext returns the shard key of ctx.
+func ShardKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(shardKey{}).(string)
+	return key, ok
+}
+
+// Shards returns the shard connection names in configuration order; only the default
+// connection when sharding is disabled.
+func Shards() []string {
+	s := currentSharding()
+	if s == nil {
+		return []string{DefaultName}
+	}
+	return append([]string(nil), s.shards...)
+}
+
+// ShardFor returns the name of the shard connection holding key. It is the default
+// connection when sharding is disabled.
+//
+// Parameters:
+//   - key: The shard key
+//
+// Returns:
+//   - string: The connection name for DB, Transaction (TxOn) and Get
+//   - error: Error if the shard function fails
+func ShardFor(key string) (string, error) {
+	s := currentSharding()
+	if s == nil {
+		return DefaultName, nil
+	}
+	return s.shardFor(key)
+}
+
+// shardFor returns the shard connection of key.
+func (s *sharding) shardFor(key string) (string, error) {
+	i, err := s.fn(key, len(s.shards))
+	if err != nil {
+		return "", err
+	}
+	if i < 0 || i >= len(s.shards) {
+		return "", errors.Errorf("shard function returned %d for %d shards", i, len(s.shards))
+	}
+	return s.shards[i], nil
+}
+
+// ShardConnection returns the name of the shard connection of the key in ctx.
+//
+// Parameters:
+//   - ctx: The context carrying the shard key
+//
+// Returns:
+//   - string: The connection name; the default connection when sharding is disabled
+//   - error: ErrMissingShardKey if ctx has no key, or an error of the shard function
+func ShardConnection(ctx context.Context) (string, error) {
+	if currentSharding() == nil {
+		return DefaultName, nil
+	}
+	key, ok := ShardKeyFromContext(ctx)
+	if !ok {
+		return "", ErrMissingShardKey
+	}
+	return ShardFor(key)
+}
+
+// ShardDB returns a session on the shard of the key in ctx, joining the transaction in
+// ctx if any. Errors resolving the shard are reported by the session.
+//
+// Unlike DB(ctx), which only routes the tables of data.sharding.tables, every statement
+// of the session runs on the shard, with the shard's replicas and plugins, including
+// raw SQL.
+//
+// Parameters:
+//   - ctx: The context carrying the shard key
+//
+// Returns:
+//   - *gorm.DB: A session bound to ctx
+func ShardDB(ctx context.Context) *gorm.DB {
+	name, err := ShardConnection(ctx)
+	if err != nil {
+		tx := Get().Session(&gorm.Session{NewDB: true}).WithContext(ctx)
+		_ = tx.AddError(err)
+		return tx
+	}
+	return DB(pinShard(ctx, name), name)
+}
+
+// pinShard returns a context routing the sharded tables to the named shard connection,
+// whatever the shard key.
+func pinShard(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, shardPinKey{}, name)
+}
+
+// registerShardRouter registers the callbacks routing the sharded tables on a connection.
+// They run after the read replica resolver. The drivers skip GORM's default write
+// transaction, so writes run on the shard without one unless the context carries a
+// transaction of the shard.
+func registerShardRouter(gormDB *gorm.DB) error {
+	cb := gormDB.Callback()
+	// InitSharding may run again, e.g. in tests.
+	if cb.Query().Get(shardRouterCallback) != nil {
+		return nil
+	}
+	for _, err := range []error{
+		cb.Query().Before("gorm:query").Register(shardRouterCallback, routeShard),
+		cb.Row().Before("gorm:row").Register(shardRouterCallback, routeShard),
+		cb.Create().Before("gorm:create").Register(shardRouterCallback, routeShard),
+		cb.Update().Before("gorm:update").Register(shardRouterCallback, routeShard),
+		cb.Delete().Before("gorm:delete").Register(shardRouterCallback, routeShard),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register shard router")
+		}
+	}
+	return nil
+}
+
+// routeShard switches a statement on a sharded table to the connection pool of the
+// shard in its context: the transaction of the shard in the context if any, else the
+// shard's primary. Statements with raw SQL are not routed.
+func routeShard(tx *gorm.DB) {
+	s := currentSharding()
+	stmt := tx.Statement
+	if s == nil || tx.Error != nil || stmt.SQL.Len() > 0 || !s.tables[baseTable(stmt.Table)] {
+		return
+	}
+
+	ctx := stmt.Context
+	name, pinned := ctx.Value(shardPinKey{}).(string)
+	if !pinned {
+		key, ok := ShardKeyFromContext(ctx)
+		if !ok {
+			_ = tx.AddError(errors.Wrapf(ErrMissingShardKey, "table %s is sharded", stmt.Table))
+			return
+		}
+		var err error
+		if name, err = s.shardFor(key); err != nil {
+			_ = tx.AddError(err)
+			return
+		}
+	}
+	if name == DefaultName {
+		return
+	}
+
+	if st := txFrom(ctx, name); st != nil {
+		stmt.ConnPool = st.db.Statement.ConnPool
+		return
+	}
+	// A transaction of the default connection cannot include rows of another shard.
+	if _, ok := stmt.ConnPool.(gorm.TxCommitter); ok {
+		_ = tx.AddError(errors.Errorf("table %s is on shard %q, run the transaction with TxOn(%q)", stmt.Table, name, name))
+		return
+	}
+	c := lookup(name)
+	if c == nil {
+		_ = tx.AddError(errors.Errorf("shard database %q is not initialized", name))
+		return
+	}
+	stmt.ConnPool = c.db.Statement.ConnPool
+}
+
+// baseTable returns a table name without schema and quotes.
+func baseTable(table string) string {
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		table = table[i+1:]
+	}
+	return strings.Trim(table, "`\"")
+}
+
+// ScatterGather runs a read on every shard concurrently and concatenates the results in
+// shard order. Each shard applies its own ORDER BY and LIMIT; callers needing a global
+// order or limit sort and trim the combined results.
+//
+//	orders, err := db.ScatterGather(ctx, func(ctx context.Context, tx *gorm.DB) ([]Order, error) {
+//		var orders []Order
+//		err := tx.Where("status = ?", "open").Find(&orders).Error
+//		return orders, err
+//	})
+//
+// Parameters:
+//   - ctx: Context for the queries; shards join a transaction of theirs in ctx
+//   - query: The read run with a session of each shard
+//
+// Returns:
+//   - []T: The results of all shards
+//   - error: The first error in shard order, wrapped with the shard name
+func ScatterGather[T any](ctx context.Context, query func(ctx context.Context, tx *gorm.DB) ([]T, error)) ([]T, error) {
+	shards := Shards()
+	results := make([][]T, len(shards))
+	errs := make([]error, len(shards))
+
+	var wg sync.WaitGroup
+	for i, name := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := pinShard(ctx, name)
+			results[i], errs[i] = query(ctx, DB(ctx, name))
+		}()
+	}
+	wg.Wait()
+
+	var all []T
+	for i, name := range shards {
+		if errs[i] != nil {
+			return nil, errors.Wrapf(errs[i], "shard %q", name)
+		}
+		all = append(all, results[i]...)
+	}
+	return all, nil
+}
@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func TestHashShard(t *testing.T) {
+	tests := []struct {
+		key    string
+		shards int
+		want   int
+	}{
+		{key: "", shards: 1, want: 0},
+		{key: "42", shards: 1, want: 0},
+		// FNV-1a 64 of "a" is 0xaf63dc4c8601ec8c
+		{key: "a", shards: 2, want: 0},
+		{key: "a", shards: 3, want: int(uint64(0xaf63dc4c8601ec8c) % 3)},
+		{key: "a", shards: 16, want: 0xc},
+	}
+	for _, tt := range tests {
+		got, err := HashShard(tt.key, tt.shards)
+		if err != nil {
+			t.Fatalf("HashShard(%q, %d) error = %v", tt.key, tt.shards, err)
+		}
+		if got != tt.want {
+			t.Errorf("HashShard(%q, %d) = %d, want %d", tt.key, tt.shards, got, tt.want)
+		}
+	}
+}
+
+func TestHashShardRange(t *testing.T) {
+	const shards = 4
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		key := strings.Repeat("k", i%7) + string(rune('a'+i%26)) + string(rune('0'+i%10))
+		got, err := HashShard(key, shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got < 0 || got >= shards {
+			t.Fatalf("HashShard(%q, %d) = %d, out of range", key, shards, got)
+		}
+		again, _ := HashShard(key, shards)
+		if again != got {
+			t.Fatalf("HashShard(%q) is not stable: %d, %d", key, got, again)
+		}
+		seen[got] = true
+	}
+	if len(seen) != shards {
+		t.Errorf("keys were routed to %d of %d shards", len(seen), shards)
+	}
+}
+
+func TestRangeShard(t *testing.T) {
+	tests := []struct {
+		name    string
+		bounds  []int64
+		shards  int
+		key     string
+		want    int
+		wantErr string
+	}{
+		{name: "single shard", shards: 1, key: "7", want: 0},
+		{name: "below first bound", bounds: []int64{100, 200}, shards: 3, key: "-5", want: 0},
+		{name: "at first bound", bounds: []int64{100, 200}, shards: 3, key: "100", want: 1},
+		{name: "between bounds", bounds: []int64{100, 200}, shards: 3, key: "199", want: 1},
+		{name: "at last bound", bounds: []int64{100, 200}, shards: 3, key: "200", want: 2},
+		{name: "above last bound", bounds: []int64{100, 200}, shards: 3, key: "9000000000", want: 2},
+		{name: "too few bounds", bounds: []int64{100}, shards: 3, key: "1", wantErr: "needs 2 bounds"},
+		{name: "too many bounds", bounds: []int64{100, 200}, shards: 2, key: "1", wantErr: "needs 1 bounds"},
+		{name: "non-integer key", bounds: []int64{100}, shards: 2, key: "abc", wantErr: `range shard key "abc"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RangeShard(tt.bounds...)(tt.key, tt.shards)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RangeShard() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RangeShard() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RangeShard(%v)(%q) = %d, want %d", tt.bounds, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+// shardedOrder is a row of the sharded orders table.
+type shardedOrder struct {
+	ID         int
+	CustomerID int
+	Status     string
+}
+
+func (shardedOrder) TableName() string { return "orders" }
+
+// routeShards registers the default connection and shard1 as shards, keys below 100 on
+// the default connection, with the orders table routed by DB(ctx). The returned sessions
+// are pinned to their shard.
+func routeShards(t *testing.T) map[string]*gorm.DB {
+	t.Helper()
+	shards := map[string]*gorm.DB{}
+	for _, name := range []string{DefaultName, "shard1"} {
+		gormDB := openSQLite(t, "route_"+name)
+		if err := gormDB.AutoMigrate(&shardedOrder{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := gormDB.Exec("CREATE TABLE customers (id INTEGER PRIMARY KEY)").Error; err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(Set(name, gormDB, log.NewStdLogger(io.Discard)))
+		shards[name] = gormDB.WithContext(pinShard(context.Background(), name))
+	}
+	err := InitSharding(&conf.Data_Sharding{
+		Enabled:     true,
+		Shards:      []string{DefaultName, "shard1"},
+		Strategy:    ShardRange,
+		RangeBounds: []int64{100},
+		Tables:      []string{"orders"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		gShardingMu.Lock()
+		gSharding = nil
+		gShardingMu.Unlock()
+	})
+	return shards
+}
+
+func TestDBRoutesShardedTables(t *testing.T) {
+	shards := routeShards(t)
+	bg := context.Background()
+	low := WithShardKey(bg, "5")
+	high := WithShardKey(bg, "150")
+
+	if err := DB(low).Create(&shardedOrder{ID: 1, CustomerID: 5}).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := DB(high).Create(&shardedOrder{ID: 2, CustomerID: 150}).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := orderRows(t, shards[DefaultName]); !reflect.DeepEqual(got, []string{"1:5"}) {
+		t.Errorf("default = %v, want [1:5]", got)
+	}
+	if got := orderRows(t, shards["shard1"]); !reflect.DeepEqual(got, []string{"2:150"}) {
+		t.Errorf("shard1 = %v, want [2:150]", got)
+	}
+
+	var orders []shardedOrder
+	if err := DB(high).Find(&orders).Error; err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(orders) != 1 || orders[0].ID != 2 {
+		t.Errorf("Find() = %v, want order 2", orders)
+	}
+	var n int64
+	if err := DB(high).Table("orders").Count(&n).Error; err != nil || n != 1 {
+		t.Errorf("Count() = %d, %v, want 1", n, err)
+	}
+
+	if err := DB(high).Model(&shardedOrder{}).Where("id = ?", 2).Update("status", "paid").Error; err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	var status string
+	if err := shards["shard1"].Table("orders").Where("id = ?", 2).Pluck("status", &status).Error; err != nil || status != "paid" {
+		t.Errorf("shard1 status = %q, %v, want paid", status, err)
+	}
+	if err := DB(high).Delete(&shardedOrder{ID: 2}).Error; err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := orderRows(t, shards["shard1"]); len(got) != 0 {
+		t.Errorf("shard1 = %v after delete, want none", got)
+	}
+
+	if err := DB(bg).Find(&orders).Error; !errors.Is(err, ErrMissingShardKey) {
+		t.Errorf("Find() without shard key error = %v, want ErrMissingShardKey", err)
+	}
+	// Other tables and raw SQL stay on the default connection.
+	if err := DB(bg).Table("customers").Count(&n).Error; err != nil {
+		t.Errorf("Count() of an unsharded table error = %v", err)
+	}
+	if err := DB(bg).Raw("SELECT COUNT(*) FROM orders").Scan(&n).Error; err != nil || n != 1 {
+		t.Errorf("raw Count() = %d, %v, want 1 on the default connection", n, err)
+	}
+}
+
+func TestDBRoutesShardTransactions(t *testing.T) {
+	shards := routeShards(t)
+	ctx := WithShardKey(context.Background(), "150")
+
+	rollback := errors.New("rollback")
+	err := Transaction(ctx, func(ctx context.Context) error {
+		if err := DB(ctx).Create(&shardedOrder{ID: 1, CustomerID: 150}).Error; err != nil {
+			return err
+		}
+		return rollback
+	}, TxOn("shard1"))
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction() error = %v, want rollback", err)
+	}
+	err = Transaction(ctx, func(ctx context.Context) error {
+		return DB(ctx).Create(&shardedOrder{ID: 2, CustomerID: 150}).Error
+	}, TxOn("shard1"))
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if got := orderRows(t, shards["shard1"]); !reflect.DeepEqual(got, []string{"2:150"}) {
+		t.Errorf("shard1 = %v, want [2:150]", got)
+	}
+
+	// A transaction of the default connection cannot write to shard1.
+	err = Transaction(ctx, func(ctx context.Context) error {
+		return DB(ctx).Create(&shardedOrder{ID: 3, CustomerID: 150}).Error
+	})
+	if err == nil || !strings.Contains(err.Error(), `TxOn("shard1")`) {
+		t.Errorf("Transaction() on the default connection error = %v, want TxOn hint", err)
+	}
+}
+
+func TestScatterGatherPinsShards(t *testing.T) {
+	shards := routeShards(t)
+	for name, id := range map[string]int{DefaultName: 1, "shard1": 2} {
+		if err := shards[name].Create(&shardedOrder{ID: id, CustomerID: id * 100}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// DB(ctx) inside the query runs on the shard being read, not the one of the key.
+	ctx := WithShardKey(context.Background(), "5")
+	orders, err := ScatterGather(ctx, func(ctx context.Context, _ *gorm.DB) ([]shardedOrder, error) {
+		var orders []shardedOrder
+		return orders, DB(ctx).Order("id").Find(&orders).Error
+	})
+	if err != nil {
+		t.Fatalf("ScatterGather() error = %v", err)
+	}
+	if len(orders) != 2 || orders[0].ID != 1 || orders[1].ID != 2 {
+		t.Errorf("ScatterGather() = %v, want orders 1 and 2", orders)
+	}
+}
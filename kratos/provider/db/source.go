@@ -0,0 +1,98 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db/driver"
+
+	"github.com/pkg/errors"
+)
+
+// passwordCommandTimeout bounds the run of a password command.
+const passwordCommandTimeout = 10 * time.Second
+
+// resolveSource returns the data source name of a connection: source if set, so an
+// override such as DATA_DATABASE_SOURCE wins, otherwise the one built by the driver from
+// dsn with the password resolved from its source.
+func resolveSource(ctx context.Context, d driver.Driver, cfg *conf.Data_Database) (string, error) {
+	if cfg.GetSource() != "" {
+		return cfg.GetSource(), nil
+	}
+	dsn := cfg.GetDsn()
+	if dsn == nil {
+		return "", errors.New("source or dsn is required")
+	}
+	if d.DSN == nil {
+		return "", errors.Errorf("driver %s does not support dsn, configure source", d.Name)
+	}
+
+	password, err := resolvePassword(ctx, dsn)
+	if err != nil {
+		return "", err
+	}
+	return d.DSN(driver.DSN{
+		Host:     dsn.GetHost(),
+		Port:     int(dsn.GetPort()),
+		User:     dsn.GetUser(),
+		Password: password,
+		DBName:   dsn.GetDbname(),
+		Params:   dsn.GetParams(),
+		TLS: driver.TLS{
+			Mode:       dsn.GetTls().GetMode(),
+			CAFile:     dsn.GetTls().GetCaFile(),
+			CertFile:   dsn.GetTls().GetCertFile(),
+			KeyFile:    dsn.GetTls().GetKeyFile(),
+			ServerName: dsn.GetTls().GetServerName(),
+		},
+	})
+}
+
+// resolvePassword returns the password of dsn from the one source that is set. Trailing
+// line breaks of a file or command output are removed.
+func resolvePassword(ctx context.Context, dsn *conf.Data_Database_Dsn) (string, error) {
+	set := 0
+	for _, s := range []string{dsn.GetPassword(), dsn.GetPasswordEnv(), dsn.GetPasswordFile(), dsn.GetPasswordCommand()} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", errors.New("set only one of password, password_env, password_file and password_command")
+	}
+
+	switch {
+	case dsn.GetPasswordEnv() != "":
+		password, ok := os.LookupEnv(dsn.GetPasswordEnv())
+		if !ok {
+			return "", errors.Errorf("password environment variable %s is not set", dsn.GetPasswordEnv())
+		}
+		return password, nil
+	case dsn.GetPasswordFile() != "":
+		b, err := os.ReadFile(dsn.GetPasswordFile())
+		if err != nil {
+			return "", errors.Wrap(err, "read password file")
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case dsn.GetPasswordCommand() != "":
+		ctx, cancel := context.WithTimeout(ctx, passwordCommandTimeout)
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", dsn.GetPasswordCommand())
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", errors.Wrapf(err, "password command failed: %s", msg)
+			}
+			return "", errors.Wrap(err, "password command failed")
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	default:
+		return dsn.GetPassword(), nil
+	}
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db/driver"
+)
+
+func TestResolvePassword(t *testing.T) {
+	t.Setenv("DBTEST_PASSWORD", "from-env")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dsn     *conf.Data_Database_Dsn
+		want    string
+		wantErr string
+	}{
+		{name: "none", dsn: &conf.Data_Database_Dsn{}, want: ""},
+		{name: "inline", dsn: &conf.Data_Database_Dsn{Password: "inline"}, want: "inline"},
+		{name: "env", dsn: &conf.Data_Database_Dsn{PasswordEnv: "DBTEST_PASSWORD"}, want: "from-env"},
+		{name: "file trims line break", dsn: &conf.Data_Database_Dsn{PasswordFile: file}, want: "from-file"},
+		{name: "command", dsn: &conf.Data_Database_Dsn{PasswordCommand: "printf 'from-command\\n'"}, want: "from-command"},
+		{
+			name:    "several sources",
+			dsn:     &conf.Data_Database_Dsn{Password: "inline", PasswordEnv: "DBTEST_PASSWORD"},
+			wantErr: "set only one of",
+		},
+		{
+			name:    "env not set",
+			dsn:     &conf.Data_Database_Dsn{PasswordEnv: "DBTEST_PASSWORD_UNSET"},
+			wantErr: "DBTEST_PASSWORD_UNSET is not set",
+		},
+		{
+			name:    "file missing",
+			dsn:     &conf.Data_Database_Dsn{PasswordFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: "read password file",
+		},
+		{
+			name:    "command fails",
+			dsn:     &conf.Data_Database_Dsn{PasswordCommand: "echo denied >&2; exit 3"},
+			wantErr: "password command failed: denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePassword(context.Background(), tt.dsn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolvePassword() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePassword() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolvePassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSource(t *testing.T) {
+	t.Setenv("DBTEST_PASSWORD", "secret")
+	d := driver.Driver{
+		Name: "test",
+		DSN: func(dsn driver.DSN) (string, error) {
+			return dsn.User + ":" + dsn.Password + "@" + dsn.Host + "/" + dsn.DBName, nil
+		},
+	}
+	dsn := &conf.Data_Database_Dsn{Host: "db", User: "app", PasswordEnv: "DBTEST_PASSWORD", Dbname: "orders"}
+
+	tests := []struct {
+		name    string
+		d       driver.Driver
+		cfg     *conf.Data_Database
+		want    string
+		wantErr string
+	}{
+		{name: "dsn", d: d, cfg: &conf.Data_Database{Dsn: dsn}, want: "app:secret@db/orders"},
+		{name: "source wins", d: d, cfg: &conf.Data_Database{Source: "override", Dsn: dsn}, want: "override"},
+		{name: "neither", d: d, cfg: &conf.Data_Database{}, wantErr: "source or dsn is required"},
+		{name: "driver without dsn", d: driver.Driver{Name: "test"}, cfg: &conf.Data_Database{Dsn: dsn}, wantErr: "does not support dsn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSource(context.Background(), tt.d, tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveSource() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSource() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,26 @@
 package sqlite3
 
 import (
+	"net/url"
+	"strings"
+
+	"kratos-project-template/provider/db/driver"
+
+	"github.com/pkg/errors"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// init registers the SQLite driver as "sqlite" (alias "sqlite3").
+func init() {
+	driver.Register("sqlite", driver.Driver{
+		Open: InitDB,
+		DSN:  DSN,
+	}, "sqlite3")
+}
+
 // InitDB initializes a SQLite database connection using GORM.
 //
 // Parameters:
@@ -21,6 +36,37 @@ func InitDB(source string, logger logger.Interface) (*gorm.DB, error) {
 		&gorm.Config{
 			SkipDefaultTransaction: true,
 			Logger:                 logger,
+			// db.Init pings with its startup context so connecting can be cancelled
+			DisableAutomaticPing: true,
 		})
 }
 
+// DSN builds a SQLite data source name from structured settings: the database file
+// with the params as query parameters, e.g. _foreign_keys=1.
+//
+// Parameters:
+//   - dsn: The structured settings; only DBName and Params apply to SQLite
+//
+// Returns:
+//   - string: The data source name
+//   - error: Error if DBName is empty or a server setting is set
+func DSN(dsn driver.DSN) (string, error) {
+	if dsn.Host != "" || dsn.Port != 0 || dsn.User != "" || dsn.Password != "" || (dsn.TLS.Mode != "" && dsn.TLS.Mode != driver.TLSDisable) {
+		return "", errors.New("sqlite only supports dbname and params")
+	}
+	if dsn.DBName == "" {
+		return "", errors.New("sqlite dbname cannot be empty")
+	}
+	if len(dsn.Params) == 0 {
+		return dsn.DBName, nil
+	}
+	params := url.Values{}
+	for k, v := range dsn.Params {
+		params.Set(k, v)
+	}
+	sep := "?"
+	if strings.Contains(dsn.DBName, "?") {
+		sep = "&"
+	}
+	return dsn.DBName + sep + params.Encode(), nil
+}
@@ -0,0 +1,50 @@
+package sqlite3
+
+import (
+	"strings"
+	"testing"
+
+	"kratos-project-template/provider/db/driver"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     driver.DSN
+		want    string
+		wantErr string
+	}{
+		{name: "file", dsn: driver.DSN{DBName: "data/app.db"}, want: "data/app.db"},
+		{
+			name: "params",
+			dsn:  driver.DSN{DBName: "data/app.db", Params: map[string]string{"_foreign_keys": "1", "_busy_timeout": "5000"}},
+			want: "data/app.db?_busy_timeout=5000&_foreign_keys=1",
+		},
+		{
+			name: "params appended to uri",
+			dsn:  driver.DSN{DBName: "file:app?mode=memory", Params: map[string]string{"cache": "shared"}},
+			want: "file:app?mode=memory&cache=shared",
+		},
+		{name: "tls disable", dsn: driver.DSN{DBName: "app.db", TLS: driver.TLS{Mode: driver.TLSDisable}}, want: "app.db"},
+		{name: "no dbname", dsn: driver.DSN{}, wantErr: "dbname cannot be empty"},
+		{name: "host", dsn: driver.DSN{Host: "db", DBName: "app.db"}, wantErr: "only supports dbname and params"},
+		{name: "tls", dsn: driver.DSN{DBName: "app.db", TLS: driver.TLS{Mode: driver.TLSRequire}}, wantErr: "only supports dbname and params"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DSN(tt.dsn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DSN() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DSN() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DSN() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
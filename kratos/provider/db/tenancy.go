@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Tenancy modes of server.tenancy.mode.
+const (
+	// TenancyColumn shares tables between tenants and isolates rows by a tenant column
+	TenancyColumn = "column"
+	// TenancySchema gives every tenant a schema on a shared connection
+	TenancySchema = "schema"
+	// TenancyDatabase gives every tenant its own named connection
+	TenancyDatabase = "database"
+)
+
+// ErrUnknownTenant is returned when the tenant in context has no schema or connection.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// validSchemaTenant matches tenant IDs usable in schema names.
+var validSchemaTenant = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// tenancy holds the tenancy settings set by InitTenancy.
+type tenancy struct {
+	mode         string
+	base         string
+	schemaPrefix string
+	databases    map[string]string
+}
+
+var (
+	// gTenancy is the tenancy configuration, nil if tenancy is disabled
+	gTenancy *tenancy
+	// gTenancyMu protects gTenancy
+	gTenancyMu sync.RWMutex
+)
+
+// InitTenancy sets up tenant isolation on the initialized connections.
+// In "column" mode the tenant plugin is registered on every connection; in "schema" and
+// "database" mode TenantDB selects the connection of the tenant in context.
+//
+// Parameters:
+//   - cfg: Tenancy configuration (may be nil to disable tenancy)
+//
+// Returns:
+//   - error: Error if the mode is unknown or a configured connection is not initialized
+func InitTenancy(cfg *conf.Server_Tenancy) error {
+	if !cfg.GetEnabled() {
+		return nil
+	}
+
+	t := &tenancy{
+		mode:         cfg.GetMode(),
+		base:         cfg.GetDatabase(),
+		schemaPrefix: cfg.GetSchemaPrefix(),
+		databases:    cfg.GetDatabases(),
+	}
+	if t.mode == "" {
+		t.mode = TenancyColumn
+	}
+	if t.base == "" {
+		t.base = DefaultName
+	}
+
+	switch t.mode {
+	case TenancyColumn:
+		for _, name := range Names() {
+			if err := Get(name).Use(tenant.New(tenant.WithColumn(cfg.GetColumn()))); err != nil {
+				return errors.Wrapf(err, "database %q tenancy", name)
+			}
+		}
+	case TenancySchema:
+		c := lookup(t.base)
+		if c == nil {
+			return errors.Errorf("tenancy database %q is not initialized", t.base)
+		}
+		if c.driver.Dialector == nil {
+			return errors.Errorf("schema tenancy is not supported for driver %s", c.driver.Name)
+		}
+	case TenancyDatabase:
+		for id, name := range t.databases {
+			if lookup(name) == nil {
+				return errors.Errorf("database %q of tenant %q is not initialized", name, id)
+			}
+		}
+	default:
+		return errors.Errorf("unsupported tenancy mode: %s", t.mode)
+	}
+
+	gTenancyMu.Lock()
+	gTenancy = t
+	gTenancyMu.Unlock()
+	return nil
+}
+
+// TenantConnection returns the name of the connection holding the data of the tenant in ctx.
+// It is the default connection when tenancy is disabled or in "column" mode.
+//
+// Parameters:
+//   - ctx: The request context carrying the tenant
+//
+// Returns:
+//   - string: The connection name for DB, Transaction (TxOn) and NewRepository (RepoConnection)
+//   - error: tenant.ErrMissingTenant if ctx has no tenant, ErrUnknownTenant if the tenant
+//     has no connection
+func TenantConnection(ctx context.Context) (string, error) {
+	gTenancyMu.RLock()
+	t := gTenancy
+	gTenancyMu.RUnlock()
+	if t == nil || t.mode == TenancyColumn {
+		return DefaultName, nil
+	}
+
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", tenant.ErrMissingTenant
+	}
+	if t.mode == TenancyDatabase {
+		name, ok := t.databases[id]
+		if !ok {
+			return "", errors.Wrapf(ErrUnknownTenant, "tenant %q", id)
+		}
+		return name, nil
+	}
+	return t.schemaConnection(id)
+}
+
+// TenantDB returns a session on the connection of the tenant in ctx, joining the
+// transaction in ctx if any. Errors resolving the tenant are reported by the session.
+//
+// Parameters:
+//   - ctx: The request context carrying the tenant
+//
+// Returns:
+//   - *gorm.DB: A session bound to ctx
+func TenantDB(ctx context.Context) *gorm.DB {
+	name, err := TenantConnection(ctx)
+	if err != nil {
+		tx := Get().Session(&gorm.Session{NewDB: true}).WithContext(ctx)
+		_ = tx.AddError(err)
+		return tx
+	}
+	return DB(ctx, name)
+}
+
+// schemaConnection returns the connection of a tenant schema, creating it on first use.
+// Tenant connections share the pool of the base connection and qualify table names with
+// the tenant schema; they are not listed by Names and are closed with the base connection.
+// Raw and Exec SQL is sent as written, so it must name the schema itself.
+func (t *tenancy) schemaConnection(id string) (string, error) {
+	if !validSchemaTenant.MatchString(id) {
+		return "", errors.Wrapf(ErrUnknownTenant, "tenant %q is not a valid schema name", id)
+	}
+	schemaName := t.schemaPrefix + id
+	name := t.base + "/" + schemaName
+	if lookup(name) != nil {
+		return name, nil
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	if lookup(name) != nil {
+		return name, nil
+	}
+	base := lookup(t.base)
+	if base == nil {
+		return "", errors.Errorf("tenancy database %q is not initialized", t.base)
+	}
+	sqlDB, err := base.db.DB()
+	if err != nil {
+		return "", errors.Wrap(err, "get sql db error")
+	}
+
+	gormDB, err := gorm.Open(base.driver.Dialector("", sqlDB), &gorm.Config{
+		Logger:               base.db.Logger,
+		NamingStrategy:       schema.NamingStrategy{TablePrefix: schemaName + "."},
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "open schema %q", schemaName)
+	}
+	if err := qualifySchema(gormDB, schemaName); err != nil {
+		return "", errors.Wrapf(err, "schema %q", schemaName)
+	}
+	// Tenant schemas are audited, isolated and measured like the base connection. Read/write
+	// splitting is not copied, as its replica pools belong to the base connection.
+	for _, pluginName := range []string{"audit", "tenant", "metrics"} {
+		if p, ok := base.db.Config.Plugins[pluginName]; ok {
+			if err := gormDB.Use(p); err != nil {
+				return "", errors.Wrapf(err, "schema %q %s", schemaName, pluginName)
+			}
+		}
+	}
+
+	connsMu.Lock()
+	conns[name] = &conn{name: name, db: gormDB, driver: base.driver, monitor: base.monitor, log: base.log}
+	connsMu.Unlock()
+	base.log.Infof("tenant schema connection opened: %s", name)
+	return name, nil
+}
+
+// qualifySchema registers callbacks qualifying the table of every statement with the tenant
+// schema. The naming strategy only covers table names it derives, not those of models
+// implementing TableName or set with Table, such as the outbox and audit tables.
+func qualifySchema(gormDB *gorm.DB, schemaName string) error {
+	qualify := func(db *gorm.DB) {
+		stmt := db.Statement
+		if stmt.Table == "" || strings.Contains(stmt.Table, ".") {
+			return
+		}
+		// Leave aliases, subqueries and names qualified by the naming strategy alone.
+		if stmt.TableExpr != nil && (len(stmt.TableExpr.Vars) > 0 || stmt.TableExpr.SQL != stmt.Quote(stmt.Table)) {
+			return
+		}
+		stmt.TableExpr = &clause.Expr{SQL: stmt.Quote(schemaName + "." + stmt.Table)}
+	}
+	cb := gormDB.Callback()
+	for _, err := range []error{
+		cb.Create().Before("*").Register("tenant:schema_create", qualify),
+		cb.Query().Before("*").Register("tenant:schema_query", qualify),
+		cb.Update().Before("*").Register("tenant:schema_update", qualify),
+		cb.Delete().Before("*").Register("tenant:schema_delete", qualify),
+		cb.Row().Before("*").Register("tenant:schema_row", qualify),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register schema callback")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type schemaOrder struct {
+	ID   uint64
+	Name string
+}
+
+type schemaMessage struct {
+	ID    uint64
+	Topic string
+}
+
+func (schemaMessage) TableName() string { return "outbox_messages" }
+
+type tenantOrder struct {
+	ID       uint64
+	TenantID string
+}
+
+func TestQualifySchema(t *testing.T) {
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/d", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		NamingStrategy:         schema.NamingStrategy{TablePrefix: "tenant_acme."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := qualifySchema(gormDB, "tenant_acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func(tx *gorm.DB) *gorm.DB
+		want string
+	}{
+		{"naming strategy", func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]schemaOrder{}) }, "FROM `tenant_acme`.`schema_orders`"},
+		{"tabler query", func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]schemaMessage{}) }, "FROM `tenant_acme`.`outbox_messages`"},
+		{"tabler create", func(tx *gorm.DB) *gorm.DB { return tx.Create(&schemaMessage{Topic: "t"}) }, "INSERT INTO `tenant_acme`.`outbox_messages`"},
+		{"tabler update", func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(&schemaMessage{ID: 1}).Update("topic", "x")
+		}, "UPDATE `tenant_acme`.`outbox_messages`"},
+		{"tabler delete", func(tx *gorm.DB) *gorm.DB { return tx.Delete(&schemaMessage{ID: 1}) }, "DELETE FROM `tenant_acme`.`outbox_messages`"},
+		{"explicit table", func(tx *gorm.DB) *gorm.DB { return tx.Table("audit_logs").Find(&[]map[string]any{}) }, "FROM `tenant_acme`.`audit_logs`"},
+		{"qualified table", func(tx *gorm.DB) *gorm.DB { return tx.Table("shared.audit_logs").Find(&[]map[string]any{}) }, "FROM `shared`.`audit_logs`"},
+		{"alias", func(tx *gorm.DB) *gorm.DB { return tx.Table("audit_logs a").Find(&[]map[string]any{}) }, "FROM audit_logs a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := tt.run(gormDB.Session(&gorm.Session{})).Statement.SQL.String()
+			if !strings.Contains(sql, tt.want) {
+				t.Errorf("sql = %q, want it to contain %q", sql, tt.want)
+			}
+		})
+	}
+}
+
+// useTenancy enables tenancy with cfg until the test ends, removing the tenant schema
+// connections it opens.
+func useTenancy(t *testing.T, cfg *conf.Server_Tenancy) error {
+	t.Helper()
+	t.Cleanup(func() {
+		gTenancyMu.Lock()
+		gTenancy = nil
+		gTenancyMu.Unlock()
+		connsMu.Lock()
+		for name := range conns {
+			if strings.Contains(name, "/") {
+				delete(conns, name)
+			}
+		}
+		connsMu.Unlock()
+	})
+	return InitTenancy(cfg)
+}
+
+// schemaOrderIDs returns the IDs in the schema_orders table of gormDB.
+func schemaOrderIDs(t *testing.T, gormDB *gorm.DB) []uint64 {
+	t.Helper()
+	ids := []uint64{}
+	if err := gormDB.Table("schema_orders").Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestTenancyColumn(t *testing.T) {
+	gormDB := openSQLite(t, "tenancy_"+t.Name())
+	if err := gormDB.AutoMigrate(&tenantOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Set(DefaultName, gormDB, log.NewStdLogger(io.Discard)))
+	if err := useTenancy(t, &conf.Server_Tenancy{Enabled: true}); err != nil {
+		t.Fatalf("InitTenancy() error = %v", err)
+	}
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	if name, err := TenantConnection(acme); err != nil || name != DefaultName {
+		t.Errorf("TenantConnection() = %q, %v, want the default connection", name, err)
+	}
+	// The tenant plugin is registered on the connections.
+	if err := TenantDB(acme).Create(&tenantOrder{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var tenantID string
+	if err := gormDB.Table("tenant_orders").Where("id = ?", 1).Pluck("tenant_id", &tenantID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tenantID != "acme" {
+		t.Errorf("tenant_id = %q, want acme", tenantID)
+	}
+	if err := TenantDB(context.Background()).Find(&[]tenantOrder{}).Error; !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("Find() without tenant error = %v, want ErrMissingTenant", err)
+	}
+}
+
+func TestTenancyDatabase(t *testing.T) {
+	shards := map[string]*gorm.DB{}
+	for _, name := range []string{DefaultName, "tenant_acme"} {
+		shards[name] = openSQLite(t, fmt.Sprintf("tenancy_%s_%s", t.Name(), name))
+		if err := shards[name].AutoMigrate(&schemaOrder{}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(Set(name, shards[name], log.NewStdLogger(io.Discard)))
+	}
+
+	err := useTenancy(t, &conf.Server_Tenancy{Enabled: true, Mode: TenancyDatabase, Databases: map[string]string{"acme": "tenant_missing"}})
+	if err == nil {
+		t.Error("InitTenancy() with an uninitialized database did not fail")
+	}
+	if err := useTenancy(t, &conf.Server_Tenancy{Enabled: true, Mode: TenancyDatabase, Databases: map[string]string{"acme": "tenant_acme"}}); err != nil {
+		t.Fatalf("InitTenancy() error = %v", err)
+	}
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	if name, err := TenantConnection(acme); err != nil || name != "tenant_acme" {
+		t.Errorf("TenantConnection() = %q, %v, want tenant_acme", name, err)
+	}
+	if err := TenantDB(acme).Create(&schemaOrder{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := schemaOrderIDs(t, shards["tenant_acme"]); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("tenant_acme orders = %v, want [1]", got)
+	}
+	if got := schemaOrderIDs(t, shards[DefaultName]); len(got) != 0 {
+		t.Errorf("default orders = %v, want none", got)
+	}
+
+	globex := tenant.WithTenant(context.Background(), "globex")
+	if _, err := TenantConnection(globex); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("TenantConnection() of an unknown tenant error = %v, want ErrUnknownTenant", err)
+	}
+	if err := TenantDB(globex).Find(&[]schemaOrder{}).Error; !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("TenantDB() of an unknown tenant error = %v, want ErrUnknownTenant", err)
+	}
+	if _, err := TenantConnection(context.Background()); !errors.Is(err, tenant.ErrMissingTenant) {
+		t.Errorf("TenantConnection() without tenant error = %v, want ErrMissingTenant", err)
+	}
+}
+
+func TestTenancySchema(t *testing.T) {
+	gormDB := openSQLite(t, "tenancy_"+t.Name())
+	t.Cleanup(Set(DefaultName, gormDB, log.NewStdLogger(io.Discard)))
+
+	if err := useTenancy(t, &conf.Server_Tenancy{Enabled: true, Mode: TenancySchema}); err == nil {
+		t.Error("InitTenancy() for a driver without a Dialector did not fail")
+	}
+	lookup(DefaultName).driver.Dialector = func(_ string, conn gorm.ConnPool) gorm.Dialector {
+		return sqlite.New(sqlite.Config{Conn: conn})
+	}
+
+	// SQLite has no schemas; an attached database plays the tenant schema.
+	attach := fmt.Sprintf("ATTACH DATABASE 'file:tenancy_%s_acme?mode=memory&cache=shared' AS tenant_acme", t.Name())
+	for _, sql := range []string{
+		attach,
+		"CREATE TABLE schema_orders (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE tenant_acme.schema_orders (id INTEGER PRIMARY KEY, name TEXT)",
+	} {
+		if err := gormDB.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := useTenancy(t, &conf.Server_Tenancy{Enabled: true, Mode: TenancySchema, SchemaPrefix: "tenant_"}); err != nil {
+		t.Fatalf("InitTenancy() error = %v", err)
+	}
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	name, err := TenantConnection(acme)
+	if err != nil || name != DefaultName+"/tenant_acme" {
+		t.Fatalf("TenantConnection() = %q, %v, want %s/tenant_acme", name, err, DefaultName)
+	}
+	if again, _ := TenantConnection(acme); again != name || Get(name) != Get(again) {
+		t.Error("TenantConnection() opened the schema connection twice")
+	}
+	// The SQLite INSERT ignores qualified table expressions, so the rows are written by
+	// hand; TestQualifySchema covers inserts.
+	for _, sql := range []string{
+		"INSERT INTO schema_orders (id, name) VALUES (1, 'main'), (2, 'main')",
+		"INSERT INTO tenant_acme.schema_orders (id, name) VALUES (1, 'a'), (3, 'c')",
+	} {
+		if err := gormDB.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := TenantDB(acme).Model(&schemaOrder{ID: 1}).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := TenantDB(acme).Delete(&schemaOrder{ID: 3}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var orders []schemaOrder
+	if err := TenantDB(acme).Find(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].ID != 1 || orders[0].Name != "b" {
+		t.Errorf("tenant orders = %+v, want order 1 named b", orders)
+	}
+	var names []string
+	if err := gormDB.Table("schema_orders").Order("id").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"main", "main"}) {
+		t.Errorf("main schema orders = %v, want them untouched", names)
+	}
+
+	if _, err := TenantConnection(tenant.WithTenant(context.Background(), "acme;drop")); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("TenantConnection() of an invalid schema name error = %v, want ErrUnknownTenant", err)
+	}
+}
+
+func TestInitTenancyMode(t *testing.T) {
+	if err := useTenancy(t, nil); err != nil {
+		t.Errorf("InitTenancy(nil) error = %v", err)
+	}
+	if name, err := TenantConnection(context.Background()); err != nil || name != DefaultName {
+		t.Errorf("TenantConnection() without tenancy = %q, %v, want the default connection", name, err)
+	}
+	if err := useTenancy(t, &conf.Server_Tenancy{Enabled: true, Mode: "table"}); err == nil {
+		t.Error("InitTenancy() with an unknown mode did not fail")
+	}
+	if err := useTenancy(t, &conf.Server_Tenancy{Enabled: true, Mode: TenancySchema, Database: "missing"}); err == nil {
+		t.Error("InitTenancy() with an uninitialized schema database did not fail")
+	}
+}
@@ -0,0 +1,102 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth/jwt"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+)
+
+// ErrTenantRequired is returned to clients when a request names no tenant.
+var ErrTenantRequired = errors.BadRequest("TENANT_REQUIRED", "the request does not identify a tenant")
+
+// Resolver extracts the tenant ID of a request; it returns "" if the request names none.
+type Resolver func(ctx context.Context, tr transport.Transporter) string
+
+// FromHeader resolves the tenant from a request header, e.g. "X-Tenant-ID".
+func FromHeader(name string) Resolver {
+	return func(_ context.Context, tr transport.Transporter) string {
+		return strings.TrimSpace(tr.RequestHeader().Get(name))
+	}
+}
+
+// FromClaim resolves the tenant from a claim of the JWT verified by the kratos jwt.Server
+// middleware, which must run before the tenant middleware.
+func FromClaim(claim string) Resolver {
+	return func(ctx context.Context, _ transport.Transporter) string {
+		claims, ok := jwt.FromContext(ctx)
+		if !ok {
+			return ""
+		}
+		mc, ok := claims.(jwtv5.MapClaims)
+		if !ok {
+			return ""
+		}
+		if v, ok := mc[claim]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+}
+
+// FromSubdomain resolves the tenant from the first label of the request host below domain,
+// e.g. "acme" for "acme.example.com" with domain "example.com".
+func FromSubdomain(domain string) Resolver {
+	suffix := "." + strings.TrimPrefix(strings.ToLower(domain), ".")
+	return func(_ context.Context, tr transport.Transporter) string {
+		var host string
+		if ht, ok := tr.(khttp.Transporter); ok {
+			host = ht.Request().Host
+		} else {
+			host = tr.RequestHeader().Get(":authority")
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(host)
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if sub == "" || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// Server returns a kratos middleware that stores the tenant of each request in its
+// context. Resolvers are tried in order and the first non-empty tenant wins.
+//
+// Parameters:
+//   - required: Reject requests without a tenant with ErrTenantRequired
+//   - resolvers: The tenant sources, e.g. FromHeader("X-Tenant-ID")
+//
+// Returns:
+//   - middleware.Middleware: The tenant middleware
+func Server(required bool, resolvers ...Resolver) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			for _, resolve := range resolvers {
+				if id := resolve(ctx, tr); id != "" {
+					return handler(WithTenant(ctx, id), req)
+				}
+			}
+			if required {
+				return nil, ErrTenantRequired
+			}
+			return handler(ctx, req)
+		}
+	}
+}
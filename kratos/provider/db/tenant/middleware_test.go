@@ -0,0 +1,128 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware/auth/jwt"
+	"github.com/go-kratos/kratos/v2/transport"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+)
+
+// headerCarrier adapts http.Header to transport.Header.
+type headerCarrier http.Header
+
+func (h headerCarrier) Get(key string) string      { return http.Header(h).Get(key) }
+func (h headerCarrier) Set(key, value string)      { http.Header(h).Set(key, value) }
+func (h headerCarrier) Add(key, value string)      { http.Header(h).Add(key, value) }
+func (h headerCarrier) Values(key string) []string { return http.Header(h).Values(key) }
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// grpcTransport is a gRPC server transport carrying request headers.
+type grpcTransport struct {
+	header headerCarrier
+}
+
+func (t *grpcTransport) Kind() transport.Kind            { return transport.KindGRPC }
+func (t *grpcTransport) Endpoint() string                { return "" }
+func (t *grpcTransport) Operation() string               { return "/demo.v1.Demo/Get" }
+func (t *grpcTransport) RequestHeader() transport.Header { return t.header }
+func (t *grpcTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+
+// httpTransport is an HTTP server transport of a request.
+type httpTransport struct {
+	grpcTransport
+	request *http.Request
+}
+
+func (t *httpTransport) Kind() transport.Kind   { return transport.KindHTTP }
+func (t *httpTransport) Request() *http.Request { return t.request }
+func (t *httpTransport) PathTemplate() string   { return "/v1/demo" }
+
+func TestFromSubdomain(t *testing.T) {
+	resolve := FromSubdomain(".Example.com")
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"acme.example.com", "acme"},
+		{"ACME.example.com:8000", "acme"},
+		{"example.com", ""},
+		{"a.b.example.com", ""},
+		{"acme.example.org", ""},
+		{"acmeexample.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			ht := &httpTransport{grpcTransport: grpcTransport{header: headerCarrier{}}, request: &http.Request{Host: tt.host}}
+			if got := resolve(context.Background(), ht); got != tt.want {
+				t.Errorf("HTTP tenant = %q, want %q", got, tt.want)
+			}
+			gt := &grpcTransport{header: headerCarrier{":authority": {tt.host}}}
+			if got := resolve(context.Background(), gt); got != tt.want {
+				t.Errorf("gRPC tenant = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromClaim(t *testing.T) {
+	resolve := FromClaim("tenant_id")
+	tr := &grpcTransport{header: headerCarrier{}}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"claim", jwt.NewContext(context.Background(), jwtv5.MapClaims{"tenant_id": "acme"}), "acme"},
+		{"numeric claim", jwt.NewContext(context.Background(), jwtv5.MapClaims{"tenant_id": float64(42)}), "42"},
+		{"missing claim", jwt.NewContext(context.Background(), jwtv5.MapClaims{"sub": "u1"}), ""},
+		{"other claims type", jwt.NewContext(context.Background(), &jwtv5.RegisteredClaims{Subject: "u1"}), ""},
+		{"no token", context.Background(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolve(tt.ctx, tr); got != tt.want {
+				t.Errorf("tenant = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		id, _ := FromContext(ctx)
+		return id, nil
+	}
+	call := func(required bool, header headerCarrier) (interface{}, error) {
+		h := Server(required, FromHeader("X-Tenant-ID"), FromHeader("X-Org-ID"))(handler)
+		ctx := transport.NewServerContext(context.Background(), &grpcTransport{header: header})
+		return h(ctx, nil)
+	}
+
+	// Resolvers are tried in order and blank values are skipped.
+	got, err := call(true, headerCarrier{"X-Tenant-Id": {" "}, "X-Org-Id": {"globex"}})
+	if err != nil || got != "globex" {
+		t.Errorf("tenant = %v, %v, want globex", got, err)
+	}
+	got, err = call(true, headerCarrier{"X-Tenant-Id": {"acme"}, "X-Org-Id": {"globex"}})
+	if err != nil || got != "acme" {
+		t.Errorf("tenant = %v, %v, want acme", got, err)
+	}
+
+	if _, err := call(true, headerCarrier{}); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("error without tenant = %v, want ErrTenantRequired", err)
+	}
+	if got, err := call(false, headerCarrier{}); err != nil || got != "" {
+		t.Errorf("optional tenant = %v, %v, want none", got, err)
+	}
+}
@@ -0,0 +1,311 @@
+// Package tenant isolates the data of tenants sharing tables. The tenant of a request is
+// stored in its context; the GORM plugin filters queries, updates and deletes of models
+// having a tenant column by that tenant, stamps it on created rows and rejects writes
+// moving rows to another tenant.
+//
+// Only statements built from a model are covered: raw SQL executed with Raw or Exec, and
+// tables of joined models, must filter by tenant themselves (see Scope). Upserts are
+// restricted to the tenant's rows on PostgreSQL and SQLite only, as MySQL's ON DUPLICATE
+// KEY UPDATE cannot be conditioned.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultColumn is the default name of the tenant column.
+const DefaultColumn = "tenant_id"
+
+var (
+	// ErrMissingTenant is returned when a tenant scoped model is used without a tenant in context.
+	ErrMissingTenant = errors.New("tenant: no tenant in context")
+	// ErrCrossTenant is returned when a write would store a row for another tenant.
+	ErrCrossTenant = errors.New("tenant: cross-tenant write")
+)
+
+type (
+	// tenantKey is the context key of the tenant ID.
+	tenantKey struct{}
+	// skipKey is the context key set by SkipTenant.
+	skipKey struct{}
+)
+
+// WithTenant returns a context scoping database access to the tenant.
+//
+// Parameters:
+//   - ctx: The parent context
+//   - id: The tenant ID
+//
+// Returns:
+//   - context.Context: A context for db.WithContext(ctx)
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant set with WithTenant.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// SkipTenant returns a context whose database access is not scoped to a tenant, for
+// system tasks such as migrations, reports across tenants or tenant provisioning.
+func SkipTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// skipped reports whether ctx was returned by SkipTenant.
+func skipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipKey{}).(bool)
+	return skip
+}
+
+// Scope filters a query by the tenant in ctx, for tables the plugin cannot see, e.g.
+// db.Table("orders").Scopes(tenant.Scope(ctx)).
+//
+// Parameters:
+//   - ctx: The request context
+//   - column: Optional tenant column; "tenant_id" if omitted
+//
+// Returns:
+//   - func(*gorm.DB) *gorm.DB: A scope for gorm.DB.Scopes
+func Scope(ctx context.Context, column ...string) func(*gorm.DB) *gorm.DB {
+	col := DefaultColumn
+	if len(column) > 0 {
+		col = column[0]
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if skipped(ctx) {
+			return db
+		}
+		id, ok := FromContext(ctx)
+		if !ok {
+			_ = db.AddError(ErrMissingTenant)
+			return db
+		}
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: col}, Value: id})
+	}
+}
+
+// Plugin is the GORM tenant isolation plugin.
+type Plugin struct {
+	column string
+}
+
+// Option configures the Plugin.
+type Option func(*Plugin)
+
+// WithColumn sets the name of the tenant column (default "tenant_id").
+// Models with a field mapped to this column are tenant scoped.
+func WithColumn(column string) Option {
+	return func(p *Plugin) {
+		if column != "" {
+			p.column = column
+		}
+	}
+}
+
+// New creates the tenant plugin; register it with gormDB.Use.
+//
+// Parameters:
+//   - opts: Optional settings
+//
+// Returns:
+//   - *Plugin: The tenant plugin
+func New(opts ...Option) *Plugin {
+	p := &Plugin{column: DefaultColumn}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Name returns the plugin name.
+func (p *Plugin) Name() string {
+	return "tenant"
+}
+
+// Initialize registers the tenant callbacks.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("tenant:create", p.beforeCreate),
+		cb.Query().Before("gorm:query").Register("tenant:query", p.beforeQuery),
+		cb.Row().Before("gorm:row").Register("tenant:row", p.beforeQuery),
+		cb.Update().Before("gorm:update").Register("tenant:update", p.beforeUpdate),
+		cb.Delete().Before("gorm:delete").Register("tenant:delete", p.beforeDelete),
+	} {
+		if err != nil {
+			return errors.Wrap(err, "register tenant callback")
+		}
+	}
+	return nil
+}
+
+// tenantOf returns the tenant column of the statement's model and the tenant in context.
+// It returns a nil field if the statement is not scoped.
+func (p *Plugin) tenantOf(db *gorm.DB) (*schema.Field, string) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || skipped(stmt.Context) {
+		return nil, ""
+	}
+	field := stmt.Schema.LookUpField(p.column)
+	if field == nil {
+		return nil, ""
+	}
+	id, ok := FromContext(stmt.Context)
+	if !ok {
+		_ = db.AddError(ErrMissingTenant)
+		return nil, ""
+	}
+	return field, id
+}
+
+// beforeQuery filters reads by tenant.
+func (p *Plugin) beforeQuery(db *gorm.DB) {
+	field, id := p.tenantOf(db)
+	if field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{p.condition(field, id)}})
+}
+
+// beforeCreate stamps the tenant on new rows and rejects rows of other tenants.
+func (p *Plugin) beforeCreate(db *gorm.DB) {
+	field, id := p.tenantOf(db)
+	if field == nil {
+		return
+	}
+	stmt := db.Statement
+
+	if rows, ok := stmt.Dest.(map[string]interface{}); ok {
+		p.stampMap(db, field, id, rows, true)
+	} else if rows, ok := stmt.Dest.([]map[string]interface{}); ok {
+		for _, row := range rows {
+			p.stampMap(db, field, id, row, true)
+		}
+	} else {
+		eachRow(stmt.ReflectValue, func(row reflect.Value) {
+			p.stampStruct(db, field, id, row)
+		})
+	}
+
+	// An upsert must not update a conflicting row of another tenant.
+	if c, ok := stmt.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, p.condition(field, id))
+			stmt.AddClause(onConflict)
+		}
+	}
+}
+
+// beforeUpdate filters updates by tenant and rejects assignments of another tenant.
+func (p *Plugin) beforeUpdate(db *gorm.DB) {
+	field, id := p.tenantOf(db)
+	if field == nil || !p.requireConditions(db) {
+		return
+	}
+	stmt := db.Statement
+
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		p.stampMap(db, field, id, dest, false)
+	default:
+		if rv := reflect.Indirect(reflect.ValueOf(dest)); rv.Kind() == reflect.Struct && rv.Type() == stmt.Schema.ModelType {
+			p.stampStruct(db, field, id, rv)
+		}
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{p.condition(field, id)}})
+}
+
+// beforeDelete filters deletes by tenant.
+func (p *Plugin) beforeDelete(db *gorm.DB) {
+	field, id := p.tenantOf(db)
+	if field == nil || !p.requireConditions(db) {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{p.condition(field, id)}})
+}
+
+// requireConditions keeps GORM's protection against updates and deletes without
+// conditions, which the tenant condition would otherwise satisfy.
+func (p *Plugin) requireConditions(db *gorm.DB) bool {
+	stmt := db.Statement
+	if _, ok := stmt.Clauses["WHERE"]; ok || db.AllowGlobalUpdate {
+		return true
+	}
+	rv := reflect.Indirect(stmt.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() > 0 {
+			return true
+		}
+	case reflect.Struct:
+		for _, field := range stmt.Schema.PrimaryFields {
+			if _, zero := field.ValueOf(stmt.Context, rv); !zero {
+				return true
+			}
+		}
+	}
+	_ = db.AddError(gorm.ErrMissingWhereClause)
+	return false
+}
+
+// stampMap rejects a map row naming another tenant; if stamp is set, a row without a
+// tenant gets the tenant in context.
+func (p *Plugin) stampMap(db *gorm.DB, field *schema.Field, id string, row map[string]interface{}, stamp bool) {
+	for _, key := range []string{field.DBName, field.Name} {
+		if v, ok := row[key]; ok {
+			if fmt.Sprint(v) != id {
+				_ = db.AddError(ErrCrossTenant)
+			}
+			return
+		}
+	}
+	if stamp {
+		row[field.DBName] = id
+	}
+}
+
+// stampStruct sets the tenant of a struct row if it is empty, or rejects it if it
+// belongs to another tenant.
+func (p *Plugin) stampStruct(db *gorm.DB, field *schema.Field, id string, row reflect.Value) {
+	v, zero := field.ValueOf(db.Statement.Context, row)
+	if !zero {
+		if fmt.Sprint(v) != id {
+			_ = db.AddError(ErrCrossTenant)
+		}
+		return
+	}
+	if !row.CanAddr() {
+		return
+	}
+	if err := field.Set(db.Statement.Context, row, id); err != nil {
+		_ = db.AddError(errors.Wrap(err, "tenant: set tenant column"))
+	}
+}
+
+// condition returns the tenant filter on the statement's table.
+func (p *Plugin) condition(field *schema.Field, id string) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id}
+}
+
+// eachRow calls fn for the struct or every element of the slice in v.
+func eachRow(v reflect.Value, fn func(row reflect.Value)) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fn(reflect.Indirect(v.Index(i)))
+		}
+	case reflect.Struct:
+		fn(v)
+	}
+}
@@ -0,0 +1,179 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"kratos-project-template/provider/db/driver"
+	_ "kratos-project-template/provider/db/sqlite3"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// order is a tenant scoped model.
+type order struct {
+	ID       uint64 `gorm:"primaryKey"`
+	TenantID string
+	Name     string
+}
+
+// country is shared by all tenants.
+type country struct {
+	Code string `gorm:"primaryKey"`
+}
+
+// openDB opens an in-memory SQLite database with the tenant plugin and orders of the
+// tenants acme (1, 2) and globex (3).
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:tenant_%s?mode=memory&cache=shared", t.Name()), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := gormDB.AutoMigrate(&order{}, &country{}); err != nil {
+		t.Fatal(err)
+	}
+	rows := []order{{ID: 1, TenantID: "acme", Name: "a1"}, {ID: 2, TenantID: "acme", Name: "a2"}, {ID: 3, TenantID: "globex", Name: "g1"}}
+	if err := gormDB.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gormDB.Use(New()); err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+// orderIDs returns the IDs of all orders, bypassing the plugin.
+func orderIDs(t *testing.T, gormDB *gorm.DB, tenantID string) []uint64 {
+	t.Helper()
+	ids := []uint64{}
+	err := gormDB.WithContext(SkipTenant(context.Background())).Model(&order{}).
+		Where("tenant_id = ?", tenantID).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestPluginQuery(t *testing.T) {
+	gormDB := openDB(t)
+	acme := gormDB.WithContext(WithTenant(context.Background(), "acme"))
+
+	var orders []order
+	if err := acme.Order("id").Find(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].ID != 1 || orders[1].ID != 2 {
+		t.Errorf("Find() = %+v, want the orders of acme", orders)
+	}
+	if err := acme.First(&order{}, 3).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("First() of another tenant's row error = %v, want ErrRecordNotFound", err)
+	}
+	var n int64
+	if err := acme.Model(&order{}).Count(&n).Error; err != nil || n != 2 {
+		t.Errorf("Count() = %d, %v, want 2", n, err)
+	}
+
+	// Models without a tenant column are not scoped, and SkipTenant reads all tenants.
+	if err := gormDB.WithContext(context.Background()).Find(&[]country{}).Error; err != nil {
+		t.Errorf("Find() of a shared model error = %v", err)
+	}
+	if err := gormDB.WithContext(SkipTenant(context.Background())).Model(&order{}).Count(&n).Error; err != nil || n != 3 {
+		t.Errorf("Count() with SkipTenant = %d, %v, want 3", n, err)
+	}
+	if err := gormDB.WithContext(context.Background()).Find(&orders).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Find() without tenant error = %v, want ErrMissingTenant", err)
+	}
+}
+
+func TestPluginWrite(t *testing.T) {
+	gormDB := openDB(t)
+	acme := gormDB.WithContext(WithTenant(context.Background(), "acme"))
+
+	// Created rows get the tenant in context.
+	created := order{ID: 4, Name: "a3"}
+	if err := acme.Create(&created).Error; err != nil {
+		t.Fatal(err)
+	}
+	if created.TenantID != "acme" {
+		t.Errorf("created TenantID = %q, want acme", created.TenantID)
+	}
+	if err := acme.Model(&order{}).Create(map[string]interface{}{"id": 5, "name": "a4"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := orderIDs(t, gormDB, "acme"); !reflect.DeepEqual(got, []uint64{1, 2, 4, 5}) {
+		t.Errorf("acme orders = %v, want [1 2 4 5]", got)
+	}
+
+	// Writes cannot reach or move rows of another tenant.
+	if err := acme.Create(&order{ID: 6, TenantID: "globex"}).Error; !errors.Is(err, ErrCrossTenant) {
+		t.Errorf("Create() for another tenant error = %v, want ErrCrossTenant", err)
+	}
+	if err := acme.Model(&order{ID: 1}).Update("tenant_id", "globex").Error; !errors.Is(err, ErrCrossTenant) {
+		t.Errorf("Update() moving a row error = %v, want ErrCrossTenant", err)
+	}
+	res := acme.Model(&order{}).Where("id IN ?", []int{1, 3}).Update("name", "renamed")
+	if res.Error != nil || res.RowsAffected != 1 {
+		t.Errorf("Update() affected %d rows, error = %v, want 1", res.RowsAffected, res.Error)
+	}
+	if res := acme.Delete(&order{ID: 3}); res.Error != nil || res.RowsAffected != 0 {
+		t.Errorf("Delete() of another tenant's row affected %d rows, error = %v", res.RowsAffected, res.Error)
+	}
+	if got := orderIDs(t, gormDB, "globex"); !reflect.DeepEqual(got, []uint64{3}) {
+		t.Errorf("globex orders = %v, want [3]", got)
+	}
+
+	// The tenant condition does not satisfy GORM's check for global updates.
+	if err := acme.Model(&order{}).Update("name", "all").Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("Update() without conditions error = %v, want ErrMissingWhereClause", err)
+	}
+}
+
+func TestPluginUpsert(t *testing.T) {
+	gormDB := openDB(t)
+	acme := gormDB.WithContext(WithTenant(context.Background(), "acme"))
+
+	// The conflicting row belongs to globex, so the upsert leaves it alone.
+	err := acme.Clauses(clause.OnConflict{UpdateAll: true}).Create(&order{ID: 3, Name: "taken"}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := gormDB.WithContext(SkipTenant(context.Background())).Model(&order{}).Where("id = ?", 3).Pluck("name", &name).Error; err != nil {
+		t.Fatal(err)
+	}
+	if name != "g1" {
+		t.Errorf("globex order renamed to %q by an acme upsert", name)
+	}
+}
+
+func TestScope(t *testing.T) {
+	gormDB := openDB(t)
+	ctx := WithTenant(context.Background(), "globex")
+
+	var ids []uint64
+	if err := gormDB.Table("orders").Scopes(Scope(ctx)).Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []uint64{3}) {
+		t.Errorf("scoped ids = %v, want [3]", ids)
+	}
+	if err := gormDB.Table("orders").Scopes(Scope(context.Background())).Pluck("id", &ids).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Scope() without tenant error = %v, want ErrMissingTenant", err)
+	}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTxMaxRetries   = 3
+	defaultTxRetryBackoff = 10 * time.Millisecond
+	maxTxRetryBackoff     = time.Second
+)
+
+// txKey is the context key of the transaction of a connection.
+type txKey struct {
+	name string
+}
+
+// txState is a running transaction or savepoint stored in the context.
+type txState struct {
+	db *gorm.DB
+
+	mu    sync.Mutex
+	hooks []func(ctx context.Context)
+}
+
+// txOptions holds the settings of Transaction.
+type txOptions struct {
+	name       string
+	sqlOpts    *sql.TxOptions
+	maxRetries int
+}
+
+// TxOption configures Transaction.
+type TxOption func(*txOptions)
+
+// TxOn runs the transaction on a named connection instead of the default one.
+func TxOn(name string) TxOption {
+	return func(o *txOptions) {
+		o.name = name
+	}
+}
+
+// TxIsolation sets the isolation level and read-only mode of the transaction.
+func TxIsolation(level sql.IsolationLevel, readOnly bool) TxOption {
+	return func(o *txOptions) {
+		o.sqlOpts = &sql.TxOptions{Isolation: level, ReadOnly: readOnly}
+	}
+}
+
+// TxMaxRetries sets how often a transaction failing with a deadlock or serialization
+// failure is retried (default 3, 0 disables retries).
+func TxMaxRetries(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = n
+	}
+}
+
+// Transaction runs fn in a database transaction. The transaction is stored in the context
+// passed to fn, so repositories using DB(ctx) join it automatically.
+//
+// A nested call with a context that already carries a transaction on the same connection
+// runs fn in a savepoint: an error rolls back to the savepoint and is returned to the
+// outer fn, which decides whether the whole transaction fails.
+//
+// If the outermost transaction fails with a deadlock or serialization failure (as reported
+// by the driver), fn is run again in a new transaction, so fn must not have side effects
+// outside the database; use AfterCommit for those.
+//
+// Parameters:
+//   - ctx: Context for the transaction
+//   - fn: The unit of work; returning an error rolls the transaction back
+//   - opts: Optional settings
+//
+// Returns:
+//   - error: The error returned by fn, or an error beginning or committing the transaction
+func Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	o := txOptions{name: DefaultName, maxRetries: defaultTxMaxRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if parent := txFrom(ctx, o.name); parent != nil {
+		return parent.savepoint(ctx, o.name, fn)
+	}
+
+	c := lookup(o.name)
+	if c == nil {
+		return errors.Errorf("database %q is not initialized", o.name)
+	}
+
+	backoff := defaultTxRetryBackoff
+	for attempt := 0; ; attempt++ {
+		st := &txState{}
+		err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			st.db = tx
+			return fn(context.WithValue(ctx, txKey{o.name}, st))
+		}, o.sqlOpts)
+		if err == nil {
+			st.runHooks(ctx)
+			return nil
+		}
+
+		if attempt >= o.maxRetries || c.driver.Retryable == nil || !c.driver.Retryable(err) {
+			return err
+		}
+		c.log.Warnf("transaction attempt %d/%d failed: %v, retrying in %v...", attempt+1, o.maxRetries+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "transaction aborted, last error: %v", err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxTxRetryBackoff {
+			backoff = maxTxRetryBackoff
+		}
+	}
+}
+
+// DB returns the database to use with ctx: the transaction started by Transaction if
+// ctx carries one for the connection, otherwise the connection itself.
+// Repositories should use it instead of Get so they join the caller's transaction.
+//
+// Parameters:
+//   - ctx: The request context
+//   - name: Optional connection name; the default connection if omitted
+//
+// Returns:
+//   - *gorm.DB: A session bound to ctx
+//
+// Panics:
+//   - If the connection has not been initialized
+func DB(ctx context.Context, name ...string) *gorm.DB {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
+	}
+	if st := txFrom(ctx, n); st != nil {
+		return st.db.WithContext(ctx)
+	}
+	return Get(n).WithContext(ctx)
+}
+
+// InTransaction reports whether ctx carries a transaction on the connection.
+//
+// Parameters:
+//   - ctx: The request context
+//   - name: Optional connection name; the default connection if omitted
+func InTransaction(ctx context.Context, name ...string) bool {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
+	}
+	return txFrom(ctx, n) != nil
+}
+
+// AfterCommit registers fn to run after the transaction in ctx commits, e.g. to publish
+// events or invalidate caches. Hooks run in registration order with the context passed
+// to the outermost Transaction; they are dropped if the transaction (or the savepoint
+// they were registered in) rolls back, and run immediately if ctx has no transaction.
+//
+// Parameters:
+//   - ctx: The context passed to the transaction's fn
+//   - fn: The function to run after commit
+//   - name: Optional connection name; the default connection if omitted
+func AfterCommit(ctx context.Context, fn func(ctx context.Context), name ...string) {
+	n := DefaultName
+	if len(name) > 0 {
+		n = name[0]
+	}
+	st := txFrom(ctx, n)
+	if st == nil {
+		fn(ctx)
+		return
+	}
+	st.mu.Lock()
+	st.hooks = append(st.hooks, fn)
+	st.mu.Unlock()
+}
+
+// txFrom returns the transaction of a connection stored in ctx, or nil.
+func txFrom(ctx context.Context, name string) *txState {
+	st, _ := ctx.Value(txKey{name}).(*txState)
+	return st
+}
+
+// savepoint runs fn in a savepoint of the transaction. Hooks registered by fn are kept
+// only if it succeeds.
+func (st *txState) savepoint(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	child := &txState{}
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		child.db = tx
+		return fn(context.WithValue(ctx, txKey{name}, child))
+	})
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	st.hooks = append(st.hooks, child.hooks...)
+	st.mu.Unlock()
+	return nil
+}
+
+// runHooks runs the after-commit hooks.
+func (st *txState) runHooks(ctx context.Context) {
+	for _, fn := range st.hooks {
+		fn(ctx)
+	}
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// useTxDB registers an in-memory SQLite database with an items table as the default
+// connection until the test ends.
+func useTxDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	gormDB := openSQLite(t, "tx_"+t.Name())
+	if err := gormDB.Exec("CREATE TABLE items (name TEXT NOT NULL)").Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Set(DefaultName, gormDB, log.NewStdLogger(io.Discard)))
+	return gormDB
+}
+
+// insertItem inserts an item with the database of ctx.
+func insertItem(ctx context.Context, name string) error {
+	return DB(ctx).Exec("INSERT INTO items (name) VALUES (?)", name).Error
+}
+
+// itemNames returns the names of the committed items.
+func itemNames(t *testing.T, gormDB *gorm.DB) []string {
+	t.Helper()
+	names := []string{}
+	if err := gormDB.Table("items").Order("name").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestTransaction(t *testing.T) {
+	gormDB := useTxDB(t)
+	ctx := context.Background()
+
+	err := Transaction(ctx, func(ctx context.Context) error {
+		if !InTransaction(ctx) {
+			t.Error("InTransaction() = false inside the transaction")
+		}
+		return insertItem(ctx, "committed")
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+
+	failed := errors.New("failed")
+	err = Transaction(ctx, func(ctx context.Context) error {
+		if err := insertItem(ctx, "rolled back"); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Errorf("Transaction() error = %v, want the error of fn", err)
+	}
+	if InTransaction(ctx) {
+		t.Error("InTransaction() = true outside the transaction")
+	}
+	if got := itemNames(t, gormDB); !reflect.DeepEqual(got, []string{"committed"}) {
+		t.Errorf("items = %v, want [committed]", got)
+	}
+}
+
+func TestTransactionSavepoint(t *testing.T) {
+	gormDB := useTxDB(t)
+	ctx := context.Background()
+	var hooks []string
+
+	err := Transaction(ctx, func(ctx context.Context) error {
+		if err := insertItem(ctx, "outer"); err != nil {
+			return err
+		}
+		// A failing nested transaction rolls back to its savepoint only.
+		nested := Transaction(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "failed savepoint") })
+			if err := insertItem(ctx, "failed savepoint"); err != nil {
+				return err
+			}
+			return errors.New("nested failed")
+		})
+		if nested == nil {
+			t.Error("nested Transaction() error = nil, want the error of fn")
+		}
+		return Transaction(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "savepoint") })
+			return insertItem(ctx, "savepoint")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if got := itemNames(t, gormDB); !reflect.DeepEqual(got, []string{"outer", "savepoint"}) {
+		t.Errorf("items = %v, want [outer savepoint]", got)
+	}
+	if !reflect.DeepEqual(hooks, []string{"savepoint"}) {
+		t.Errorf("hooks run = %v, want [savepoint]", hooks)
+	}
+}
+
+func TestAfterCommit(t *testing.T) {
+	useTxDB(t)
+	ctx := context.Background()
+	var hooks []string
+
+	err := Transaction(ctx, func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "first") })
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "second") })
+		if len(hooks) != 0 {
+			t.Error("hook ran before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(hooks, []string{"first", "second"}) {
+		t.Errorf("hooks run = %v, want [first second]", hooks)
+	}
+
+	// Hooks of a rolled back transaction are dropped.
+	hooks = nil
+	_ = Transaction(ctx, func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "rolled back") })
+		return errors.New("failed")
+	})
+	if len(hooks) != 0 {
+		t.Errorf("hooks run = %v after rollback, want none", hooks)
+	}
+
+	// Without a transaction the hook runs at once.
+	AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "now") })
+	if !reflect.DeepEqual(hooks, []string{"now"}) {
+		t.Errorf("hooks run = %v without a transaction, want [now]", hooks)
+	}
+}
+
+func TestTransactionRetry(t *testing.T) {
+	errDeadlock := errors.New("deadlock")
+	tests := []struct {
+		name     string
+		opts     []TxOption
+		failures int
+		err      error
+		attempts int
+		wantErr  bool
+	}{
+		{name: "retried until it succeeds", failures: 2, err: errDeadlock, attempts: 3},
+		{name: "retry budget", opts: []TxOption{TxMaxRetries(1)}, failures: 5, err: errDeadlock, attempts: 2, wantErr: true},
+		{name: "retries disabled", opts: []TxOption{TxMaxRetries(0)}, failures: 1, err: errDeadlock, attempts: 1, wantErr: true},
+		{name: "other errors are not retried", failures: 1, err: errors.New("constraint"), attempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDB := useTxDB(t)
+			lookup(DefaultName).driver.Retryable = func(err error) bool { return errors.Is(err, errDeadlock) }
+
+			attempts := 0
+			err := Transaction(context.Background(), func(ctx context.Context) error {
+				attempts++
+				if err := insertItem(ctx, "attempt"); err != nil {
+					return err
+				}
+				if attempts <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("fn ran %d times, want %d", attempts, tt.attempts)
+			}
+			// Failed attempts leave nothing behind.
+			want := []string{}
+			if !tt.wantErr {
+				want = []string{"attempt"}
+			}
+			if got := itemNames(t, gormDB); !reflect.DeepEqual(got, want) {
+				t.Errorf("items = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+// Package election provides leader election for singleton background tasks.
+// An Elector campaigns for a lease held in a Backend (Redis or a database advisory lock),
+// renews it while it is leader and hands out a monotonically increasing fencing token for
+// every leadership term. It implements transport.Server so campaigning starts and stops
+// with the kratos application.
+package election
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Server = (*Elector)(nil)
+
+const (
+	defaultLease         = 15 * time.Second
+	defaultRenewInterval = 5 * time.Second
+	defaultRetryInterval = 5 * time.Second
+)
+
+// Backend stores the leadership lease.
+type Backend interface {
+	// TryAcquire attempts to take the lease for the candidate id.
+	// It returns the fencing token of the new term when the lease was acquired.
+	TryAcquire(ctx context.Context, id string, ttl time.Duration) (token int64, acquired bool, err error)
+	// Renew extends the lease held by id. It returns false if the lease was lost.
+	Renew(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	// Release gives up the lease held by id.
+	Release(ctx context.Context, id string) error
+}
+
+// ChangeFunc is called when leadership is gained or lost.
+type ChangeFunc func(isLeader bool, token int64)
+
+// Elector campaigns for leadership of a named election.
+type Elector struct {
+	name          string
+	id            string
+	backend       Backend
+	lease         time.Duration
+	renewInterval time.Duration
+	retryInterval time.Duration
+	log           *log.Helper
+
+	mu        sync.RWMutex
+	leader    bool
+	token     int64
+	changed   chan struct{}
+	callbacks []ChangeFunc
+
+	lifecycle sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+	stopped   chan struct{}
+	stopOnce  sync.Once
+}
+
+// New creates an elector.
+//
+// Parameters:
+//   - name: The election name; candidates with the same name compete for one lease
+//   - backend: The lease backend
+//   - cfg: Election configuration (may be nil to use defaults)
+//   - logger: Logger instance for leadership changes
+//
+// Returns:
+//   - *Elector: A new elector; it campaigns once started
+func New(name string, backend Backend, cfg *conf.Server_Election, logger log.Logger) *Elector {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	e := &Elector{
+		name:          name,
+		id:            fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+		backend:       backend,
+		lease:         defaultLease,
+		renewInterval: defaultRenewInterval,
+		retryInterval: defaultRetryInterval,
+		log:           log.NewHelper(log.With(logger, "module", "election")),
+		changed:       make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	if cfg.GetLease() != nil {
+		e.lease = cfg.GetLease().AsDuration()
+	}
+	if cfg.GetRenewInterval() != nil {
+		e.renewInterval = cfg.GetRenewInterval().AsDuration()
+	}
+	if cfg.GetRetryInterval() != nil {
+		e.retryInterval = cfg.GetRetryInterval().AsDuration()
+	}
+	return e
+}
+
+// newAlwaysLeader creates an elector that is permanently leader.
+// It is used when election is disabled so single-replica deployments need no backend.
+func newAlwaysLeader(logger log.Logger) *Elector {
+	e := New("", nil, nil, logger)
+	e.leader = true
+	return e
+}
+
+// ID returns the candidate identifier of this process.
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether this process currently holds the lease.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Token returns the fencing token of the current term, or 0 if not leader.
+// Pass it along with writes so stale leaders can be rejected downstream.
+func (e *Elector) Token() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if !e.leader {
+		return 0
+	}
+	return e.token
+}
+
+// OnChange registers a callback invoked whenever leadership is gained or lost.
+// Callbacks run synchronously on the campaign goroutine and should return quickly.
+func (e *Elector) OnChange(fn ChangeFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.callbacks = append(e.callbacks, fn)
+}
+
+// RunWhenLeader runs fn for every leadership term until ctx is done or the elector stops.
+// The context passed to fn is cancelled as soon as leadership is lost. If fn returns
+// while this process is still leader, RunWhenLeader returns fn's result.
+//
+// Parameters:
+//   - ctx: Context bounding the whole run
+//   - fn: The singleton task
+//
+// Returns:
+//   - error: ctx.Err() if ctx is done, fn's error if it returns during its term,
+//     or nil when the elector stops
+func (e *Elector) RunWhenLeader(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		e.mu.RLock()
+		leader, token, changed := e.leader, e.token, e.changed
+		e.mu.RUnlock()
+
+		if !leader {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-e.stopped:
+				return nil
+			case <-changed:
+				continue
+			}
+		}
+
+		termCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changed:
+			case <-e.stopped:
+			case <-termCtx.Done():
+			}
+			cancel()
+		}()
+
+		err := fn(termCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		select {
+		case <-e.stopped:
+			return nil
+		default:
+		}
+		if e.IsLeader() && e.Token() == token {
+			return err
+		}
+		if err != nil {
+			e.log.Warnf("[election] task ended with leadership loss: %v", err)
+		}
+	}
+}
+
+// Start campaigns for leadership until Stop is called or ctx is cancelled.
+//
+// Parameters:
+//   - ctx: The application context
+//
+// Returns:
+//   - error: Always nil; backend errors are logged and retried
+func (e *Elector) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer close(done)
+
+	e.lifecycle.Lock()
+	e.cancel = cancel
+	e.done = done
+	e.lifecycle.Unlock()
+
+	defer e.stopOnce.Do(func() { close(e.stopped) })
+
+	if e.backend == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	e.log.Infof("[election] campaigning: name=%s, id=%s", e.name, e.id)
+	var lastRenewed time.Time
+	for {
+		if !e.IsLeader() {
+			token, acquired, err := e.backend.TryAcquire(ctx, e.id, e.lease)
+			if err != nil && ctx.Err() == nil {
+				e.log.Warnf("[election] acquire lease failed: %v", err)
+			}
+			if acquired {
+				lastRenewed = time.Now()
+				e.setLeader(true, token)
+			}
+		} else {
+			ok, err := e.backend.Renew(ctx, e.id, e.lease)
+			switch {
+			case err == nil && ok:
+				lastRenewed = time.Now()
+			case err == nil && !ok:
+				e.setLeader(false, 0)
+			case ctx.Err() == nil:
+				e.log.Warnf("[election] renew lease failed: %v", err)
+				// Step down before the lease can expire so two leaders never overlap.
+				if time.Since(lastRenewed) >= e.lease-e.renewInterval {
+					e.setLeader(false, 0)
+				}
+			}
+		}
+
+		wait := e.retryInterval
+		if e.IsLeader() {
+			wait = e.renewInterval
+		}
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Stop stops campaigning, releases the lease if held and waits for Start to return.
+func (e *Elector) Stop(ctx context.Context) error {
+	e.lifecycle.Lock()
+	cancel, done := e.cancel, e.done
+	e.lifecycle.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resign releases the lease on shutdown.
+func (e *Elector) resign() {
+	if !e.IsLeader() {
+		return
+	}
+	e.setLeader(false, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.renewInterval)
+	defer cancel()
+	if err := e.backend.Release(ctx, e.id); err != nil {
+		e.log.Warnf("[election] release lease failed: %v", err)
+	}
+}
+
+// setLeader records a leadership change and notifies waiters and callbacks.
+func (e *Elector) setLeader(leader bool, token int64) {
+	e.mu.Lock()
+	if e.leader == leader {
+		e.mu.Unlock()
+		return
+	}
+	e.leader = leader
+	e.token = token
+	close(e.changed)
+	e.changed = make(chan struct{})
+	callbacks := append([]ChangeFunc(nil), e.callbacks...)
+	e.mu.Unlock()
+
+	if leader {
+		e.log.Infof("[election] became leader: name=%s, id=%s, token=%d", e.name, e.id, token)
+	} else {
+		e.log.Infof("[election] lost leadership: name=%s, id=%s", e.name, e.id)
+	}
+	for _, fn := range callbacks {
+		fn(leader, token)
+	}
+}
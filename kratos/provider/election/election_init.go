@@ -0,0 +1,94 @@
+package election
+
+import (
+	"context"
+	"sync"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/cache"
+	"kratos-project-template/provider/db"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+const defaultName = "kratos-project-template"
+
+var (
+	// gElector is the global elector instance
+	gElector *Elector
+	// gElectorMu protects gElector
+	gElectorMu sync.Mutex
+)
+
+// Init initializes the global elector based on configuration.
+//
+// Supported backends:
+//   - "redis" or empty: Redis lease with TTL
+//   - "db": Advisory lock on the configured Postgres or MySQL database
+//
+// When election is disabled the global elector is always leader, so code written with
+// RunWhenLeader behaves the same on single-replica deployments.
+//
+// Parameters:
+//   - ctx: Context for the initialization operation
+//   - cfg: Election configuration (may be nil to disable election)
+//   - logger: Logger instance for logging
+//
+// Returns:
+//   - error: Error if the backend is unknown or not available
+func Init(ctx context.Context, cfg *conf.Server_Election, logger log.Logger) error {
+	if !cfg.GetEnabled() {
+		gElectorMu.Lock()
+		gElector = newAlwaysLeader(logger)
+		gElectorMu.Unlock()
+		return nil
+	}
+
+	name := cfg.GetName()
+	if name == "" {
+		name = defaultName
+	}
+
+	var backend Backend
+	switch cfg.GetBackend() {
+	case "", "redis":
+		rdb := cache.GetRedisClient()
+		if rdb == nil {
+			return errors.New("redis election backend requires an initialized redis client")
+		}
+		backend = NewRedisBackend(rdb, name)
+	case "db":
+		gdb := db.Get()
+		sqlDB, err := gdb.DB()
+		if err != nil {
+			return errors.Wrap(err, "get sql db error")
+		}
+		b, err := NewSQLBackend(sqlDB, gdb.Dialector.Name(), name)
+		if err != nil {
+			return err
+		}
+		backend = b
+	default:
+		return errors.Errorf("unsupported election backend: %s", cfg.GetBackend())
+	}
+
+	gElectorMu.Lock()
+	gElector = New(name, backend, cfg, logger)
+	gElectorMu.Unlock()
+
+	log.NewHelper(logger).Infof("leader election initialized: name=%s, backend=%s", name, cfg.GetBackend())
+	return nil
+}
+
+// Default returns the global elector.
+// An always-leader elector is created on first use if Init has not been called.
+func Default() *Elector {
+	gElectorMu.Lock()
+	defer gElectorMu.Unlock()
+
+	if gElector == nil {
+		gElector = newAlwaysLeader(log.GetLogger())
+	}
+	return gElector
+}
@@ -0,0 +1,221 @@
+package election
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// memBackend is a lease shared by the electors of a test.
+type memBackend struct {
+	mu       sync.Mutex
+	holder   string
+	token    int64
+	renewErr error
+}
+
+func (b *memBackend) TryAcquire(_ context.Context, id string, _ time.Duration) (int64, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.holder != "" {
+		return 0, false, nil
+	}
+	b.holder = id
+	b.token++
+	return b.token, true, nil
+}
+
+func (b *memBackend) Renew(_ context.Context, id string, _ time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.renewErr != nil {
+		return false, b.renewErr
+	}
+	return b.holder == id, nil
+}
+
+func (b *memBackend) Release(_ context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.holder == id {
+		b.holder = ""
+	}
+	return nil
+}
+
+// expire drops the lease as if it timed out.
+func (b *memBackend) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holder = ""
+}
+
+func (b *memBackend) setRenewErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.renewErr = err
+}
+
+// newElector creates an elector with short intervals and starts it until the test ends.
+func newElector(t *testing.T, b Backend) *Elector {
+	t.Helper()
+	e := New("test", b, &conf.Server_Election{
+		Lease:         durationpb.New(100 * time.Millisecond),
+		RenewInterval: durationpb.New(10 * time.Millisecond),
+		RetryInterval: durationpb.New(10 * time.Millisecond),
+	}, log.NewStdLogger(io.Discard))
+	go func() { _ = e.Start(context.Background()) }()
+	t.Cleanup(func() { _ = e.Stop(context.Background()) })
+	return e
+}
+
+// eventually fails the test if cond does not hold within a second.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not happen", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestElectorSingleLeader(t *testing.T) {
+	b := &memBackend{}
+	first := newElector(t, b)
+	eventually(t, "first elector becoming leader", first.IsLeader)
+	if got := first.Token(); got != 1 {
+		t.Errorf("Token() = %d, want 1", got)
+	}
+
+	second := newElector(t, b)
+	time.Sleep(50 * time.Millisecond)
+	if second.IsLeader() || second.Token() != 0 {
+		t.Error("second elector became leader while the lease was held")
+	}
+
+	// Stopping the leader releases the lease, and the next term gets a higher token.
+	if err := first.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if first.IsLeader() {
+		t.Error("stopped elector is still leader")
+	}
+	eventually(t, "second elector taking over", second.IsLeader)
+	if got := second.Token(); got != 2 {
+		t.Errorf("Token() = %d, want 2", got)
+	}
+}
+
+func TestElectorLosesExpiredLease(t *testing.T) {
+	b := &memBackend{}
+	e := newElector(t, b)
+	var mu sync.Mutex
+	var changes []bool
+	e.OnChange(func(isLeader bool, token int64) {
+		mu.Lock()
+		changes = append(changes, isLeader)
+		mu.Unlock()
+	})
+	eventually(t, "becoming leader", e.IsLeader)
+
+	// Another candidate takes the expired lease before the elector renews it.
+	b.expire()
+	if _, ok, _ := b.TryAcquire(context.Background(), "other", time.Second); !ok {
+		t.Fatal("lease was not free")
+	}
+	eventually(t, "losing leadership", func() bool { return !e.IsLeader() })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("OnChange calls = %v, want [true false]", changes)
+	}
+}
+
+func TestElectorStepsDownWhenRenewFails(t *testing.T) {
+	b := &memBackend{}
+	e := newElector(t, b)
+	eventually(t, "becoming leader", e.IsLeader)
+
+	// The backend is unreachable: the elector keeps leadership for a while, then steps
+	// down before the lease it can no longer renew expires.
+	b.setRenewErr(errors.New("connection refused"))
+	time.Sleep(30 * time.Millisecond)
+	if !e.IsLeader() {
+		t.Error("stepped down on the first failed renewal")
+	}
+	eventually(t, "stepping down", func() bool { return !e.IsLeader() })
+}
+
+func TestRunWhenLeader(t *testing.T) {
+	b := &memBackend{}
+	e := newElector(t, b)
+
+	terms := make(chan int64, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- e.RunWhenLeader(context.Background(), func(ctx context.Context) error {
+			terms <- e.Token()
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+
+	if got := <-terms; got != 1 {
+		t.Errorf("first term token = %d, want 1", got)
+	}
+	// Losing the lease cancels the task, which runs again in the next term.
+	b.expire()
+	b.mu.Lock()
+	b.holder, b.token = "other", b.token+1
+	b.mu.Unlock()
+	eventually(t, "losing leadership", func() bool { return !e.IsLeader() })
+	b.expire()
+	select {
+	case got := <-terms:
+		if got != 3 {
+			t.Errorf("second term token = %d, want 3", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task did not run in the next term")
+	}
+
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunWhenLeader() after Stop error = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RunWhenLeader() did not return after Stop")
+	}
+}
+
+func TestRunWhenLeaderReturnsTaskResult(t *testing.T) {
+	e := newAlwaysLeader(log.NewStdLogger(io.Discard))
+	want := errors.New("task failed")
+	if err := e.RunWhenLeader(context.Background(), func(ctx context.Context) error { return want }); err != want {
+		t.Errorf("RunWhenLeader() error = %v, want %v", err, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := e.RunWhenLeader(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}); err != context.Canceled {
+		t.Errorf("RunWhenLeader() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
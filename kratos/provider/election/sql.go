@@ -0,0 +1,211 @@
+package election
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var _ Backend = (*SQLBackend)(nil)
+
+// fencingTable stores the last fencing token issued for every election name.
+const fencingTable = "leader_fencing_tokens"
+
+// SQLBackend holds leadership through a session-level advisory lock.
+// Postgres uses pg_try_advisory_lock and MySQL uses GET_LOCK. The lock lives as long as
+// the dedicated connection, so the lease TTL is not used: leadership ends when the lock
+// is released or the connection drops. Fencing tokens are kept in the
+// leader_fencing_tokens table, which is created on first use.
+type SQLBackend struct {
+	db      *sql.DB
+	dialect string
+	name    string
+	lockKey int64
+
+	mu          sync.Mutex
+	conn        *sql.Conn
+	tableExists bool
+}
+
+// NewSQLBackend creates an advisory-lock backend.
+//
+// Parameters:
+//   - db: The database handle
+//   - dialect: The database dialect, "postgres" or "mysql"
+//   - name: The election name
+//
+// Returns:
+//   - *SQLBackend: A new backend
+//   - error: Error if the dialect does not support advisory locks
+func NewSQLBackend(db *sql.DB, dialect, name string) (*SQLBackend, error) {
+	switch dialect {
+	case "postgres", "mysql":
+	default:
+		return nil, errors.Errorf("advisory-lock election is not supported by %s", dialect)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte("election:" + name))
+	return &SQLBackend{
+		db:      db,
+		dialect: dialect,
+		name:    name,
+		lockKey: int64(h.Sum64()),
+	}, nil
+}
+
+// TryAcquire takes the advisory lock on a dedicated connection and issues a fencing token.
+func (b *SQLBackend) TryAcquire(ctx context.Context, _ string, _ time.Duration) (int64, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.ensureTable(ctx); err != nil {
+		return 0, false, err
+	}
+
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "get election connection")
+	}
+
+	var acquired bool
+	switch b.dialect {
+	case "postgres":
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", b.lockKey).Scan(&acquired)
+	case "mysql":
+		var res sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", b.mysqlLockName()).Scan(&res)
+		acquired = res.Valid && res.Int64 == 1
+	}
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return 0, false, errors.Wrap(err, "acquire advisory lock")
+	}
+
+	token, err := b.nextToken(ctx, conn)
+	if err != nil {
+		b.unlock(ctx, conn)
+		return 0, false, err
+	}
+
+	b.conn = conn
+	return token, true, nil
+}
+
+// Renew checks that the connection still holds the advisory lock.
+func (b *SQLBackend) Renew(ctx context.Context, _ string, _ time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return false, nil
+	}
+
+	var (
+		held bool
+		err  error
+	)
+	switch b.dialect {
+	case "postgres":
+		// A bigint advisory key is split into classid (high 32 bits) and objid (low 32 bits).
+		err = b.conn.QueryRowContext(ctx, `SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND pid = pg_backend_pid()
+			  AND objsubid = 1 AND ((classid::bigint << 32) | objid::bigint) = $1)`, b.lockKey).Scan(&held)
+	case "mysql":
+		err = b.conn.QueryRowContext(ctx, "SELECT COALESCE(IS_USED_LOCK(?) = CONNECTION_ID(), 0)", b.mysqlLockName()).Scan(&held)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		// The lock is bound to the session; a broken connection means it is gone.
+		_ = b.conn.Close()
+		b.conn = nil
+		return false, nil
+	}
+	if !held {
+		_ = b.conn.Close()
+		b.conn = nil
+	}
+	return held, nil
+}
+
+// Release unlocks the advisory lock and closes the dedicated connection.
+func (b *SQLBackend) Release(ctx context.Context, _ string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return nil
+	}
+	b.unlock(ctx, b.conn)
+	b.conn = nil
+	return nil
+}
+
+// unlock releases the advisory lock held by conn and closes it.
+func (b *SQLBackend) unlock(ctx context.Context, conn *sql.Conn) {
+	switch b.dialect {
+	case "postgres":
+		_, _ = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", b.lockKey)
+	case "mysql":
+		_, _ = conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", b.mysqlLockName())
+	}
+	// Closing the session releases the lock even if the unlock statement failed.
+	_ = conn.Close()
+}
+
+// nextToken increments and returns the fencing token of the election.
+func (b *SQLBackend) nextToken(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var token int64
+	switch b.dialect {
+	case "postgres":
+		err := conn.QueryRowContext(ctx, `INSERT INTO `+fencingTable+` (name, token) VALUES ($1, 1)
+			ON CONFLICT (name) DO UPDATE SET token = `+fencingTable+`.token + 1
+			RETURNING token`, b.name).Scan(&token)
+		if err != nil {
+			return 0, errors.Wrap(err, "issue fencing token")
+		}
+	case "mysql":
+		// LAST_INSERT_ID(expr) makes the new value readable on the same connection.
+		_, err := conn.ExecContext(ctx, `INSERT INTO `+fencingTable+` (name, token) VALUES (?, LAST_INSERT_ID(1))
+			ON DUPLICATE KEY UPDATE token = LAST_INSERT_ID(token + 1)`, b.name)
+		if err != nil {
+			return 0, errors.Wrap(err, "issue fencing token")
+		}
+		if err := conn.QueryRowContext(ctx, "SELECT LAST_INSERT_ID()").Scan(&token); err != nil {
+			return 0, errors.Wrap(err, "read fencing token")
+		}
+	}
+	return token, nil
+}
+
+// ensureTable creates the fencing token table if needed.
+func (b *SQLBackend) ensureTable(ctx context.Context) error {
+	if b.tableExists {
+		return nil
+	}
+	_, err := b.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+fencingTable+` (
+		name VARCHAR(191) NOT NULL PRIMARY KEY,
+		token BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "create fencing token table")
+	}
+	b.tableExists = true
+	return nil
+}
+
+// mysqlLockName returns the GET_LOCK name, which MySQL limits to 64 characters.
+func (b *SQLBackend) mysqlLockName() string {
+	name := "election:" + b.name
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
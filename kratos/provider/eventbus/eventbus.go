@@ -0,0 +1,377 @@
+// Package eventbus provides a publish/subscribe event bus with typed topics.
+// Without a transport, events are dispatched in-process. With a transport (Redis pub/sub
+// or Redis Streams), events are published to Redis and every replica dispatches them to
+// its local subscribers, so the same API works for local and distributed fan-out.
+package eventbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned when publishing to or subscribing on a closed bus.
+var ErrClosed = errors.New("eventbus: bus is closed")
+
+// Envelope wraps an encoded event with its metadata.
+type Envelope struct {
+	// ID uniquely identifies the event.
+	ID string `json:"id"`
+	// Topic is the topic the event was published to.
+	Topic string `json:"topic"`
+	// Payload is the JSON encoded event.
+	Payload json.RawMessage `json:"payload"`
+	// Source identifies the process that published the event.
+	Source string `json:"source"`
+	// PublishedAt is the time the event was published.
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Topic is a named channel carrying events of type T.
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic declares a typed topic.
+//
+// Parameters:
+//   - name: The topic name, e.g. "user.created"
+//
+// Returns:
+//   - Topic[T]: A topic usable with Publish and Subscribe
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the topic name.
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Transport delivers encoded envelopes between replicas.
+type Transport interface {
+	// Publish sends an encoded envelope to all subscribers of the topic.
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe starts delivering encoded envelopes of the topic to deliver.
+	// The returned function stops the delivery.
+	Subscribe(ctx context.Context, topic string, deliver func(data []byte)) (func(), error)
+	// Close releases transport resources.
+	Close() error
+}
+
+// handlerFunc is the type-erased form of a subscriber handler.
+type handlerFunc func(ctx context.Context, env *Envelope) error
+
+// remoteSub is the transport subscription of a topic.
+type remoteSub struct {
+	cancel func()
+	// stopped drops deliveries that arrive while the subscription is being cancelled
+	stopped atomic.Bool
+}
+
+// Bus dispatches published events to subscribers.
+type Bus struct {
+	transport Transport
+	source    string
+	log       *log.Helper
+
+	mu     sync.RWMutex
+	subs   map[string][]*Subscription
+	remote map[string]*remoteSub
+	closed bool
+
+	// cancels tracks transport subscriptions being cancelled in the background
+	cancels sync.WaitGroup
+}
+
+// Option configures a Bus.
+type Option func(*Bus)
+
+// WithTransport makes the bus publish through t instead of dispatching in-process.
+func WithTransport(t Transport) Option {
+	return func(b *Bus) { b.transport = t }
+}
+
+// New creates an event bus.
+//
+// Parameters:
+//   - logger: Logger instance for reporting subscriber failures
+//   - opts: Optional settings such as WithTransport
+//
+// Returns:
+//   - *Bus: A new event bus
+func New(logger log.Logger, opts ...Option) *Bus {
+	host, _ := os.Hostname()
+	b := &Bus{
+		source: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		log:    log.NewHelper(log.With(logger, "module", "eventbus")),
+		subs:   make(map[string][]*Subscription),
+		remote: make(map[string]*remoteSub),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish publishes an event to a topic.
+// In local mode, synchronous subscribers run before Publish returns and their errors
+// are returned joined; asynchronous subscribers are queued. With a transport, Publish
+// returns once the transport accepted the event.
+//
+// Parameters:
+//   - ctx: Context passed to synchronous local subscribers
+//   - b: The bus to publish on
+//   - topic: The typed topic
+//   - event: The event value, encoded as JSON
+//
+// Returns:
+//   - error: Error if encoding, the transport or a synchronous local subscriber fails
+func Publish[T any](ctx context.Context, b *Bus, topic Topic[T], event T) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "encode event for topic %s", topic.name)
+	}
+	return b.publish(ctx, &Envelope{
+		ID:          newEventID(),
+		Topic:       topic.name,
+		Payload:     payload,
+		Source:      b.source,
+		PublishedAt: time.Now(),
+	})
+}
+
+// Subscribe registers a handler for a topic.
+//
+// Parameters:
+//   - b: The bus to subscribe on
+//   - topic: The typed topic
+//   - handler: The function called for every event; panics are recovered and logged
+//   - opts: Optional settings such as Async
+//
+// Returns:
+//   - *Subscription: The subscription, used to unsubscribe
+//   - error: Error if the bus is closed or the transport subscription fails
+func Subscribe[T any](b *Bus, topic Topic[T], handler func(ctx context.Context, event T) error, opts ...SubscribeOption) (*Subscription, error) {
+	fn := func(ctx context.Context, env *Envelope) error {
+		var event T
+		if err := json.Unmarshal(env.Payload, &event); err != nil {
+			return errors.Wrapf(err, "decode event %s of topic %s", env.ID, env.Topic)
+		}
+		return handler(ctx, event)
+	}
+	return b.subscribe(topic.name, fn, opts...)
+}
+
+// Close stops transport deliveries, drains asynchronous subscribers and closes the transport.
+// It waits for transport deliveries in progress, so it must not be called from a handler.
+//
+// Parameters:
+//   - ctx: Context bounding the wait for asynchronous subscribers
+//
+// Returns:
+//   - error: Error if draining times out or the transport fails to close
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	remotes := make([]*remoteSub, 0, len(b.remote))
+	for topic, r := range b.remote {
+		r.stopped.Store(true)
+		remotes = append(remotes, r)
+		delete(b.remote, topic)
+	}
+	var subs []*Subscription
+	for _, list := range b.subs {
+		subs = append(subs, list...)
+	}
+	b.subs = make(map[string][]*Subscription)
+	b.mu.Unlock()
+
+	// Cancelling waits for the delivery goroutines, which may need b.mu to dispatch.
+	for _, r := range remotes {
+		r.cancel()
+	}
+	b.cancels.Wait()
+
+	for _, s := range subs {
+		if err := s.drain(ctx); err != nil {
+			return err
+		}
+	}
+
+	if b.transport != nil {
+		return b.transport.Close()
+	}
+	return nil
+}
+
+// publish routes an envelope to the transport or to local subscribers.
+func (b *Bus) publish(ctx context.Context, env *Envelope) error {
+	b.mu.RLock()
+	closed := b.closed
+	b.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	if b.transport == nil {
+		return b.dispatch(ctx, env)
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "encode event envelope")
+	}
+	return errors.Wrapf(b.transport.Publish(ctx, env.Topic, data), "publish event to topic %s", env.Topic)
+}
+
+// deliver handles an envelope received from the transport.
+func (b *Bus) deliver(data []byte) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		b.log.Errorf("[eventbus] drop malformed event: %v", err)
+		return
+	}
+	if err := b.dispatch(context.Background(), &env); err != nil {
+		b.log.Errorf("[eventbus] handle event %s of topic %s failed: %v", env.ID, env.Topic, err)
+	}
+}
+
+// dispatch passes an envelope to every local subscriber of its topic.
+func (b *Bus) dispatch(ctx context.Context, env *Envelope) error {
+	b.mu.RLock()
+	subs := append([]*Subscription(nil), b.subs[env.Topic]...)
+	b.mu.RUnlock()
+
+	ctx = contextWithEnvelope(ctx, env)
+	var errs []error
+	for _, s := range subs {
+		if err := s.handle(ctx, env); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// subscribe registers a type-erased handler and starts transport delivery for new topics.
+// The transport is called without holding b.mu, as it may need a round trip to Redis.
+func (b *Bus) subscribe(topic string, fn handlerFunc, opts ...SubscribeOption) (*Subscription, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if _, ok := b.remote[topic]; b.transport == nil || ok {
+		s := newSubscription(b, topic, fn, opts...)
+		b.subs[topic] = append(b.subs[topic], s)
+		b.mu.Unlock()
+		return s, nil
+	}
+	b.mu.Unlock()
+
+	r := &remoteSub{}
+	cancel, err := b.transport.Subscribe(context.Background(), topic, func(data []byte) {
+		if !r.stopped.Load() {
+			b.deliver(data)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "subscribe to topic %s", topic)
+	}
+	r.cancel = cancel
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		cancel()
+		return nil, ErrClosed
+	}
+	if _, ok := b.remote[topic]; ok {
+		// A concurrent subscribe started delivery of the topic first.
+		r.stopped.Store(true)
+		b.stopRemote(r)
+	} else {
+		b.remote[topic] = r
+	}
+	s := newSubscription(b, topic, fn, opts...)
+	b.subs[topic] = append(b.subs[topic], s)
+	b.mu.Unlock()
+	return s, nil
+}
+
+// unsubscribe removes a subscription and stops transport delivery for unused topics.
+func (b *Bus) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := b.subs[s.topic]
+	for i, cur := range list {
+		if cur == s {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) > 0 {
+		b.subs[s.topic] = list
+		return
+	}
+
+	delete(b.subs, s.topic)
+	if r, ok := b.remote[s.topic]; ok {
+		r.stopped.Store(true)
+		delete(b.remote, s.topic)
+		b.stopRemote(r)
+	}
+}
+
+// stopRemote cancels a transport subscription in the background. Cancelling waits for
+// the delivery goroutine, which is the caller when a handler unsubscribes itself.
+func (b *Bus) stopRemote(r *remoteSub) {
+	b.cancels.Add(1)
+	go func() {
+		defer b.cancels.Done()
+		r.cancel()
+	}()
+}
+
+// envelopeKey is the context key for the envelope being dispatched.
+type envelopeKey struct{}
+
+// contextWithEnvelope stores the envelope in the context passed to handlers.
+func contextWithEnvelope(ctx context.Context, env *Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, env)
+}
+
+// EnvelopeFromContext returns the envelope of the event being handled.
+//
+// Parameters:
+//   - ctx: The context passed to a subscriber handler
+//
+// Returns:
+//   - *Envelope: The envelope, with ID, source and publish time
+//   - bool: False if ctx does not belong to an event handler
+func EnvelopeFromContext(ctx context.Context) (*Envelope, bool) {
+	env, ok := ctx.Value(envelopeKey{}).(*Envelope)
+	return env, ok
+}
+
+// newEventID generates a random event identifier.
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/cache"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPrefix       = "events"
+	defaultStreamMaxLen = 10000
+)
+
+var (
+	// gBus is the global event bus instance
+	gBus *Bus
+	// gBusMu protects gBus
+	gBusMu sync.Mutex
+)
+
+// Init initializes the global event bus based on configuration.
+//
+// Supported transports:
+//   - "local" or empty: in-process dispatch only
+//   - "redis_pubsub": Redis pub/sub fan-out across replicas
+//   - "redis_stream": Redis Streams fan-out across replicas
+//
+// Parameters:
+//   - ctx: Context for the initialization operation
+//   - cfg: Event bus configuration (may be nil for a local bus)
+//   - logger: Logger instance for logging
+//
+// Returns:
+//   - error: Error if the transport is unknown or Redis is not available
+func Init(ctx context.Context, cfg *conf.Data_EventBus, logger log.Logger) error {
+	prefix := cfg.GetPrefix()
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+
+	var opts []Option
+	switch cfg.GetTransport() {
+	case "", "local":
+	case "redis_pubsub":
+		rdb := cache.GetRedisClient()
+		if rdb == nil {
+			return errors.New("redis_pubsub transport requires an initialized redis client")
+		}
+		opts = append(opts, WithTransport(NewRedisPubSubTransport(rdb, prefix)))
+	case "redis_stream":
+		rdb := cache.GetRedisClient()
+		if rdb == nil {
+			return errors.New("redis_stream transport requires an initialized redis client")
+		}
+		maxLen := cfg.GetStreamMaxLen()
+		if maxLen == 0 {
+			maxLen = defaultStreamMaxLen
+		}
+		opts = append(opts, WithTransport(NewRedisStreamTransport(rdb, prefix, maxLen)))
+	default:
+		return errors.Errorf("unsupported event bus transport: %s", cfg.GetTransport())
+	}
+
+	gBusMu.Lock()
+	gBus = New(logger, opts...)
+	gBusMu.Unlock()
+
+	log.NewHelper(logger).Infof("event bus initialized: transport=%s", cfg.GetTransport())
+	return nil
+}
+
+// Default returns the global event bus.
+// A local bus is created on first use if Init has not been called.
+func Default() *Bus {
+	gBusMu.Lock()
+	defer gBusMu.Unlock()
+
+	if gBus == nil {
+		gBus = New(log.GetLogger())
+	}
+	return gBus
+}
@@ -0,0 +1,380 @@
+package eventbus
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+// memTransport delivers events on one goroutine per subscription. Like the Redis pub/sub
+// transport, cancelling waits for the delivery goroutine to exit.
+type memTransport struct {
+	mu   sync.Mutex
+	subs map[string][]chan []byte
+}
+
+func newMemTransport() *memTransport {
+	return &memTransport{subs: make(map[string][]chan []byte)}
+}
+
+func (t *memTransport) Publish(_ context.Context, topic string, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, ch := range t.subs[topic] {
+		ch <- data
+	}
+	return nil
+}
+
+func (t *memTransport) Subscribe(_ context.Context, topic string, deliver func(data []byte)) (func(), error) {
+	msgs := make(chan []byte, 16)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	t.mu.Lock()
+	t.subs[topic] = append(t.subs[topic], msgs)
+	t.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		for {
+			// Pending messages are delivered before a stop is noticed.
+			select {
+			case data := <-msgs:
+				deliver(data)
+				continue
+			default:
+			}
+			select {
+			case data := <-msgs:
+				deliver(data)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		t.mu.Lock()
+		list := t.subs[topic]
+		for i, ch := range list {
+			if ch == msgs {
+				t.subs[topic] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+		t.mu.Unlock()
+		close(stop)
+		<-done
+	}, nil
+}
+
+func (t *memTransport) Close() error {
+	return nil
+}
+
+var testTopic = NewTopic[string]("test.event")
+
+func testLogger() log.Logger {
+	return log.NewStdLogger(io.Discard)
+}
+
+// within fails the test if fn does not return in time, e.g. because of a deadlock.
+func within(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return", what)
+	}
+}
+
+func TestUnsubscribeFromHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SubscribeOption
+	}{
+		{name: "sync"},
+		{name: "async", opts: []SubscribeOption{Async(4, 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(testLogger(), WithTransport(newMemTransport()))
+			handled := make(chan string, 4)
+			var sub *Subscription
+			var err error
+			sub, err = Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+				sub.Unsubscribe()
+				handled <- event
+				return nil
+			}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Publish(context.Background(), b, testTopic, "first"); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-handled:
+				if got != "first" {
+					t.Errorf("handled %q, want first", got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("handler unsubscribing itself deadlocked")
+			}
+			within(t, "Close", func() { _ = b.Close(context.Background()) })
+			if len(handled) != 0 {
+				t.Errorf("handled %d events after unsubscribing", len(handled))
+			}
+		})
+	}
+}
+
+func TestCloseDuringDelivery(t *testing.T) {
+	b := New(testLogger(), WithTransport(newMemTransport()))
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	if _, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second event is delivered after the first handler returns, while Close is
+	// waiting for the delivery goroutine.
+	for _, event := range []string{"first", "second"} {
+		if err := Publish(context.Background(), b, testTopic, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-entered
+
+	closed := make(chan error, 1)
+	go func() { closed <- b.Close(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close during delivery deadlocked")
+	}
+	if err := Publish(context.Background(), b, testTopic, "late"); err != ErrClosed {
+		t.Errorf("Publish() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestUnsubscribeDuringDelivery(t *testing.T) {
+	b := New(testLogger(), WithTransport(newMemTransport()))
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	sub, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []string{"first", "second"} {
+		if err := Publish(context.Background(), b, testTopic, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-entered
+
+	within(t, "Unsubscribe", sub.Unsubscribe)
+	close(release)
+	within(t, "Close", func() { _ = b.Close(context.Background()) })
+	if len(entered) != 0 {
+		t.Error("event delivered after Unsubscribe returned")
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	b := New(testLogger(), WithTransport(newMemTransport()))
+	defer func() { _ = b.Close(context.Background()) }()
+
+	first, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Unsubscribe()
+
+	handled := make(chan string, 4)
+	if _, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		handled <- event
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Publish(context.Background(), b, testTopic, "again"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-handled:
+		if got != "again" {
+			t.Errorf("handled %q, want again", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered after resubscribing")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(handled) != 0 {
+		t.Error("event delivered twice after resubscribing")
+	}
+}
+
+func TestLocalPublishSync(t *testing.T) {
+	b := New(testLogger())
+	defer func() { _ = b.Close(context.Background()) }()
+
+	var handled []string
+	var source string
+	handlers := []func(ctx context.Context, event string) error{
+		func(ctx context.Context, event string) error {
+			return errors.New("first failed")
+		},
+		func(ctx context.Context, event string) error {
+			panic("second panicked")
+		},
+		func(ctx context.Context, event string) error {
+			if env, ok := EnvelopeFromContext(ctx); ok {
+				source = env.Source
+			}
+			handled = append(handled, event)
+			return nil
+		},
+	}
+	for _, h := range handlers {
+		if _, err := Subscribe(b, testTopic, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Failing and panicking subscribers do not keep the event from the others, and
+	// their errors are returned together.
+	err := Publish(context.Background(), b, testTopic, "created")
+	if err == nil || !strings.Contains(err.Error(), "first failed") || !strings.Contains(err.Error(), "second panicked") {
+		t.Errorf("Publish() error = %v, want both subscriber errors", err)
+	}
+	if !reflect.DeepEqual(handled, []string{"created"}) {
+		t.Errorf("handled %v, want [created]", handled)
+	}
+	if source != b.source {
+		t.Errorf("envelope source = %q, want %q", source, b.source)
+	}
+
+	if err := Publish(context.Background(), b, NewTopic[string]("other.event"), "ignored"); err != nil {
+		t.Errorf("Publish() to a topic without subscribers error = %v", err)
+	}
+}
+
+func TestLocalPublishAsync(t *testing.T) {
+	b := New(testLogger())
+	var mu sync.Mutex
+	var handled []string
+	release := make(chan struct{})
+	if _, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		<-release
+		mu.Lock()
+		handled = append(handled, event)
+		mu.Unlock()
+		return errors.New("async errors are only logged")
+	}, Async(8, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Publish returns before the subscriber runs.
+	events := []string{"a", "b", "c"}
+	for _, event := range events {
+		within(t, "Publish", func() {
+			if err := Publish(context.Background(), b, testTopic, event); err != nil {
+				t.Errorf("Publish() error = %v", err)
+			}
+		})
+	}
+	close(release)
+
+	// Close drains the queued events, handled in order by the single worker.
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !reflect.DeepEqual(handled, events) {
+		t.Errorf("handled %v, want %v", handled, events)
+	}
+	if err := Publish(context.Background(), b, testTopic, "late"); err != ErrClosed {
+		t.Errorf("Publish() after Close error = %v, want ErrClosed", err)
+	}
+	if _, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error { return nil }); err != ErrClosed {
+		t.Errorf("Subscribe() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestLocalCloseTimeout(t *testing.T) {
+	b := New(testLogger())
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		<-release
+		return nil
+	}, Async(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Publish(context.Background(), b, testTopic, "stuck"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestLocalUnsubscribe(t *testing.T) {
+	b := New(testLogger())
+	defer func() { _ = b.Close(context.Background()) }()
+
+	var first, second int
+	sub, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		first++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Subscribe(b, testTopic, func(ctx context.Context, event string) error {
+		second++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Publish(context.Background(), b, testTopic, "one"); err != nil {
+		t.Fatal(err)
+	}
+	sub.Unsubscribe()
+	if err := Publish(context.Background(), b, testTopic, "two"); err != nil {
+		t.Fatal(err)
+	}
+	if first != 1 || second != 2 {
+		t.Errorf("handled %d and %d events, want 1 and 2", first, second)
+	}
+}
@@ -0,0 +1,152 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	_ Transport = (*RedisPubSubTransport)(nil)
+	_ Transport = (*RedisStreamTransport)(nil)
+)
+
+// streamReadBlock bounds XREAD so stream readers notice cancellation promptly.
+const streamReadBlock = 2 * time.Second
+
+// RedisPubSubTransport fans events out through Redis pub/sub channels.
+// Delivery is at-most-once: replicas that are disconnected miss events.
+type RedisPubSubTransport struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+// NewRedisPubSubTransport creates a pub/sub transport.
+//
+// Parameters:
+//   - rdb: The Redis client
+//   - prefix: Channel name prefix; the channel of a topic is "<prefix>:<topic>"
+//
+// Returns:
+//   - *RedisPubSubTransport: A new transport
+func NewRedisPubSubTransport(rdb redis.UniversalClient, prefix string) *RedisPubSubTransport {
+	return &RedisPubSubTransport{rdb: rdb, prefix: prefix}
+}
+
+// Publish sends the envelope to the topic channel.
+func (t *RedisPubSubTransport) Publish(ctx context.Context, topic string, data []byte) error {
+	return t.rdb.Publish(ctx, t.prefix+":"+topic, data).Err()
+}
+
+// Subscribe listens on the topic channel until the returned function is called.
+func (t *RedisPubSubTransport) Subscribe(ctx context.Context, topic string, deliver func(data []byte)) (func(), error) {
+	ps := t.rdb.Subscribe(ctx, t.prefix+":"+topic)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range ps.Channel() {
+			deliver([]byte(msg.Payload))
+		}
+	}()
+
+	return func() {
+		_ = ps.Close()
+		<-done
+	}, nil
+}
+
+// Close is a no-op; the Redis client is owned by the cache provider.
+func (t *RedisPubSubTransport) Close() error {
+	return nil
+}
+
+// RedisStreamTransport fans events out through one Redis stream per topic.
+// Every replica reads the stream independently from the point it subscribed, so events
+// published while a replica is briefly disconnected are delivered once it reconnects.
+type RedisStreamTransport struct {
+	rdb    redis.UniversalClient
+	prefix string
+	maxLen int64
+
+	wg sync.WaitGroup
+}
+
+// NewRedisStreamTransport creates a streams transport.
+//
+// Parameters:
+//   - rdb: The Redis client
+//   - prefix: Stream name prefix; the stream of a topic is "<prefix>:<topic>"
+//   - maxLen: Approximate maximum length of each stream (0 disables trimming)
+//
+// Returns:
+//   - *RedisStreamTransport: A new transport
+func NewRedisStreamTransport(rdb redis.UniversalClient, prefix string, maxLen int64) *RedisStreamTransport {
+	return &RedisStreamTransport{rdb: rdb, prefix: prefix, maxLen: maxLen}
+}
+
+// Publish appends the envelope to the topic stream.
+func (t *RedisStreamTransport) Publish(ctx context.Context, topic string, data []byte) error {
+	return t.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: t.prefix + ":" + topic,
+		MaxLen: t.maxLen,
+		Approx: t.maxLen > 0,
+		Values: map[string]interface{}{"event": data},
+	}).Err()
+}
+
+// Subscribe reads new entries of the topic stream until the returned function is called.
+func (t *RedisStreamTransport) Subscribe(ctx context.Context, topic string, deliver func(data []byte)) (func(), error) {
+	stream := t.prefix + ":" + topic
+
+	// Start after the current last entry so only new events are delivered.
+	msgs, err := t.rdb.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	lastID := "0-0"
+	if len(msgs) > 0 {
+		lastID = msgs[0].ID
+	}
+
+	readCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		for readCtx.Err() == nil {
+			res, err := t.rdb.XRead(readCtx, &redis.XReadArgs{
+				Streams: []string{stream, lastID},
+				Block:   streamReadBlock,
+			}).Result()
+			if err != nil {
+				if !errors.Is(err, redis.Nil) && readCtx.Err() == nil {
+					time.Sleep(time.Second)
+				}
+				continue
+			}
+			for _, st := range res {
+				for _, msg := range st.Messages {
+					lastID = msg.ID
+					if data, ok := msg.Values["event"].(string); ok {
+						deliver([]byte(data))
+					}
+				}
+			}
+		}
+	}()
+
+	return cancel, nil
+}
+
+// Close waits for stream readers to exit.
+func (t *RedisStreamTransport) Close() error {
+	t.wg.Wait()
+	return nil
+}
@@ -0,0 +1,171 @@
+package eventbus
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAsyncBuffer  = 256
+	defaultAsyncWorkers = 1
+)
+
+// subscribeOptions holds per-subscription settings.
+type subscribeOptions struct {
+	async   bool
+	buffer  int
+	workers int
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(*subscribeOptions)
+
+// Async makes the subscriber run on its own goroutines instead of the publisher's.
+// Events are queued in a buffer; when it is full, dispatch waits for free space.
+//
+// Parameters:
+//   - buffer: Queue capacity (default 256 when <= 0)
+//   - workers: Number of goroutines handling events; 1 preserves event order (default 1 when <= 0)
+func Async(buffer, workers int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.async = true
+		o.buffer = buffer
+		o.workers = workers
+	}
+}
+
+// asyncEvent is an event queued for an asynchronous subscriber.
+type asyncEvent struct {
+	ctx context.Context
+	env *Envelope
+}
+
+// Subscription is a registered subscriber of a topic.
+type Subscription struct {
+	bus   *Bus
+	topic string
+	fn    handlerFunc
+
+	// async delivery state; queue is nil for synchronous subscribers
+	mu      sync.RWMutex
+	queue   chan asyncEvent
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// newSubscription creates a subscription and starts its workers if it is asynchronous.
+func newSubscription(b *Bus, topic string, fn handlerFunc, opts ...SubscribeOption) *Subscription {
+	o := subscribeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Subscription{bus: b, topic: topic, fn: fn}
+	if !o.async {
+		return s
+	}
+
+	if o.buffer <= 0 {
+		o.buffer = defaultAsyncBuffer
+	}
+	if o.workers <= 0 {
+		o.workers = defaultAsyncWorkers
+	}
+	s.queue = make(chan asyncEvent, o.buffer)
+	for i := 0; i < o.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for ev := range s.queue {
+				if err := s.invoke(ev.ctx, ev.env); err != nil {
+					s.bus.log.Errorf("[eventbus] async subscriber of topic %s failed on event %s: %v", s.topic, ev.env.ID, err)
+				}
+			}
+		}()
+	}
+	return s
+}
+
+// Topic returns the topic of the subscription.
+func (s *Subscription) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe removes the subscription from the bus. It does not wait for handlers, so
+// a handler may unsubscribe its own subscription. Events already queued for an
+// asynchronous subscriber are still handled.
+func (s *Subscription) Unsubscribe() {
+	s.bus.unsubscribe(s)
+	s.stop()
+}
+
+// handle runs the handler inline or queues the event for asynchronous handling.
+func (s *Subscription) handle(ctx context.Context, env *Envelope) error {
+	if s.queue == nil {
+		return s.invoke(ctx, env)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return nil
+	}
+
+	// Asynchronous handlers outlive the publisher's request.
+	ev := asyncEvent{ctx: context.WithoutCancel(ctx), env: env}
+	select {
+	case s.queue <- ev:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "queue event %s for async subscriber of topic %s", env.ID, s.topic)
+	}
+}
+
+// invoke calls the handler and isolates panics so one subscriber cannot break others.
+func (s *Subscription) invoke(ctx context.Context, env *Envelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.bus.log.Errorf("[eventbus] subscriber of topic %s panicked on event %s: %v\n%s", s.topic, env.ID, r, debug.Stack())
+			err = errors.Errorf("subscriber of topic %s panicked: %v", s.topic, r)
+		}
+	}()
+	return s.fn(ctx, env)
+}
+
+// stop makes an asynchronous subscription stop accepting events; its workers exit once
+// the queued events are handled.
+func (s *Subscription) stop() {
+	if s.queue == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+}
+
+// drain stops accepting events and waits for queued asynchronous events to be handled.
+func (s *Subscription) drain(ctx context.Context) error {
+	if s.queue == nil {
+		return nil
+	}
+	s.stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "drain subscriber of topic %s", s.topic)
+	}
+}
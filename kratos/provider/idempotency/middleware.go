@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/auth/jwt"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Errors returned to clients.
+var (
+	// ErrInProgress is returned while the first request with the same key is still running.
+	ErrInProgress = errors.Conflict("IDEMPOTENCY_REQUEST_IN_PROGRESS",
+		"a request with the same Idempotency-Key is still being processed")
+	// ErrKeyReused is returned when a key is reused with a different request payload.
+	ErrKeyReused = errors.New(http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+		"Idempotency-Key was already used with a different request payload")
+)
+
+// Server returns a kratos middleware that enforces Idempotency-Key semantics for
+// HTTP (POST, PUT, PATCH, DELETE) and unary gRPC requests.
+// Requests without the header pass through unchanged. Only successful replies are
+// stored; when the handler fails the key is released so the client can retry.
+// If Redis is unreachable the middleware fails open and calls the handler.
+//
+// Keys are scoped by operation, tenant and the subject of the JWT verified by the kratos
+// jwt.Server middleware, so clients reusing a key never get each other's responses. Add
+// this middleware after the tenant and jwt middleware.
+//
+// Parameters:
+//   - store: The idempotency store
+//   - logger: Logger instance for reporting store failures
+//
+// Returns:
+//   - middleware.Middleware: The idempotency middleware
+func Server(store *Store, logger log.Logger) middleware.Middleware {
+	logHelper := log.NewHelper(log.With(logger, "module", "idempotency"))
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			key := tr.RequestHeader().Get(HeaderKey)
+			if key == "" {
+				return handler(ctx, req)
+			}
+			if ht, ok := tr.(khttp.Transporter); ok && !isMutating(ht.Request().Method) {
+				return handler(ctx, req)
+			}
+
+			scope := callerScope(ctx, tr.Operation())
+			fingerprint, err := requestFingerprint(scope, req)
+			if err != nil {
+				return nil, errors.BadRequest("IDEMPOTENCY_FINGERPRINT", err.Error())
+			}
+
+			rec, reserved, err := store.Reserve(ctx, scope, key, fingerprint)
+			if err != nil {
+				logHelper.Warnf("idempotency store unavailable, processing request without it: %v", err)
+				return handler(ctx, req)
+			}
+
+			if !reserved {
+				return replay(tr, rec, fingerprint)
+			}
+
+			reply, err := handler(ctx, req)
+			bgCtx := context.WithoutCancel(ctx)
+			if err != nil {
+				if rerr := store.Release(bgCtx, scope, key, rec); rerr != nil {
+					logHelper.Warnf("release idempotency key failed: %v", rerr)
+				}
+				return reply, err
+			}
+
+			if err := complete(bgCtx, store, scope, key, rec, reply); err != nil {
+				logHelper.Warnf("store idempotent response failed: %v", err)
+			}
+			return reply, nil
+		}
+	}
+}
+
+// replay answers a retry from an existing record.
+func replay(tr transport.Transporter, rec *Record, fingerprint string) (interface{}, error) {
+	if rec.Fingerprint != fingerprint {
+		return nil, ErrKeyReused
+	}
+	if rec.InProgress() {
+		return nil, ErrInProgress
+	}
+
+	var anyMsg anypb.Any
+	if err := proto.Unmarshal(rec.Response, &anyMsg); err != nil {
+		return nil, errors.InternalServer("IDEMPOTENCY_REPLAY", "stored response is corrupted")
+	}
+	reply, err := anyMsg.UnmarshalNew()
+	if err != nil {
+		return nil, errors.InternalServer("IDEMPOTENCY_REPLAY", "stored response type is unknown")
+	}
+
+	tr.ReplyHeader().Set(HeaderReplayed, "true")
+	return reply, nil
+}
+
+// complete serializes a successful reply into the record.
+func complete(ctx context.Context, store *Store, scope, key string, rec *Record, reply interface{}) error {
+	msg, ok := reply.(proto.Message)
+	if !ok {
+		// Only protobuf replies can be replayed; let the client retry instead.
+		return store.Release(ctx, scope, key, rec)
+	}
+
+	anyMsg, err := anypb.New(msg)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(anyMsg)
+	if err != nil {
+		return err
+	}
+	rec.Response = data
+	return store.Complete(ctx, scope, key, rec)
+}
+
+// callerScope returns the namespace of a key: the operation and, if the request has a
+// tenant or an authenticated subject, a hash of both.
+func callerScope(ctx context.Context, operation string) string {
+	tenantID, _ := tenant.FromContext(ctx)
+	var subject string
+	if claims, ok := jwt.FromContext(ctx); ok {
+		subject, _ = claims.GetSubject()
+	}
+	if tenantID == "" && subject == "" {
+		return operation
+	}
+	h := sha256.Sum256([]byte(tenantID + "\x00" + subject))
+	return operation + ":" + hex.EncodeToString(h[:8])
+}
+
+// requestFingerprint hashes the operation and the request payload.
+func requestFingerprint(operation string, req interface{}) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if msg, ok := req.(proto.Message); ok {
+		data, err = proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	} else {
+		data, err = json.Marshal(req)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(operation))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isMutating reports whether an HTTP method changes server state.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,248 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"kratos-project-template/provider/db/tenant"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeRedis answers the store scripts in memory instead of a Redis server.
+type fakeRedis struct {
+	mu      sync.Mutex
+	records map[string]string
+	down    bool
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		c := cmd.(*redis.Cmd)
+		if f.down {
+			c.SetErr(&net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")})
+			return c.Err()
+		}
+
+		// EVALSHA sha numkeys key argv...
+		args := cmd.Args()
+		key := args[3].(string)
+		cur, ok := f.records[key]
+		switch args[1] {
+		case reserveScript.Hash():
+			if !ok {
+				f.records[key] = string(args[4].([]byte))
+				c.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			c.SetVal(cur)
+		case ownerScript.Hash():
+			var rec Record
+			if !ok || json.Unmarshal([]byte(cur), &rec) != nil || rec.Token != args[4] {
+				c.SetVal(int64(0))
+				return nil
+			}
+			if data, _ := args[5].([]byte); len(data) > 0 {
+				f.records[key] = string(data)
+			} else {
+				delete(f.records, key)
+			}
+			c.SetVal(int64(1))
+		default:
+			c.SetErr(fmt.Errorf("unexpected command %v", args))
+		}
+		return c.Err()
+	}
+}
+
+func newFakeStore() (*Store, *fakeRedis) {
+	f := &fakeRedis{records: make(map[string]string)}
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	rdb.AddHook(f)
+	return NewStore(rdb, nil), f
+}
+
+// headerCarrier adapts http.Header to transport.Header.
+type headerCarrier http.Header
+
+func (h headerCarrier) Get(key string) string      { return http.Header(h).Get(key) }
+func (h headerCarrier) Set(key, value string)      { http.Header(h).Set(key, value) }
+func (h headerCarrier) Add(key, value string)      { http.Header(h).Add(key, value) }
+func (h headerCarrier) Values(key string) []string { return http.Header(h).Values(key) }
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// testTransport is a gRPC server transport carrying request and reply headers.
+type testTransport struct {
+	request headerCarrier
+	reply   headerCarrier
+}
+
+func (t *testTransport) Kind() transport.Kind            { return transport.KindGRPC }
+func (t *testTransport) Endpoint() string                { return "" }
+func (t *testTransport) Operation() string               { return "/demo.v1.Demo/Create" }
+func (t *testTransport) RequestHeader() transport.Header { return t.request }
+func (t *testTransport) ReplyHeader() transport.Header   { return t.reply }
+
+// counter is a handler counting its calls and failing while err is set.
+type counter struct {
+	calls int
+	err   error
+}
+
+func (c *counter) handle(ctx context.Context, req interface{}) (interface{}, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return wrapperspb.String(fmt.Sprintf("reply %d", c.calls)), nil
+}
+
+// call runs the middleware with the idempotency key, returning the reply and whether it
+// was replayed.
+func call(ctx context.Context, h middleware.Handler, key, payload string) (interface{}, bool, error) {
+	tr := &testTransport{request: headerCarrier{}, reply: headerCarrier{}}
+	if key != "" {
+		tr.request.Set(HeaderKey, key)
+	}
+	reply, err := h(transport.NewServerContext(ctx, tr), wrapperspb.String(payload))
+	return reply, tr.reply.Get(HeaderReplayed) == "true", err
+}
+
+func TestServerReplay(t *testing.T) {
+	store, _ := newFakeStore()
+	c := &counter{}
+	h := Server(store, log.NewStdLogger(io.Discard))(c.handle)
+	ctx := context.Background()
+
+	first, replayed, err := call(ctx, h, "k1", "order")
+	if err != nil || replayed {
+		t.Fatalf("first call = %v, replayed %v, error %v", first, replayed, err)
+	}
+	retry, replayed, err := call(ctx, h, "k1", "order")
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+	if !replayed || !proto.Equal(retry.(proto.Message), first.(proto.Message)) {
+		t.Errorf("retry = %v, replayed %v, want %v replayed", retry, replayed, first)
+	}
+	if c.calls != 1 {
+		t.Errorf("handler called %d times, want 1", c.calls)
+	}
+
+	// A key reused with another payload is rejected.
+	if _, _, err := call(ctx, h, "k1", "other order"); errors.Code(err) != http.StatusUnprocessableEntity {
+		t.Errorf("reused key error = %v, want 422", err)
+	}
+	// Requests without a key are not deduplicated.
+	for i := 0; i < 2; i++ {
+		if _, replayed, err := call(ctx, h, "", "order"); err != nil || replayed {
+			t.Errorf("call without key replayed %v, error %v", replayed, err)
+		}
+	}
+	if c.calls != 3 {
+		t.Errorf("handler called %d times, want 3", c.calls)
+	}
+}
+
+func TestServerInProgress(t *testing.T) {
+	store, _ := newFakeStore()
+	ctx := context.Background()
+	var h middleware.Handler
+	var inner error
+	h = Server(store, log.NewStdLogger(io.Discard))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		// A duplicate arriving while the first request runs.
+		_, _, inner = call(ctx, h, "k1", "order")
+		return wrapperspb.String("done"), nil
+	})
+
+	if _, _, err := call(ctx, h, "k1", "order"); err != nil {
+		t.Fatalf("call error = %v", err)
+	}
+	if errors.Code(inner) != http.StatusConflict {
+		t.Errorf("concurrent duplicate error = %v, want 409", inner)
+	}
+}
+
+func TestServerReleasesFailedRequests(t *testing.T) {
+	store, f := newFakeStore()
+	c := &counter{err: errors.ServiceUnavailable("DOWN", "try again")}
+	h := Server(store, log.NewStdLogger(io.Discard))(c.handle)
+	ctx := context.Background()
+
+	if _, _, err := call(ctx, h, "k1", "order"); errors.Code(err) != http.StatusServiceUnavailable {
+		t.Fatalf("call error = %v, want the handler error", err)
+	}
+	if len(f.records) != 0 {
+		t.Errorf("failed request left %d records", len(f.records))
+	}
+
+	// The retry runs the handler again.
+	c.err = nil
+	if _, replayed, err := call(ctx, h, "k1", "order"); err != nil || replayed {
+		t.Errorf("retry replayed %v, error %v", replayed, err)
+	}
+	if c.calls != 2 {
+		t.Errorf("handler called %d times, want 2", c.calls)
+	}
+}
+
+func TestServerScopesKeysByTenant(t *testing.T) {
+	store, _ := newFakeStore()
+	c := &counter{}
+	h := Server(store, log.NewStdLogger(io.Discard))(c.handle)
+
+	for _, tenantID := range []string{"acme", "globex"} {
+		ctx := tenant.WithTenant(context.Background(), tenantID)
+		if _, replayed, err := call(ctx, h, "k1", "order"); err != nil || replayed {
+			t.Errorf("tenant %s: replayed %v, error %v", tenantID, replayed, err)
+		}
+	}
+	if c.calls != 2 {
+		t.Errorf("handler called %d times, want 2", c.calls)
+	}
+}
+
+func TestServerFailsOpen(t *testing.T) {
+	store, f := newFakeStore()
+	f.down = true
+	c := &counter{}
+	h := Server(store, log.NewStdLogger(io.Discard))(c.handle)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := call(context.Background(), h, "k1", "order"); err != nil {
+			t.Fatalf("call error = %v", err)
+		}
+	}
+	if c.calls != 2 {
+		t.Errorf("handler called %d times, want 2", c.calls)
+	}
+}
@@ -0,0 +1,201 @@
+// Package idempotency implements Idempotency-Key handling for mutating endpoints.
+// The first request carrying a key reserves it in Redis together with a fingerprint of
+// the request; once the handler succeeds the serialized response is stored and replayed
+// for retries with the same key. Concurrent duplicates are rejected with 409 Conflict and
+// retries whose payload differs from the original are rejected with 422.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// HeaderKey is the request header carrying the idempotency key.
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed is set on responses that were replayed from the store.
+const HeaderReplayed = "Idempotent-Replayed"
+
+const (
+	defaultPrefix  = "idempotency"
+	defaultTTL     = 24 * time.Hour
+	defaultLockTTL = time.Minute
+
+	stateInProgress = "in_progress"
+	stateCompleted  = "completed"
+)
+
+// reserveScript reserves a key or returns the existing record.
+//
+// KEYS[1]: record key
+// ARGV[1]: new record, ARGV[2]: lock TTL in milliseconds
+var reserveScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return false
+end
+return redis.call('GET', KEYS[1])
+`)
+
+// ownerScript completes or releases a record only if it is still held by the caller.
+//
+// KEYS[1]: record key
+// ARGV[1]: lock token, ARGV[2]: new record ("" deletes), ARGV[3]: TTL in milliseconds
+var ownerScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if not cur then
+	return 0
+end
+if cjson.decode(cur)['token'] ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1
+`)
+
+// Record is the stored state of an idempotency key.
+type Record struct {
+	// State is "in_progress" while the first request runs and "completed" afterwards.
+	State string `json:"state"`
+	// Token identifies the request holding the key.
+	Token string `json:"token"`
+	// Fingerprint is a hash of the request the key was first used with.
+	Fingerprint string `json:"fingerprint"`
+	// Response is the serialized response replayed for retries.
+	Response []byte `json:"response,omitempty"`
+	// Meta carries transport specific response data such as status code or content type.
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// Store keeps idempotency records in Redis.
+type Store struct {
+	rdb     redis.UniversalClient
+	prefix  string
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewStore creates a Redis-backed idempotency store.
+//
+// Parameters:
+//   - rdb: The Redis client
+//   - cfg: Idempotency configuration (may be nil to use defaults)
+//
+// Returns:
+//   - *Store: A new store
+func NewStore(rdb redis.UniversalClient, cfg *conf.Server_Idempotency) *Store {
+	s := &Store{
+		rdb:     rdb,
+		prefix:  defaultPrefix,
+		ttl:     defaultTTL,
+		lockTTL: defaultLockTTL,
+	}
+	if cfg.GetPrefix() != "" {
+		s.prefix = cfg.GetPrefix()
+	}
+	if cfg.GetTtl() != nil {
+		s.ttl = cfg.GetTtl().AsDuration()
+	}
+	if cfg.GetLockTtl() != nil {
+		s.lockTTL = cfg.GetLockTtl().AsDuration()
+	}
+	return s
+}
+
+// Reserve claims a key for a request.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - scope: Namespace of the key, usually the operation or route
+//   - key: The client supplied idempotency key
+//   - fingerprint: Hash of the request
+//
+// Returns:
+//   - *Record: The new in-progress record if reserved, otherwise the existing record
+//   - bool: True if the key was reserved by this call
+//   - error: Error if the Redis operation fails
+func (s *Store) Reserve(ctx context.Context, scope, key, fingerprint string) (*Record, bool, error) {
+	rec := &Record{State: stateInProgress, Token: newToken(), Fingerprint: fingerprint}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "encode idempotency record")
+	}
+
+	res, err := reserveScript.Run(ctx, s.rdb, []string{s.recordKey(scope, key)}, data, s.lockTTL.Milliseconds()).Result()
+	if errors.Is(err, redis.Nil) {
+		return rec, true, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "reserve idempotency key")
+	}
+
+	raw, _ := res.(string)
+	var existing Record
+	if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+		return nil, false, errors.Wrap(err, "decode idempotency record")
+	}
+	return &existing, false, nil
+}
+
+// Complete stores the response of a reserved key so later retries replay it.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - scope: Namespace of the key
+//   - key: The client supplied idempotency key
+//   - rec: The record returned by Reserve, with Response and Meta filled in
+//
+// Returns:
+//   - error: Error if the Redis operation fails
+func (s *Store) Complete(ctx context.Context, scope, key string, rec *Record) error {
+	rec.State = stateCompleted
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return errors.Wrap(err, "encode idempotency record")
+	}
+	err = ownerScript.Run(ctx, s.rdb, []string{s.recordKey(scope, key)}, rec.Token, data, s.ttl.Milliseconds()).Err()
+	return errors.Wrap(err, "complete idempotency key")
+}
+
+// Release frees a reserved key so the request can be retried, e.g. after a failure.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - scope: Namespace of the key
+//   - key: The client supplied idempotency key
+//   - rec: The record returned by Reserve
+//
+// Returns:
+//   - error: Error if the Redis operation fails
+func (s *Store) Release(ctx context.Context, scope, key string, rec *Record) error {
+	err := ownerScript.Run(ctx, s.rdb, []string{s.recordKey(scope, key)}, rec.Token, "", 0).Err()
+	return errors.Wrap(err, "release idempotency key")
+}
+
+// recordKey builds the Redis key of a record.
+func (s *Store) recordKey(scope, key string) string {
+	return s.prefix + ":" + scope + ":" + key
+}
+
+// InProgress reports whether the record belongs to a request that is still running.
+func (r *Record) InProgress() bool {
+	return r.State == stateInProgress
+}
+
+// newToken generates a random lock token.
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
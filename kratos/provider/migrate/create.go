@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// versionLayout formats migration versions as UTC timestamps.
+	versionLayout = "20060102150405"
+	// importPath is the import path of this package used by generated Go migrations.
+	importPath = "kratos-project-template/provider/migrate"
+)
+
+// invalidNameChars matches characters not allowed in migration names.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// goTemplate is the skeleton of a new Go migration.
+const goTemplate = `package %s
+
+import (
+	"context"
+	"database/sql"
+
+	"%s"
+)
+
+func init() {
+	migrate.Register(%d, %q, up%d, down%d)
+}
+
+func up%d(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+func down%d(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+`
+
+// Create writes the files of a new migration versioned with the current UTC time.
+//
+// Parameters:
+//   - dir: Directory of the SQL migration files
+//   - name: Migration name; characters other than letters, digits and "_" become "_"
+//   - goMigration: Create a Go migration in the parent directory of dir instead of SQL files
+//
+// Returns:
+//   - []string: Paths of the created files
+//   - error: Error if a file cannot be written
+func Create(dir, name string, goMigration bool) ([]string, error) {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name cannot be empty")
+	}
+	version := time.Now().UTC().Format(versionLayout)
+
+	type file struct{ path, content string }
+	var files []file
+	if goMigration {
+		pkgDir := filepath.Dir(filepath.Clean(dir))
+		abs, err := filepath.Abs(pkgDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "resolve migrations package")
+		}
+		var v int64
+		_, _ = fmt.Sscan(version, &v)
+		files = append(files, file{
+			path:    filepath.Join(pkgDir, version+"_"+name+".go"),
+			content: fmt.Sprintf(goTemplate, filepath.Base(abs), importPath, v, name, v, v, v, v),
+		})
+	} else {
+		files = append(files,
+			file{path: filepath.Join(dir, version+"_"+name+".up.sql"), content: "-- " + name + " (up)\n"},
+			file{path: filepath.Join(dir, version+"_"+name+".down.sql"), content: "-- " + name + " (down)\n"},
+		)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "create migrations directory")
+	}
+	var created []string
+	for _, f := range files {
+		if err := os.WriteFile(f.path, []byte(f.content), 0o644); err != nil {
+			return created, errors.Wrapf(err, "write %s", f.path)
+		}
+		created = append(created, f.path)
+	}
+	return created, nil
+}
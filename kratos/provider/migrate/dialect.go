@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// lockPollInterval is how often a waiting migrator retries the SQLite lock.
+	lockPollInterval = 500 * time.Millisecond
+	// staleLockAge is the age after which a SQLite lock left by a crashed migrator is taken over.
+	staleLockAge = time.Hour
+)
+
+// dialect abstracts the database specific parts of the migrator.
+type dialect interface {
+	// rebind converts "?" placeholders to the dialect's placeholder style.
+	rebind(query string) string
+	// lock takes the migration lock and returns the function releasing it.
+	lock(ctx context.Context, db *sql.DB, table string, timeout time.Duration) (func(), error)
+}
+
+// newDialect returns the dialect for a gorm dialector name.
+func newDialect(name string) (dialect, error) {
+	switch name {
+	case "mysql":
+		return mysqlDialect{}, nil
+	case "postgres":
+		return postgresDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	default:
+		return nil, errors.Errorf("unsupported migration dialect: %s", name)
+	}
+}
+
+// mysqlDialect locks with GET_LOCK on a dedicated connection.
+type mysqlDialect struct{}
+
+func (mysqlDialect) rebind(query string) string {
+	return query
+}
+
+func (mysqlDialect) lock(ctx context.Context, db *sql.DB, table string, timeout time.Duration) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var got sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", table, int(timeout.Seconds())).Scan(&got)
+	if err == nil && got.Int64 != 1 {
+		err = errors.Errorf("timed out after %v waiting for another migration", timeout)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", table)
+		_ = conn.Close()
+	}, nil
+}
+
+// postgresDialect locks with a session-level advisory lock on a dedicated connection.
+type postgresDialect struct{}
+
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (postgresDialect) lock(ctx context.Context, db *sql.DB, table string, timeout time.Duration) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(table))
+	key := int64(h.Sum64())
+
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := conn.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		_ = conn.Close()
+		if lockCtx.Err() != nil && ctx.Err() == nil {
+			return nil, errors.Errorf("timed out after %v waiting for another migration", timeout)
+		}
+		return nil, err
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		_ = conn.Close()
+	}, nil
+}
+
+// sqliteDialect locks by inserting the single row of a lock table.
+// A lock older than staleLockAge is considered stale and taken over.
+type sqliteDialect struct{}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) lock(ctx context.Context, db *sql.DB, table string, timeout time.Duration) (func(), error) {
+	lockTable := table + "_lock"
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+lockTable+` (
+		id INTEGER NOT NULL PRIMARY KEY,
+		locked_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		now := time.Now().UTC()
+		_, _ = db.ExecContext(ctx, "DELETE FROM "+lockTable+" WHERE id = 1 AND locked_at < ?", now.Add(-staleLockAge))
+		res, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO "+lockTable+" (id, locked_at) VALUES (1, ?)", now)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			return func() {
+				_, _ = db.ExecContext(context.Background(), "DELETE FROM "+lockTable+" WHERE id = 1")
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("timed out after %v waiting for another migration", timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
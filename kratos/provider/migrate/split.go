@@ -0,0 +1,95 @@
+package migrate
+
+import "strings"
+
+// splitStatements splits a SQL script into statements on semicolons.
+// Semicolons inside quotes, comments and PostgreSQL dollar-quoted bodies are ignored, so
+// scripts run the same on drivers that do not accept several statements per Exec.
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		start int
+	)
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); stmt != "" && !onlyComments(stmt) {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if j := strings.IndexByte(script[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if j := strings.Index(script[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(script)
+			}
+		case c == '$':
+			if tag := dollarTag(script[i:]); tag != "" {
+				if j := strings.Index(script[i+len(tag):], tag); j >= 0 {
+					i += len(tag) + j + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c == ';':
+			flush(i)
+			start = i + 1
+		}
+	}
+	if start < len(script) {
+		flush(len(script))
+	}
+	return stmts
+}
+
+// skipQuoted returns the index of the quote closing the one at i.
+// Doubled quotes and backslash escapes inside the literal are skipped.
+func skipQuoted(script string, i int, quote byte) int {
+	for j := i + 1; j < len(script); j++ {
+		switch script[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(script) && script[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(script)
+}
+
+// dollarTag returns the dollar-quote tag ("$$" or "$name$") starting s, if any.
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1]
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 1 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+// onlyComments reports whether a statement contains nothing but comments.
+func onlyComments(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+// Package outbox implements the transactional outbox pattern. Events are inserted into an
+// outbox table within the caller's database transaction, so they are stored if and only
+// if the business change commits. A Relay running with the application publishes the
+// stored events (to Redis Streams by default) in insertion order per aggregate, retries
+// failed publishes with backoff and deletes delivered events after a retention period.
+//
+// Delivery is at-least-once: consumers should deduplicate by the message ID.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// DefaultTable is the default name of the outbox table.
+const DefaultTable = "outbox_messages"
+
+// Message statuses.
+const (
+	// StatusPending messages wait to be published
+	StatusPending = "pending"
+	// StatusDelivered messages were published and are deleted after the retention period
+	StatusDelivered = "delivered"
+	// StatusFailed messages exhausted their publish attempts; see Requeue
+	StatusFailed = "failed"
+)
+
+// Message is a row of the outbox table.
+type Message struct {
+	ID uint64 `gorm:"primaryKey;autoIncrement;index:idx_outbox_status,priority:2" json:"id"`
+	// Topic is the event topic, e.g. "order.created"
+	Topic string `gorm:"size:255;not null" json:"topic"`
+	// AggregateID identifies the entity the event belongs to; events of the same
+	// aggregate are published in insertion order
+	AggregateID string `gorm:"size:255;not null;index" json:"aggregate_id"`
+	// Payload is the JSON encoded event
+	Payload string `gorm:"type:text;not null" json:"payload"`
+	// Headers is the JSON encoded event metadata, empty if none
+	Headers string `gorm:"type:text" json:"headers,omitempty"`
+	// Status is "pending", "delivered" or "failed"
+	Status string `gorm:"size:16;not null;index:idx_outbox_status,priority:1" json:"status"`
+	// Attempts is the number of failed publish attempts
+	Attempts int `gorm:"not null;default:0" json:"attempts"`
+	// LastError is the error of the last failed publish attempt
+	LastError string `gorm:"type:text" json:"last_error,omitempty"`
+	// NextAttemptAt is when a failed message is published again
+	NextAttemptAt time.Time `gorm:"not null" json:"next_attempt_at"`
+	// CreatedAt is when the message was added
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	// DeliveredAt is when the message was published
+	DeliveredAt *time.Time `gorm:"index" json:"delivered_at,omitempty"`
+}
+
+// TableName returns the default outbox table name.
+func (Message) TableName() string {
+	return DefaultTable
+}
+
+// settings locate the outbox table.
+type settings struct {
+	database string
+	table    string
+}
+
+var (
+	// gSettings is the outbox table location set by Init
+	gSettings = settings{database: db.DefaultName, table: DefaultTable}
+	// gSettingsMu protects gSettings
+	gSettingsMu sync.RWMutex
+
+	// wake signals the relay of this process that messages were committed.
+	wake = make(chan struct{}, 1)
+)
+
+// Init configures the outbox table and optionally creates it.
+//
+// Parameters:
+//   - ctx: Context for the migration
+//   - cfg: Outbox configuration (may be nil to use defaults)
+//   - logger: Logger instance for logging
+//
+// Returns:
+//   - error: Error if the configured connection is not initialized or the migration fails
+func Init(ctx context.Context, cfg *conf.Data_Outbox, logger log.Logger) error {
+	s := settingsFrom(cfg)
+	if !slices.Contains(db.Names(), s.database) {
+		return errors.Errorf("outbox database %q is not initialized", s.database)
+	}
+	if cfg.GetAutoMigrate() {
+		// A database started degraded gets the table once it is up.
+		err := db.OnConnect(ctx, s.database, func(ctx context.Context, gormDB *gorm.DB) error {
+			return errors.Wrap(gormDB.Table(s.table).AutoMigrate(&Message{}), "migrate outbox table")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	gSettingsMu.Lock()
+	gSettings = s
+	gSettingsMu.Unlock()
+
+	log.NewHelper(logger).Infof("outbox initialized: database=%s, table=%s", s.database, s.table)
+	return nil
+}
+
+// settingsFrom returns the table location of cfg with defaults applied.
+func settingsFrom(cfg *conf.Data_Outbox) settings {
+	s := settings{database: db.DefaultName, table: DefaultTable}
+	if cfg.GetDatabase() != "" {
+		s.database = cfg.GetDatabase()
+	}
+	if cfg.GetTable() != "" {
+		s.table = cfg.GetTable()
+	}
+	return s
+}
+
+// currentSettings returns the settings set by Init.
+func currentSettings() settings {
+	gSettingsMu.RLock()
+	defer gSettingsMu.RUnlock()
+	return gSettings
+}
+
+// addOptions holds per-message settings.
+type addOptions struct {
+	headers map[string]string
+}
+
+// AddOption configures a single Add call.
+type AddOption func(*addOptions)
+
+// Header adds a metadata entry to the message, e.g. a correlation ID.
+func Header(key, value string) AddOption {
+	return func(o *addOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		o.headers[key] = value
+	}
+}
+
+// Add stores an event in the outbox. Call it inside db.Transaction with the context passed
+// to the transaction's fn so the event commits or rolls back with the business change;
+// the transaction must be on the outbox connection (data.outbox.database).
+//
+// Parameters:
+//   - ctx: The transaction context
+//   - topic: The event topic
+//   - aggregateID: The entity the event belongs to; events with the same ID are published in order
+//   - payload: The event; encoded as JSON unless it is []byte or json.RawMessage
+//   - opts: Optional message settings (Header)
+//
+// Returns:
+//   - *Message: The stored message
+//   - error: Error if encoding or the insert fails
+func Add(ctx context.Context, topic, aggregateID string, payload interface{}, opts ...AddOption) (*Message, error) {
+	if topic == "" {
+		return nil, errors.New("outbox topic cannot be empty")
+	}
+	o := &addOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	data, err := encodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	msg := &Message{
+		Topic:         topic,
+		AggregateID:   aggregateID,
+		Payload:       string(data),
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if len(o.headers) > 0 {
+		headers, err := json.Marshal(o.headers)
+		if err != nil {
+			return nil, errors.Wrap(err, "encode outbox headers")
+		}
+		msg.Headers = string(headers)
+	}
+
+	s := currentSettings()
+	if err := db.DB(ctx, s.database).Table(s.table).Create(msg).Error; err != nil {
+		return nil, errors.Wrap(err, "add outbox message")
+	}
+	db.AfterCommit(ctx, func(context.Context) { notify() }, s.database)
+	return msg, nil
+}
+
+// Requeue resets failed messages to pending so the relay publishes them again.
+//
+// Parameters:
+//   - ctx: Context for the update
+//   - ids: The IDs of the failed messages
+//
+// Returns:
+//   - error: Error if the update fails
+func Requeue(ctx context.Context, ids ...uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s := currentSettings()
+	err := db.DB(ctx, s.database).Table(s.table).
+		Where("id IN ? AND status = ?", ids, StatusFailed).
+		Updates(map[string]interface{}{
+			"status":          StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+	if err != nil {
+		return errors.Wrap(err, "requeue outbox messages")
+	}
+	notify()
+	return nil
+}
+
+// notify wakes the relay of this process without blocking.
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// encodePayload encodes an event payload as JSON.
+func encodePayload(payload interface{}) ([]byte, error) {
+	switch p := payload.(type) {
+	case []byte:
+		return p, nil
+	case json.RawMessage:
+		return p, nil
+	default:
+		data, err := json.Marshal(payload)
+		return data, errors.Wrap(err, "encode outbox payload")
+	}
+}
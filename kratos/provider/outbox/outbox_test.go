@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestAdd(t *testing.T) {
+	gormDB := useOutbox(t)
+	ctx := context.Background()
+
+	// Drain a wake-up left by another test.
+	select {
+	case <-wake:
+	default:
+	}
+
+	err := db.Transaction(ctx, func(ctx context.Context) error {
+		_, err := Add(ctx, "order.created", "order-1", map[string]int{"total": 42}, Header("trace_id", "abc"))
+		if len(wake) != 0 {
+			t.Error("relay woken before the transaction committed")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	if len(wake) != 1 {
+		t.Error("relay not woken after commit")
+	}
+
+	rollback := errors.New("rollback")
+	err = db.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := Add(ctx, "order.created", "order-2", []byte(`{"total":1}`)); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction() error = %v, want rollback", err)
+	}
+
+	var msgs []Message
+	if err := gormDB.Order("id").Find(&msgs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("outbox has %d messages, want only the committed one", len(msgs))
+	}
+	msg := msgs[0]
+	if msg.Topic != "order.created" || msg.AggregateID != "order-1" || msg.Status != StatusPending {
+		t.Errorf("message = %+v, want a pending order.created of order-1", msg)
+	}
+	if msg.Payload != `{"total":42}` || msg.Headers != `{"trace_id":"abc"}` {
+		t.Errorf("payload = %s, headers = %s", msg.Payload, msg.Headers)
+	}
+
+	if _, err := Add(ctx, "", "order-1", nil); err == nil {
+		t.Error("Add() without a topic did not fail")
+	}
+}
+
+func TestRelayFailAndRequeue(t *testing.T) {
+	gormDB := useOutbox(t)
+	past := time.Now().Add(-time.Minute)
+	insertMessages(t, gormDB, []string{"a"}, []time.Time{past})
+
+	p := &recorder{fail: map[string]bool{`{"n":1}`: true}}
+	r := NewRelay(p, nil, &conf.Data_Outbox{MaxAttempts: 2}, log.NewStdLogger(io.Discard))
+	r.SetBackoff(func(int) time.Duration { return -time.Second })
+	ctx := context.Background()
+
+	// The message is retried until its attempts are exhausted, then marked failed.
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.relayBatch(ctx); err != nil {
+			t.Fatalf("relayBatch() error = %v", err)
+		}
+	}
+	var msg Message
+	if err := gormDB.First(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.Status != StatusFailed || msg.Attempts != 2 || msg.LastError != "publish failed" {
+		t.Fatalf("message = %+v, want failed after 2 attempts", msg)
+	}
+
+	// Requeued messages are published again.
+	delete(p.fail, `{"n":1}`)
+	if err := Requeue(ctx, msg.ID); err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+	if _, delivered, err := r.relayBatch(ctx); err != nil || delivered != 1 {
+		t.Fatalf("relayBatch() delivered %d, error = %v, want 1", delivered, err)
+	}
+	if err := gormDB.First(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.Status != StatusDelivered || msg.DeliveredAt == nil {
+		t.Errorf("message = %+v, want delivered", msg)
+	}
+}
+
+func TestRelayCleanup(t *testing.T) {
+	gormDB := useOutbox(t)
+	insertMessages(t, gormDB, []string{"a", "b", "c"}, []time.Time{time.Now(), time.Now(), time.Now()})
+	old, recent := time.Now().Add(-2*time.Hour), time.Now()
+	for id, at := range map[int]time.Time{1: old, 2: recent} {
+		err := gormDB.Model(&Message{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": StatusDelivered, "delivered_at": at}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRelay(&recorder{}, nil, &conf.Data_Outbox{Retention: durationpb.New(time.Hour)}, log.NewStdLogger(io.Discard))
+	if err := r.cleanup(context.Background()); err != nil {
+		t.Fatalf("cleanup() error = %v", err)
+	}
+
+	var ids []uint64
+	if err := gormDB.Model(&Message{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Errorf("remaining messages = %v, want [2 3]", ids)
+	}
+}
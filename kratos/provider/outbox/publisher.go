@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Publisher = (*RedisStreamPublisher)(nil)
+
+const (
+	defaultPrefix       = "outbox"
+	defaultStreamMaxLen = 10000
+)
+
+// Publisher delivers outbox messages to the message broker.
+// Publish must return an error unless the broker has durably accepted the message.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+// Publish calls f(ctx, msg).
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// RedisStreamPublisher appends messages to one Redis stream per topic.
+type RedisStreamPublisher struct {
+	rdb    redis.UniversalClient
+	prefix string
+	maxLen int64
+}
+
+// NewRedisStreamPublisher creates a Redis Streams publisher.
+// The stream of a topic is "<prefix>:<topic>"; each entry has the fields id, topic,
+// aggregate_id, payload, headers and created_at.
+//
+// Parameters:
+//   - rdb: The Redis client
+//   - cfg: Outbox configuration (may be nil to use defaults)
+//
+// Returns:
+//   - *RedisStreamPublisher: A new publisher
+func NewRedisStreamPublisher(rdb redis.UniversalClient, cfg *conf.Data_Outbox) *RedisStreamPublisher {
+	p := &RedisStreamPublisher{rdb: rdb, prefix: defaultPrefix, maxLen: defaultStreamMaxLen}
+	if cfg.GetPrefix() != "" {
+		p.prefix = cfg.GetPrefix()
+	}
+	if cfg.GetStreamMaxLen() != 0 {
+		p.maxLen = cfg.GetStreamMaxLen()
+	}
+	return p
+}
+
+// Publish appends the message to its topic stream.
+func (p *RedisStreamPublisher) Publish(ctx context.Context, msg *Message) error {
+	return p.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.prefix + ":" + msg.Topic,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: map[string]interface{}{
+			"id":           strconv.FormatUint(msg.ID, 10),
+			"topic":        msg.Topic,
+			"aggregate_id": msg.AggregateID,
+			"payload":      msg.Payload,
+			"headers":      msg.Headers,
+			"created_at":   msg.CreatedAt.UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+}
@@ -0,0 +1,285 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/election"
+	"kratos-project-template/provider/queue"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ transport.Server = (*Relay)(nil)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 10
+	defaultRetention    = 24 * time.Hour
+	// cleanupInterval is how often delivered messages past the retention are deleted.
+	cleanupInterval = 10 * time.Minute
+)
+
+// Relay publishes pending outbox messages.
+// It implements transport.Server so it starts and stops with the kratos application.
+//
+// Only the leader of the elector relays, so messages are published by a single process
+// in insertion order. When a message fails, later messages of the same aggregate wait until
+// it is published or marked failed after max_attempts; messages without an aggregate ID
+// are not held back.
+type Relay struct {
+	settings     settings
+	publisher    Publisher
+	elector      *election.Elector
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	retention    time.Duration
+	backoff      queue.BackoffFunc
+	log          *log.Helper
+
+	// lifecycle protects cancel and done, which are set by Start and read by Stop
+	lifecycle sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewRelay creates an outbox relay.
+//
+// Parameters:
+//   - publisher: Delivers the messages, e.g. NewRedisStreamPublisher
+//   - elector: Elects the relaying process; nil relays in every process
+//   - cfg: Outbox configuration (may be nil to use defaults)
+//   - logger: Logger instance for relay logging
+//
+// Returns:
+//   - *Relay: A new relay
+func NewRelay(publisher Publisher, elector *election.Elector, cfg *conf.Data_Outbox, logger log.Logger) *Relay {
+	r := &Relay{
+		settings:     settingsFrom(cfg),
+		publisher:    publisher,
+		elector:      elector,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		maxAttempts:  defaultMaxAttempts,
+		retention:    defaultRetention,
+		backoff:      queue.DefaultBackoff,
+		log:          log.NewHelper(log.With(logger, "module", "outbox")),
+	}
+	if cfg.GetPollInterval() != nil {
+		r.pollInterval = cfg.GetPollInterval().AsDuration()
+	}
+	if cfg.GetBatchSize() > 0 {
+		r.batchSize = int(cfg.GetBatchSize())
+	}
+	if cfg.GetMaxAttempts() > 0 {
+		r.maxAttempts = int(cfg.GetMaxAttempts())
+	}
+	if cfg.GetRetention() != nil {
+		r.retention = cfg.GetRetention().AsDuration()
+	}
+	return r
+}
+
+// SetBackoff replaces the retry backoff policy.
+func (r *Relay) SetBackoff(fn queue.BackoffFunc) {
+	if fn != nil {
+		r.backoff = fn
+	}
+}
+
+// Start relays messages until Stop is called or ctx is cancelled.
+//
+// Parameters:
+//   - ctx: The application context
+//
+// Returns:
+//   - error: Always nil; database and publish errors are logged and retried
+func (r *Relay) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer close(done)
+
+	r.lifecycle.Lock()
+	r.cancel = cancel
+	r.done = done
+	r.lifecycle.Unlock()
+
+	r.log.Infof("[outbox] relay started: database=%s, table=%s, batch=%d",
+		r.settings.database, r.settings.table, r.batchSize)
+	if r.elector == nil {
+		r.run(ctx)
+	} else {
+		err := r.elector.RunWhenLeader(ctx, func(ctx context.Context) error {
+			r.run(ctx)
+			return nil
+		})
+		// A nil error means the elector stopped before the application.
+		if err == nil {
+			<-ctx.Done()
+		}
+	}
+
+	r.log.Infof("[outbox] relay stopped")
+	return nil
+}
+
+// Stop signals the relay to stop and waits for the current batch or ctx to expire.
+func (r *Relay) Stop(ctx context.Context) error {
+	r.lifecycle.Lock()
+	cancel, done := r.cancel, r.done
+	r.lifecycle.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run polls the outbox until ctx is done. Commits in this process wake it up early.
+func (r *Relay) run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+
+	for {
+		fetched, delivered, err := r.relayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Warnf("[outbox] relay messages failed: %v", err)
+		}
+		if time.Since(lastCleanup) >= cleanupInterval {
+			if err := r.cleanup(ctx); err != nil && ctx.Err() == nil {
+				r.log.Warnf("[outbox] delete delivered messages failed: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		// A full batch that made progress likely has more messages behind it.
+		if fetched == r.batchSize && delivered > 0 && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// relayBatch publishes the oldest pending messages in order.
+// It returns the number of messages read and the number published.
+func (r *Relay) relayBatch(ctx context.Context) (int, int, error) {
+	var msgs []Message
+	// The outbox must be read from the primary, a replica may lag behind.
+	err := r.pending(db.WithPrimary(ctx), time.Now()).
+		Order("id").Limit(r.batchSize).
+		Find(&msgs).Error
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "read outbox")
+	}
+
+	delivered := 0
+	blocked := make(map[string]bool)
+	for i := range msgs {
+		msg := &msgs[i]
+		if msg.AggregateID != "" && blocked[msg.AggregateID] {
+			continue
+		}
+
+		if err := r.publisher.Publish(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return len(msgs), delivered, nil
+			}
+			blocked[msg.AggregateID] = true
+			if err := r.fail(ctx, msg, err); err != nil {
+				return len(msgs), delivered, err
+			}
+			continue
+		}
+
+		err := r.table(ctx).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+			"status":       StatusDelivered,
+			"delivered_at": time.Now(),
+		}).Error
+		if err != nil {
+			// The message stays pending and is published again: consumers deduplicate by ID.
+			return len(msgs), delivered, errors.Wrapf(err, "mark outbox message %d delivered", msg.ID)
+		}
+		delivered++
+	}
+	return len(msgs), delivered, nil
+}
+
+// pending selects the messages that may be published at now: pending messages that are
+// due and have no earlier pending message of their aggregate waiting for a retry. The
+// filter runs in SQL, so the messages held back behind a failing aggregate do not fill
+// the batch and starve the other aggregates.
+func (r *Relay) pending(ctx context.Context, now time.Time) *gorm.DB {
+	table := r.settings.table
+	waiting := db.Get(r.settings.database).Session(&gorm.Session{NewDB: true}).WithContext(ctx).
+		Table("? AS w", clause.Table{Name: table}).Select("1").
+		Where("w.aggregate_id = ? AND w.id < ? AND w.status = ? AND w.next_attempt_at > ?",
+			clause.Column{Table: table, Name: "aggregate_id"}, clause.Column{Table: table, Name: "id"},
+			StatusPending, now)
+	return r.table(ctx).
+		Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+		Where("aggregate_id = ? OR NOT EXISTS (?)", "", waiting)
+}
+
+// fail records a failed publish attempt and schedules a retry, or marks the message
+// failed when its attempts are exhausted.
+func (r *Relay) fail(ctx context.Context, msg *Message, cause error) error {
+	attempts := msg.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": cause.Error(),
+	}
+	if attempts >= r.maxAttempts {
+		updates["status"] = StatusFailed
+		r.log.Errorf("[outbox] message %d (topic=%s, aggregate=%s) failed after %d attempts: %v",
+			msg.ID, msg.Topic, msg.AggregateID, attempts, cause)
+	} else {
+		delay := r.backoff(attempts)
+		updates["next_attempt_at"] = time.Now().Add(delay)
+		r.log.Warnf("[outbox] publish message %d (topic=%s) failed, retrying in %v: %v",
+			msg.ID, msg.Topic, delay, cause)
+	}
+
+	err := r.table(ctx).Where("id = ?", msg.ID).Updates(updates).Error
+	return errors.Wrapf(err, "record outbox message %d failure", msg.ID)
+}
+
+// cleanup deletes delivered messages older than the retention period.
+func (r *Relay) cleanup(ctx context.Context) error {
+	res := r.table(ctx).
+		Where("status = ? AND delivered_at < ?", StatusDelivered, time.Now().Add(-r.retention)).
+		Delete(&Message{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		r.log.Infof("[outbox] deleted %d delivered messages", res.RowsAffected)
+	}
+	return nil
+}
+
+// table returns a session on the outbox table.
+func (r *Relay) table(ctx context.Context) *gorm.DB {
+	return db.Get(r.settings.database).WithContext(ctx).Table(r.settings.table)
+}
@@ -0,0 +1,175 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/driver"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useOutbox registers an in-memory SQLite database with an outbox table as the default
+// connection until the test ends.
+func useOutbox(t *testing.T) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := gormDB.AutoMigrate(&Message{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Set(db.DefaultName, gormDB, log.NewStdLogger(io.Discard)))
+	return gormDB
+}
+
+// insertMessages stores pending messages of the aggregates, one per entry, due at the
+// given times.
+func insertMessages(t *testing.T, gormDB *gorm.DB, aggregates []string, due []time.Time) {
+	t.Helper()
+	for i, aggregate := range aggregates {
+		msg := Message{
+			Topic:         "test.event",
+			AggregateID:   aggregate,
+			Payload:       fmt.Sprintf(`{"n":%d}`, i+1),
+			Status:        StatusPending,
+			NextAttemptAt: due[i],
+			CreatedAt:     time.Now(),
+		}
+		if err := gormDB.Create(&msg).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recorder publishes messages by recording their payloads, failing those in fail.
+type recorder struct {
+	fail      map[string]bool
+	published []string
+}
+
+func (p *recorder) Publish(_ context.Context, msg *Message) error {
+	if p.fail[msg.Payload] {
+		return errors.New("publish failed")
+	}
+	p.published = append(p.published, msg.Payload)
+	return nil
+}
+
+func TestRelayBatch(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		aggregates []string
+		due        []time.Time
+		fail       []string
+		want       []string
+	}{
+		{
+			name:       "aggregate waiting for a retry does not fill the batch",
+			aggregates: []string{"a", "a", "a", "b"},
+			due:        []time.Time{future, past, past, past},
+			want:       []string{`{"n":4}`},
+		},
+		{
+			name:       "messages without an aggregate are not held back",
+			aggregates: []string{"a", "", "a", ""},
+			due:        []time.Time{future, past, past, past},
+			want:       []string{`{"n":2}`, `{"n":4}`},
+		},
+		{
+			name:       "in order per aggregate",
+			aggregates: []string{"a", "b", "a", "b"},
+			due:        []time.Time{past, past, past, past},
+			want:       []string{`{"n":1}`, `{"n":2}`},
+		},
+		{
+			name:       "failed message holds back its aggregate",
+			aggregates: []string{"a", "a", "b"},
+			due:        []time.Time{past, past, past},
+			fail:       []string{`{"n":1}`},
+			want:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDB := useOutbox(t)
+			insertMessages(t, gormDB, tt.aggregates, tt.due)
+
+			p := &recorder{fail: make(map[string]bool)}
+			for _, payload := range tt.fail {
+				p.fail[payload] = true
+			}
+			r := NewRelay(p, nil, &conf.Data_Outbox{BatchSize: 2}, log.NewStdLogger(io.Discard))
+			r.SetBackoff(func(int) time.Duration { return time.Hour })
+			if _, _, err := r.relayBatch(context.Background()); err != nil {
+				t.Fatalf("relayBatch() error = %v", err)
+			}
+			if got := append([]string{}, p.published...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("published %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelayBatchRetry(t *testing.T) {
+	gormDB := useOutbox(t)
+	past := time.Now().Add(-time.Minute)
+	insertMessages(t, gormDB, []string{"a", "a", "b"}, []time.Time{past, past, past})
+
+	p := &recorder{fail: map[string]bool{`{"n":1}`: true}}
+	r := NewRelay(p, nil, &conf.Data_Outbox{BatchSize: 2}, log.NewStdLogger(io.Discard))
+	r.SetBackoff(func(int) time.Duration { return time.Hour })
+	ctx := context.Background()
+
+	// The first message of "a" fails: "a" waits for its retry while "b" is published.
+	for i := 0; i < 2; i++ {
+		if _, _, err := r.relayBatch(ctx); err != nil {
+			t.Fatalf("relayBatch() error = %v", err)
+		}
+	}
+	if want := []string{`{"n":3}`}; !reflect.DeepEqual(p.published, want) {
+		t.Fatalf("published %v, want %v", p.published, want)
+	}
+
+	// Once the retry is due the aggregate is published in order.
+	delete(p.fail, `{"n":1}`)
+	if err := gormDB.Model(&Message{}).Where("id = ?", 1).Update("next_attempt_at", past).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.relayBatch(ctx); err != nil {
+		t.Fatalf("relayBatch() error = %v", err)
+	}
+	if want := []string{`{"n":3}`, `{"n":1}`, `{"n":2}`}; !reflect.DeepEqual(p.published, want) {
+		t.Errorf("published %v, want %v", p.published, want)
+	}
+	var pending int64
+	if err := gormDB.Model(&Message{}).Where("status = ?", StatusPending).Count(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("%d messages still pending", pending)
+	}
+}
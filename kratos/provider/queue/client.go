@@ -0,0 +1,271 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"kratos-project-template/internal/conf"
+	"kratos-project-template/provider/cache"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// gClient is the global queue client instance
+	gClient *Client
+)
+
+// Client enqueues jobs and inspects dead letters.
+type Client struct {
+	rdb        redis.UniversalClient
+	prefix     string
+	maxRetries int
+}
+
+// NewClient creates a queue client on top of an existing Redis client.
+//
+// Parameters:
+//   - rdb: The Redis client used to store jobs
+//   - cfg: Queue configuration (may be nil to use defaults)
+//
+// Returns:
+//   - *Client: A new queue client
+func NewClient(rdb redis.UniversalClient, cfg *conf.Data_Queue) *Client {
+	c := &Client{
+		rdb:        rdb,
+		prefix:     defaultPrefix,
+		maxRetries: defaultMaxRetries,
+	}
+	if cfg.GetPrefix() != "" {
+		c.prefix = cfg.GetPrefix()
+	}
+	if cfg.GetMaxRetries() > 0 {
+		c.maxRetries = int(cfg.GetMaxRetries())
+	}
+	return c
+}
+
+// Init initializes the global queue client using the global Redis client.
+//
+// Parameters:
+//   - ctx: Context for the initialization operation
+//   - cfg: Queue configuration
+//   - logger: Logger instance for logging initialization messages
+//
+// Returns:
+//   - error: Error if the Redis client is not available
+//
+// If cfg is nil or cfg.Enabled is false, the client is still created so that producers
+// can enqueue jobs consumed by workers running in other processes.
+func Init(ctx context.Context, cfg *conf.Data_Queue, logger log.Logger) error {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return errors.New("queue requires an initialized redis client")
+	}
+
+	gClient = NewClient(rdb, cfg)
+	log.NewHelper(logger).Infof("job queue initialized: prefix=%s", gClient.prefix)
+	return nil
+}
+
+// Get returns the global queue client.
+// The returned client reports ErrNotInitialized if Init has not succeeded.
+func Get() *Client {
+	if gClient == nil {
+		return &Client{}
+	}
+	return gClient
+}
+
+// enqueueOptions holds per-job enqueue settings.
+type enqueueOptions struct {
+	queue      string
+	processAt  time.Time
+	maxRetries int
+	id         string
+}
+
+// EnqueueOption configures a single Enqueue call.
+type EnqueueOption func(*enqueueOptions)
+
+// Queue sets the queue the job is added to.
+func Queue(name string) EnqueueOption {
+	return func(o *enqueueOptions) { o.queue = name }
+}
+
+// Delay schedules the job to run after d.
+func Delay(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) { o.processAt = time.Now().Add(d) }
+}
+
+// ProcessAt schedules the job to run at t.
+func ProcessAt(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) { o.processAt = t }
+}
+
+// MaxRetries overrides the retry budget of the job.
+func MaxRetries(n int) EnqueueOption {
+	return func(o *enqueueOptions) { o.maxRetries = n }
+}
+
+// JobID sets an explicit job identifier instead of a random one.
+func JobID(id string) EnqueueOption {
+	return func(o *enqueueOptions) { o.id = id }
+}
+
+// Enqueue adds a job to a queue.
+// Jobs without a schedule are appended to the queue stream immediately; delayed jobs
+// are stored in the delayed set and moved to the stream by the worker scheduler.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - jobType: The job type used to select the handler
+//   - payload: The job argument; encoded as JSON unless it is []byte or json.RawMessage
+//   - opts: Optional enqueue settings (Queue, Delay, ProcessAt, MaxRetries, JobID)
+//
+// Returns:
+//   - *Job: The enqueued job
+//   - error: Error if encoding or the Redis operation fails
+func (c *Client) Enqueue(ctx context.Context, jobType string, payload interface{}, opts ...EnqueueOption) (*Job, error) {
+	if c.rdb == nil {
+		return nil, ErrNotInitialized
+	}
+	if jobType == "" {
+		return nil, errors.New("job type cannot be empty")
+	}
+
+	o := enqueueOptions{queue: DefaultQueue, maxRetries: c.maxRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.id == "" {
+		o.id = newJobID()
+	}
+
+	data, err := encodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:         o.id,
+		Type:       jobType,
+		Queue:      o.queue,
+		Payload:    data,
+		MaxRetries: o.maxRetries,
+		EnqueuedAt: time.Now(),
+	}
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode job")
+	}
+
+	if !o.processAt.IsZero() && o.processAt.After(time.Now()) {
+		err = c.rdb.ZAdd(ctx, delayedKey(c.prefix, o.queue), redis.Z{
+			Score:  float64(o.processAt.UnixMilli()),
+			Member: encoded,
+		}).Err()
+		return job, errors.Wrapf(err, "schedule job %s", job.ID)
+	}
+
+	err = c.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey(c.prefix, o.queue),
+		Values: map[string]interface{}{"job": encoded},
+	}).Err()
+	return job, errors.Wrapf(err, "enqueue job %s", job.ID)
+}
+
+// DeadLetters returns up to count jobs from the dead-letter stream of a queue, oldest first.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - queue: The queue name
+//   - count: Maximum number of jobs to return
+//
+// Returns:
+//   - []*Job: Dead-lettered jobs; MessageID identifies the dead-letter entry
+//   - error: Error if the Redis operation fails
+func (c *Client) DeadLetters(ctx context.Context, queue string, count int64) ([]*Job, error) {
+	if c.rdb == nil {
+		return nil, ErrNotInitialized
+	}
+
+	msgs, err := c.rdb.XRangeN(ctx, deadKey(c.prefix, queue), "-", "+", count).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "read dead letters of queue %s", queue)
+	}
+
+	jobs := make([]*Job, 0, len(msgs))
+	for _, msg := range msgs {
+		job, err := decodeMessage(queue, msg)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RequeueDeadLetter moves a dead-lettered job back to its queue with a fresh retry budget.
+//
+// Parameters:
+//   - ctx: Context for the Redis operation
+//   - queue: The queue name
+//   - messageID: The dead-letter entry ID returned by Job.MessageID
+//
+// Returns:
+//   - error: Error if the entry does not exist or the Redis operation fails
+func (c *Client) RequeueDeadLetter(ctx context.Context, queue, messageID string) error {
+	if c.rdb == nil {
+		return ErrNotInitialized
+	}
+
+	msgs, err := c.rdb.XRangeN(ctx, deadKey(c.prefix, queue), messageID, messageID, 1).Result()
+	if err != nil {
+		return errors.Wrapf(err, "read dead letter %s", messageID)
+	}
+	if len(msgs) == 0 {
+		return errors.Errorf("dead letter %s not found", messageID)
+	}
+
+	job, err := decodeMessage(queue, msgs[0])
+	if err != nil {
+		return err
+	}
+	job.Attempt = 0
+	job.LastError = ""
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return errors.Wrap(err, "encode job")
+	}
+
+	pipe := c.rdb.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey(c.prefix, queue),
+		Values: map[string]interface{}{"job": encoded},
+	})
+	pipe.XDel(ctx, deadKey(c.prefix, queue), messageID)
+	_, err = pipe.Exec(ctx)
+	return errors.Wrapf(err, "requeue dead letter %s", messageID)
+}
+
+// decodeMessage converts a stream entry into a Job.
+func decodeMessage(queue string, msg redis.XMessage) (*Job, error) {
+	raw, ok := msg.Values["job"].(string)
+	if !ok {
+		return nil, errors.Errorf("stream entry %s has no job field", msg.ID)
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return nil, errors.Wrapf(err, "decode stream entry %s", msg.ID)
+	}
+	if job.Queue == "" {
+		job.Queue = queue
+	}
+	job.messageID = msg.ID
+	return &job, nil
+}
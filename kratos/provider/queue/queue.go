@@ -0,0 +1,187 @@
+// Package queue provides a background job queue built on Redis Streams.
+// Jobs are appended to a per-queue stream and consumed by a worker Server through a
+// consumer group. Failed jobs are retried with exponential backoff via a delayed set,
+// jobs that exhaust their retry budget are moved to a dead-letter stream, and jobs left
+// pending by crashed workers are reclaimed with XAUTOCLAIM after a visibility timeout.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultQueue is the queue used when no queue is specified.
+	DefaultQueue = "default"
+	// defaultPrefix is the default Redis key prefix for all queue keys.
+	defaultPrefix = "queue"
+	// defaultMaxRetries is the default retry budget per job.
+	defaultMaxRetries = 5
+)
+
+var (
+	// ErrNotInitialized is returned when the queue client is used before Init.
+	ErrNotInitialized = errors.New("queue: client is not initialized")
+	// ErrSkipRetry can be wrapped by handlers to send a job straight to the dead-letter stream.
+	ErrSkipRetry = errors.New("queue: skip retry")
+)
+
+// Job is a unit of work stored in a queue.
+type Job struct {
+	// ID uniquely identifies the job across retries.
+	ID string `json:"id"`
+	// Type selects the handler that processes the job.
+	Type string `json:"type"`
+	// Queue is the name of the queue the job belongs to.
+	Queue string `json:"queue"`
+	// Payload is the JSON encoded job argument.
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Attempt is the number of failed attempts so far.
+	Attempt int `json:"attempt"`
+	// MaxRetries is the number of retries allowed before the job is dead-lettered.
+	MaxRetries int `json:"max_retries"`
+	// EnqueuedAt is the time the job was first enqueued.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	// LastError is the error message of the most recent failed attempt.
+	LastError string `json:"last_error,omitempty"`
+
+	// messageID is the stream entry ID the job was read from.
+	messageID string
+}
+
+// MessageID returns the stream entry ID the job was delivered from.
+// It is empty for jobs that have not been read from a stream.
+func (j *Job) MessageID() string {
+	return j.messageID
+}
+
+// Decode unmarshals the job payload into v.
+//
+// Parameters:
+//   - v: Pointer to the value to decode into
+//
+// Returns:
+//   - error: Error if the payload is not valid JSON for v
+func (j *Job) Decode(v interface{}) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(j.Payload, v), "decode payload of job %s", j.ID)
+}
+
+// Handler processes jobs of a single type.
+type Handler interface {
+	// ProcessJob handles the job. Returning a non-nil error schedules a retry.
+	ProcessJob(ctx context.Context, job *Job) error
+}
+
+// HandlerFunc adapts an ordinary function to the Handler interface.
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+// ProcessJob calls f(ctx, job).
+func (f HandlerFunc) ProcessJob(ctx context.Context, job *Job) error {
+	return f(ctx, job)
+}
+
+// TypedHandler creates a Handler that decodes the job payload into T before calling fn.
+// A payload that cannot be decoded is never retried.
+//
+// Parameters:
+//   - fn: The function to call with the decoded payload
+//
+// Returns:
+//   - Handler: A handler usable with Server.Handle
+//
+// Example:
+//
+//	srv.Handle("email:send", queue.TypedHandler(func(ctx context.Context, p SendEmail) error {
+//	    return mailer.Send(ctx, p.To, p.Body)
+//	}))
+func TypedHandler[T any](fn func(ctx context.Context, payload T) error) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) error {
+		var payload T
+		if err := job.Decode(&payload); err != nil {
+			return SkipRetry(err)
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// SkipRetry marks err as permanent so the job is dead-lettered without further retries.
+//
+// Parameters:
+//   - err: The underlying error
+//
+// Returns:
+//   - error: An error that matches ErrSkipRetry with errors.Is
+func SkipRetry(err error) error {
+	if err == nil {
+		return ErrSkipRetry
+	}
+	return &skipRetryError{err: err}
+}
+
+// skipRetryError wraps a permanent handler error.
+type skipRetryError struct {
+	err error
+}
+
+func (e *skipRetryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *skipRetryError) Unwrap() error {
+	return e.err
+}
+
+func (e *skipRetryError) Is(target error) bool {
+	return target == ErrSkipRetry
+}
+
+// streamKey returns the stream holding ready jobs of a queue.
+// Keys of one queue share a hash tag so Lua scripts work on Redis Cluster.
+func streamKey(prefix, queue string) string {
+	return fmt.Sprintf("%s:{%s}:stream", prefix, queue)
+}
+
+// delayedKey returns the sorted set holding scheduled and retrying jobs of a queue.
+func delayedKey(prefix, queue string) string {
+	return fmt.Sprintf("%s:{%s}:delayed", prefix, queue)
+}
+
+// deadKey returns the dead-letter stream of a queue.
+func deadKey(prefix, queue string) string {
+	return fmt.Sprintf("%s:{%s}:dead", prefix, queue)
+}
+
+// newJobID generates a random job identifier.
+func newJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// encodePayload converts a payload value into JSON.
+// Byte slices and json.RawMessage are assumed to already be JSON encoded.
+func encodePayload(payload interface{}) (json.RawMessage, error) {
+	switch p := payload.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return p, nil
+	case []byte:
+		return json.RawMessage(p), nil
+	default:
+		data, err := json.Marshal(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "encode job payload")
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,144 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+)
+
+type sendEmail struct {
+	To string `json:"to"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	var got sendEmail
+	h := TypedHandler(func(ctx context.Context, p sendEmail) error {
+		got = p
+		return nil
+	})
+
+	if err := h.ProcessJob(context.Background(), &Job{ID: "1", Payload: json.RawMessage(`{"to":"a@example.com"}`)}); err != nil {
+		t.Fatalf("ProcessJob() error = %v", err)
+	}
+	if got.To != "a@example.com" {
+		t.Errorf("payload = %+v, want to a@example.com", got)
+	}
+
+	// A payload that cannot be decoded is never retried.
+	err := h.ProcessJob(context.Background(), &Job{ID: "2", Payload: json.RawMessage(`"not an object"`)})
+	if !errors.Is(err, ErrSkipRetry) {
+		t.Errorf("ProcessJob() with a malformed payload error = %v, want ErrSkipRetry", err)
+	}
+}
+
+func TestSkipRetry(t *testing.T) {
+	cause := errors.New("invalid address")
+	err := SkipRetry(cause)
+	if !errors.Is(err, ErrSkipRetry) {
+		t.Error("SkipRetry() does not match ErrSkipRetry")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("SkipRetry() does not match its cause")
+	}
+	if err.Error() != cause.Error() {
+		t.Errorf("SkipRetry() message = %q, want %q", err.Error(), cause.Error())
+	}
+	if !errors.Is(SkipRetry(nil), ErrSkipRetry) {
+		t.Error("SkipRetry(nil) does not match ErrSkipRetry")
+	}
+	if !errors.Is(errors.Wrap(err, "send"), ErrSkipRetry) {
+		t.Error("wrapped SkipRetry() does not match ErrSkipRetry")
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		min     time.Duration
+	}{
+		{attempt: 0, min: time.Second},
+		{attempt: 1, min: time.Second},
+		{attempt: 2, min: 2 * time.Second},
+		{attempt: 5, min: 16 * time.Second},
+		{attempt: 20, min: 10 * time.Minute},
+		{attempt: 100, min: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			// Up to 20% jitter is added on top of the delay.
+			if d := DefaultBackoff(tt.attempt); d < tt.min || d > tt.min+tt.min/5 {
+				t.Fatalf("DefaultBackoff(%d) = %v, want between %v and %v", tt.attempt, d, tt.min, tt.min+tt.min/5)
+			}
+		}
+	}
+}
+
+func TestEncodePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    string
+	}{
+		{name: "nil", payload: nil, want: ""},
+		{name: "raw message", payload: json.RawMessage(`{"a":1}`), want: `{"a":1}`},
+		{name: "bytes", payload: []byte(`[1,2]`), want: `[1,2]`},
+		{name: "value", payload: sendEmail{To: "a@example.com"}, want: `{"to":"a@example.com"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodePayload(tt.payload)
+			if err != nil {
+				t.Fatalf("encodePayload() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("encodePayload() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := encodePayload(func() {}); err == nil {
+		t.Error("encodePayload() of a func did not fail")
+	}
+}
+
+func TestClientNotInitialized(t *testing.T) {
+	ctx := context.Background()
+	c := &Client{}
+	if _, err := c.Enqueue(ctx, "email:send", nil); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Enqueue() error = %v, want ErrNotInitialized", err)
+	}
+	if _, err := c.DeadLetters(ctx, DefaultQueue, 10); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("DeadLetters() error = %v, want ErrNotInitialized", err)
+	}
+	if err := c.RequeueDeadLetter(ctx, DefaultQueue, "1-0"); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("RequeueDeadLetter() error = %v, want ErrNotInitialized", err)
+	}
+}
+
+func TestServerInvokeRecoversPanic(t *testing.T) {
+	s := NewServer(nil, nil, log.NewStdLogger(io.Discard))
+	err := s.invoke(context.Background(), HandlerFunc(func(ctx context.Context, job *Job) error {
+		panic("boom")
+	}), &Job{ID: "1"})
+	if err == nil || err.Error() != "panic: boom" {
+		t.Errorf("invoke() error = %v, want panic: boom", err)
+	}
+	if errors.Is(err, ErrSkipRetry) {
+		t.Error("a panicking job is dead-lettered instead of retried")
+	}
+}
+
+func TestKeysShareHashTag(t *testing.T) {
+	// Lua scripts touch the stream and the delayed set of a queue, which must map to the
+	// same Redis Cluster slot.
+	for _, key := range []string{streamKey("queue", "mail"), delayedKey("queue", "mail"), deadKey("queue", "mail")} {
+		if want := "queue:{mail}:"; key[:len(want)] != want {
+			t.Errorf("key %s does not start with %s", key, want)
+		}
+	}
+}
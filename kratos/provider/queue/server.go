@@ -0,0 +1,461 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"kratos-project-template/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ transport.Server = (*Server)(nil)
+
+const (
+	defaultGroup             = "workers"
+	defaultConcurrency       = 10
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultPollInterval      = time.Second
+	// readBlock bounds XREADGROUP so fetchers notice shutdown promptly.
+	readBlock = 2 * time.Second
+	// batchSize bounds the number of entries moved per scheduler or reclaimer tick.
+	batchSize = 100
+)
+
+// promoteScript atomically moves due jobs from the delayed set to the queue stream.
+//
+// KEYS[1]: delayed set, KEYS[2]: queue stream
+// ARGV[1]: current time in milliseconds, ARGV[2]: batch size
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(due) do
+	redis.call('XADD', KEYS[2], '*', 'job', job)
+	redis.call('ZREM', KEYS[1], job)
+end
+return #due
+`)
+
+// BackoffFunc returns the delay before retrying a job after its n-th failed attempt.
+type BackoffFunc func(attempt int) time.Duration
+
+// DefaultBackoff is an exponential backoff starting at one second, capped at ten minutes,
+// with up to 20% random jitter.
+func DefaultBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := time.Second << min(attempt-1, 20)
+	if d > 10*time.Minute {
+		d = 10 * time.Minute
+	}
+	return d + time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+// Server is a job worker that consumes queues through a Redis consumer group.
+// It implements transport.Server so it starts and stops with the kratos application.
+type Server struct {
+	rdb               redis.UniversalClient
+	prefix            string
+	queues            []string
+	group             string
+	consumer          string
+	concurrency       int
+	visibilityTimeout time.Duration
+	pollInterval      time.Duration
+	backoff           BackoffFunc
+	log               *log.Helper
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	// lifecycle protects cancel and done, which are set by Start and read by Stop
+	lifecycle sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewServer creates a job worker server.
+//
+// Parameters:
+//   - rdb: The Redis client used to read jobs
+//   - cfg: Queue configuration (may be nil to use defaults)
+//   - logger: Logger instance for worker logging
+//
+// Returns:
+//   - *Server: A worker server; register handlers with Handle before starting it
+func NewServer(rdb redis.UniversalClient, cfg *conf.Data_Queue, logger log.Logger) *Server {
+	host, _ := os.Hostname()
+	s := &Server{
+		rdb:               rdb,
+		prefix:            defaultPrefix,
+		queues:            []string{DefaultQueue},
+		group:             defaultGroup,
+		consumer:          fmt.Sprintf("%s-%d", host, os.Getpid()),
+		concurrency:       defaultConcurrency,
+		visibilityTimeout: defaultVisibilityTimeout,
+		pollInterval:      defaultPollInterval,
+		backoff:           DefaultBackoff,
+		log:               log.NewHelper(log.With(logger, "module", "queue")),
+		handlers:          make(map[string]Handler),
+	}
+
+	if cfg.GetPrefix() != "" {
+		s.prefix = cfg.GetPrefix()
+	}
+	if len(cfg.GetQueues()) > 0 {
+		s.queues = cfg.GetQueues()
+	}
+	if cfg.GetGroup() != "" {
+		s.group = cfg.GetGroup()
+	}
+	if cfg.GetConcurrency() > 0 {
+		s.concurrency = int(cfg.GetConcurrency())
+	}
+	if cfg.GetVisibilityTimeout() != nil {
+		s.visibilityTimeout = cfg.GetVisibilityTimeout().AsDuration()
+	}
+	if cfg.GetPollInterval() != nil {
+		s.pollInterval = cfg.GetPollInterval().AsDuration()
+	}
+
+	return s
+}
+
+// SetBackoff replaces the retry backoff policy.
+func (s *Server) SetBackoff(fn BackoffFunc) {
+	if fn != nil {
+		s.backoff = fn
+	}
+}
+
+// Handle registers the handler for a job type.
+// Registering a handler for a type that already has one replaces it.
+func (s *Server) Handle(jobType string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = h
+}
+
+// HandleFunc registers a function as the handler for a job type.
+func (s *Server) HandleFunc(jobType string, fn func(ctx context.Context, job *Job) error) {
+	s.Handle(jobType, HandlerFunc(fn))
+}
+
+// Start runs the worker until Stop is called or ctx is cancelled.
+// It creates the consumer groups, then runs one fetcher per queue, the delayed-job
+// scheduler, the pending-job reclaimer and a pool of handler goroutines.
+//
+// Parameters:
+//   - ctx: The application context
+//
+// Returns:
+//   - error: Always nil; Redis errors are logged and retried so the worker survives outages
+func (s *Server) Start(ctx context.Context) error {
+	// In-flight handlers are allowed to finish during shutdown.
+	handlerCtx := context.WithoutCancel(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer close(done)
+
+	s.lifecycle.Lock()
+	s.cancel = cancel
+	s.done = done
+	s.lifecycle.Unlock()
+
+	for _, q := range s.queues {
+		// Groups missing because Redis is down are created again by fetch once it recovers.
+		if err := s.ensureGroup(ctx, q); err != nil {
+			s.log.Warnf("[queue] %v", err)
+		}
+	}
+	s.log.Infof("[queue] worker started: queues=%v, group=%s, consumer=%s, concurrency=%d",
+		s.queues, s.group, s.consumer, s.concurrency)
+
+	jobs := make(chan *Job)
+
+	var producers sync.WaitGroup
+	for _, q := range s.queues {
+		producers.Add(1)
+		go func(q string) {
+			defer producers.Done()
+			s.fetch(ctx, q, jobs)
+		}(q)
+	}
+	producers.Add(1)
+	go func() {
+		defer producers.Done()
+		s.maintain(ctx)
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				s.process(handlerCtx, job)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	producers.Wait()
+	close(jobs)
+	workers.Wait()
+
+	s.log.Infof("[queue] worker stopped")
+	return nil
+}
+
+// Stop signals the worker to stop and waits for in-flight jobs to finish or ctx to expire.
+// Jobs that were read but not processed stay pending and are reclaimed later.
+func (s *Server) Stop(ctx context.Context) error {
+	s.lifecycle.Lock()
+	cancel, done := s.cancel, s.done
+	s.lifecycle.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ensureGroup creates the consumer group of a queue, creating the stream if needed.
+func (s *Server) ensureGroup(ctx context.Context, queue string) error {
+	err := s.rdb.XGroupCreateMkStream(ctx, streamKey(s.prefix, queue), s.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.Wrapf(err, "create consumer group %s for queue %s", s.group, queue)
+	}
+	return nil
+}
+
+// fetch reads new entries of a queue and hands them to the worker pool.
+func (s *Server) fetch(ctx context.Context, queue string, jobs chan<- *Job) {
+	stream := streamKey(s.prefix, queue)
+
+	for ctx.Err() == nil {
+		res, err := s.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{stream, ">"},
+			Count:    int64(s.concurrency),
+			Block:    readBlock,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				_ = s.ensureGroup(ctx, queue)
+			} else {
+				s.log.Warnf("[queue] read queue %s failed: %v", queue, err)
+			}
+			s.sleep(ctx, s.pollInterval)
+			continue
+		}
+
+		for _, st := range res {
+			for _, msg := range st.Messages {
+				job, err := decodeMessage(queue, msg)
+				if err != nil {
+					s.log.Errorf("[queue] drop malformed entry %s: %v", msg.ID, err)
+					s.discard(ctx, queue, msg)
+					continue
+				}
+
+				select {
+				case jobs <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// maintain periodically promotes due delayed jobs and reclaims stale pending jobs.
+func (s *Server) maintain(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, q := range s.queues {
+			if err := s.promote(ctx, q); err != nil && ctx.Err() == nil {
+				s.log.Warnf("[queue] promote delayed jobs of queue %s failed: %v", q, err)
+			}
+			if err := s.reclaim(ctx, q); err != nil && ctx.Err() == nil {
+				s.log.Warnf("[queue] reclaim pending jobs of queue %s failed: %v", q, err)
+			}
+		}
+	}
+}
+
+// promote moves due jobs from the delayed set of a queue to its stream.
+func (s *Server) promote(ctx context.Context, queue string) error {
+	keys := []string{delayedKey(s.prefix, queue), streamKey(s.prefix, queue)}
+	for {
+		n, err := promoteScript.Run(ctx, s.rdb, keys, time.Now().UnixMilli(), batchSize).Int()
+		if err != nil {
+			return err
+		}
+		if n < batchSize {
+			return nil
+		}
+	}
+}
+
+// reclaim claims entries that stayed pending longer than the visibility timeout
+// and requeues them as a failed attempt, or dead-letters them if the budget is spent.
+func (s *Server) reclaim(ctx context.Context, queue string) error {
+	start := "0-0"
+	for {
+		msgs, next, err := s.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   streamKey(s.prefix, queue),
+			Group:    s.group,
+			Consumer: s.consumer,
+			MinIdle:  s.visibilityTimeout,
+			Start:    start,
+			Count:    batchSize,
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			job, err := decodeMessage(queue, msg)
+			if err != nil {
+				s.discard(ctx, queue, msg)
+				continue
+			}
+			s.log.Warnf("[queue] reclaimed job %s (type=%s) after visibility timeout", job.ID, job.Type)
+			s.fail(ctx, job, errors.New("visibility timeout exceeded"))
+		}
+
+		if next == "0-0" || next == "" {
+			return nil
+		}
+		start = next
+	}
+}
+
+// process runs the handler of a job and records the outcome.
+func (s *Server) process(ctx context.Context, job *Job) {
+	s.mu.RLock()
+	h, ok := s.handlers[job.Type]
+	s.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = SkipRetry(errors.Errorf("no handler registered for job type %q", job.Type))
+	} else {
+		err = s.invoke(ctx, h, job)
+	}
+
+	if err == nil {
+		s.ack(ctx, job)
+		return
+	}
+	s.log.Warnf("[queue] job %s (type=%s, attempt=%d) failed: %v", job.ID, job.Type, job.Attempt+1, err)
+	s.fail(ctx, job, err)
+}
+
+// invoke calls the handler and converts panics into errors.
+func (s *Server) invoke(ctx context.Context, h Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic: %v", r)
+		}
+	}()
+	return h.ProcessJob(ctx, job)
+}
+
+// ack removes a successfully processed job from the stream.
+func (s *Server) ack(ctx context.Context, job *Job) {
+	stream := streamKey(s.prefix, job.Queue)
+	pipe := s.rdb.TxPipeline()
+	pipe.XAck(ctx, stream, s.group, job.messageID)
+	pipe.XDel(ctx, stream, job.messageID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.log.Errorf("[queue] ack job %s failed: %v", job.ID, err)
+	}
+}
+
+// fail schedules a retry of the job or moves it to the dead-letter stream.
+func (s *Server) fail(ctx context.Context, job *Job, cause error) {
+	messageID := job.messageID
+	job.Attempt++
+	job.LastError = cause.Error()
+
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		s.log.Errorf("[queue] encode job %s failed: %v", job.ID, err)
+		return
+	}
+
+	stream := streamKey(s.prefix, job.Queue)
+	pipe := s.rdb.TxPipeline()
+	if errors.Is(cause, ErrSkipRetry) || job.Attempt > job.MaxRetries {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: deadKey(s.prefix, job.Queue),
+			Values: map[string]interface{}{"job": encoded},
+		})
+		s.log.Errorf("[queue] job %s (type=%s) moved to dead letters after %d attempts: %v",
+			job.ID, job.Type, job.Attempt, cause)
+	} else {
+		retryAt := time.Now().Add(s.backoff(job.Attempt))
+		pipe.ZAdd(ctx, delayedKey(s.prefix, job.Queue), redis.Z{
+			Score:  float64(retryAt.UnixMilli()),
+			Member: encoded,
+		})
+	}
+	pipe.XAck(ctx, stream, s.group, messageID)
+	pipe.XDel(ctx, stream, messageID)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.log.Errorf("[queue] record failure of job %s failed: %v", job.ID, err)
+	}
+}
+
+// discard acknowledges and deletes an entry that cannot be decoded.
+func (s *Server) discard(ctx context.Context, queue string, msg redis.XMessage) {
+	stream := streamKey(s.prefix, queue)
+	pipe := s.rdb.TxPipeline()
+	if len(msg.Values) > 0 {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: deadKey(s.prefix, queue), Values: msg.Values})
+	}
+	pipe.XAck(ctx, stream, s.group, msg.ID)
+	pipe.XDel(ctx, stream, msg.ID)
+	_, _ = pipe.Exec(ctx)
+}
+
+// sleep waits for d or until ctx is cancelled.
+func (s *Server) sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,237 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"kratos-project-template/provider/db"
+	"kratos-project-template/provider/db/driver"
+	_ "kratos-project-template/provider/db/sqlite3"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useDB registers an in-memory SQLite database with roles and settings tables as the
+// default connection until the test ends.
+func useDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	d, err := driver.Lookup("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormDB, err := d.Open(fmt.Sprintf("file:seed_%s?mode=memory&cache=shared", t.Name()), logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	for _, sql := range []string{
+		"CREATE TABLE roles (id INTEGER PRIMARY KEY, code TEXT, name TEXT)",
+		"CREATE TABLE settings (scope TEXT, name TEXT, value TEXT)",
+	} {
+		if err := gormDB.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(db.Set(db.DefaultName, gormDB, log.NewStdLogger(io.Discard)))
+	return gormDB
+}
+
+// useSet registers a Go seed set until the test ends.
+func useSet(t *testing.T, s Set) {
+	t.Helper()
+	Register(s)
+	t.Cleanup(func() {
+		registeredMu.Lock()
+		delete(registered, s.Name)
+		registeredMu.Unlock()
+	})
+}
+
+// roleNames returns the names of the roles by code.
+func roleNames(t *testing.T, gormDB *gorm.DB) map[string]string {
+	t.Helper()
+	var rows []Row
+	if err := gormDB.Table("roles").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]string, len(rows))
+	for _, r := range rows {
+		names[fmt.Sprint(r["code"])] = fmt.Sprint(r["name"])
+	}
+	return names
+}
+
+var seedFiles = fstest.MapFS{
+	"010_roles.yml": {Data: []byte(`
+tables:
+  - table: roles
+    keys: [code]
+    rows:
+      - code: admin
+        name: Administrator
+      - code: viewer
+        name: Viewer
+`)},
+	"020_settings.json": {Data: []byte(`{
+  "envs": ["dev"],
+  "tables": [{
+    "table": "settings",
+    "keys": ["scope", "name"],
+    "insert_only": true,
+    "rows": [{"scope": "mail", "name": "smtp", "value": {"host": "localhost", "port": 25}}]
+  }]
+}`)},
+	"README.md": {Data: []byte("not a seed file")},
+}
+
+func TestRun(t *testing.T) {
+	gormDB := useDB(t)
+	useSet(t, Set{Name: "030_go", Envs: []string{"dev"}, Run: func(ctx context.Context, tx *gorm.DB) error {
+		return Upsert(ctx, tx, "roles", []string{"code"}, false, Row{"code": "admin", "name": "Admin"})
+	}})
+	s, err := New(seedFiles, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	var names []string
+	for _, set := range s.Sets("prod") {
+		names = append(names, set.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"010_roles"}) {
+		t.Errorf("Sets(prod) = %v, want only the sets without envs", names)
+	}
+
+	results, err := s.Run(ctx, "dev")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []Result{
+		{Set: "010_roles", Inserted: 2},
+		{Set: "020_settings", Inserted: 1},
+		{Set: "030_go", Updated: 1},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Run() = %+v, want %+v", results, want)
+	}
+	if got := roleNames(t, gormDB); !reflect.DeepEqual(got, map[string]string{"admin": "Admin", "viewer": "Viewer"}) {
+		t.Errorf("roles = %v", got)
+	}
+	var value string
+	if err := gormDB.Table("settings").Where("name = ?", "smtp").Pluck("value", &value).Error; err != nil {
+		t.Fatal(err)
+	}
+	if value != `{"host":"localhost","port":25}` {
+		t.Errorf("nested value stored as %q, want JSON", value)
+	}
+
+	// Running the seeds again updates rows instead of duplicating them.
+	results, err = s.Run(ctx, "dev", "010_roles", "020_settings")
+	if err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	want = []Result{{Set: "010_roles", Updated: 2}, {Set: "020_settings", Unchanged: 1}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("second Run() = %+v, want %+v", results, want)
+	}
+	if got := roleNames(t, gormDB); len(got) != 2 || got["admin"] != "Administrator" {
+		t.Errorf("roles after the second run = %v", got)
+	}
+
+	if _, err := s.Run(ctx, "prod", "020_settings"); err == nil {
+		t.Error("Run() of a set of another environment did not fail")
+	}
+}
+
+func TestRunRollsBackFailedSet(t *testing.T) {
+	gormDB := useDB(t)
+	failed := errors.New("failed")
+	useSet(t, Set{Name: "010_ok", Run: func(ctx context.Context, tx *gorm.DB) error {
+		return Upsert(ctx, tx, "roles", []string{"code"}, false, Row{"code": "admin", "name": "Admin"})
+	}})
+	useSet(t, Set{Name: "020_failing", Run: func(ctx context.Context, tx *gorm.DB) error {
+		if err := Upsert(ctx, tx, "roles", []string{"code"}, false, Row{"code": "viewer", "name": "Viewer"}); err != nil {
+			return err
+		}
+		return failed
+	}})
+	s, err := New(nil, log.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Run(context.Background(), "")
+	if !errors.Is(err, failed) {
+		t.Fatalf("Run() error = %v, want the error of the set", err)
+	}
+	if len(results) != 1 || results[0].Set != "010_ok" {
+		t.Errorf("Run() = %+v, want the result of the applied set", results)
+	}
+	if got := roleNames(t, gormDB); !reflect.DeepEqual(got, map[string]string{"admin": "Admin"}) {
+		t.Errorf("roles = %v, want the failed set rolled back", got)
+	}
+}
+
+func TestUpsertErrors(t *testing.T) {
+	gormDB := useDB(t)
+	ctx := context.Background()
+	for _, code := range []string{"dup", "dup"} {
+		if err := gormDB.Exec("INSERT INTO roles (code) VALUES (?)", code).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		keys []string
+		row  Row
+		want string
+	}{
+		{name: "no keys", row: Row{"code": "a"}, want: "at least one key column"},
+		{name: "missing key", keys: []string{"code"}, row: Row{"name": "a"}, want: "missing key column code"},
+		{name: "ambiguous keys", keys: []string{"code"}, row: Row{"code": "dup", "name": "a"}, want: "match 2 rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Upsert(ctx, gormDB, "roles", tt.keys, false, tt.row)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Upsert() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{name: "unknown field", files: fstest.MapFS{"roles.yml": {Data: []byte("tabels: []")}}},
+		{name: "table without keys", files: fstest.MapFS{"roles.yml": {Data: []byte("tables: [{table: roles}]")}}},
+		{name: "duplicate set", files: fstest.MapFS{
+			"roles.yml":  {Data: []byte("tables: []")},
+			"roles.json": {Data: []byte(`{"tables": []}`)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.files, log.NewStdLogger(io.Discard)); err == nil {
+				t.Error("New() error = nil")
+			}
+		})
+	}
+}